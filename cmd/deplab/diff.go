@@ -0,0 +1,75 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFromImage        string
+	diffFromImageTar     string
+	diffFromMetadataFile string
+	diffToImage          string
+	diffToImageTar       string
+	diffToMetadataFile   string
+	diffOutput           string
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFromImage, "from-image", "", "image to compare from")
+	diffCmd.Flags().StringVar(&diffFromImageTar, "from-image-tar", "", "`path` to tarball of the image to compare from")
+	diffCmd.Flags().StringVar(&diffFromMetadataFile, "from-metadata-file", "", "`path` to a deplab metadata file to compare from")
+	diffCmd.Flags().StringVar(&diffToImage, "to-image", "", "image to compare to")
+	diffCmd.Flags().StringVar(&diffToImageTar, "to-image-tar", "", "`path` to tarball of the image to compare to")
+	diffCmd.Flags().StringVar(&diffToMetadataFile, "to-metadata-file", "", "`path` to a deplab metadata file to compare to")
+	diffCmd.Flags().StringVar(&diffOutput, "output", deplab.TextOutput, "`format` of the printed differences, one of: "+strings.Join(deplab.DiffOutputFormats, ", "))
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:     "diff",
+	Short:   "prints the dependency differences between two images",
+	Long:    `prints the packages added, removed and changed between two images, per package list, along with the changes of their base, git sources and archives. Each image is read like deplab inspect does, or from a metadata file written by deplab.`,
+	PreRunE: validateDiffFlags,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return deplab.RunDiff(common.DiffParams{
+			FromImage:            diffFromImage,
+			FromImageTarPath:     diffFromImageTar,
+			FromMetadataFilePath: diffFromMetadataFile,
+			ToImage:              diffToImage,
+			ToImageTarPath:       diffToImageTar,
+			ToMetadataFilePath:   diffToMetadataFile,
+			OutputFormat:         diffOutput,
+		})
+	},
+}
+
+func validateDiffFlags(cmd *cobra.Command, _ []string) error {
+	for _, side := range []string{"from", "to"} {
+		set := 0
+		for _, flag := range []string{"image", "image-tar", "metadata-file"} {
+			if isFlagSet(cmd, side+"-"+flag) {
+				set++
+			}
+		}
+
+		if set != 1 {
+			return fmt.Errorf("ERROR: requires exactly one of --%[1]s-image, --%[1]s-image-tar or --%[1]s-metadata-file", side)
+		}
+	}
+
+	if !isOneOf(diffOutput, deplab.DiffOutputFormats) {
+		return fmt.Errorf("ERROR: --output must be one of: %s", strings.Join(deplab.DiffOutputFormats, ", "))
+	}
+
+	return nil
+}
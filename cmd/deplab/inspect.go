@@ -5,15 +5,39 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	inspectOutput      string
+	verifyKeyPath      string
+	referrersOCILayout string
+)
+
 func init() {
 	inspectCmd.Flags().StringVarP(&inputImageTar, "image-tar", "p", "", "`path` to tarball of input image. Cannot be used with --image flag")
 	inspectCmd.Flags().StringVarP(&inputImage, "image", "i", "", "image which will be inspected by deplab. Cannot be used with --image-tar flag")
+	inspectCmd.Flags().StringVar(&inspectOutput, "output", deplab.JSONOutput, "`format` of the printed metadata, one of: "+strings.Join(deplab.OutputFormats, ", "))
+	inspectCmd.Flags().StringVar(&cyclonedxFormat, "cyclonedx-format", cyclonedx.JSONFormat, "`format` of the CycloneDX bill of materials printed with --output cyclonedx, one of: "+strings.Join(cyclonedx.Formats, ", "))
+
+	inspectCmd.Flags().StringSliceVar(&providerNames, "providers", []string{}, "comma separated `names` of the only providers to run, see deplab providers")
+	inspectCmd.Flags().StringSliceVar(&skipProviderNames, "skip-providers", []string{}, "comma separated `names` of providers not to run, see deplab providers")
+	inspectCmd.Flags().BoolVar(&dpkgLicenses, "dpkg-licenses", false, "read the licenses of debian packages from their /usr/share/doc/*/copyright files")
+	inspectCmd.Flags().BoolVar(&verifyPackages, "verify-packages", false, "report the files of debian packages which are missing or modified since they were installed")
+	inspectCmd.Flags().BoolVar(&unaccountedFiles, "unaccounted-files", false, "list the executables, shared libraries and archives which are not owned by any package")
+	inspectCmd.Flags().BoolVar(&attributeLayers, "attribute-layers", false, "record on each package the image layer which introduced it")
+	inspectCmd.Flags().StringVar(&baseImage, "base-image", "", "base image `reference` whose packages are reported apart from the application. Cannot be used with --base-image-tar flag")
+	inspectCmd.Flags().StringVar(&baseImageTar, "base-image-tar", "", "`path` to tarball of the base image whose packages are reported apart from the application. Cannot be used with --base-image flag")
+	inspectCmd.Flags().BoolVar(&annotatedBaseImage, "annotated-base-image", false, "pull the base image annotated on the image manifest to report its packages apart from the application. Cannot be used with --base-image or --base-image-tar flags")
+	inspectCmd.Flags().StringVar(&verifyKeyPath, "verify-key", "", "`path` to an ed25519 or ECDSA public key in PEM format to verify the signature of the label with")
+	inspectCmd.Flags().StringVar(&referrersOCILayout, "referrers-oci-layout", "", "`path` to an OCI image layout directory to discover the artifacts attached to the image in, next to its repository")
+	inspectCmd.Flags().StringVar(&warningsFilePath, "warnings-file", "", "write the warnings as JSON to a file at this `path`")
 
 	rootCmd.AddCommand(inspectCmd)
 }
@@ -21,10 +45,27 @@ func init() {
 var inspectCmd = &cobra.Command{
 	Use:     "inspect",
 	Short:   "prints the deplab label to stdout",
-	Long:    `prints the deplab "io.deplab.metadata" label in the config file of an OCI compatible image to stdout.  The label will be printed in json format.`,
+	Long:    `prints the deplab "io.deplab.metadata" label in the config file of an OCI compatible image to stdout.  The label will be printed in json format, or as an SPDX document or a CycloneDX bill of materials with --output spdx or --output cyclonedx.`,
 	PreRunE: validateInspectFlags,
 	RunE: func(_ *cobra.Command, _ []string) error {
-		return deplab.RunInspect(inputImage, inputImageTar)
+		return deplab.RunInspect(common.InspectParams{
+			InputImage:         inputImage,
+			InputImageTarPath:  inputImageTar,
+			OutputFormat:       inspectOutput,
+			CycloneDXFormat:    cyclonedxFormat,
+			Providers:          providerNames,
+			SkipProviders:      skipProviderNames,
+			DpkgLicenses:       dpkgLicenses,
+			VerifyPackages:     verifyPackages,
+			UnaccountedFiles:   unaccountedFiles,
+			AttributeLayers:    attributeLayers,
+			BaseImage:          baseImage,
+			BaseImageTarPath:   baseImageTar,
+			AnnotatedBaseImage: annotatedBaseImage,
+			VerifyKeyPath:      verifyKeyPath,
+			ReferrersOCILayout: referrersOCILayout,
+			WarningsFilePath:   warningsFilePath,
+		})
 	},
 }
 
@@ -35,5 +76,17 @@ func validateInspectFlags(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("ERROR: cannot accept both --image and --image-tar")
 	}
 
-	return nil
+	if !isOneOf(inspectOutput, deplab.OutputFormats) {
+		return fmt.Errorf("ERROR: --output must be one of: %s", strings.Join(deplab.OutputFormats, ", "))
+	}
+
+	if !isOneOf(cyclonedxFormat, cyclonedx.Formats) {
+		return fmt.Errorf("ERROR: --cyclonedx-format must be one of: %s", strings.Join(cyclonedx.Formats, ", "))
+	}
+
+	if err := validateBaseImageFlags(cmd); err != nil {
+		return err
+	}
+
+	return validateProviderFlags()
 }
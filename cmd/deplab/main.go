@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/vmware-tanzu/dependency-labeler/pkg/attestation"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
 
 	"github.com/spf13/cobra"
 )
@@ -20,12 +24,35 @@ var (
 	inputImage                string
 	inputImageTar             string
 	outputImageTar            string
+	outputImage               string
+	outputOCILayout           string
 	gitPaths                  []string
 	metadataFilePath          string
 	dpkgFilePath              string
+	spdxFilePath              string
+	cyclonedxFilePath         string
+	cyclonedxFormat           string
+	attestationFilePath       string
+	attestationPredicate      string
+	attach                    []string
+	attachRepository          string
+	attachOCILayout           string
 	tag                       string
 	additionalSourceUrls      []string
 	ignoreValidationErrors    bool
+	dpkgLicenses              bool
+	verifyPackages            bool
+	unaccountedFiles          bool
+	attributeLayers           bool
+	baseImage                 string
+	baseImageTar              string
+	annotatedBaseImage        bool
+	signingKeyPath            string
+	compressLabel             bool
+	compressLabelThreshold    int
+	providerNames             []string
+	skipProviderNames         []string
+	warningsFilePath          string
 )
 
 func init() {
@@ -33,12 +60,35 @@ func init() {
 	rootCmd.Flags().StringVarP(&inputImage, "image", "i", "", "image which will be analysed by deplab. Cannot be used with --image-tar flag")
 	rootCmd.Flags().StringVarP(&inputImageTar, "image-tar", "p", "", "`path` to tarball of input image. Cannot be used with --image flag")
 	rootCmd.Flags().StringVarP(&outputImageTar, "output-tar", "o", "", "`path` to write a tarball of the image to")
+	rootCmd.Flags().StringVar(&outputImage, "output-image", "", "image `reference` to push the labelled image to")
+	rootCmd.Flags().StringVar(&outputOCILayout, "output-oci-layout", "", "`path` to an OCI image layout directory to write the image to")
 	rootCmd.Flags().StringVarP(&metadataFilePath, "metadata-file", "m", "", "write metadata to this file at the given `path`")
 	rootCmd.Flags().StringVarP(&dpkgFilePath, "dpkg-file", "d", "", "write dpkg list metadata in (modified) 'dpkg -l' format to a file at this `path`")
+	rootCmd.Flags().StringVar(&spdxFilePath, "spdx-file", "", "write metadata as an SPDX 2.3 JSON document to a file at this `path`")
+	rootCmd.Flags().StringVar(&cyclonedxFilePath, "cyclonedx-file", "", "write metadata as a CycloneDX 1.5 bill of materials to a file at this `path`")
+	rootCmd.Flags().StringVar(&cyclonedxFormat, "cyclonedx-format", cyclonedx.JSONFormat, "`format` of the CycloneDX bill of materials, one of: "+strings.Join(cyclonedx.Formats, ", "))
+	rootCmd.Flags().StringVar(&attestationFilePath, "attestation-file", "", "write an in-toto attestation of the labelled image to a file at this `path`, in a DSSE envelope when --signing-key is given")
+	rootCmd.Flags().StringVar(&attestationPredicate, "attestation-predicate", attestation.SLSAPredicate, "`predicate` of the attestation, one of: "+strings.Join(attestation.Predicates, ", "))
+	rootCmd.Flags().StringSliceVar(&attach, "attach", []string{}, "comma separated `kinds` of artifacts to attach to the input image, which is left untouched, one of: "+strings.Join(deplab.ArtifactKinds, ", "))
+	rootCmd.Flags().StringVar(&attachRepository, "attach-repository", "", "`repository` to push the artifacts of --attach to, defaults to the repository of --image")
+	rootCmd.Flags().StringVar(&attachOCILayout, "attach-oci-layout", "", "`path` to an OCI image layout directory to write the artifacts of --attach to")
 	rootCmd.Flags().StringVarP(&tag, "tag", "t", "", "tags the output image")
 	rootCmd.Flags().StringArrayVarP(&additionalSourceUrls, "additional-source-url", "u", []string{}, "`url` to the source of an added dependency")
 	rootCmd.Flags().StringArrayVarP(&additionalSourceFilePaths, "additional-sources-file", "a", []string{}, "`path` to file describing additional sources")
 	rootCmd.Flags().BoolVar(&ignoreValidationErrors, "ignore-validation-errors", false, "Set flag to ignore validation errors")
+	rootCmd.Flags().StringSliceVar(&providerNames, "providers", []string{}, "comma separated `names` of the only providers to run, see deplab providers")
+	rootCmd.Flags().StringSliceVar(&skipProviderNames, "skip-providers", []string{}, "comma separated `names` of providers not to run, see deplab providers")
+	rootCmd.Flags().BoolVar(&dpkgLicenses, "dpkg-licenses", false, "read the licenses of debian packages from their /usr/share/doc/*/copyright files")
+	rootCmd.Flags().BoolVar(&verifyPackages, "verify-packages", false, "report the files of debian packages which are missing or modified since they were installed")
+	rootCmd.Flags().BoolVar(&unaccountedFiles, "unaccounted-files", false, "list the executables, shared libraries and archives which are not owned by any package")
+	rootCmd.Flags().BoolVar(&attributeLayers, "attribute-layers", false, "record on each package the image layer which introduced it")
+	rootCmd.Flags().StringVar(&baseImage, "base-image", "", "base image `reference` whose packages are reported apart from the application. Cannot be used with --base-image-tar flag")
+	rootCmd.Flags().StringVar(&baseImageTar, "base-image-tar", "", "`path` to tarball of the base image whose packages are reported apart from the application. Cannot be used with --base-image flag")
+	rootCmd.Flags().BoolVar(&annotatedBaseImage, "annotated-base-image", false, "pull the base image annotated on the image manifest to report its packages apart from the application. Cannot be used with --base-image or --base-image-tar flags")
+	rootCmd.Flags().StringVar(&signingKeyPath, "signing-key", "", "`path` to an ed25519 or ECDSA private key in PEM format to sign the label with")
+	rootCmd.Flags().BoolVar(&compressLabel, "compress-label", false, "write the label gzipped and base64 encoded under "+metadata.CompressedLabelName+" when its JSON is longer than --compress-label-threshold")
+	rootCmd.Flags().IntVar(&compressLabelThreshold, "compress-label-threshold", 65536, "size in `bytes` of the JSON of the label above which --compress-label compresses it")
+	rootCmd.Flags().StringVar(&warningsFilePath, "warnings-file", "", "write the warnings as JSON to a file at this `path`")
 }
 
 var rootCmd = &cobra.Command{
@@ -61,10 +111,62 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("ERROR: cannot accept both --image and --image-tar")
 	}
 
-	if !isFlagSet(cmd, "metadata-file") && !isFlagSet(cmd, "dpkg-file") && !isFlagSet(cmd, "output-tar") {
-		return fmt.Errorf("ERROR: requires one of --metadata-file, --dpkg-file, or --output-tar")
+	if !isFlagSet(cmd, "metadata-file") && !isFlagSet(cmd, "dpkg-file") && !isFlagSet(cmd, "spdx-file") && !isFlagSet(cmd, "cyclonedx-file") && !isFlagSet(cmd, "attestation-file") && len(attach) == 0 && !isFlagSet(cmd, "output-tar") && !isFlagSet(cmd, "output-image") && !isFlagSet(cmd, "output-oci-layout") {
+		return fmt.Errorf("ERROR: requires one of --metadata-file, --dpkg-file, --spdx-file, --cyclonedx-file, --attestation-file, --attach, --output-tar, --output-image, or --output-oci-layout")
 	}
 
+	if !isOneOf(cyclonedxFormat, cyclonedx.Formats) {
+		return fmt.Errorf("ERROR: --cyclonedx-format must be one of: %s", strings.Join(cyclonedx.Formats, ", "))
+	}
+
+	if !isOneOf(attestationPredicate, attestation.Predicates) {
+		return fmt.Errorf("ERROR: --attestation-predicate must be one of: %s", strings.Join(attestation.Predicates, ", "))
+	}
+
+	if compressLabelThreshold < 0 {
+		return fmt.Errorf("ERROR: --compress-label-threshold cannot be negative")
+	}
+
+	if err := validateAttachFlags(cmd); err != nil {
+		return err
+	}
+
+	if err := validateBaseImageFlags(cmd); err != nil {
+		return err
+	}
+
+	return validateProviderFlags()
+}
+
+func validateAttachFlags(cmd *cobra.Command) error {
+	for _, kind := range attach {
+		if !isOneOf(kind, deplab.ArtifactKinds) {
+			return fmt.Errorf("ERROR: unknown artifact %s, --attach must be one of: %s", kind, strings.Join(deplab.ArtifactKinds, ", "))
+		}
+	}
+
+	if len(attach) > 0 && isFlagSet(cmd, "image-tar") && !isFlagSet(cmd, "attach-repository") && !isFlagSet(cmd, "attach-oci-layout") {
+		return fmt.Errorf("ERROR: --attach with --image-tar requires one of --attach-repository or --attach-oci-layout")
+	}
+	return nil
+}
+
+func validateBaseImageFlags(cmd *cobra.Command) error {
+	if isFlagSet(cmd, "base-image") && isFlagSet(cmd, "base-image-tar") {
+		return fmt.Errorf("ERROR: cannot accept both --base-image and --base-image-tar")
+	}
+	if annotatedBaseImage && (isFlagSet(cmd, "base-image") || isFlagSet(cmd, "base-image-tar")) {
+		return fmt.Errorf("ERROR: cannot accept --annotated-base-image with --base-image or --base-image-tar")
+	}
+	return nil
+}
+
+func validateProviderFlags() error {
+	for _, name := range append(append([]string{}, providerNames...), skipProviderNames...) {
+		if !isOneOf(name, deplab.ProviderNames()) {
+			return fmt.Errorf("ERROR: unknown provider %s, must be one of: %s", name, strings.Join(deplab.ProviderNames(), ", "))
+		}
+	}
 	return nil
 }
 
@@ -78,6 +180,15 @@ func isFlagSet(cmd *cobra.Command, flagName string) bool {
 	return flag != ""
 }
 
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func run(_ *cobra.Command, _ []string) {
 	err := deplab.Run(
 		common.RunParams{
@@ -86,11 +197,34 @@ func run(_ *cobra.Command, _ []string) {
 			GitPaths:                  gitPaths,
 			Tag:                       tag,
 			OutputImageTar:            outputImageTar,
+			OutputImage:               outputImage,
+			OutputOCILayout:           outputOCILayout,
 			MetadataFilePath:          metadataFilePath,
 			DpkgFilePath:              dpkgFilePath,
+			SPDXFilePath:              spdxFilePath,
+			CycloneDXFilePath:         cyclonedxFilePath,
+			CycloneDXFormat:           cyclonedxFormat,
+			AttestationFilePath:       attestationFilePath,
+			AttestationPredicate:      attestationPredicate,
+			Attach:                    attach,
+			AttachRepository:          attachRepository,
+			AttachOCILayout:           attachOCILayout,
 			AdditionalSourceUrls:      additionalSourceUrls,
 			AdditionalSourceFilePaths: additionalSourceFilePaths,
 			IgnoreValidationErrors:    ignoreValidationErrors,
+			DpkgLicenses:              dpkgLicenses,
+			VerifyPackages:            verifyPackages,
+			UnaccountedFiles:          unaccountedFiles,
+			AttributeLayers:           attributeLayers,
+			BaseImage:                 baseImage,
+			BaseImageTarPath:          baseImageTar,
+			AnnotatedBaseImage:        annotatedBaseImage,
+			SigningKeyPath:            signingKeyPath,
+			CompressLabel:             compressLabel,
+			CompressLabelThreshold:    compressLabelThreshold,
+			Providers:                 providerNames,
+			SkipProviders:             skipProviderNames,
+			WarningsFilePath:          warningsFilePath,
 		})
 	if err != nil {
 		log.Fatalf("deplab failed to run. %s\n", err)
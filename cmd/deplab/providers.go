@@ -0,0 +1,51 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+}
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "lists the providers which can be selected with --providers and --skip-providers",
+	Long:  `lists the providers, in the order they run, with the commands which run them, what they need from the image and what they report.`,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCOMMANDS\tREQUIRES\tDESCRIPTION")
+
+		for _, p := range deplab.Providers {
+			var commands []string
+			if p.Generate {
+				commands = append(commands, "deplab")
+			}
+			if p.Inspect {
+				commands = append(commands, "inspect")
+			}
+
+			var requirements []string
+			for _, requirement := range p.Requirements {
+				requirements = append(requirements, string(requirement))
+			}
+			if len(requirements) == 0 {
+				requirements = []string{"-"}
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, strings.Join(commands, ","), strings.Join(requirements, ","), p.Description)
+		}
+
+		return w.Flush()
+	},
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanImage        string
+	scanImageTar     string
+	scanMetadataFile string
+	scanDatabase     string
+	scanOutput       string
+	scanFailOn       string
+)
+
+func init() {
+	scanCmd.Flags().StringVar(&scanImage, "image", "", "image to scan")
+	scanCmd.Flags().StringVar(&scanImageTar, "image-tar", "", "`path` to tarball of the image to scan")
+	scanCmd.Flags().StringVar(&scanMetadataFile, "metadata-file", "", "`path` to a deplab metadata file to scan")
+	scanCmd.Flags().StringVar(&scanDatabase, "db", "", "`path` to the directory of the advisory database")
+	scanCmd.Flags().StringVar(&scanOutput, "output", deplab.TableOutput, "`format` of the printed findings, one of: "+strings.Join(deplab.ScanOutputFormats, ", "))
+	scanCmd.Flags().StringVar(&scanFailOn, "fail-on", "", "exit with an error when finding vulnerabilities of this `severity` or higher, one of: "+strings.Join(severityNames(), ", "))
+
+	rootCmd.AddCommand(scanCmd)
+}
+
+var scanCmd = &cobra.Command{
+	Use:     "scan",
+	Short:   "prints the known vulnerabilities of the dependencies of an image",
+	Long:    `prints the vulnerabilities of the packages of an image found in a local advisory database of OSV records, debian security tracker exports and OVAL definitions, comparing versions with the rules of each distribution. The image is read like deplab inspect does, or from a metadata file written by deplab.`,
+	PreRunE: validateScanFlags,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cmd.SilenceUsage = true
+
+		return deplab.RunScan(common.ScanParams{
+			Image:            scanImage,
+			ImageTarPath:     scanImageTar,
+			MetadataFilePath: scanMetadataFile,
+			DatabasePath:     scanDatabase,
+			OutputFormat:     scanOutput,
+			FailOn:           scanFailOn,
+		})
+	},
+}
+
+func validateScanFlags(cmd *cobra.Command, _ []string) error {
+	set := 0
+	for _, flag := range []string{"image", "image-tar", "metadata-file"} {
+		if isFlagSet(cmd, flag) {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("ERROR: requires exactly one of --image, --image-tar or --metadata-file")
+	}
+
+	if !isFlagSet(cmd, "db") {
+		return fmt.Errorf("ERROR: requires --db")
+	}
+
+	if !isOneOf(scanOutput, deplab.ScanOutputFormats) {
+		return fmt.Errorf("ERROR: --output must be one of: %s", strings.Join(deplab.ScanOutputFormats, ", "))
+	}
+
+	if scanFailOn != "" && !isOneOf(scanFailOn, severityNames()) {
+		return fmt.Errorf("ERROR: --fail-on must be one of: %s", strings.Join(severityNames(), ", "))
+	}
+
+	return nil
+}
+
+func severityNames() []string {
+	var names []string
+	for _, severity := range scan.Severities {
+		names = append(names, string(severity))
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/deplab"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyImage        string
+	verifyImageTar     string
+	verifyMetadataFile string
+	verifyPolicyFile   string
+	verifyOutput       string
+)
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyImage, "image", "", "image to verify")
+	verifyCmd.Flags().StringVar(&verifyImageTar, "image-tar", "", "`path` to tarball of the image to verify")
+	verifyCmd.Flags().StringVar(&verifyMetadataFile, "metadata-file", "", "`path` to a deplab metadata file to verify")
+	verifyCmd.Flags().StringVar(&verifyPolicyFile, "policy", "", "`path` to the YAML policy file")
+	verifyCmd.Flags().StringVar(&verifyOutput, "output", deplab.TextOutput, "`format` of the printed violations, one of: "+strings.Join(deplab.VerifyOutputFormats, ", "))
+
+	rootCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:     "verify",
+	Short:   "verifies the dependencies of an image against a policy",
+	Long:    `prints the violations of a YAML policy by the dependencies of an image: denied packages and versions, allowed and denied licenses, a required git dependency, a required base distribution and a maximum image age. The image is read like deplab inspect does, or from a metadata file written by deplab. Exits with an error when the policy is violated.`,
+	PreRunE: validateVerifyFlags,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cmd.SilenceUsage = true
+
+		return deplab.RunVerify(common.VerifyParams{
+			Image:            verifyImage,
+			ImageTarPath:     verifyImageTar,
+			MetadataFilePath: verifyMetadataFile,
+			PolicyFilePath:   verifyPolicyFile,
+			OutputFormat:     verifyOutput,
+		})
+	},
+}
+
+func validateVerifyFlags(cmd *cobra.Command, _ []string) error {
+	set := 0
+	for _, flag := range []string{"image", "image-tar", "metadata-file"} {
+		if isFlagSet(cmd, flag) {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("ERROR: requires exactly one of --image, --image-tar or --metadata-file")
+	}
+
+	if !isFlagSet(cmd, "policy") {
+		return fmt.Errorf("ERROR: requires --policy")
+	}
+
+	if !isOneOf(verifyOutput, deplab.VerifyOutputFormats) {
+		return fmt.Errorf("ERROR: --output must be one of: %s", strings.Join(deplab.VerifyOutputFormats, ", "))
+	}
+
+	return nil
+}
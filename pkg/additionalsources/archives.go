@@ -5,7 +5,6 @@ package additionalsources
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
@@ -14,6 +13,7 @@ import (
 	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
 )
 
 type HTTPHeadFn func(url string) (resp *http.Response, err error)
@@ -28,7 +28,7 @@ func ArchiveUrlProvider(_ image.Image, params common.RunParams, md metadata.Meta
 		if !ok {
 			errMsg := fmt.Sprintf("failed to validate additional source url: %s", message)
 			if params.IgnoreValidationErrors {
-				log.Printf("warning: %s", errMsg) //TODO return warning?
+				params.Warnings.Add(warnings.InvalidSourceURL, archiveURL, errMsg)
 			} else {
 				return metadata.Metadata{}, fmt.Errorf("error: %s", errMsg)
 			}
@@ -46,7 +46,7 @@ func AdditionalSourcesProvider(_ image.Image, params common.RunParams, md metada
 		if err != nil {
 			errMsg := fmt.Sprintf("could not parse additional sources file: %s, %s", additionalSourcesFile, err)
 			if params.IgnoreValidationErrors {
-				log.Printf("warning: %s", errMsg) //TODO want to return warnings, and deal with logging in caller
+				params.Warnings.Add(warnings.InvalidSourcesFile, additionalSourcesFile, errMsg)
 			} else {
 				return metadata.Metadata{}, fmt.Errorf("error: %s", errMsg)
 			}
@@ -54,7 +54,7 @@ func AdditionalSourcesProvider(_ image.Image, params common.RunParams, md metada
 		archiveUrls = append(archiveUrls, archiveUrlsFromAdditionalSourcesFile...)
 		md.Dependencies = append(md.Dependencies, gitVcsFromAdditionalSourcesFile...)
 	}
-	return ArchiveUrlProvider(nil, common.RunParams{AdditionalSourceUrls: archiveUrls}, md)
+	return ArchiveUrlProvider(nil, common.RunParams{AdditionalSourceUrls: archiveUrls, Warnings: params.Warnings}, md)
 }
 
 func BuildArchiveDependencyMetadata(archiveUrl string) (metadata.Dependency, error) {
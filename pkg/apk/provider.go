@@ -0,0 +1,178 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package apk
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	InstalledDBPath  = "/lib/apk/db/installed"
+	RepositoriesPath = "/etc/apk/repositories"
+)
+
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	packages := getApkPackages(dli, params.Warnings)
+
+	if len(packages) != 0 {
+		sourceMetadata := metadata.ApkPackageListSourceMetadata{
+			Packages:     packages,
+			Repositories: getApkRepositories(dli),
+		}
+
+		version, err := common.Digest(sourceMetadata)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not get digest for source metadata: %w", err)
+		}
+
+		md.Dependencies = append(md.Dependencies, metadata.Dependency{
+			Type: metadata.ApkPackageListSourceType,
+			Source: metadata.Source{
+				Type: "inline",
+				Version: map[string]interface{}{
+					"sha256": version,
+				},
+				Metadata: sourceMetadata,
+			},
+		})
+	}
+	return md, nil
+}
+
+func getApkRepositories(dli image.Image) []string {
+	repositories := []string{}
+
+	content, err := dli.GetFileContent(RepositoriesPath)
+	if err != nil {
+		// in this case an empty or non-existent file is not an error
+		return repositories
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") && len(trimmed) != 0 {
+			repositories = append(repositories, trimmed)
+		}
+	}
+
+	collator := collate.New(language.BritishEnglish)
+	sort.Slice(repositories, func(i, j int) bool {
+		return collator.CompareString(repositories[i], repositories[j]) < 0
+	})
+
+	return repositories
+}
+
+func getApkPackages(dli image.Image, collector *warnings.Collector) []metadata.ApkPackage {
+	var packages []metadata.ApkPackage
+
+	installedDBString, err := dli.GetFileContent(InstalledDBPath)
+	if err != nil {
+		// in this case an empty or non-existent file is not an error
+		installedDBString = ""
+	}
+
+	for _, entryString := range strings.Split(installedDBString, "\n\n") {
+		if !isRecord(entryString) {
+			continue
+		}
+
+		entry, err := ParseInstalledDBEntry(entryString)
+		if err != nil {
+			collector.Add(warnings.InvalidPackageEntry, InstalledDBPath, fmt.Sprintf("skipping entry: %s", err))
+			continue
+		}
+		packages = append(packages, entry)
+	}
+
+	collator := collate.New(language.BritishEnglish)
+	sort.Slice(packages, func(i, j int) bool {
+		return collator.CompareString(packages[i].Package, packages[j].Package) < 0
+	})
+
+	return packages
+}
+
+// ParseInstalledDBEntry parses a package entry of the apk installed database,
+// made of single letter keys, see https://wiki.alpinelinux.org/wiki/Apk_spec
+func ParseInstalledDBEntry(content string) (metadata.ApkPackage, error) {
+	pkg := metadata.ApkPackage{}
+
+	for _, inputLine := range strings.Split(content, "\n") {
+		if len(inputLine) < 2 || inputLine[1] != ':' {
+			continue
+		}
+		value := strings.TrimSpace(inputLine[2:])
+		switch inputLine[0] {
+		case 'P':
+			pkg.Package = value
+		case 'V':
+			pkg.Version = value
+		case 'A':
+			pkg.Architecture = value
+		case 'L':
+			pkg.License = value
+		case 'o':
+			pkg.Origin = value
+		case 'c':
+			pkg.Commit = value
+		case 'm':
+			pkg.Maintainer = value
+		default:
+			continue
+		}
+	}
+
+	if pkg.Package == "" {
+		return pkg, fmt.Errorf("invalid installed database entry")
+	}
+
+	return pkg, nil
+}
+
+// OwnedFiles lists the paths of the files installed by the packages of the
+// installed database, given as R: lines following the F: line of their
+// directory
+func OwnedFiles(installedDB string) []string {
+	var files []string
+	dir := "/"
+	for _, line := range strings.Split(installedDB, "\n") {
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		switch line[0] {
+		case 'P':
+			dir = "/"
+		case 'F':
+			dir = path.Join("/", strings.TrimSpace(line[2:]))
+		case 'R':
+			files = append(files, path.Join(dir, strings.TrimSpace(line[2:])))
+		}
+	}
+	return files
+}
+
+// isRecord tells whether an entry holds any of the single letter keys of the
+// installed database, rather than just blank lines
+func isRecord(entry string) bool {
+	for _, line := range strings.Split(entry, "\n") {
+		if len(line) > 1 && line[1] == ':' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package apk_test
+
+import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/apk"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+const installedDB = `C:Q1uf0cVKfvA+qgr3JEb4CK+0GaDiI=
+P:zlib
+V:1.2.11-r3
+A:x86_64
+S:51398
+I:110592
+T:A compression/decompression Library
+U:https://zlib.net/
+L:Zlib
+o:zlib
+m:Natanael Copa <ncopa@alpinelinux.org>
+t:1578054236
+c:388a4fb3640f8ccbd18e105df3ad741dca4247e1
+D:so:libc.musl-x86_64.so.1
+p:so:libz.so.1=1.2.11
+F:lib
+R:libz.so.1
+a:0:0:755
+Z:Q1sWH5vfnZ6Gt5ve3XH7S8TqkzfeE=
+
+C:Q1VOxHlMHHLT/xr8ZzW8+tN2bXNGk=
+P:musl
+V:1.1.24-r2
+A:x86_64
+S:377011
+I:614400
+T:the musl c library (libc) implementation
+U:https://musl.libc.org/
+L:MIT
+o:musl
+m:Timo Teräs <timo.teras@iki.fi>
+t:1584790550
+c:bfe5a5ecb0ed2a1bcd4e8a5f0b5a1b1c0f1a6b22
+F:lib
+R:ld-musl-x86_64.so.1
+
+C:Q1tdDmzvB2IJSHxBvSgT3GDoPxbYw=
+P:libcrypto1.1
+V:1.1.1g-r0
+A:x86_64
+L:OpenSSL
+o:openssl
+m:Timo Teras <timo.teras@iki.fi>
+c:a8c2c5bd0e8d4b3c7e27b59b8e39cb0f1d30c5d8
+`
+
+var _ = Describe("Apk", func() {
+	Describe("ParseInstalledDBEntry", func() {
+		It("parses an installed database entry", func() {
+			Expect(ParseInstalledDBEntry(`C:Q1uf0cVKfvA+qgr3JEb4CK+0GaDiI=
+P:zlib
+V:1.2.11-r3
+A:x86_64
+T:A compression/decompression Library
+L:Zlib
+o:zlib
+m:Natanael Copa <ncopa@alpinelinux.org>
+c:388a4fb3640f8ccbd18e105df3ad741dca4247e1
+F:lib
+R:libz.so.1`)).To(Equal(metadata.ApkPackage{
+				Package:      "zlib",
+				Version:      "1.2.11-r3",
+				Architecture: "x86_64",
+				License:      "Zlib",
+				Origin:       "zlib",
+				Commit:       "388a4fb3640f8ccbd18e105df3ad741dca4247e1",
+				Maintainer:   "Natanael Copa <ncopa@alpinelinux.org>",
+			}))
+		})
+
+		It("returns error if entry does not contain a package name", func() {
+			_, err := ParseInstalledDBEntry("\n")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("OwnedFiles", func() {
+		It("lists the files of the packages under their directories", func() {
+			Expect(OwnedFiles(`P:musl
+V:1.1.24-r2
+F:lib
+R:ld-musl-x86_64.so.1
+R:libc.musl-x86_64.so.1
+
+P:busybox
+F:bin
+R:busybox
+F:etc
+R:securetty
+`)).To(Equal([]string{
+				"/lib/ld-musl-x86_64.so.1",
+				"/lib/libc.musl-x86_64.so.1",
+				"/bin/busybox",
+				"/etc/securetty",
+			}))
+		})
+	})
+
+	Describe("Provider", func() {
+		Context("when the image has an installed database", func() {
+			It("adds the sorted list of packages and the repositories", func() {
+				md, err := Provider(test_utils.FakeImage{Files: map[string]string{
+					InstalledDBPath: installedDB,
+					RepositoriesPath: `http://dl-cdn.alpinelinux.org/alpine/v3.11/main
+# http://dl-cdn.alpinelinux.org/alpine/edge/testing
+
+http://dl-cdn.alpinelinux.org/alpine/v3.11/community
+`,
+				}}, common.RunParams{}, metadata.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				apk, ok := test_utils.SelectApkDependency(md.Dependencies)
+				Expect(ok).To(BeTrue())
+				Expect(apk.Source.Type).To(Equal("inline"))
+				Expect(apk.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+
+				sourceMetadata := apk.Source.Metadata.(metadata.ApkPackageListSourceMetadata)
+				Expect(sourceMetadata.Repositories).To(Equal([]string{
+					"http://dl-cdn.alpinelinux.org/alpine/v3.11/community",
+					"http://dl-cdn.alpinelinux.org/alpine/v3.11/main",
+				}))
+
+				var names []string
+				for _, p := range sourceMetadata.Packages {
+					names = append(names, p.Package)
+				}
+				Expect(names).To(Equal([]string{"libcrypto1.1", "musl", "zlib"}))
+			})
+
+			It("reports no repositories when the repositories file is missing", func() {
+				md, err := Provider(test_utils.FakeImage{Files: map[string]string{
+					InstalledDBPath: installedDB,
+				}}, common.RunParams{}, metadata.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				apk, ok := test_utils.SelectApkDependency(md.Dependencies)
+				Expect(ok).To(BeTrue())
+				Expect(apk.Source.Metadata.(metadata.ApkPackageListSourceMetadata).Repositories).To(BeEmpty())
+			})
+
+			It("warns about the entries without a package name", func() {
+				collector := warnings.NewCollector()
+
+				md, err := Provider(test_utils.FakeImage{Files: map[string]string{
+					InstalledDBPath: installedDB + "\nV:1.0-r0\nA:x86_64\n\n",
+				}}, common.RunParams{Warnings: collector}, metadata.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				apk, ok := test_utils.SelectApkDependency(md.Dependencies)
+				Expect(ok).To(BeTrue())
+				Expect(apk.Source.Metadata.(metadata.ApkPackageListSourceMetadata).Packages).To(HaveLen(3))
+
+				Expect(collector.Warnings()).To(ConsistOf(warnings.Warning{
+					Code:    warnings.InvalidPackageEntry,
+					Message: "skipping entry: invalid installed database entry",
+					Subject: InstalledDBPath,
+				}))
+			})
+		})
+
+		Context("when the image has no installed database", func() {
+			It("does not modify the metadata content", func() {
+				md, err := Provider(test_utils.FakeImage{}, common.RunParams{}, metadata.Metadata{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(md).To(Equal(metadata.Metadata{}))
+			})
+		})
+	})
+})
@@ -0,0 +1,149 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// emptyConfig is the config of artifacts, which have none
+var emptyConfig = []byte("{}")
+
+// Artifact is a document attached to an image, e.g. its metadata, a bill of
+// materials or an attestation
+type Artifact struct {
+	Type    string
+	Content []byte
+}
+
+// Store holds artifacts next to the images they refer to: the repository of
+// a registry or an OCI image layout
+type Store interface {
+	// Attach stores the artifacts with subject as their subject
+	Attach(subject Descriptor, artifacts []Artifact, created time.Time) error
+	// Referrers lists the artifacts whose subject has the digest
+	Referrers(digest string) ([]Descriptor, error)
+	// Fetch reads the content of the artifact of the manifest descriptor
+	Fetch(manifest Descriptor) ([]byte, error)
+}
+
+// blob is content stored under its digest
+type blob struct {
+	Descriptor
+	content []byte
+}
+
+// packed is an artifact ready to store: its manifest and the blobs it refers
+// to
+type packed struct {
+	manifest blob
+	blobs    []blob
+	// referrer is the descriptor of the manifest in the referrers of the
+	// subject
+	referrer Descriptor
+}
+
+// pack builds the manifest of the artifact, with an empty config and its
+// content as single layer
+func pack(a Artifact, subject Descriptor, created time.Time) (packed, error) {
+	config := newBlob(EmptyMediaType, emptyConfig)
+	content := newBlob(a.Type, a.Content)
+	annotations := map[string]string{CreatedAnnotation: created.UTC().Format(time.RFC3339)}
+
+	raw, err := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  a.Type,
+		Config:        config.Descriptor,
+		Layers:        []Descriptor{content.Descriptor},
+		Subject:       &subject,
+		Annotations:   annotations,
+	})
+	if err != nil {
+		return packed{}, fmt.Errorf("could not encode artifact manifest: %w", err)
+	}
+	manifest := newBlob(ManifestMediaType, raw)
+
+	referrer := manifest.Descriptor
+	referrer.ArtifactType = a.Type
+	referrer.Annotations = annotations
+
+	return packed{
+		manifest: manifest,
+		blobs:    []blob{config, content},
+		referrer: referrer,
+	}, nil
+}
+
+// asReferrer completes the descriptor of an artifact manifest with its
+// artifact type and annotations, when the subject of the manifest has the
+// digest
+func asReferrer(descriptor Descriptor, raw []byte, digest string) (Descriptor, bool) {
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil || m.Subject == nil || m.Subject.Digest != digest {
+		return Descriptor{}, false
+	}
+
+	descriptor.ArtifactType = m.ArtifactType
+	if descriptor.ArtifactType == "" {
+		descriptor.ArtifactType = m.Config.MediaType
+	}
+	descriptor.Annotations = m.Annotations
+	return descriptor, true
+}
+
+// content is the descriptor of the single layer of an artifact manifest
+func content(raw []byte) (Descriptor, error) {
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Descriptor{}, fmt.Errorf("could not parse artifact manifest: %w", err)
+	}
+	if len(m.Layers) != 1 {
+		return Descriptor{}, fmt.Errorf("expected an artifact manifest with one layer, found %d", len(m.Layers))
+	}
+	return m.Layers[0], nil
+}
+
+// SubjectDescriptor describes the manifest of an image as the subject of
+// artifacts
+func SubjectDescriptor(image v1.Descriptor) Descriptor {
+	return Descriptor{MediaType: string(image.MediaType), Digest: image.Digest.String(), Size: image.Size}
+}
+
+// OfType filters the descriptors by artifact type
+func OfType(descriptors []Descriptor, artifactType string) []Descriptor {
+	var result []Descriptor
+	for _, d := range descriptors {
+		if d.ArtifactType == artifactType {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+func newBlob(mediaType string, content []byte) blob {
+	return blob{
+		Descriptor: Descriptor{
+			MediaType: mediaType,
+			Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+			Size:      int64(len(content)),
+		},
+		content: content,
+	}
+}
+
+// verify checks that the content matches the digest of its descriptor
+func verify(d Descriptor, content []byte) error {
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+	if !strings.EqualFold(digest, d.Digest) {
+		return fmt.Errorf("content of %s has digest %s", d.Digest, digest)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/artifact"
+)
+
+var _ = Describe("Artifact", func() {
+	var (
+		subject   artifact.Descriptor
+		artifacts []artifact.Artifact
+		created   time.Time
+	)
+
+	BeforeEach(func() {
+		img, err := random.Image(64, 1)
+		Expect(err).ToNot(HaveOccurred())
+		descriptor, err := partial.Descriptor(img)
+		Expect(err).ToNot(HaveOccurred())
+		subject = artifact.SubjectDescriptor(*descriptor)
+
+		artifacts = []artifact.Artifact{
+			{Type: artifact.MetadataType, Content: []byte(`{"dependencies":[]}`)},
+			{Type: artifact.SPDXType, Content: []byte(`{"spdxVersion":"SPDX-2.3"}`)},
+		}
+		created = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	})
+
+	itAttachesTheArtifacts := func(store func() artifact.Store) {
+		It("lists the attached artifacts as referrers of the subject", func() {
+			Expect(store().Attach(subject, artifacts, created)).To(Succeed())
+
+			referrers, err := store().Referrers(subject.Digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(referrers).To(HaveLen(2))
+
+			for i, referrer := range referrers {
+				Expect(referrer.MediaType).To(Equal(artifact.ManifestMediaType))
+				Expect(referrer.ArtifactType).To(Equal(artifacts[i].Type))
+				Expect(referrer.Annotations).To(HaveKeyWithValue(artifact.CreatedAnnotation, "2020-01-02T03:04:05Z"))
+
+				content, err := store().Fetch(referrer)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(artifacts[i].Content))
+			}
+		})
+
+		It("filters the referrers by artifact type", func() {
+			Expect(store().Attach(subject, artifacts, created)).To(Succeed())
+
+			referrers, err := store().Referrers(subject.Digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			metadataReferrers := artifact.OfType(referrers, artifact.MetadataType)
+			Expect(metadataReferrers).To(HaveLen(1))
+			Expect(metadataReferrers[0].ArtifactType).To(Equal(artifact.MetadataType))
+		})
+
+		It("has no referrers for other digests", func() {
+			Expect(store().Attach(subject, artifacts, created)).To(Succeed())
+
+			referrers, err := store().Referrers("sha256:" + strings.Repeat("0", 64))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(referrers).To(BeEmpty())
+		})
+	}
+
+	Context("Layout", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-artifact-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		itAttachesTheArtifacts(func() artifact.Store {
+			return artifact.NewLayout(dir)
+		})
+
+		It("adds to the artifacts of an existing layout", func() {
+			layout := artifact.NewLayout(dir)
+			Expect(layout.Attach(subject, artifacts[:1], created)).To(Succeed())
+			Expect(layout.Attach(subject, artifacts[1:], created)).To(Succeed())
+
+			referrers, err := layout.Referrers(subject.Digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(referrers).To(HaveLen(2))
+		})
+
+		It("fails to attach to a path which cannot be opened as a layout", func() {
+			path := filepath.Join(dir, "file")
+			Expect(ioutil.WriteFile(path, []byte("not a layout"), 0644)).To(Succeed())
+
+			err := artifact.NewLayout(path).Attach(subject, artifacts, created)
+			Expect(err).To(MatchError(ContainSubstring("could not open oci layout at " + path)))
+		})
+
+		It("fails to list the referrers of a missing layout", func() {
+			_, err := artifact.NewLayout(dir + "/missing").Referrers(subject.Digest)
+			Expect(err).To(MatchError(ContainSubstring("could not read oci layout")))
+		})
+	})
+
+	Context("Registry", func() {
+		var (
+			server       *httptest.Server
+			registryHost string
+		)
+
+		newRegistry := func() artifact.Store {
+			r, err := artifact.NewRegistry(registryHost + "/deplab/app")
+			Expect(err).ToNot(HaveOccurred())
+			return r
+		}
+
+		Context("without the referrers API", func() {
+			BeforeEach(func() {
+				server = httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+				registryHost = strings.TrimPrefix(server.URL, "http://")
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			itAttachesTheArtifacts(newRegistry)
+
+			It("tags the referrers after the digest of the subject", func() {
+				Expect(newRegistry().Attach(subject, artifacts, created)).To(Succeed())
+
+				tag := registryHost + "/deplab/app:" + strings.Replace(subject.Digest, ":", "-", 1)
+				raw, err := crane.Manifest(tag)
+				Expect(err).ToNot(HaveOccurred())
+
+				var index artifact.Index
+				Expect(json.Unmarshal(raw, &index)).To(Succeed())
+				Expect(index.MediaType).To(Equal(artifact.IndexMediaType))
+				Expect(index.Manifests).To(HaveLen(2))
+			})
+
+			It("does not tag the referrers twice when attaching the artifacts again", func() {
+				Expect(newRegistry().Attach(subject, artifacts, created)).To(Succeed())
+				Expect(newRegistry().Attach(subject, artifacts, created)).To(Succeed())
+
+				listed, err := newRegistry().Referrers(subject.Digest)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listed).To(HaveLen(2))
+			})
+		})
+
+		Context("with the referrers API", func() {
+			var referrers, nextReferrers artifact.Index
+
+			BeforeEach(func() {
+				referrers = artifact.Index{SchemaVersion: 2, MediaType: artifact.IndexMediaType, Manifests: []artifact.Descriptor{}}
+				nextReferrers = artifact.Index{SchemaVersion: 2, MediaType: artifact.IndexMediaType, Manifests: []artifact.Descriptor{}}
+
+				handler := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if strings.Contains(r.URL.Path, "/referrers/") {
+						w.Header().Set("Content-Type", artifact.IndexMediaType)
+						page := referrers
+						if r.URL.Query().Get("page") == "2" {
+							page = nextReferrers
+						} else if len(nextReferrers.Manifests) > 0 {
+							w.Header().Set("Link", `<`+r.URL.Path+`?page=2>; rel="next"`)
+						}
+						Expect(json.NewEncoder(w).Encode(page)).To(Succeed())
+						return
+					}
+					handler.ServeHTTP(w, r)
+				}))
+				registryHost = strings.TrimPrefix(server.URL, "http://")
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("lists the referrers returned by the registry", func() {
+				referrers.Manifests = []artifact.Descriptor{{
+					MediaType:    artifact.ManifestMediaType,
+					ArtifactType: artifact.MetadataType,
+					Digest:       "sha256:" + strings.Repeat("1", 64),
+					Size:         123,
+				}}
+
+				listed, err := newRegistry().Referrers(subject.Digest)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listed).To(Equal(referrers.Manifests))
+			})
+
+			It("lists the referrers of every page returned by the registry", func() {
+				referrers.Manifests = []artifact.Descriptor{{
+					MediaType:    artifact.ManifestMediaType,
+					ArtifactType: artifact.MetadataType,
+					Digest:       "sha256:" + strings.Repeat("1", 64),
+					Size:         123,
+				}}
+				nextReferrers.Manifests = []artifact.Descriptor{{
+					MediaType:    artifact.ManifestMediaType,
+					ArtifactType: artifact.SPDXType,
+					Digest:       "sha256:" + strings.Repeat("2", 64),
+					Size:         456,
+				}}
+
+				listed, err := newRegistry().Referrers(subject.Digest)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(listed).To(Equal(append(referrers.Manifests, nextReferrers.Manifests...)))
+			})
+
+			It("does not tag the referrers", func() {
+				Expect(newRegistry().Attach(subject, artifacts, created)).To(Succeed())
+
+				_, err := crane.Manifest(registryHost + "/deplab/app:" + strings.Replace(subject.Digest, ":", "-", 1))
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
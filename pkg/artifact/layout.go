@@ -0,0 +1,139 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+)
+
+// Layout stores artifacts in an OCI image layout, with their manifests listed
+// in its index. The referrers of an image are the manifests of the index whose
+// subject is the image.
+type Layout struct {
+	path string
+}
+
+func NewLayout(path string) Layout {
+	return Layout{path: path}
+}
+
+func (l Layout) Attach(subject Descriptor, artifacts []Artifact, created time.Time) error {
+	ociLayout, err := image.OpenOCILayout(l.path)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range artifacts {
+		p, err := pack(a, subject, created)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range append(p.blobs, p.manifest) {
+			hash, err := v1.NewHash(b.Digest)
+			if err != nil {
+				return fmt.Errorf("invalid digest %s: %w", b.Digest, err)
+			}
+			err = ociLayout.WriteBlob(hash, ioutil.NopCloser(bytes.NewReader(b.content)))
+			if err != nil {
+				return fmt.Errorf("could not write blob %s to oci layout at %s: %w", b.Digest, l.path, err)
+			}
+		}
+
+		hash, err := v1.NewHash(p.manifest.Digest)
+		if err != nil {
+			return fmt.Errorf("invalid digest %s: %w", p.manifest.Digest, err)
+		}
+		err = ociLayout.AppendDescriptor(v1.Descriptor{
+			MediaType:   types.MediaType(ManifestMediaType),
+			Size:        p.manifest.Size,
+			Digest:      hash,
+			Annotations: p.referrer.Annotations,
+		})
+		if err != nil {
+			return fmt.Errorf("could not add artifact to oci layout at %s: %w", l.path, err)
+		}
+	}
+
+	return nil
+}
+
+func (l Layout) Referrers(digest string) ([]Descriptor, error) {
+	ociLayout, err := layout.FromPath(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read oci layout at %s: %w", l.path, err)
+	}
+
+	index, err := ociLayout.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("could not read index of oci layout at %s: %w", l.path, err)
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not read index of oci layout at %s: %w", l.path, err)
+	}
+
+	var result []Descriptor
+	for _, d := range manifest.Manifests {
+		if d.MediaType != types.MediaType(ManifestMediaType) {
+			continue
+		}
+
+		raw, err := ociLayout.Bytes(d.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest %s of oci layout at %s: %w", d.Digest, l.path, err)
+		}
+
+		descriptor := Descriptor{MediaType: string(d.MediaType), Digest: d.Digest.String(), Size: d.Size}
+		if referrer, ok := asReferrer(descriptor, raw, digest); ok {
+			result = append(result, referrer)
+		}
+	}
+	return result, nil
+}
+
+func (l Layout) Fetch(manifest Descriptor) ([]byte, error) {
+	ociLayout, err := layout.FromPath(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read oci layout at %s: %w", l.path, err)
+	}
+
+	raw, err := l.blob(ociLayout, manifest.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := content(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := l.blob(ociLayout, layer.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return data, verify(layer, data)
+}
+
+func (l Layout) blob(ociLayout layout.Path, digest string) ([]byte, error) {
+	hash, err := v1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest %s: %w", digest, err)
+	}
+
+	data, err := ociLayout.Bytes(hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob %s of oci layout at %s: %w", digest, l.path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// digester is an image which knows the digest of its manifest
+type digester interface {
+	Digest() (string, error)
+}
+
+// Provider merges the metadata attached to the image as artifacts, found in
+// the repository of the image and in the OCI image layout of
+// --referrers-oci-layout. Artifacts which cannot be read from the registry
+// are reported as warnings.
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	d, ok := dli.(digester)
+	if !ok || params.InputImage == "" && params.ReferrersOCILayout == "" {
+		return md, nil
+	}
+
+	digest, err := d.Digest()
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+
+	if params.InputImage != "" {
+		ref, err := name.ParseReference(params.InputImage)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("invalid image reference %s: %w", params.InputImage, err)
+		}
+
+		md, err = mergeAttached(Registry{repository: ref.Context()}, digest, md, params.Warnings)
+		if err != nil {
+			params.Warnings.Add(warnings.UnreadableArtifact, ref.Context().String(), err.Error())
+		}
+	}
+
+	if params.ReferrersOCILayout != "" {
+		md, err = mergeAttached(NewLayout(params.ReferrersOCILayout), digest, md, params.Warnings)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+	}
+
+	return md, nil
+}
+
+// mergeAttached adds the git and archive dependencies of the metadata
+// artifacts of the store referring to the digest to md, next to the ones of
+// the label. Differences between the package lists of the artifacts and md, and
+// the other artifacts, such as SBOMs or attestations, which are not merged, are
+// reported as warnings. The metadata read before an error is kept.
+func mergeAttached(store Store, digest string, md metadata.Metadata, collector *warnings.Collector) (metadata.Metadata, error) {
+	referrers, err := store.Referrers(digest)
+	if err != nil {
+		return md, err
+	}
+
+	for _, referrer := range referrers {
+		if referrer.ArtifactType != MetadataType {
+			collector.Add(warnings.UnmergedArtifact, referrer.Digest, fmt.Sprintf("the %s artifact attached to the image is not merged into the metadata", referrer.ArtifactType))
+		}
+	}
+
+	for _, referrer := range OfType(referrers, MetadataType) {
+		content, err := store.Fetch(referrer)
+		if err != nil {
+			return md, err
+		}
+
+		attached := metadata.Metadata{}
+		if err := json.Unmarshal(content, &attached); err != nil {
+			return md, fmt.Errorf("cannot parse the metadata artifact %s: %w", referrer.Digest, err)
+		}
+
+		_, mergeWarnings := metadata.Merge(attached, md)
+		for _, warning := range mergeWarnings {
+			collector.Add(warnings.ExistingLabelMismatch, string(warning), "difference identified in matching metadata elements already attached to image")
+		}
+
+		md, err = addSourceDependencies(md, attached)
+		if err != nil {
+			return md, err
+		}
+	}
+
+	return md, nil
+}
+
+// addSourceDependencies adds the git and archive dependencies of attached
+// which md does not have yet
+func addSourceDependencies(md, attached metadata.Metadata) (metadata.Metadata, error) {
+	known := map[string]bool{}
+	for _, dependency := range md.Dependencies {
+		key, err := json.Marshal(dependency)
+		if err != nil {
+			return md, fmt.Errorf("could not encode dependency: %w", err)
+		}
+		known[string(key)] = true
+	}
+
+	for _, dependency := range attached.Dependencies {
+		if dependency.Source.Type != metadata.GitSourceType && dependency.Source.Type != metadata.ArchiveType {
+			continue
+		}
+
+		key, err := json.Marshal(dependency)
+		if err != nil {
+			return md, fmt.Errorf("could not encode dependency: %w", err)
+		}
+		if known[string(key)] {
+			continue
+		}
+		known[string(key)] = true
+		md.Dependencies = append(md.Dependencies, dependency)
+	}
+
+	return md, nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/artifact"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+type digestImage struct {
+	image.Image
+	digest string
+}
+
+func (i digestImage) Digest() (string, error) {
+	return i.digest, nil
+}
+
+var _ = Describe("Provider", func() {
+	var (
+		dir     string
+		subject artifact.Descriptor
+	)
+
+	gitDependency := func(url string) metadata.Dependency {
+		return metadata.Dependency{
+			Type: metadata.PackageType,
+			Source: metadata.Source{
+				Type:     metadata.GitSourceType,
+				Version:  map[string]interface{}{"commit": "abc123"},
+				Metadata: map[string]interface{}{"url": url, "refs": []interface{}{}},
+			},
+		}
+	}
+
+	archiveDependency := metadata.Dependency{
+		Type: metadata.PackageType,
+		Source: metadata.Source{
+			Type:     metadata.ArchiveType,
+			Metadata: map[string]interface{}{"url": "https://example.com/app.tgz"},
+		},
+	}
+
+	attach := func(md metadata.Metadata) {
+		content, err := json.Marshal(md)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(artifact.NewLayout(dir).Attach(subject, []artifact.Artifact{{Type: artifact.MetadataType, Content: content}}, time.Now())).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "deplab-artifact-provider-")
+		Expect(err).ToNot(HaveOccurred())
+
+		subject = artifact.Descriptor{MediaType: artifact.ManifestMediaType, Digest: "sha256:" + strings.Repeat("a", 64), Size: 100}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("keeps the dependencies of the label next to the ones of the attached artifacts", func() {
+		label := metadata.Metadata{Dependencies: []metadata.Dependency{gitDependency("https://example.com/label.git"), archiveDependency}}
+
+		attach(metadata.Metadata{Dependencies: []metadata.Dependency{gitDependency("https://example.com/label.git"), gitDependency("https://example.com/first.git")}})
+		attach(metadata.Metadata{Dependencies: []metadata.Dependency{archiveDependency, gitDependency("https://example.com/second.git")}})
+
+		md, err := artifact.Provider(digestImage{digest: subject.Digest}, common.RunParams{
+			ReferrersOCILayout: dir,
+			Warnings:           warnings.NewCollector(),
+		}, label)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(md.Dependencies).To(Equal([]metadata.Dependency{
+			gitDependency("https://example.com/label.git"),
+			archiveDependency,
+			gitDependency("https://example.com/first.git"),
+			gitDependency("https://example.com/second.git"),
+		}))
+	})
+
+	It("reports the attached artifacts which are not merged as warnings", func() {
+		Expect(artifact.NewLayout(dir).Attach(subject, []artifact.Artifact{{Type: artifact.SPDXType, Content: []byte("{}")}}, time.Now())).To(Succeed())
+		attach(metadata.Metadata{Dependencies: []metadata.Dependency{gitDependency("https://example.com/first.git")}})
+
+		collector := warnings.NewCollector()
+		md, err := artifact.Provider(digestImage{digest: subject.Digest}, common.RunParams{
+			ReferrersOCILayout: dir,
+			Warnings:           collector,
+		}, metadata.Metadata{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(md.Dependencies).To(Equal([]metadata.Dependency{gitDependency("https://example.com/first.git")}))
+		Expect(collector.Warnings()).To(ConsistOf(And(
+			HaveField("Code", warnings.UnmergedArtifact),
+			HaveField("Message", ContainSubstring(artifact.SPDXType)),
+		)))
+	})
+
+	It("ignores the artifacts of other images", func() {
+		attach(metadata.Metadata{Dependencies: []metadata.Dependency{gitDependency("https://example.com/other.git")}})
+
+		md, err := artifact.Provider(digestImage{digest: "sha256:" + strings.Repeat("b", 64)}, common.RunParams{
+			ReferrersOCILayout: dir,
+			Warnings:           warnings.NewCollector(),
+		}, metadata.Metadata{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(md.Dependencies).To(BeEmpty())
+	})
+})
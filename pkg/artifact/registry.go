@@ -0,0 +1,290 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// Registry stores artifacts in a repository of a registry. The referrers are
+// listed by the referrers API, or for registries without it by the index
+// tagged after the digest of the subject, e.g. sha256-<hex>.
+type Registry struct {
+	repository name.Repository
+}
+
+func NewRegistry(repository string) (Registry, error) {
+	repo, err := name.NewRepository(repository)
+	if err != nil {
+		return Registry{}, fmt.Errorf("invalid repository %s: %w", repository, err)
+	}
+	return Registry{repository: repo}, nil
+}
+
+func (r Registry) Attach(subject Descriptor, artifacts []Artifact, created time.Time) error {
+	var referrers []Descriptor
+	for _, a := range artifacts {
+		p, err := pack(a, subject, created)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range p.blobs {
+			err := remote.WriteLayer(r.repository, static.NewLayer(b.content, types.MediaType(b.MediaType)), r.options()...)
+			if err != nil {
+				return fmt.Errorf("could not push blob %s to %s: %w", b.Digest, r.repository, err)
+			}
+		}
+
+		ref := r.repository.Digest(p.manifest.Digest)
+		err = remote.Put(ref, rawManifest{raw: p.manifest.content, mediaType: ManifestMediaType}, r.options()...)
+		if err != nil {
+			return fmt.Errorf("could not push artifact manifest to %s: %w", ref, err)
+		}
+		referrers = append(referrers, p.referrer)
+	}
+
+	_, supported, err := r.referrersAPI(subject.Digest)
+	if err != nil {
+		return err
+	}
+	if supported {
+		return nil
+	}
+	return r.tagReferrers(subject.Digest, referrers)
+}
+
+func (r Registry) Referrers(digest string) ([]Descriptor, error) {
+	index, supported, err := r.referrersAPI(digest)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		index, err = r.referrersTag(digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return index.Manifests, nil
+}
+
+func (r Registry) Fetch(manifest Descriptor) ([]byte, error) {
+	descriptor, err := remote.Get(r.repository.Digest(manifest.Digest), r.options()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch artifact manifest %s: %w", manifest.Digest, err)
+	}
+
+	layer, err := content(descriptor.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := remote.Layer(r.repository.Digest(layer.Digest), r.options()...)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch artifact content %s: %w", layer.Digest, err)
+	}
+	reader, err := blob.Compressed()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch artifact content %s: %w", layer.Digest, err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not read artifact content %s: %w", layer.Digest, err)
+	}
+	return data, verify(layer, data)
+}
+
+// referrersAPI lists the referrers with the referrers API, following the
+// pages the registry splits them in, telling whether the registry supports it
+func (r Registry) referrersAPI(digest string) (Index, bool, error) {
+	auth, err := authn.DefaultKeychain.Resolve(r.repository)
+	if err != nil {
+		return Index{}, false, fmt.Errorf("could not find credentials for %s: %w", r.repository, err)
+	}
+
+	tr, err := transport.New(r.repository.Registry, auth, http.DefaultTransport, []string{r.repository.Scope(transport.PullScope)})
+	if err != nil {
+		return Index{}, false, fmt.Errorf("could not connect to %s: %w", r.repository.Registry, err)
+	}
+	client := &http.Client{Transport: tr}
+
+	page := &url.URL{
+		Scheme: r.repository.Registry.Scheme(),
+		Host:   r.repository.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/referrers/%s", r.repository.RepositoryStr(), digest),
+	}
+
+	var index Index
+	visited := map[string]bool{}
+	for page != nil {
+		if visited[page.String()] {
+			return Index{}, false, fmt.Errorf("could not list referrers of %s: page %s is linked twice", digest, page)
+		}
+		visited[page.String()] = true
+
+		pageIndex, next, supported, err := referrersPage(client, page)
+		if err != nil {
+			return Index{}, false, fmt.Errorf("could not list referrers of %s: %w", digest, err)
+		}
+		if !supported {
+			return Index{}, false, nil
+		}
+
+		pageIndex.Manifests = append(index.Manifests, pageIndex.Manifests...)
+		index = pageIndex
+		page = next
+	}
+	return index, true, nil
+}
+
+// referrersPage reads a page of referrers, returning the url of the next
+// page given by the Link header, if any
+func referrersPage(client *http.Client, page *url.URL) (Index, *url.URL, bool, error) {
+	resp, err := client.Get(page.String())
+	if err != nil {
+		return Index{}, nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Index{}, nil, false, nil
+	}
+	if err := transport.CheckError(resp, http.StatusOK); err != nil {
+		return Index{}, nil, false, err
+	}
+
+	var index Index
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return Index{}, nil, false, fmt.Errorf("could not parse page %s: %w", page, err)
+	}
+
+	next, err := nextPage(page, resp.Header.Values("Link"))
+	if err != nil {
+		return Index{}, nil, false, err
+	}
+	return index, next, true, nil
+}
+
+// nextPage returns the url of the link with rel="next" of the Link headers,
+// resolved against the url of the page, or nil when there is none
+func nextPage(page *url.URL, links []string) (*url.URL, error) {
+	for _, header := range links {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				param = strings.Replace(strings.TrimSpace(param), " ", "", -1)
+				if param != `rel="next"` && param != "rel=next" {
+					continue
+				}
+
+				next, err := page.Parse(strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid next page link %s: %w", target, err)
+				}
+				return next, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// referrersTag reads the index tagged after the digest, empty when there is
+// no such tag
+func (r Registry) referrersTag(digest string) (Index, error) {
+	tag := r.referrersTagName(digest)
+
+	descriptor, err := remote.Get(tag, r.options()...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return Index{SchemaVersion: 2, MediaType: IndexMediaType}, nil
+		}
+		return Index{}, fmt.Errorf("could not fetch referrers %s: %w", tag, err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(descriptor.Manifest, &index); err != nil {
+		return Index{}, fmt.Errorf("could not parse referrers %s: %w", tag, err)
+	}
+	return index, nil
+}
+
+// tagReferrers adds the referrers to the index tagged after the digest,
+// unless it lists them already
+func (r Registry) tagReferrers(digest string, referrers []Descriptor) error {
+	index, err := r.referrersTag(digest)
+	if err != nil {
+		return err
+	}
+
+	listed := map[string]bool{}
+	for _, referrer := range index.Manifests {
+		listed[referrer.Digest] = true
+	}
+	for _, referrer := range referrers {
+		if listed[referrer.Digest] {
+			continue
+		}
+		listed[referrer.Digest] = true
+		index.Manifests = append(index.Manifests, referrer)
+	}
+
+	raw, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("could not encode referrers: %w", err)
+	}
+
+	tag := r.referrersTagName(digest)
+	err = remote.Put(tag, rawManifest{raw: raw, mediaType: IndexMediaType}, r.options()...)
+	if err != nil {
+		return fmt.Errorf("could not push referrers %s: %w", tag, err)
+	}
+	return nil
+}
+
+// referrersTagName is the tag of the referrers of the digest, for registries
+// without the referrers API
+func (r Registry) referrersTagName(digest string) name.Tag {
+	return r.repository.Tag(strings.Replace(digest, ":", "-", 1))
+}
+
+func (r Registry) options() []remote.Option {
+	return []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+}
+
+// rawManifest pushes a manifest as is
+type rawManifest struct {
+	raw       []byte
+	mediaType string
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return types.MediaType(m.mediaType), nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package artifact
+
+const (
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	IndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	EmptyMediaType    = "application/vnd.oci.empty.v1+json"
+)
+
+// Artifact types, which are also the media types of their content
+const (
+	MetadataType      = "application/vnd.deplab.metadata.v1+json"
+	SPDXType          = "application/spdx+json"
+	CycloneDXJSONType = "application/vnd.cyclonedx+json"
+	CycloneDXXMLType  = "application/vnd.cyclonedx+xml"
+	InTotoType        = "application/vnd.in-toto+json"
+	DSSEType          = "application/vnd.dsse.envelope.v1+json"
+)
+
+// CreatedAnnotation records when an artifact was attached
+const CreatedAnnotation = "org.opencontainers.image.created"
+
+// Descriptor points at a blob or a manifest. In an index of referrers, it
+// carries the artifact type and the annotations of the manifest.
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Data         []byte            `json:"data,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest used for an artifact, whose subject is
+// the image the artifact refers to
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Subject       *Descriptor       `json:"subject,omitempty"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Index lists the artifacts referring to an image
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []Descriptor `json:"manifests"`
+}
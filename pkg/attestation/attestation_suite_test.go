@@ -0,0 +1,16 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package attestation_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAttestation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Attestation Suite")
+}
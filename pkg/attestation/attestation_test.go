@@ -0,0 +1,208 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package attestation_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/attestation"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+var _ = Describe("Attestation", func() {
+	deplab := metadata.Provenance{Name: "deplab", Version: "1.2.3", URL: "https://github.com/vmware-tanzu/dependency-labeler"}
+
+	md := metadata.Metadata{
+		Base:       metadata.Base{"id": "debian"},
+		Provenance: []metadata.Provenance{{Name: "kpack", Version: "0.1.0", URL: "https://github.com/pivotal/kpack"}, deplab},
+		Dependencies: []metadata.Dependency{
+			{
+				Type: metadata.PackageType,
+				Source: metadata.Source{
+					Type:     metadata.GitSourceType,
+					Version:  map[string]interface{}{"commit": "0123456789abcdef"},
+					Metadata: metadata.GitSourceMetadata{URL: "https://github.com/example/app.git", Refs: []string{"v1.0.0"}},
+				},
+			},
+			{
+				Type: metadata.PackageType,
+				Source: metadata.Source{
+					Type:     metadata.ArchiveType,
+					Metadata: map[string]interface{}{"url": "https://example.com/source.tar.gz"},
+				},
+			},
+		},
+	}
+
+	build := attestation.Build{
+		Name:        "example.com/app:latest",
+		Digest:      "sha256:aaaa",
+		Image:       "debian:bullseye",
+		ImageDigest: "sha256:bbbb",
+		Builder:     deplab,
+		StartedOn:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		FinishedOn:  time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC),
+	}
+
+	Describe("BuildStatement", func() {
+		It("makes the labelled image the subject", func() {
+			statement, err := attestation.BuildStatement(md, build, attestation.SLSAPredicate)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statement.Type).To(Equal(attestation.StatementType))
+			Expect(statement.Subject).To(Equal([]attestation.Subject{
+				{Name: "example.com/app:latest", Digest: map[string]string{"sha256": "aaaa"}},
+			}))
+		})
+
+		It("resolves the input image, the git repositories and the archives in the slsa predicate", func() {
+			statement, err := attestation.BuildStatement(md, build, attestation.SLSAPredicate)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statement.PredicateType).To(Equal(attestation.SLSAPredicateType))
+			provenance := statement.Predicate.(attestation.Provenance)
+
+			Expect(provenance.BuildDefinition.BuildType).To(Equal(attestation.BuildType))
+			Expect(provenance.BuildDefinition.ExternalParameters).To(Equal(map[string]interface{}{"image": "debian:bullseye"}))
+			Expect(provenance.BuildDefinition.ResolvedDependencies).To(Equal([]attestation.ResourceDescriptor{
+				{Name: "debian:bullseye", Digest: map[string]string{"sha256": "bbbb"}},
+				{
+					URI:         "git+https://github.com/example/app.git",
+					Digest:      map[string]string{"gitCommit": "0123456789abcdef"},
+					Annotations: map[string]interface{}{"refs": []string{"v1.0.0"}},
+				},
+				{URI: "https://example.com/source.tar.gz"},
+			}))
+		})
+
+		It("names deplab as the builder with the other tools of the provenance as its dependencies", func() {
+			statement, err := attestation.BuildStatement(md, build, attestation.SLSAPredicate)
+			Expect(err).ToNot(HaveOccurred())
+
+			runDetails := statement.Predicate.(attestation.Provenance).RunDetails
+			Expect(runDetails.Builder).To(Equal(attestation.Builder{
+				ID:      "https://github.com/vmware-tanzu/dependency-labeler",
+				Version: map[string]string{"deplab": "1.2.3"},
+				BuilderDependencies: []attestation.ResourceDescriptor{
+					{Name: "kpack", URI: "https://github.com/pivotal/kpack", Annotations: map[string]interface{}{"version": "0.1.0"}},
+				},
+			}))
+			Expect(runDetails.Metadata).To(Equal(attestation.BuildMetadata{
+				StartedOn:  "2020-01-02T03:04:05Z",
+				FinishedOn: "2020-01-02T03:04:06Z",
+			}))
+		})
+
+		It("uses the metadata as the deplab predicate", func() {
+			statement, err := attestation.BuildStatement(md, build, attestation.DeplabPredicate)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statement.PredicateType).To(Equal(attestation.DeplabPredicateType))
+			Expect(statement.Predicate).To(Equal(md))
+		})
+
+		It("rejects unknown predicates", func() {
+			_, err := attestation.BuildStatement(md, build, "spdx")
+			Expect(err).To(MatchError(ContainSubstring(`unknown predicate "spdx"`)))
+		})
+
+		It("rejects digests which are not of the form algorithm:hex", func() {
+			invalid := build
+			invalid.Digest = "aaaa"
+
+			_, err := attestation.BuildStatement(md, invalid, attestation.SLSAPredicate)
+			Expect(err).To(MatchError(ContainSubstring("invalid digest of the labelled image")))
+		})
+	})
+
+	Describe("WriteAttestationFile", func() {
+		var (
+			dir       string
+			statement attestation.Statement
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-attestation-")
+			Expect(err).ToNot(HaveOccurred())
+
+			statement, err = attestation.BuildStatement(md, build, attestation.SLSAPredicate)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("writes the statement", func() {
+			path := filepath.Join(dir, "attestation.json")
+			Expect(attestation.WriteAttestationFile(statement, nil, path)).To(Succeed())
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			var written map[string]interface{}
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+			Expect(written).To(HaveKeyWithValue("_type", attestation.StatementType))
+			Expect(written).To(HaveKeyWithValue("predicateType", attestation.SLSAPredicateType))
+		})
+
+		It("wraps the statement in a DSSE envelope signed with the signing key", func() {
+			public, private, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			der, err := x509.MarshalPKCS8PrivateKey(private)
+			Expect(err).ToNot(HaveOccurred())
+			keyPath := filepath.Join(dir, "deplab.key")
+			Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+
+			signer, err := signature.LoadSigner(keyPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			path := filepath.Join(dir, "attestation.json")
+			Expect(attestation.WriteAttestationFile(statement, signer, path)).To(Succeed())
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+
+			var envelope attestation.Envelope
+			Expect(json.Unmarshal(content, &envelope)).To(Succeed())
+			Expect(envelope.PayloadType).To(Equal(attestation.PayloadType))
+			Expect(envelope.Signatures).To(HaveLen(1))
+
+			keyID, err := signature.KeyID(public)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envelope.Signatures[0].KeyID).To(Equal(keyID))
+
+			payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+			Expect(err).ToNot(HaveOccurred())
+			sig, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ed25519.Verify(public, attestation.PAE(envelope.PayloadType, payload), sig)).To(BeTrue())
+
+			var signed attestation.Statement
+			Expect(json.Unmarshal(payload, &signed)).To(Succeed())
+			Expect(signed.Subject).To(Equal(statement.Subject))
+		})
+	})
+
+	Describe("PAE", func() {
+		It("encodes the lengths of the payload type and of the payload", func() {
+			Expect(string(attestation.PAE("http://example.com/HelloWorld", []byte("hello world")))).
+				To(Equal("DSSEv1 29 http://example.com/HelloWorld 11 hello world"))
+		})
+	})
+})
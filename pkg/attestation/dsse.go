@@ -0,0 +1,39 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package attestation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+// Sign wraps the statement in a DSSE envelope signed by signer
+func Sign(statement Statement, signer *signature.Signer) (Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("could not encode statement: %w", err)
+	}
+
+	sig, err := signer.SignData(PAE(PayloadType, payload))
+	if err != nil {
+		return Envelope{}, fmt.Errorf("could not sign statement: %w", err)
+	}
+
+	return Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []EnvelopeSignature{{
+			KeyID: signer.KeyID(),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// PAE is the pre-authentication encoding of the payload, which DSSE signs
+func PAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package attestation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+// WriteAttestationFile writes the statement, wrapped in a DSSE envelope when
+// signer is not nil
+func WriteAttestationFile(statement Statement, signer *signature.Signer, attestationFilePath string) error {
+	var content interface{} = statement
+	if signer != nil {
+		envelope, err := Sign(statement, signer)
+		if err != nil {
+			return err
+		}
+		content = envelope
+	}
+
+	attestationFile, err := os.Create(attestationFilePath)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %w", attestationFilePath, err)
+	}
+	defer attestationFile.Close()
+
+	encoder := json.NewEncoder(attestationFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(content)
+	if err != nil {
+		return fmt.Errorf("could not write attestation file: %w", err)
+	}
+	return nil
+}
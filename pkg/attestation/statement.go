@@ -0,0 +1,161 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package attestation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// Build describes the run of deplab which labelled the image
+type Build struct {
+	// Name and Digest identify the labelled image, the digest being of the
+	// form sha256:<hex>
+	Name   string
+	Digest string
+	// Image and ImageDigest identify the input image
+	Image       string
+	ImageDigest string
+	Builder     metadata.Provenance
+	StartedOn   time.Time
+	FinishedOn  time.Time
+}
+
+// BuildStatement makes an in-toto statement whose subject is the labelled
+// image, with either an SLSA provenance predicate or the deplab metadata as
+// predicate
+func BuildStatement(md metadata.Metadata, build Build, predicate string) (Statement, error) {
+	subjectDigest, err := digestSet(build.Digest)
+	if err != nil {
+		return Statement{}, fmt.Errorf("invalid digest of the labelled image: %w", err)
+	}
+
+	statement := Statement{
+		Type:    StatementType,
+		Subject: []Subject{{Name: build.Name, Digest: subjectDigest}},
+	}
+
+	switch predicate {
+	case SLSAPredicate:
+		provenance, err := buildProvenance(md, build)
+		if err != nil {
+			return Statement{}, err
+		}
+		statement.PredicateType = SLSAPredicateType
+		statement.Predicate = provenance
+	case DeplabPredicate:
+		statement.PredicateType = DeplabPredicateType
+		statement.Predicate = md
+	default:
+		return Statement{}, fmt.Errorf("unknown predicate %q, must be one of: %s", predicate, strings.Join(Predicates, ", "))
+	}
+
+	return statement, nil
+}
+
+// buildProvenance resolves the input image, the git repositories and the
+// archives the image is built from, and names deplab as the builder with the
+// tools of the provenance of the metadata as its dependencies
+func buildProvenance(md metadata.Metadata, build Build) (Provenance, error) {
+	var dependencies []ResourceDescriptor
+
+	if build.ImageDigest != "" {
+		imageDigest, err := digestSet(build.ImageDigest)
+		if err != nil {
+			return Provenance{}, fmt.Errorf("invalid digest of the input image: %w", err)
+		}
+		dependencies = append(dependencies, ResourceDescriptor{Name: build.Image, Digest: imageDigest})
+	}
+
+	for _, dependency := range md.AllDependencies() {
+		switch dependency.Source.Type {
+		case metadata.GitSourceType:
+			descriptor, err := gitDescriptor(dependency)
+			if err != nil {
+				return Provenance{}, err
+			}
+			dependencies = append(dependencies, descriptor)
+		case metadata.ArchiveType:
+			var sourceMetadata metadata.ArchiveSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return Provenance{}, err
+			}
+			dependencies = append(dependencies, ResourceDescriptor{URI: sourceMetadata.URL})
+		}
+	}
+
+	var builderDependencies []ResourceDescriptor
+	for _, p := range md.Provenance {
+		if p == build.Builder {
+			continue
+		}
+		descriptor := ResourceDescriptor{Name: p.Name, URI: p.URL}
+		if p.Version != "" {
+			descriptor.Annotations = map[string]interface{}{"version": p.Version}
+		}
+		builderDependencies = append(builderDependencies, descriptor)
+	}
+
+	return Provenance{
+		BuildDefinition: BuildDefinition{
+			BuildType:            BuildType,
+			ExternalParameters:   map[string]interface{}{"image": build.Image},
+			ResolvedDependencies: dependencies,
+		},
+		RunDetails: RunDetails{
+			Builder: Builder{
+				ID:                  build.Builder.URL,
+				Version:             map[string]string{build.Builder.Name: build.Builder.Version},
+				BuilderDependencies: builderDependencies,
+			},
+			Metadata: BuildMetadata{
+				StartedOn:  timestamp(build.StartedOn),
+				FinishedOn: timestamp(build.FinishedOn),
+			},
+		},
+	}, nil
+}
+
+// gitDescriptor names a git dependency by the url of its repository, as
+// git+<url>, with its commit as digest
+func gitDescriptor(dependency metadata.Dependency) (ResourceDescriptor, error) {
+	var sourceMetadata metadata.GitSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return ResourceDescriptor{}, err
+	}
+
+	uri := sourceMetadata.URL
+	if !strings.HasPrefix(uri, "git+") {
+		uri = "git+" + uri
+	}
+	descriptor := ResourceDescriptor{URI: uri}
+
+	if commit, ok := dependency.Source.Version["commit"].(string); ok && commit != "" {
+		descriptor.Digest = map[string]string{"gitCommit": commit}
+	}
+	if len(sourceMetadata.Refs) > 0 {
+		descriptor.Annotations = map[string]interface{}{"refs": sourceMetadata.Refs}
+	}
+
+	return descriptor, nil
+}
+
+// digestSet converts an algorithm:hex digest into an in-toto digest set
+func digestSet(digest string) (map[string]string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("digest %q is not of the form algorithm:hex", digest)
+	}
+	return map[string]string{parts[0]: parts[1]}, nil
+}
+
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
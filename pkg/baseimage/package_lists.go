@@ -0,0 +1,185 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package baseimage
+
+import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// packageList is the source metadata of a package list, whose packages are
+// told apart by the layer which introduced them
+type packageList interface {
+	len() int
+	layer(i int) *metadata.Layer
+	// clearLayers drops the layers recorded on the packages
+	clearLayers()
+	// part returns the source metadata holding only the packages at indexes
+	part(indexes []int) interface{}
+}
+
+// packageLists creates, for each type of package list, the empty packageList
+// its source metadata is decoded into
+var packageLists = map[string]func() packageList{
+	metadata.DebianPackageListSourceType: func() packageList { return &dpkgList{} },
+	metadata.RPMPackageListSourceType:    func() packageList { return &rpmList{} },
+	metadata.ApkPackageListSourceType:    func() packageList { return &apkList{} },
+	metadata.GoBinariesSourceType:        func() packageList { return &goBinaries{} },
+	metadata.PythonPackageListSourceType: func() packageList { return &pythonList{} },
+	metadata.NpmPackageListSourceType:    func() packageList { return &npmList{} },
+	metadata.MavenPackageListSourceType:  func() packageList { return &mavenList{} },
+}
+
+type dpkgList struct {
+	metadata.DebianPackageListSourceMetadata
+}
+
+func (l *dpkgList) len() int                    { return len(l.Packages) }
+func (l *dpkgList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *dpkgList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *dpkgList) part(indexes []int) interface{} {
+	part := l.DebianPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
+
+type rpmList struct {
+	metadata.RpmPackageListSourceMetadata
+}
+
+func (l *rpmList) len() int                    { return len(l.Packages) }
+func (l *rpmList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *rpmList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *rpmList) part(indexes []int) interface{} {
+	part := l.RpmPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
+
+type apkList struct {
+	metadata.ApkPackageListSourceMetadata
+}
+
+func (l *apkList) len() int                    { return len(l.Packages) }
+func (l *apkList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *apkList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *apkList) part(indexes []int) interface{} {
+	part := l.ApkPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
+
+type goBinaries struct {
+	metadata.GoBinariesSourceMetadata
+}
+
+func (l *goBinaries) len() int                    { return len(l.Binaries) }
+func (l *goBinaries) layer(i int) *metadata.Layer { return l.Binaries[i].Layer }
+
+func (l *goBinaries) clearLayers() {
+	for i := range l.Binaries {
+		l.Binaries[i].Layer = nil
+	}
+}
+
+func (l *goBinaries) part(indexes []int) interface{} {
+	part := l.GoBinariesSourceMetadata
+	part.Binaries = nil
+	for _, i := range indexes {
+		part.Binaries = append(part.Binaries, l.Binaries[i])
+	}
+	return part
+}
+
+type pythonList struct {
+	metadata.PythonPackageListSourceMetadata
+}
+
+func (l *pythonList) len() int                    { return len(l.Packages) }
+func (l *pythonList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *pythonList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *pythonList) part(indexes []int) interface{} {
+	part := l.PythonPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
+
+type npmList struct {
+	metadata.NpmPackageListSourceMetadata
+}
+
+func (l *npmList) len() int                    { return len(l.Packages) }
+func (l *npmList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *npmList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *npmList) part(indexes []int) interface{} {
+	part := l.NpmPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
+
+type mavenList struct {
+	metadata.MavenPackageListSourceMetadata
+}
+
+func (l *mavenList) len() int                    { return len(l.Packages) }
+func (l *mavenList) layer(i int) *metadata.Layer { return l.Packages[i].Layer }
+
+func (l *mavenList) clearLayers() {
+	for i := range l.Packages {
+		l.Packages[i].Layer = nil
+	}
+}
+
+func (l *mavenList) part(indexes []int) interface{} {
+	part := l.MavenPackageListSourceMetadata
+	part.Packages = nil
+	for _, i := range indexes {
+		part.Packages = append(part.Packages, l.Packages[i])
+	}
+	return part
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package baseimage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/layers"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+// Annotations of the manifest of an image naming the image it is built on
+const (
+	BaseNameAnnotation   = "org.opencontainers.image.base.name"
+	BaseDigestAnnotation = "org.opencontainers.image.base.digest"
+)
+
+// Provider splits the package lists reported by the providers run before it
+// between the base image, given by --base-image or --base-image-tar or, with
+// --annotated-base-image, annotated on the image, and the application built
+// on it. The packages
+// introduced by the layers the image shares with its base image are moved to
+// the BaseDependencies, unless the base image has a deplab label of its own,
+// whose dependencies are reused instead.
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	var (
+		ref  string
+		base v1.Image
+		err  error
+	)
+
+	switch {
+	case params.BaseImage != "":
+		ref = params.BaseImage
+		base, err = crane.Pull(ref)
+	case params.BaseImageTarPath != "":
+		ref = params.BaseImageTarPath
+		base, err = crane.Load(ref)
+	case params.AnnotatedBaseImage:
+		ref, base, err = annotatedBaseImage(dli, params, &md)
+	}
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not load base image %s: %w", ref, err)
+	}
+	if base == nil {
+		return md, nil
+	}
+
+	baseLayers, err := sharedLayers(dli, base)
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not compare the layers of base image %s: %w", ref, err)
+	}
+
+	// the packages are told apart by the layer which introduced them, which
+	// is only recorded in the label with --attribute-layers
+	if !params.AttributeLayers {
+		md, err = layers.Attribute(dli, md)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+	}
+
+	s := split{baseLayers: baseLayers, clearLayers: !params.AttributeLayers}
+	for _, dependency := range md.Dependencies {
+		if err := s.add(dependency); err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not split %s between the base image and the application: %w", dependency.Type, err)
+		}
+	}
+
+	label, found, err := deplabLabel(base)
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not read the label of base image %s: %w", ref, err)
+	}
+	if found {
+		s.base = label.Dependencies
+	}
+
+	digest, err := base.Digest()
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not get digest of base image %s: %w", ref, err)
+	}
+
+	md.Base = withBaseImage(md.Base, ref, digest.String())
+	md.Dependencies = s.application
+	md.BaseDependencies = s.base
+	return md, nil
+}
+
+// annotatedBaseImage pulls the base image annotated on the manifest of the
+// image, if any. A base image annotated by digest only is recorded in md but
+// cannot be pulled. A base image which cannot be pulled, e.g. without access
+// to its registry, is reported as a warning and left out.
+func annotatedBaseImage(dli image.Image, params common.RunParams, md *metadata.Metadata) (string, v1.Image, error) {
+	manifest, err := dli.GetManifest()
+	if err != nil {
+		return "", nil, fmt.Errorf("could not get image manifest: %w", err)
+	}
+
+	baseName := manifest.Annotations[BaseNameAnnotation]
+	baseDigest := manifest.Annotations[BaseDigestAnnotation]
+	if baseDigest == "" {
+		return "", nil, nil
+	}
+	if baseName == "" {
+		params.Warnings.Add(warnings.UnknownBaseImage, baseDigest, "cannot pull the base image annotated on the image without its name")
+		md.Base = withBaseImage(md.Base, "", baseDigest)
+		return "", nil, nil
+	}
+
+	reference, err := name.ParseReference(baseName)
+	if err != nil {
+		params.Warnings.Add(warnings.UnknownBaseImage, baseName, fmt.Sprintf("invalid base image name annotated on the image: %s", err))
+		return "", nil, nil
+	}
+
+	base, err := crane.Pull(reference.Context().Digest(baseDigest).String())
+	if err == nil {
+		// the config is read later on, so it is fetched here along with the
+		// manifest
+		_, err = base.ConfigFile()
+	}
+	if err != nil {
+		params.Warnings.Add(warnings.UnknownBaseImage, baseName, fmt.Sprintf("cannot pull the base image annotated on the image: %s", err))
+		return "", nil, nil
+	}
+	return baseName, base, nil
+}
+
+// sharedLayers returns the digests of the layers of the base image, which
+// must be the first layers of the image
+func sharedLayers(dli image.Image, base v1.Image) (map[string]bool, error) {
+	imageLayers, err := dli.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the image layers: %w", err)
+	}
+
+	baseLayers, err := base.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the base image layers: %w", err)
+	}
+	if len(baseLayers) > len(imageLayers) {
+		return nil, fmt.Errorf("the base image has more layers than the image")
+	}
+
+	digests := map[string]bool{}
+	for i, layer := range baseLayers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("could not get digest of base image layer %d: %w", i, err)
+		}
+		if digest.String() != imageLayers[i].Digest {
+			return nil, fmt.Errorf("the image is not built on the base image, layer %d is %s instead of %s", i, imageLayers[i].Digest, digest)
+		}
+		digests[digest.String()] = true
+	}
+	return digests, nil
+}
+
+func deplabLabel(base v1.Image) (metadata.Metadata, bool, error) {
+	config, err := base.ConfigFile()
+	if err != nil {
+		return metadata.Metadata{}, false, fmt.Errorf("could not get image config: %w", err)
+	}
+
+	label, ok, err := metadata.ReadLabel(config.Config.Labels)
+	if err != nil || !ok {
+		return metadata.Metadata{}, false, err
+	}
+
+	var md metadata.Metadata
+	if err := json.Unmarshal([]byte(label), &md); err != nil {
+		return metadata.Metadata{}, false, fmt.Errorf("cannot parse the label %s: %w", label, err)
+	}
+	return md, true, nil
+}
+
+// withBaseImage returns a copy of base recording the base image, as base may
+// be one of the shared bases of the metadata package
+func withBaseImage(base metadata.Base, ref, digest string) metadata.Base {
+	withImage := metadata.Base{}
+	for k, v := range base {
+		withImage[k] = v
+	}
+	if ref != "" {
+		withImage[metadata.BaseImageKey] = ref
+	}
+	withImage[metadata.BaseImageDigestKey] = digest
+	return withImage
+}
+
+// split holds the dependencies of the base image and of the application,
+// the packages of a package list being told apart by the layer which
+// introduced them
+type split struct {
+	baseLayers  map[string]bool
+	clearLayers bool
+	base        []metadata.Dependency
+	application []metadata.Dependency
+}
+
+func (s *split) inBase(layer *metadata.Layer) bool {
+	return layer != nil && s.baseLayers[layer.Digest]
+}
+
+func (s *split) add(dependency metadata.Dependency) error {
+	newList, ok := packageLists[dependency.Type]
+	if !ok {
+		// sources, archives and buildpack metadata belong to the application
+		s.application = append(s.application, dependency)
+		return nil
+	}
+
+	list := newList()
+	if err := metadata.DecodeSourceMetadata(dependency.Source, list); err != nil {
+		return err
+	}
+
+	var base, application []int
+	for i := 0; i < list.len(); i++ {
+		if s.inBase(list.layer(i)) {
+			base = append(base, i)
+		} else {
+			application = append(application, i)
+		}
+	}
+	if s.clearLayers {
+		list.clearLayers()
+	}
+
+	if len(base) > 0 {
+		part, err := layers.WithSourceMetadata(dependency, list.part(base))
+		if err != nil {
+			return err
+		}
+		s.base = append(s.base, part)
+	}
+	if len(application) > 0 {
+		part, err := layers.WithSourceMetadata(dependency, list.part(application))
+		if err != nil {
+			return err
+		}
+		s.application = append(s.application, part)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package baseimage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/baseimage"
+)
+
+var _ = Describe("baseimage", func() {
+	Describe("Provider", func() {
+		var (
+			dir        string
+			base       v1.Image
+			baseLayers []image.Layer
+			appLayer   = image.Layer{Digest: "sha256:app", CreatedBy: "/bin/sh -c apt-get install -y curl"}
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-base-image-")
+			Expect(err).ToNot(HaveOccurred())
+
+			base = empty.Image
+			baseLayers = nil
+			for i := 0; i < 2; i++ {
+				layer, err := random.Layer(64, types.DockerLayer)
+				Expect(err).ToNot(HaveOccurred())
+				base, err = mutate.AppendLayers(base, layer)
+				Expect(err).ToNot(HaveOccurred())
+
+				digest, err := layer.Digest()
+				Expect(err).ToNot(HaveOccurred())
+				baseLayers = append(baseLayers, image.Layer{Digest: digest.String()})
+			}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		saveBase := func() string {
+			path := filepath.Join(dir, "base.tgz")
+			Expect(crane.Save(base, "base:latest", path)).To(Succeed())
+			return path
+		}
+
+		dpkgMetadata := func() metadata.Metadata {
+			packages := metadata.DebianPackageListSourceMetadata{
+				Packages: []metadata.DpkgPackage{
+					{Package: "curl", Version: "7.64.0-4", Layer: &metadata.Layer{Digest: appLayer.Digest}},
+					{Package: "libc6", Version: "2.28-10", Layer: &metadata.Layer{Digest: baseLayers[1].Digest}},
+					{Package: "unknown", Version: "1.0"},
+				},
+				AptSources: []string{"deb http://deb.debian.org/debian buster main"},
+			}
+			return metadata.Metadata{
+				Base: metadata.Base{"id": "debian"},
+				Dependencies: []metadata.Dependency{
+					{
+						Type:   metadata.DebianPackageListSourceType,
+						Source: metadata.Source{Type: "inline", Metadata: packages},
+					},
+					{
+						Type:   metadata.PackageType,
+						Source: metadata.Source{Type: metadata.GitSourceType, Metadata: metadata.GitSourceMetadata{URL: "https://example.com/app.git"}},
+					},
+				},
+			}
+		}
+
+		packageNames := func(dependency metadata.Dependency) []string {
+			var names []string
+			for _, pkg := range dependency.Source.Metadata.(metadata.DebianPackageListSourceMetadata).Packages {
+				names = append(names, pkg.Package)
+			}
+			return names
+		}
+
+		It("splits the packages introduced by the layers of the base image from the application", func() {
+			path := saveBase()
+			dli := test_utils.FakeImage{ImageLayers: append(append([]image.Layer{}, baseLayers...), appLayer)}
+
+			md, err := Provider(dli, common.RunParams{BaseImageTarPath: path, AttributeLayers: true}, dpkgMetadata())
+			Expect(err).ToNot(HaveOccurred())
+
+			digest, err := base.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(md.Base).To(Equal(metadata.Base{
+				"id":                        "debian",
+				metadata.BaseImageKey:       path,
+				metadata.BaseImageDigestKey: digest.String(),
+			}))
+
+			Expect(md.BaseDependencies).To(HaveLen(1))
+			Expect(packageNames(md.BaseDependencies[0])).To(Equal([]string{"libc6"}))
+
+			Expect(md.Dependencies).To(HaveLen(2))
+			Expect(packageNames(md.Dependencies[0])).To(Equal([]string{"curl", "unknown"}))
+			Expect(md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata).AptSources).To(HaveLen(1))
+			Expect(md.Dependencies[1].Type).To(Equal(metadata.PackageType))
+
+			Expect(md.BaseDependencies[0].Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+			Expect(md.BaseDependencies[0].Source.Version["sha256"]).ToNot(Equal(md.Dependencies[0].Source.Version["sha256"]))
+		})
+
+		It("does not record the layers of the packages without --attribute-layers", func() {
+			path := saveBase()
+			dli := test_utils.FakeImage{
+				ImageLayers: append(append([]image.Layer{}, baseLayers...), appLayer),
+				History: map[string][]test_utils.FakeVersion{
+					"/var/lib/dpkg/status": {
+						{Layer: 1, Content: "Package: libc6\nVersion: 2.28-10\n"},
+						{Layer: 2, Content: "Package: libc6\nVersion: 2.28-10\n\nPackage: curl\nVersion: 7.64.0-4\n"},
+					},
+				},
+			}
+
+			md := dpkgMetadata()
+			packages := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			for i := range packages.Packages {
+				packages.Packages[i].Layer = nil
+			}
+
+			md, err := Provider(dli, common.RunParams{BaseImageTarPath: path}, md)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(packageNames(md.BaseDependencies[0])).To(Equal([]string{"libc6"}))
+			Expect(packageNames(md.Dependencies[0])).To(Equal([]string{"curl", "unknown"}))
+			for _, dependency := range []metadata.Dependency{md.BaseDependencies[0], md.Dependencies[0]} {
+				for _, pkg := range dependency.Source.Metadata.(metadata.DebianPackageListSourceMetadata).Packages {
+					Expect(pkg.Layer).To(BeNil())
+				}
+			}
+		})
+
+		It("reuses the dependencies of the label of the base image", func() {
+			label, err := json.Marshal(metadata.Metadata{
+				Dependencies: []metadata.Dependency{{
+					Type:   metadata.DebianPackageListSourceType,
+					Source: metadata.Source{Type: "inline", Version: map[string]interface{}{"sha256": "labelled"}},
+				}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			config, err := base.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+			config.Config.Labels = map[string]string{"io.deplab.metadata": string(label)}
+			base, err = mutate.Config(base, config.Config)
+			Expect(err).ToNot(HaveOccurred())
+
+			path := saveBase()
+			dli := test_utils.FakeImage{ImageLayers: append(append([]image.Layer{}, baseLayers...), appLayer)}
+
+			md, err := Provider(dli, common.RunParams{BaseImageTarPath: path, AttributeLayers: true}, dpkgMetadata())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.BaseDependencies).To(HaveLen(1))
+			Expect(md.BaseDependencies[0].Source.Version["sha256"]).To(Equal("labelled"))
+			Expect(packageNames(md.Dependencies[0])).To(Equal([]string{"curl", "unknown"}))
+		})
+
+		It("fails when the image is not built on the base image", func() {
+			path := saveBase()
+			dli := test_utils.FakeImage{ImageLayers: []image.Layer{baseLayers[0], appLayer}}
+
+			_, err := Provider(dli, common.RunParams{BaseImageTarPath: path, AttributeLayers: true}, dpkgMetadata())
+			Expect(err).To(MatchError(ContainSubstring("the image is not built on the base image")))
+		})
+
+		It("records a base image annotated by digest only", func() {
+			collector := warnings.NewCollector()
+			dli := test_utils.FakeImage{Manifest: &v1.Manifest{Annotations: map[string]string{BaseDigestAnnotation: "sha256:base"}}}
+
+			md, err := Provider(dli, common.RunParams{AnnotatedBaseImage: true, Warnings: collector}, dpkgMetadata())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Base).To(Equal(metadata.Base{"id": "debian", metadata.BaseImageDigestKey: "sha256:base"}))
+			Expect(md.BaseDependencies).To(BeEmpty())
+			Expect(md.Dependencies).To(Equal(dpkgMetadata().Dependencies))
+			Expect(collector.Warnings()).To(ConsistOf(HaveField("Code", warnings.UnknownBaseImage)))
+		})
+
+		It("reports an annotated base image which cannot be pulled as a warning", func() {
+			collector := warnings.NewCollector()
+			dli := test_utils.FakeImage{Manifest: &v1.Manifest{Annotations: map[string]string{
+				BaseNameAnnotation:   "127.0.0.1:1/base:latest",
+				BaseDigestAnnotation: "sha256:" + strings.Repeat("0", 64),
+			}}}
+
+			md, err := Provider(dli, common.RunParams{AnnotatedBaseImage: true, Warnings: collector}, dpkgMetadata())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md).To(Equal(dpkgMetadata()))
+			Expect(collector.Warnings()).To(ConsistOf(HaveField("Code", warnings.UnknownBaseImage)))
+		})
+
+		It("does not read the base image annotated on the image without --annotated-base-image", func() {
+			collector := warnings.NewCollector()
+			dli := test_utils.FakeImage{Manifest: &v1.Manifest{Annotations: map[string]string{
+				BaseNameAnnotation:   "127.0.0.1:1/base:latest",
+				BaseDigestAnnotation: "sha256:" + strings.Repeat("0", 64),
+			}}}
+
+			md, err := Provider(dli, common.RunParams{Warnings: collector}, dpkgMetadata())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md).To(Equal(dpkgMetadata()))
+			Expect(collector.Warnings()).To(BeEmpty())
+		})
+
+		It("fails when the base image given cannot be pulled", func() {
+			_, err := Provider(test_utils.FakeImage{}, common.RunParams{BaseImage: "127.0.0.1:1/base:latest"}, dpkgMetadata())
+			Expect(err).To(MatchError(ContainSubstring("could not load base image 127.0.0.1:1/base:latest")))
+		})
+
+		It("does nothing without a base image", func() {
+			md := dpkgMetadata()
+
+			Expect(Provider(test_utils.FakeImage{}, common.RunParams{}, md)).To(Equal(md))
+		})
+	})
+})
@@ -8,6 +8,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
 )
 
 type RunParams struct {
@@ -16,11 +18,82 @@ type RunParams struct {
 	GitPaths                  []string
 	Tag                       string
 	OutputImageTar            string
+	OutputImage               string
+	OutputOCILayout           string
 	MetadataFilePath          string
 	DpkgFilePath              string
+	SPDXFilePath              string
+	CycloneDXFilePath         string
+	CycloneDXFormat           string
+	AttestationFilePath       string
+	AttestationPredicate      string
+	Attach                    []string
+	AttachRepository          string
+	AttachOCILayout           string
+	ReferrersOCILayout        string
 	AdditionalSourceUrls      []string
 	AdditionalSourceFilePaths []string
 	IgnoreValidationErrors    bool
+	DpkgLicenses              bool
+	VerifyPackages            bool
+	UnaccountedFiles          bool
+	AttributeLayers           bool
+	BaseImage                 string
+	BaseImageTarPath          string
+	AnnotatedBaseImage        bool
+	SigningKeyPath            string
+	CompressLabel             bool
+	CompressLabelThreshold    int
+	Providers                 []string
+	SkipProviders             []string
+	WarningsFilePath          string
+	Warnings                  *warnings.Collector
+}
+
+type InspectParams struct {
+	InputImageTarPath  string
+	InputImage         string
+	OutputFormat       string
+	CycloneDXFormat    string
+	Providers          []string
+	SkipProviders      []string
+	DpkgLicenses       bool
+	VerifyPackages     bool
+	UnaccountedFiles   bool
+	AttributeLayers    bool
+	BaseImage          string
+	BaseImageTarPath   string
+	AnnotatedBaseImage bool
+	VerifyKeyPath      string
+	ReferrersOCILayout string
+	WarningsFilePath   string
+}
+
+type DiffParams struct {
+	FromImage            string
+	FromImageTarPath     string
+	FromMetadataFilePath string
+	ToImage              string
+	ToImageTarPath       string
+	ToMetadataFilePath   string
+	OutputFormat         string
+}
+
+type ScanParams struct {
+	Image            string
+	ImageTarPath     string
+	MetadataFilePath string
+	DatabasePath     string
+	OutputFormat     string
+	FailOn           string
+}
+
+type VerifyParams struct {
+	Image            string
+	ImageTarPath     string
+	MetadataFilePath string
+	PolicyFilePath   string
+	OutputFormat     string
 }
 
 func Digest(sourceMetadata interface{}) (string, error) {
@@ -0,0 +1,441 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package cyclonedx
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/purl"
+)
+
+// BuildBOM converts the deplab metadata of an image into a CycloneDX bill of
+// materials whose subject is the image.
+func BuildBOM(md metadata.Metadata, name string, timestamp time.Time) (BOM, error) {
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return BOM{}, err
+	}
+
+	b := builder{
+		distro: md.Base["id"],
+		refs:   map[string]bool{},
+		bom: BOM{
+			XMLNS:        XMLNS,
+			BOMFormat:    BOMFormat,
+			SpecVersion:  SpecVersion,
+			SerialNumber: serialNumber,
+			Version:      1,
+			Metadata: BOMMetadata{
+				Timestamp: timestamp.UTC().Format(time.RFC3339),
+				Tools:     tools(md.Provenance),
+				Component: Component{
+					Type: ContainerType,
+					Name: name,
+				},
+			},
+			Components: []Component{},
+		},
+	}
+
+	if len(md.Base) > 0 {
+		b.addOperatingSystem(md.Base)
+	}
+
+	for _, dependency := range md.AllDependencies() {
+		var err error
+
+		switch {
+		case dependency.Type == metadata.DebianPackageListSourceType:
+			err = b.addDebianPackages(dependency)
+		case dependency.Type == metadata.RPMPackageListSourceType:
+			err = b.addRpmPackages(dependency)
+		case dependency.Type == metadata.ApkPackageListSourceType:
+			err = b.addApkPackages(dependency)
+		case dependency.Type == metadata.PythonPackageListSourceType:
+			err = b.addPythonPackages(dependency)
+		case dependency.Type == metadata.NpmPackageListSourceType:
+			err = b.addNpmPackages(dependency)
+		case dependency.Type == metadata.MavenPackageListSourceType:
+			err = b.addMavenPackages(dependency)
+		case dependency.Type == metadata.GoBinariesSourceType:
+			err = b.addGoBinaries(dependency)
+		case dependency.Type == metadata.BuildpackMetadataType:
+			err = b.addBuildpackBOM(dependency)
+		case dependency.Source.Type == metadata.GitSourceType:
+			err = b.addGitDependency(dependency)
+		case dependency.Source.Type == metadata.ArchiveType:
+			err = b.addArchiveDependency(dependency)
+		}
+
+		if err != nil {
+			return BOM{}, fmt.Errorf("could not convert %s dependency: %w", dependency.Type, err)
+		}
+	}
+
+	return b.bom, nil
+}
+
+type builder struct {
+	bom    BOM
+	refs   map[string]bool
+	distro string
+}
+
+func (b *builder) add(c Component) {
+	ref := c.PURL
+	if ref == "" {
+		ref = c.Type + ":" + c.Name + "@" + c.Version
+	}
+
+	c.BOMRef = ref
+	for i := 2; b.refs[c.BOMRef]; i++ {
+		c.BOMRef = fmt.Sprintf("%s#%d", ref, i)
+	}
+	b.refs[c.BOMRef] = true
+
+	b.bom.Components = append(b.bom.Components, c)
+}
+
+func (b *builder) addOperatingSystem(base metadata.Base) {
+	name := base["id"]
+	if name == "" {
+		name = base["name"]
+	}
+
+	b.add(Component{
+		Type:        OperatingSystemType,
+		Name:        name,
+		Version:     base["version_id"],
+		Description: base["pretty_name"],
+	})
+}
+
+func (b *builder) addDebianPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.DebianPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		var debianLicenses []LicenseChoice
+		for _, license := range pkg.Licenses {
+			debianLicenses = append(debianLicenses, licenses(license)...)
+		}
+
+		b.add(Component{
+			Type:     LibraryType,
+			Name:     pkg.Package,
+			Version:  pkg.Version,
+			Licenses: debianLicenses,
+			PURL: purl.New(purl.DebType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+				"arch": pkg.Architecture,
+			}),
+			Properties: []Property{
+				{Name: "deplab:source_package", Value: pkg.Source.Package},
+				{Name: "deplab:source_version", Value: pkg.Source.Version},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addRpmPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.RpmPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Component{
+			Type:     LibraryType,
+			Name:     pkg.Package,
+			Version:  pkg.Version,
+			Licenses: licenses(pkg.License),
+			PURL: purl.New(purl.RPMType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+				"arch": pkg.Architecture,
+			}),
+			Properties: []Property{
+				{Name: "deplab:source_rpm", Value: pkg.SourceRpm},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addApkPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.ApkPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Component{
+			Type:     LibraryType,
+			Name:     pkg.Package,
+			Version:  pkg.Version,
+			Licenses: licenses(pkg.License),
+			PURL: purl.New(purl.ApkType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+				"arch": pkg.Architecture,
+			}),
+			Properties: []Property{
+				{Name: "deplab:origin", Value: pkg.Origin},
+				{Name: "deplab:commit", Value: pkg.Commit},
+				{Name: "deplab:maintainer", Value: pkg.Maintainer},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addPythonPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.PythonPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Component{
+			Type:     LibraryType,
+			Name:     pkg.Package,
+			Version:  pkg.Version,
+			Licenses: licenses(pkg.License),
+			PURL:     purl.PyPI(pkg.Package, pkg.Version),
+			Properties: []Property{
+				{Name: "deplab:installer", Value: pkg.Installer},
+				{Name: "deplab:interpreter", Value: pkg.Interpreter},
+				{Name: "deplab:location", Value: pkg.Location},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addNpmPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.NpmPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		c := Component{
+			Type:     LibraryType,
+			Name:     pkg.Package,
+			Version:  pkg.Version,
+			Licenses: licenses(pkg.License),
+			PURL:     purl.Npm(pkg.Package, pkg.Version),
+			Properties: []Property{
+				{Name: "deplab:location", Value: pkg.Location},
+			},
+		}
+
+		if pkg.Resolved != "" {
+			c.ExternalReferences = []ExternalReference{{Type: "distribution", URL: pkg.Resolved}}
+		}
+
+		if pkg.Integrity != "" {
+			c.Properties = append(c.Properties, Property{Name: "deplab:integrity", Value: pkg.Integrity})
+		}
+
+		b.add(c)
+	}
+
+	return nil
+}
+
+func (b *builder) addMavenPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.MavenPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Component{
+			Type:    LibraryType,
+			Group:   pkg.GroupID,
+			Name:    pkg.ArtifactID,
+			Version: pkg.Version,
+			PURL:    purl.New(purl.MavenType, pkg.GroupID, pkg.ArtifactID, pkg.Version, nil),
+			Properties: []Property{
+				{Name: "deplab:path", Value: pkg.Path},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addGoBinaries(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.GoBinariesSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, binary := range sourceMetadata.Binaries {
+		main := binary.Module.Resolved()
+		b.add(Component{
+			Type:    ApplicationType,
+			Name:    binary.Package,
+			Version: main.Version,
+			PURL:    purl.Golang(main.Path, main.Version),
+			Properties: []Property{
+				{Name: "deplab:path", Value: binary.Path},
+				{Name: "deplab:go_version", Value: binary.GoVersion},
+			},
+		})
+
+		for _, module := range binary.Modules {
+			resolved := module.Resolved()
+			c := Component{
+				Type:    LibraryType,
+				Name:    resolved.Path,
+				Version: resolved.Version,
+				PURL:    purl.Golang(resolved.Path, resolved.Version),
+				Properties: []Property{
+					{Name: "deplab:path", Value: binary.Path},
+				},
+			}
+
+			if resolved.Sum != "" {
+				c.Properties = append(c.Properties, Property{Name: "deplab:go_sum", Value: resolved.Sum})
+			}
+
+			b.add(c)
+		}
+	}
+
+	return nil
+}
+
+func (b *builder) addBuildpackBOM(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.BuildpackBOMSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, bom := range sourceMetadata.BillOfMaterials {
+		c := Component{
+			Type:    LibraryType,
+			Name:    bom.Name,
+			Version: bom.Version,
+			Properties: []Property{
+				{Name: "deplab:buildpack_id", Value: bom.Buildpack.ID},
+				{Name: "deplab:buildpack_version", Value: bom.Buildpack.Version},
+			},
+		}
+
+		if uri, ok := bom.Metadata["uri"].(string); ok && uri != "" {
+			c.ExternalReferences = []ExternalReference{{Type: "distribution", URL: uri}}
+		}
+
+		if sha256, ok := bom.Metadata["sha256"].(string); ok && sha256 != "" {
+			c.Hashes = []Hash{{Algorithm: "SHA-256", Content: sha256}}
+		}
+
+		if bomPurl, ok := bom.Metadata["purl"].(string); ok && bomPurl != "" {
+			c.PURL = bomPurl
+		}
+
+		if entries, ok := bom.Metadata["licenses"].([]interface{}); ok {
+			for _, entry := range entries {
+				if license, ok := entry.(map[string]interface{}); ok {
+					if licenseType, ok := license["type"].(string); ok && licenseType != "" {
+						c.Licenses = append(c.Licenses, licenses(licenseType)...)
+					}
+				}
+			}
+		}
+
+		b.add(c)
+	}
+
+	return nil
+}
+
+func (b *builder) addGitDependency(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.GitSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	commit, _ := dependency.Source.Version["commit"].(string)
+
+	c := Component{
+		Type:               LibraryType,
+		Name:               sourceMetadata.URL,
+		Version:            commit,
+		ExternalReferences: []ExternalReference{{Type: "vcs", URL: sourceMetadata.URL}},
+	}
+	for _, ref := range sourceMetadata.Refs {
+		c.Properties = append(c.Properties, Property{Name: "deplab:git_ref", Value: ref})
+	}
+
+	b.add(c)
+
+	return nil
+}
+
+func (b *builder) addArchiveDependency(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.ArchiveSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	b.add(Component{
+		Type:               LibraryType,
+		Name:               sourceMetadata.URL,
+		ExternalReferences: []ExternalReference{{Type: "distribution", URL: sourceMetadata.URL}},
+	})
+
+	return nil
+}
+
+func licenses(license string) []LicenseChoice {
+	if license == "" {
+		return nil
+	}
+	return []LicenseChoice{{License: License{Name: license}}}
+}
+
+func tools(provenance []metadata.Provenance) []Tool {
+	var result []Tool
+	for _, p := range provenance {
+		tool := Tool{Name: p.Name, Version: p.Version}
+		if p.URL != "" {
+			tool.ExternalReferences = []ExternalReference{{Type: "website", URL: p.URL}}
+		}
+		result = append(result, tool)
+	}
+	return result
+}
+
+func newSerialNumber() (string, error) {
+	u := make([]byte, 16)
+	_, err := rand.Read(u)
+	if err != nil {
+		return "", fmt.Errorf("could not generate serial number: %w", err)
+	}
+
+	// version 4, variant 10 as per RFC 4122
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package cyclonedx_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("BuildBOM", func() {
+	var (
+		md        metadata.Metadata
+		timestamp = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		md = metadata.Metadata{
+			Base: metadata.Base{
+				"id":          "photon",
+				"version_id":  "3.0",
+				"pretty_name": "VMware Photon OS/Linux",
+			},
+			Provenance: []metadata.Provenance{{Name: "deplab", Version: "0.42.0", URL: "https://example.com/deplab"}},
+			Dependencies: []metadata.Dependency{
+				{
+					Type: metadata.DebianPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.DebianPackageListSourceMetadata{
+							Packages: []metadata.DpkgPackage{
+								{Package: "tzdata", Version: "2019c-0ubuntu0.18.04", Architecture: "all", Source: metadata.PackageSource{Package: "tzdata", Version: "2019c-0ubuntu0.18.04"}},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.RPMPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						// as read back from a label
+						Metadata: map[string]interface{}{
+							"packages": []interface{}{
+								map[string]interface{}{"package": "bash", "version": "4.4.18", "architecture": "x86_64", "license": "GPLv3+", "source_rpm": "bash-4.4.18-1.src.rpm"},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.BuildpackMetadataType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.BuildpackBOMSourceMetadata{
+							BillOfMaterials: []metadata.BuildpackBOM{
+								{
+									Name:      "openjdk-jre",
+									Version:   "11.0.6",
+									Metadata:  metadata.BuildpackBOMMetadata{"sha256": "abc123", "uri": "https://example.com/openjdk.tar.gz"},
+									Buildpack: metadata.Buildpack{ID: "org.cloudfoundry.openjdk", Version: "1.0.0"},
+								},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.PackageType,
+					Source: metadata.Source{
+						Type:     metadata.GitSourceType,
+						Version:  map[string]interface{}{"commit": "d2c1234"},
+						Metadata: metadata.GitSourceMetadata{URL: "https://example.com/repo.git", Refs: []string{"0.5.0"}},
+					},
+				},
+				{
+					Type: metadata.PackageType,
+					Source: metadata.Source{
+						Type:     metadata.ArchiveType,
+						Metadata: metadata.ArchiveSourceMetadata{URL: "https://example.com/source.tar.gz"},
+					},
+				},
+			},
+		}
+	})
+
+	It("describes the image in the bom metadata", func() {
+		bom, err := BuildBOM(md, "example.com/image:tag", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.BOMFormat).To(Equal("CycloneDX"))
+		Expect(bom.SpecVersion).To(Equal("1.5"))
+		Expect(bom.Version).To(Equal(1))
+		Expect(bom.SerialNumber).To(MatchRegexp(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(bom.Metadata.Timestamp).To(Equal("2020-01-02T03:04:05Z"))
+		Expect(bom.Metadata.Component).To(Equal(Component{Type: "container", Name: "example.com/image:tag"}))
+	})
+
+	It("uses the provenance as tools", func() {
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Metadata.Tools).To(ConsistOf(Tool{
+			Name:               "deplab",
+			Version:            "0.42.0",
+			ExternalReferences: []ExternalReference{{Type: "website", URL: "https://example.com/deplab"}},
+		}))
+	})
+
+	It("uses the base as the operating system component", func() {
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Type":        Equal("operating-system"),
+			"Name":        Equal("photon"),
+			"Version":     Equal("3.0"),
+			"Description": Equal("VMware Photon OS/Linux"),
+		})))
+	})
+
+	It("maps every dependency to components", func() {
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(HaveLen(6))
+		Expect(bom.Components).To(SatisfyAll(
+			ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name": Equal("tzdata"),
+				"PURL": Equal("pkg:deb/photon/tzdata@2019c-0ubuntu0.18.04?arch=all"),
+			})),
+			ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name":     Equal("bash"),
+				"PURL":     Equal("pkg:rpm/photon/bash@4.4.18?arch=x86_64"),
+				"Licenses": ConsistOf(LicenseChoice{License: License{Name: "GPLv3+"}}),
+			})),
+			ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name":               Equal("openjdk-jre"),
+				"Hashes":             ConsistOf(Hash{Algorithm: "SHA-256", Content: "abc123"}),
+				"ExternalReferences": ConsistOf(ExternalReference{Type: "distribution", URL: "https://example.com/openjdk.tar.gz"}),
+			})),
+			ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name":               Equal("https://example.com/repo.git"),
+				"Version":            Equal("d2c1234"),
+				"ExternalReferences": ConsistOf(ExternalReference{Type: "vcs", URL: "https://example.com/repo.git"}),
+				"Properties":         ConsistOf(Property{Name: "deplab:git_ref", Value: "0.5.0"}),
+			})),
+			ContainElement(MatchFields(IgnoreExtras, Fields{
+				"ExternalReferences": ConsistOf(ExternalReference{Type: "distribution", URL: "https://example.com/source.tar.gz"}),
+			})),
+		))
+	})
+
+	It("maps apk packages to components", func() {
+		md = metadata.Metadata{
+			Base: metadata.Base{"id": "alpine", "version_id": "3.11.6"},
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.ApkPackageListSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					Metadata: metadata.ApkPackageListSourceMetadata{
+						Packages: []metadata.ApkPackage{{
+							Package:      "libcrypto1.1",
+							Version:      "1.1.1g-r0",
+							Architecture: "x86_64",
+							License:      "OpenSSL",
+							Origin:       "openssl",
+							Commit:       "a8c2c5bd",
+							Maintainer:   "Timo Teras <timo.teras@iki.fi>",
+						}},
+					},
+				},
+			}},
+		}
+
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":     Equal("libcrypto1.1"),
+			"Version":  Equal("1.1.1g-r0"),
+			"PURL":     Equal("pkg:apk/alpine/libcrypto1.1@1.1.1g-r0?arch=x86_64"),
+			"Licenses": ConsistOf(LicenseChoice{License: License{Name: "OpenSSL"}}),
+			"Properties": ConsistOf(
+				Property{Name: "deplab:origin", Value: "openssl"},
+				Property{Name: "deplab:commit", Value: "a8c2c5bd"},
+				Property{Name: "deplab:maintainer", Value: "Timo Teras <timo.teras@iki.fi>"},
+			),
+		})))
+	})
+
+	It("maps python packages to components", func() {
+		md = metadata.Metadata{
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.PythonPackageListSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					Metadata: metadata.PythonPackageListSourceMetadata{
+						Packages: []metadata.PythonPackage{{
+							Package:     "PyYAML",
+							Version:     "5.3.1",
+							License:     "MIT",
+							Installer:   "pip",
+							Interpreter: "/usr/local/bin/python3.8",
+							Location:    "/usr/local/lib/python3.8/site-packages",
+						}},
+					},
+				},
+			}},
+		}
+
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Type":     Equal(LibraryType),
+			"Name":     Equal("PyYAML"),
+			"Version":  Equal("5.3.1"),
+			"PURL":     Equal("pkg:pypi/pyyaml@5.3.1"),
+			"Licenses": ConsistOf(LicenseChoice{License: License{Name: "MIT"}}),
+			"Properties": ConsistOf(
+				Property{Name: "deplab:installer", Value: "pip"},
+				Property{Name: "deplab:interpreter", Value: "/usr/local/bin/python3.8"},
+				Property{Name: "deplab:location", Value: "/usr/local/lib/python3.8/site-packages"},
+			),
+		})))
+	})
+
+	It("maps maven packages to components with their group", func() {
+		md = metadata.Metadata{
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.MavenPackageListSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					Metadata: metadata.MavenPackageListSourceMetadata{
+						Packages: []metadata.MavenPackage{{
+							GroupID:    "org.springframework",
+							ArtifactID: "spring-core",
+							Version:    "5.2.9.RELEASE",
+							Path:       "/app/app.jar!/BOOT-INF/lib/spring-core-5.2.9.RELEASE.jar",
+						}},
+					},
+				},
+			}},
+		}
+
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Group":   Equal("org.springframework"),
+			"Name":    Equal("spring-core"),
+			"Version": Equal("5.2.9.RELEASE"),
+			"PURL":    Equal("pkg:maven/org.springframework/spring-core@5.2.9.RELEASE"),
+			"Properties": ConsistOf(
+				Property{Name: "deplab:path", Value: "/app/app.jar!/BOOT-INF/lib/spring-core-5.2.9.RELEASE.jar"},
+			),
+		})))
+	})
+
+	It("maps go binaries to an application component and its modules", func() {
+		md = metadata.Metadata{
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.GoBinariesSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					Metadata: metadata.GoBinariesSourceMetadata{
+						Binaries: []metadata.GoBinary{{
+							Path:      "/app/server",
+							GoVersion: "go1.15.2",
+							Package:   "example.com/server/cmd/server",
+							Module:    metadata.GoModule{Path: "example.com/server", Version: "v1.2.0"},
+							Modules: []metadata.GoModule{{
+								Path:    "github.com/spf13/cobra",
+								Version: "v0.0.5",
+								Sum:     "h1:f0B+LdTX/Jhs7XzfRmmKoP/E1J+S29lsHHmbGGrM1so=",
+							}},
+						}},
+					},
+				},
+			}},
+		}
+
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bom.Components).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(ApplicationType),
+				"Name":    Equal("example.com/server/cmd/server"),
+				"Version": Equal("v1.2.0"),
+				"PURL":    Equal("pkg:golang/example.com/server@v1.2.0"),
+				"Properties": ConsistOf(
+					Property{Name: "deplab:path", Value: "/app/server"},
+					Property{Name: "deplab:go_version", Value: "go1.15.2"},
+				),
+			}),
+			MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(LibraryType),
+				"Name":    Equal("github.com/spf13/cobra"),
+				"Version": Equal("v0.0.5"),
+				"PURL":    Equal("pkg:golang/github.com/spf13/cobra@v0.0.5"),
+				"Properties": ConsistOf(
+					Property{Name: "deplab:path", Value: "/app/server"},
+					Property{Name: "deplab:go_sum", Value: "h1:f0B+LdTX/Jhs7XzfRmmKoP/E1J+S29lsHHmbGGrM1so="},
+				),
+			}),
+		))
+	})
+
+	It("generates unique bom-refs", func() {
+		md.Dependencies = append(md.Dependencies, md.Dependencies...)
+
+		bom, err := BuildBOM(md, "image", timestamp)
+		Expect(err).ToNot(HaveOccurred())
+
+		refs := map[string]bool{}
+		for _, c := range bom.Components {
+			Expect(c.BOMRef).ToNot(BeEmpty())
+			Expect(refs).ToNot(HaveKey(c.BOMRef))
+			refs[c.BOMRef] = true
+		}
+	})
+})
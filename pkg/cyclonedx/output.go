@@ -0,0 +1,66 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package cyclonedx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+const (
+	JSONFormat = "json"
+	XMLFormat  = "xml"
+)
+
+var Formats = []string{JSONFormat, XMLFormat}
+
+func WriteCycloneDXFile(md metadata.Metadata, name string, cyclonedxFilePath string, format string) error {
+	bom, err := BuildBOM(md, name, time.Now())
+	if err != nil {
+		return fmt.Errorf("could not generate cyclonedx bom: %w", err)
+	}
+
+	cyclonedxFile, err := os.Create(cyclonedxFilePath)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %w", cyclonedxFilePath, err)
+	}
+	defer cyclonedxFile.Close()
+
+	err = Encode(cyclonedxFile, bom, format)
+	if err != nil {
+		return fmt.Errorf("could not write cyclonedx file: %w", err)
+	}
+	return nil
+}
+
+// Encode writes the bom to w in the given format, either json or xml
+func Encode(w io.Writer, bom BOM, format string) error {
+	switch format {
+	case JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(bom)
+	case XMLFormat:
+		_, err := io.WriteString(w, xml.Header)
+		if err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		err = encoder.Encode(bom)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n")
+		return err
+	default:
+		return fmt.Errorf("unsupported cyclonedx format %s", format)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package cyclonedx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+)
+
+var _ = Describe("outputs", func() {
+	Describe("Encode", func() {
+		var bom BOM
+
+		BeforeEach(func() {
+			var err error
+			bom, err = BuildBOM(test_utils.MetadataSample, "image", time.Now())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("writes json", func() {
+			buf := bytes.Buffer{}
+			Expect(Encode(&buf, bom, JSONFormat)).To(Succeed())
+
+			var fields map[string]interface{}
+			Expect(json.Unmarshal(buf.Bytes(), &fields)).To(Succeed())
+			Expect(fields).To(SatisfyAll(
+				HaveKeyWithValue("bomFormat", "CycloneDX"),
+				HaveKeyWithValue("specVersion", "1.5"),
+				HaveKey("components"),
+			))
+		})
+
+		It("writes xml in the cyclonedx namespace", func() {
+			buf := bytes.Buffer{}
+			Expect(Encode(&buf, bom, XMLFormat)).To(Succeed())
+
+			Expect(xml.Unmarshal(buf.Bytes(), &struct{}{})).To(Succeed())
+			Expect(buf.String()).To(SatisfyAll(
+				HavePrefix(`<?xml version="1.0" encoding="UTF-8"?>`),
+				ContainSubstring(`<bom xmlns="http://cyclonedx.org/schema/bom/1.5"`),
+				ContainSubstring(`<component type="library" bom-ref="pkg:deb/foobar@0.42.0-version?arch=amd46">`),
+				ContainSubstring(`<purl>pkg:deb/foobar@0.42.0-version?arch=amd46</purl>`),
+				Not(ContainSubstring("<hashes>")),
+			))
+		})
+
+		It("returns an error for an unsupported format", func() {
+			Expect(Encode(&bytes.Buffer{}, bom, "yaml")).To(MatchError(ContainSubstring("unsupported cyclonedx format yaml")))
+		})
+	})
+
+	Describe("WriteCycloneDXFile", func() {
+		DescribeTable("when the file can be written", func(path string) {
+			defer test_utils.CleanupFile(path)
+
+			err := WriteCycloneDXFile(test_utils.MetadataSample, "image", path, JSONFormat)
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`"bomFormat": "CycloneDX"`))
+		},
+			Entry("the file exists", test_utils.ExistingFileName()),
+			Entry("the file does not exists", test_utils.NonExistingFileName()),
+		)
+
+		Describe("and the bom can't be written", func() {
+			It("returns an error", func() {
+				err := WriteCycloneDXFile(test_utils.MetadataSample, "image", "a-path-that-does-not-exist/bom.json", JSONFormat)
+
+				Expect(err).To(MatchError(ContainSubstring("a-path-that-does-not-exist/bom.json")))
+			})
+		})
+	})
+})
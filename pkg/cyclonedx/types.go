@@ -0,0 +1,163 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package cyclonedx
+
+import "encoding/xml"
+
+const (
+	BOMFormat   = "CycloneDX"
+	SpecVersion = "1.5"
+	XMLNS       = "http://cyclonedx.org/schema/bom/1.5"
+
+	ContainerType       = "container"
+	OperatingSystemType = "operating-system"
+	LibraryType         = "library"
+	ApplicationType     = "application"
+)
+
+// BOM fields are declared in the order required by the CycloneDX XML schema
+type BOM struct {
+	XMLName      xml.Name    `json:"-" xml:"bom"`
+	XMLNS        string      `json:"-" xml:"xmlns,attr"`
+	BOMFormat    string      `json:"bomFormat" xml:"-"`
+	SpecVersion  string      `json:"specVersion" xml:"-"`
+	SerialNumber string      `json:"serialNumber" xml:"serialNumber,attr"`
+	Version      int         `json:"version" xml:"version,attr"`
+	Metadata     BOMMetadata `json:"metadata" xml:"metadata"`
+	Components   []Component `json:"components" xml:"components>component"`
+}
+
+type BOMMetadata struct {
+	Timestamp string    `json:"timestamp" xml:"timestamp"`
+	Tools     []Tool    `json:"tools,omitempty" xml:"tools>tool,omitempty"`
+	Component Component `json:"component" xml:"component"`
+}
+
+type Tool struct {
+	Vendor             string              `json:"vendor,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+}
+
+// MarshalXML omits the wrapping element of empty lists, which the
+// "parent>child" tag syntax of encoding/xml would otherwise always emit
+func (t Tool) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Vendor             string              `xml:"vendor,omitempty"`
+		Name               string              `xml:"name"`
+		Version            string              `xml:"version,omitempty"`
+		ExternalReferences *externalReferences `xml:"externalReferences,omitempty"`
+	}{
+		Vendor:             t.Vendor,
+		Name:               t.Name,
+		Version:            t.Version,
+		ExternalReferences: newExternalReferences(t.ExternalReferences),
+	}, start)
+}
+
+type Component struct {
+	Type               string              `json:"type"`
+	BOMRef             string              `json:"bom-ref,omitempty"`
+	Group              string              `json:"group,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	Hashes             []Hash              `json:"hashes,omitempty"`
+	Licenses           []LicenseChoice     `json:"licenses,omitempty"`
+	PURL               string              `json:"purl,omitempty"`
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+	Properties         []Property          `json:"properties,omitempty"`
+}
+
+// MarshalXML omits the wrapping element of empty lists, which the
+// "parent>child" tag syntax of encoding/xml would otherwise always emit
+func (c Component) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	xc := struct {
+		Type               string              `xml:"type,attr"`
+		BOMRef             string              `xml:"bom-ref,attr,omitempty"`
+		Group              string              `xml:"group,omitempty"`
+		Name               string              `xml:"name"`
+		Version            string              `xml:"version,omitempty"`
+		Description        string              `xml:"description,omitempty"`
+		Hashes             *hashes             `xml:"hashes,omitempty"`
+		Licenses           *licenseChoices     `xml:"licenses,omitempty"`
+		PURL               string              `xml:"purl,omitempty"`
+		ExternalReferences *externalReferences `xml:"externalReferences,omitempty"`
+		Properties         *properties         `xml:"properties,omitempty"`
+	}{
+		Type:               c.Type,
+		BOMRef:             c.BOMRef,
+		Group:              c.Group,
+		Name:               c.Name,
+		Version:            c.Version,
+		Description:        c.Description,
+		PURL:               c.PURL,
+		ExternalReferences: newExternalReferences(c.ExternalReferences),
+	}
+
+	if len(c.Hashes) != 0 {
+		xc.Hashes = &hashes{Hashes: c.Hashes}
+	}
+	if len(c.Licenses) != 0 {
+		xc.Licenses = &licenseChoices{Licenses: c.Licenses}
+	}
+	if len(c.Properties) != 0 {
+		xc.Properties = &properties{Properties: c.Properties}
+	}
+
+	return e.EncodeElement(xc, start)
+}
+
+type hashes struct {
+	Hashes []Hash `xml:"hash"`
+}
+
+type licenseChoices struct {
+	Licenses []LicenseChoice `xml:"license"`
+}
+
+type properties struct {
+	Properties []Property `xml:"property"`
+}
+
+type externalReferences struct {
+	References []ExternalReference `xml:"reference"`
+}
+
+func newExternalReferences(references []ExternalReference) *externalReferences {
+	if len(references) == 0 {
+		return nil
+	}
+	return &externalReferences{References: references}
+}
+
+type Hash struct {
+	Algorithm string `json:"alg" xml:"alg,attr"`
+	Content   string `json:"content" xml:",chardata"`
+}
+
+// LicenseChoice serializes as {"license": {"name": ...}} in JSON and
+// <license><name>...</name></license> in XML
+type LicenseChoice struct {
+	License License `json:"license" xml:"name"`
+}
+
+type License struct {
+	Name string `json:"name"`
+}
+
+func (l License) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(l.Name, start)
+}
+
+type ExternalReference struct {
+	Type string `json:"type" xml:"type,attr"`
+	URL  string `json:"url" xml:"url"`
+}
+
+type Property struct {
+	Name  string `json:"name" xml:"name,attr"`
+	Value string `json:"value" xml:",chardata"`
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/artifact"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/attestation"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/spdx"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Kinds of the artifacts attached with --attach
+const (
+	MetadataArtifact    = "metadata"
+	AttestationArtifact = "attestation"
+)
+
+var ArtifactKinds = []string{MetadataArtifact, SPDXOutput, CycloneDXOutput, AttestationArtifact}
+
+// attachArtifacts attaches the documents of the metadata to the input image,
+// which is left untouched, as artifacts whose subject is the image. They are
+// pushed to the --attach-repository, or the repository of the input image,
+// and written to the --attach-oci-layout.
+func attachArtifacts(dli image.LayerFSImage, params common.RunParams, md metadata.Metadata, signer *signature.Signer, started time.Time) error {
+	descriptor, err := dli.Descriptor()
+	if err != nil {
+		return err
+	}
+	subject := artifact.SubjectDescriptor(*descriptor)
+
+	var artifacts []artifact.Artifact
+	for _, kind := range params.Attach {
+		a, err := buildArtifact(kind, params, md, subject, signer, started)
+		if err != nil {
+			return fmt.Errorf("could not build %s artifact: %w", kind, err)
+		}
+		artifacts = append(artifacts, a)
+	}
+
+	var stores []artifact.Store
+
+	repository := params.AttachRepository
+	if repository == "" && params.AttachOCILayout == "" && params.InputImage != "" {
+		ref, err := name.ParseReference(params.InputImage)
+		if err != nil {
+			return fmt.Errorf("invalid image reference %s: %w", params.InputImage, err)
+		}
+		repository = ref.Context().String()
+	}
+	if repository != "" {
+		registry, err := artifact.NewRegistry(repository)
+		if err != nil {
+			return err
+		}
+		stores = append(stores, registry)
+	}
+
+	if params.AttachOCILayout != "" {
+		stores = append(stores, artifact.NewLayout(params.AttachOCILayout))
+	}
+
+	if len(stores) == 0 {
+		return fmt.Errorf("no repository or oci layout to attach the artifacts to")
+	}
+
+	created := time.Now()
+	for _, store := range stores {
+		if err := store.Attach(subject, artifacts, created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildArtifact(kind string, params common.RunParams, md metadata.Metadata, subject artifact.Descriptor, signer *signature.Signer, started time.Time) (artifact.Artifact, error) {
+	name := documentName(params.InputImage, params.InputImageTarPath, "")
+
+	switch kind {
+	case MetadataArtifact:
+		content, err := json.Marshal(md)
+		if err != nil {
+			return artifact.Artifact{}, fmt.Errorf("could not marshal json: %w", err)
+		}
+		return artifact.Artifact{Type: artifact.MetadataType, Content: content}, nil
+
+	case SPDXOutput:
+		doc, err := spdx.BuildDocument(md, name, Provenance, time.Now())
+		if err != nil {
+			return artifact.Artifact{}, err
+		}
+		content, err := json.Marshal(doc)
+		if err != nil {
+			return artifact.Artifact{}, fmt.Errorf("could not marshal json: %w", err)
+		}
+		return artifact.Artifact{Type: artifact.SPDXType, Content: content}, nil
+
+	case CycloneDXOutput:
+		bom, err := cyclonedx.BuildBOM(md, name, time.Now())
+		if err != nil {
+			return artifact.Artifact{}, err
+		}
+		var content bytes.Buffer
+		if err := cyclonedx.Encode(&content, bom, params.CycloneDXFormat); err != nil {
+			return artifact.Artifact{}, err
+		}
+		artifactType := artifact.CycloneDXJSONType
+		if params.CycloneDXFormat == cyclonedx.XMLFormat {
+			artifactType = artifact.CycloneDXXMLType
+		}
+		return artifact.Artifact{Type: artifactType, Content: content.Bytes()}, nil
+
+	case AttestationArtifact:
+		statement, err := attestation.BuildStatement(md, attestation.Build{
+			Name:        name,
+			Digest:      subject.Digest,
+			Image:       name,
+			ImageDigest: subject.Digest,
+			Builder:     Provenance,
+			StartedOn:   started,
+			FinishedOn:  time.Now(),
+		}, params.AttestationPredicate)
+		if err != nil {
+			return artifact.Artifact{}, err
+		}
+
+		if signer == nil {
+			content, err := json.Marshal(statement)
+			if err != nil {
+				return artifact.Artifact{}, fmt.Errorf("could not marshal json: %w", err)
+			}
+			return artifact.Artifact{Type: artifact.InTotoType, Content: content}, nil
+		}
+
+		envelope, err := attestation.Sign(statement, signer)
+		if err != nil {
+			return artifact.Artifact{}, err
+		}
+		content, err := json.Marshal(envelope)
+		if err != nil {
+			return artifact.Artifact{}, fmt.Errorf("could not marshal json: %w", err)
+		}
+		return artifact.Artifact{Type: artifact.DSSEType, Content: content}, nil
+
+	default:
+		return artifact.Artifact{}, fmt.Errorf("unknown artifact %s", kind)
+	}
+}
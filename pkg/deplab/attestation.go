@@ -0,0 +1,48 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/attestation"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+// writeAttestation writes an in-toto statement about the labelled image,
+// signed in a DSSE envelope when a signing key was given
+func writeAttestation(dli image.LayerFSImage, params common.RunParams, md metadata.Metadata, signer *signature.Signer, started time.Time) error {
+	digest, err := dli.LabelledDigest(md)
+	if err != nil {
+		return err
+	}
+
+	imageDigest, err := dli.Digest()
+	if err != nil {
+		return err
+	}
+
+	name := params.OutputImage
+	if name == "" {
+		name = documentName(params.InputImage, params.InputImageTarPath, params.Tag)
+	}
+
+	statement, err := attestation.BuildStatement(md, attestation.Build{
+		Name:        name,
+		Digest:      digest,
+		Image:       documentName(params.InputImage, params.InputImageTarPath, ""),
+		ImageDigest: imageDigest,
+		Builder:     Provenance,
+		StartedOn:   started,
+		FinishedOn:  time.Now(),
+	}, params.AttestationPredicate)
+	if err != nil {
+		return err
+	}
+
+	return attestation.WriteAttestationFile(statement, signer, params.AttestationFilePath)
+}
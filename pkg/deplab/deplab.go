@@ -7,29 +7,40 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/vmware-tanzu/dependency-labeler/pkg/kpack"
 	"os"
-	"strings"
+	"path/filepath"
+	"time"
 
-	"github.com/vmware-tanzu/dependency-labeler/pkg/cnb"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
 
-	"github.com/vmware-tanzu/dependency-labeler/pkg/additionalsources"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
-	"github.com/vmware-tanzu/dependency-labeler/pkg/rpm"
-
-	"github.com/vmware-tanzu/dependency-labeler/pkg/git"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
 
-	"github.com/vmware-tanzu/dependency-labeler/pkg/osrelease"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/spdx"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
 type provider func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)
 
+const (
+	JSONOutput      = "json"
+	SPDXOutput      = "spdx"
+	CycloneDXOutput = "cyclonedx"
+)
+
+var OutputFormats = []string{JSONOutput, SPDXOutput, CycloneDXOutput}
+
 var Version = "0.0.0-dev"
 var Provenance = metadata.Provenance{
 	Name:    "deplab",
@@ -38,30 +49,38 @@ var Provenance = metadata.Provenance{
 }
 
 func Run(params common.RunParams) error {
+	started := time.Now()
+
+	providers, err := SelectProviders(false, params.Providers, params.SkipProviders)
+	if err != nil {
+		return fmt.Errorf("could not select providers: %w", err)
+	}
+
+	var signer *signature.Signer
+	if params.SigningKeyPath != "" {
+		signer, err = signature.LoadSigner(params.SigningKeyPath)
+		if err != nil {
+			return fmt.Errorf("could not load signing key: %w", err)
+		}
+	}
+
 	dli, err := image.NewDeplabImage(params.InputImage, params.InputImageTarPath)
 
 	if err != nil {
 		return fmt.Errorf("could not load image: %w", err)
 	}
 	defer dli.Cleanup()
+	dli = dli.WithSigner(signer)
+	if params.CompressLabel {
+		dli = dli.WithCompressedLabel(params.CompressLabelThreshold)
+	}
 
 	md := metadata.Metadata{Dependencies: make([]metadata.Dependency, 0)}
+	collector := warnings.NewCollector()
 
-	for _, provider := range []provider{
-		dpkg.Provider,
-		rpm.Provider,
-		cnb.Provider,
-		git.Provider,
-		additionalsources.ArchiveUrlProvider,
-		additionalsources.AdditionalSourcesProvider,
-		osrelease.Provider,
-		ProvenanceProvider,
-	} {
-		if md2, err := provider(&dli, params, md); err == nil {
-			md = md2
-		} else {
-			return fmt.Errorf("error generating dependencies: %w", err)
-		}
+	md, err = runProviders(&dli, providers, params, collector, md)
+	if err != nil {
+		return fmt.Errorf("error generating dependencies: %w", err)
 	}
 
 	err = writeOutputs(dli, params, md)
@@ -69,35 +88,141 @@ func Run(params common.RunParams) error {
 		return fmt.Errorf("could not write outputs: %w", err)
 	}
 
-	return nil
+	if params.AttestationFilePath != "" {
+		err = writeAttestation(dli, params, md, signer, started)
+		if err != nil {
+			return fmt.Errorf("could not write attestation file: %w", err)
+		}
+	}
+
+	if len(params.Attach) > 0 {
+		err = attachArtifacts(dli, params, md, signer, started)
+		if err != nil {
+			return fmt.Errorf("could not attach artifacts: %w", err)
+		}
+	}
+
+	return reportWarnings(collector, params.WarningsFilePath)
+}
+
+func RunInspect(params common.InspectParams) error {
+	collector := warnings.NewCollector()
+
+	dli, err := openImage(params.InputImage, params.InputImageTarPath)
+	if err != nil {
+		return err
+	}
+	defer dli.Cleanup()
+
+	// the signature is checked against the label of the image the metadata
+	// is read from, so that a tag moved in between is not trusted
+	if params.VerifyKeyPath != "" {
+		err := verifySignature(&dli, params.VerifyKeyPath)
+		if err != nil {
+			return fmt.Errorf("inspect cannot verify the signature of the label of image '%s%s': %w", params.InputImageTarPath, params.InputImage, err)
+		}
+	}
+
+	inspectMetadata, err := inspectImage(&dli, params.InputImage, params.InputImageTarPath, common.RunParams{
+		Providers:          params.Providers,
+		SkipProviders:      params.SkipProviders,
+		DpkgLicenses:       params.DpkgLicenses,
+		VerifyPackages:     params.VerifyPackages,
+		UnaccountedFiles:   params.UnaccountedFiles,
+		AttributeLayers:    params.AttributeLayers,
+		BaseImage:          params.BaseImage,
+		BaseImageTarPath:   params.BaseImageTarPath,
+		AnnotatedBaseImage: params.AnnotatedBaseImage,
+		ReferrersOCILayout: params.ReferrersOCILayout,
+	}, collector)
+	if err != nil {
+		return err
+	}
+
+	name := documentName(params.InputImage, params.InputImageTarPath, "")
+
+	switch params.OutputFormat {
+	case SPDXOutput:
+		doc, buildErr := spdx.BuildDocument(inspectMetadata, name, Provenance, time.Now())
+		if buildErr != nil {
+			return fmt.Errorf("inspect cannot generate spdx document for image '%s%s': %w", params.InputImageTarPath, params.InputImage, buildErr)
+		}
+		err = printJSON(doc)
+	case CycloneDXOutput:
+		bom, buildErr := cyclonedx.BuildBOM(inspectMetadata, name, time.Now())
+		if buildErr != nil {
+			return fmt.Errorf("inspect cannot generate cyclonedx bom for image '%s%s': %w", params.InputImageTarPath, params.InputImage, buildErr)
+		}
+		err = cyclonedx.Encode(os.Stdout, bom, params.CycloneDXFormat)
+	default:
+		err = printJSON(inspectMetadata)
+	}
+	if err != nil {
+		return err
+	}
+
+	return reportWarnings(collector, params.WarningsFilePath)
+}
+
+// inspect reads the metadata of an image, merging what the providers find
+// with any existing deplab label. The providers are selected by, and run
+// with, the given params.
+func inspect(inputImage, inputImageTarPath string, params common.RunParams, collector *warnings.Collector) (metadata.Metadata, error) {
+	dli, err := openImage(inputImage, inputImageTarPath)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	defer dli.Cleanup()
+
+	return inspectImage(&dli, inputImage, inputImageTarPath, params, collector)
+}
+
+// openImage loads the image to inspect
+func openImage(inputImage, inputImageTarPath string) (image.LayerFSImage, error) {
+	dli, err := image.NewDeplabImage(inputImage, inputImageTarPath)
+	if err != nil {
+		return image.LayerFSImage{}, fmt.Errorf("inspect cannot open the provided image from '%s%s': %s", inputImage, inputImageTarPath, err)
+	}
+	return dli, nil
 }
 
-func RunInspect(inputImage, inputImageTar string) error {
-	dli, err := image.NewDeplabImage(inputImage, inputImageTar)
+// inspectImage runs the inspect providers against the loaded image
+func inspectImage(dli image.Image, inputImage, inputImageTarPath string, params common.RunParams, collector *warnings.Collector) (metadata.Metadata, error) {
+	providers, err := SelectProviders(true, params.Providers, params.SkipProviders)
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("inspect cannot select providers: %w", err)
+	}
 
+	params.InputImage, params.InputImageTarPath = inputImage, inputImageTarPath
+
+	inspectMetadata, err := runProviders(dli, providers, params, collector, metadata.Metadata{})
 	if err != nil {
-		return fmt.Errorf("inspect cannot open the provided image from '%s%s': %s", inputImage, inputImageTar, err)
+		return metadata.Metadata{}, fmt.Errorf("inspect error generating dependencies for image '%s%s': %w", inputImageTarPath, inputImage, err)
 	}
 
-	inspectMetadata := metadata.Metadata{}
+	return inspectMetadata, nil
+}
+
+// reportWarnings prints the summary of the warnings to stderr and writes them
+// to the warnings file, if any
+func reportWarnings(collector *warnings.Collector, warningsFilePath string) error {
+	err := warnings.WriteSummary(os.Stderr, collector.Warnings())
+	if err != nil {
+		return fmt.Errorf("could not print warnings: %w", err)
+	}
 
-	for _, provider := range []provider{
-		dpkg.Provider,
-		rpm.Provider,
-		cnb.Provider,
-		osrelease.Provider,
-		kpack.Provider,
-		ProvenanceProvider,
-		ExistingLabelProvider,
-	} {
-		if md2, err := provider(&dli, common.RunParams{}, inspectMetadata); err == nil {
-			inspectMetadata = md2
-		} else {
-			return fmt.Errorf("inspect error generating dependencies for image '%s%s': %w", inputImageTar, inputImage, err)
+	if warningsFilePath != "" {
+		err := warnings.WriteWarningsFile(collector.Warnings(), warningsFilePath)
+		if err != nil {
+			return fmt.Errorf("could not write warnings file: %w", err)
 		}
 	}
 
-	label, err := json.Marshal(inspectMetadata)
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	label, err := json.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("cannot generate json: %w", err)
 	}
@@ -105,7 +230,7 @@ func RunInspect(inputImage, inputImageTar string) error {
 	stdOutBuffer := bytes.Buffer{}
 	err = json.Indent(&stdOutBuffer, label, "", "  ")
 	if err != nil {
-		return fmt.Errorf("inspect cannot pretty print the label of the provided image '%s%s', label: %s: %w", inputImageTar, inputImage, label, err)
+		return fmt.Errorf("inspect cannot pretty print the label %s: %w", label, err)
 	}
 
 	fmt.Println(stdOutBuffer.String())
@@ -121,6 +246,22 @@ func writeOutputs(dli image.Image, params common.RunParams, md metadata.Metadata
 		}
 	}
 
+	if params.OutputImage != "" {
+		err := dli.PushWithMetadata(md, params.OutputImage)
+
+		if err != nil {
+			return fmt.Errorf("error pushing image to %s: %w", params.OutputImage, err)
+		}
+	}
+
+	if params.OutputOCILayout != "" {
+		err := dli.WriteOCILayoutWithMetadata(md, params.OutputOCILayout, params.Tag)
+
+		if err != nil {
+			return fmt.Errorf("error writing oci layout to %s: %w", params.OutputOCILayout, err)
+		}
+	}
+
 	if params.MetadataFilePath != "" {
 		err := metadata.WriteMetadataFile(md, params.MetadataFilePath)
 		if err != nil {
@@ -135,22 +276,101 @@ func writeOutputs(dli image.Image, params common.RunParams, md metadata.Metadata
 		}
 	}
 
+	if params.SPDXFilePath != "" {
+		err := spdx.WriteSPDXFile(md, documentName(params.InputImage, params.InputImageTarPath, params.Tag), Provenance, params.SPDXFilePath)
+		if err != nil {
+			return fmt.Errorf("could not write spdx file: %w", err)
+		}
+	}
+
+	if params.CycloneDXFilePath != "" {
+		err := cyclonedx.WriteCycloneDXFile(md, documentName(params.InputImage, params.InputImageTarPath, params.Tag), params.CycloneDXFilePath, params.CycloneDXFormat)
+		if err != nil {
+			return fmt.Errorf("could not write cyclonedx file: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// documentName identifies the image in generated documents, preferring the
+// output tag over the name of the input
+func documentName(inputImage, inputImageTar, tag string) string {
+	if tag != "" {
+		return tag
+	}
+	if inputImage != "" {
+		return inputImage
+	}
+	return filepath.Base(inputImageTar)
+}
+
+// verifySignature checks the signature label of the image against its deplab
+// label, with the public key at verifyKeyPath
+func verifySignature(dli image.Image, verifyKeyPath string) error {
+	verifier, err := signature.LoadVerifier(verifyKeyPath)
+	if err != nil {
+		return fmt.Errorf("could not load verification key: %w", err)
+	}
+
+	config, err := dli.GetConfig()
+	if err != nil {
+		return fmt.Errorf("could not read the config of the image: %w", err)
+	}
+
+	label, ok, err := metadata.ReadLabel(config.Config.Labels)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("the image has no deplab label")
+	}
+	signatureLabel, ok := config.Config.Labels[signature.LabelName]
+	if !ok {
+		return fmt.Errorf("the image has no %s label", signature.LabelName)
+	}
+
+	return verifier.Verify(label, signatureLabel)
+}
+
+// imageConfig reads the config of the image, without reading its layers
+func imageConfig(inputImage, inputImageTarPath string) (*v1.ConfigFile, error) {
+	var (
+		img v1.Image
+		err error
+	)
+	if inputImage != "" {
+		img, err = crane.Pull(inputImage)
+	} else {
+		img, err = crane.Load(inputImageTarPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load image: %w", err)
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the config of the image: %w", err)
+	}
+	return config, nil
+}
+
 func ProvenanceProvider(_ image.Image, _ common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
 	md.Provenance = append(md.Provenance, Provenance)
 	return md, nil
 }
 
-func ExistingLabelProvider(dli image.Image, _ common.RunParams, md metadata.Metadata) (m metadata.Metadata, err error) {
+func ExistingLabelProvider(dli image.Image, params common.RunParams, md metadata.Metadata) (m metadata.Metadata, err error) {
 	cf, err := dli.GetConfig()
 	if err != nil {
 		return metadata.Metadata{}, fmt.Errorf("cannot retrieve the Config file: %w", err)
 	}
 
 	existingMetadata := metadata.Metadata{}
-	existinglabel, foundDeplab := cf.Config.Labels["io.deplab.metadata"]
+	existinglabel, foundDeplab, err := metadata.ReadLabel(cf.Config.Labels)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
 
 	if foundDeplab {
 		var err = json.Unmarshal([]byte(existinglabel), &existingMetadata)
@@ -166,13 +386,9 @@ func ExistingLabelProvider(dli image.Image, _ common.RunParams, md metadata.Meta
 		}
 	}
 
-	mergedMetadata, warnings := metadata.Merge(existingMetadata, md)
-	if len(warnings) > 0 {
-		var warnStrings []string
-		for _, warning := range warnings {
-			warnStrings = append(warnStrings, string(warning))
-		}
-		fmt.Fprintln(os.Stderr, "Difference identified in matching metadata elements already present on image:", strings.Join(warnStrings, ", "))
+	mergedMetadata, mergeWarnings := metadata.Merge(existingMetadata, md)
+	for _, warning := range mergeWarnings {
+		params.Warnings.Add(warnings.ExistingLabelMismatch, string(warning), "difference identified in matching metadata elements already present on image")
 	}
 
 	return mergedMetadata, nil
@@ -0,0 +1,57 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/diff"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+const TextOutput = "text"
+
+var DiffOutputFormats = []string{TextOutput, JSONOutput}
+
+func RunDiff(params common.DiffParams) error {
+	collector := warnings.NewCollector()
+
+	from, err := readMetadata(params.FromImage, params.FromImageTarPath, params.FromMetadataFilePath, collector)
+	if err != nil {
+		return fmt.Errorf("diff cannot read the first image: %w", err)
+	}
+
+	to, err := readMetadata(params.ToImage, params.ToImageTarPath, params.ToMetadataFilePath, collector)
+	if err != nil {
+		return fmt.Errorf("diff cannot read the second image: %w", err)
+	}
+
+	d, err := diff.Compare(from, to)
+	if err != nil {
+		return fmt.Errorf("diff cannot compare the metadata: %w", err)
+	}
+
+	if params.OutputFormat == JSONOutput {
+		err = printJSON(d)
+	} else {
+		err = diff.WriteText(os.Stdout, d)
+	}
+	if err != nil {
+		return err
+	}
+
+	return reportWarnings(collector, "")
+}
+
+// readMetadata reads the metadata of an image like inspect does, or from a
+// metadata file written by deplab
+func readMetadata(inputImage, inputImageTarPath, metadataFilePath string, collector *warnings.Collector) (metadata.Metadata, error) {
+	if metadataFilePath != "" {
+		return metadata.ReadMetadataFile(metadataFilePath)
+	}
+	return inspect(inputImage, inputImageTarPath, common.RunParams{}, collector)
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+// readLabelledMetadata reads the metadata of the deplab label of an image, as
+// it is, or from a metadata file written by deplab. Images without a label are
+// inspected. The config of the image is returned along, nil for a metadata
+// file.
+func readLabelledMetadata(inputImage, inputImageTarPath, metadataFilePath string, collector *warnings.Collector) (metadata.Metadata, *v1.ConfigFile, error) {
+	if metadataFilePath != "" {
+		md, err := metadata.ReadMetadataFile(metadataFilePath)
+		return md, nil, err
+	}
+
+	config, err := imageConfig(inputImage, inputImageTarPath)
+	if err != nil {
+		return metadata.Metadata{}, nil, err
+	}
+
+	label, ok, err := metadata.ReadLabel(config.Config.Labels)
+	if err != nil {
+		return metadata.Metadata{}, nil, err
+	}
+	if !ok {
+		md, err := inspect(inputImage, inputImageTarPath, common.RunParams{}, collector)
+		return md, config, err
+	}
+
+	md := metadata.Metadata{}
+	if err := json.Unmarshal([]byte(label), &md); err != nil {
+		return metadata.Metadata{}, nil, fmt.Errorf("cannot parse the label %s: %w", metadata.LabelName, err)
+	}
+	return md, config, nil
+}
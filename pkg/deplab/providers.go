@@ -0,0 +1,298 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/additionalsources"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/apk"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/artifact"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/baseimage"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cnb"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/git"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/gobinary"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/java"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/kpack"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/layers"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/npm"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/osrelease"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/python"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/rpm"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/unaccounted"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+// Requirement is what a provider needs from the image it runs against
+type Requirement string
+
+const (
+	// ImageFiles is met by reading files from the image layers
+	ImageFiles Requirement = "files"
+	// ImageConfig reads the labels of the image config
+	ImageConfig Requirement = "config"
+	// ImageReferrers reads the artifacts referring to the image
+	ImageReferrers Requirement = "referrers"
+)
+
+// scanner returns the visitor of the files a provider reads, in the walk of
+// the image shared by all the providers, and the provider to run in place of
+// Run once the walk is done
+type scanner func(image.Image, common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error))
+
+// NamedProvider is a provider which can be selected by name with the
+// --providers and --skip-providers flags
+type NamedProvider struct {
+	Name         string
+	Description  string
+	Requirements []Requirement
+	Generate     bool
+	Inspect      bool
+	Run          provider
+	Scan         scanner
+}
+
+// Providers are run in this order, by deplab when Generate is set and by
+// deplab inspect when Inspect is set
+var Providers = []NamedProvider{
+	{
+		Name:         "dpkg",
+		Description:  "debian packages and apt sources",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          dpkg.Provider,
+		Scan:         dpkg.Scan,
+	},
+	{
+		Name:         "rpm",
+		Description:  "packages of the rpm database",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          rpm.Provider,
+	},
+	{
+		Name:         "apk",
+		Description:  "alpine packages and repositories",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          apk.Provider,
+	},
+	{
+		Name:         "go",
+		Description:  "build information of go binaries",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          gobinary.Provider,
+		Scan:         gobinary.Scan,
+	},
+	{
+		Name:         "python",
+		Description:  "python distributions of site-packages and dist-packages directories",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          python.Provider,
+		Scan:         python.Scan,
+	},
+	{
+		Name:         "npm",
+		Description:  "npm packages of node_modules directories",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          npm.Provider,
+		Scan:         npm.Scan,
+	},
+	{
+		Name:         "java",
+		Description:  "maven packages of jar, war and ear archives",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          java.Provider,
+		Scan:         java.Scan,
+	},
+	{
+		Name:         "unaccounted-files",
+		Description:  "executables, shared libraries and archives owned by no package, with --unaccounted-files",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          unaccounted.Provider,
+		Scan:         unaccounted.Scan,
+	},
+	{
+		Name:         "layers",
+		Description:  "image layers which introduced the packages, with --attribute-layers",
+		Requirements: []Requirement{ImageFiles, ImageConfig},
+		Generate:     true,
+		Inspect:      true,
+		Run:          layers.Provider,
+	},
+	{
+		Name:         "cnb",
+		Description:  "bill of materials of cloud native buildpacks",
+		Requirements: []Requirement{ImageConfig},
+		Generate:     true,
+		Inspect:      true,
+		Run:          cnb.Provider,
+	},
+	{
+		Name:        "git",
+		Description: "commits of the --git repositories",
+		Generate:    true,
+		Run:         git.Provider,
+	},
+	{
+		Name:        "additional-source-url",
+		Description: "archives of the --additional-source-url flags",
+		Generate:    true,
+		Run:         additionalsources.ArchiveUrlProvider,
+	},
+	{
+		Name:        "additional-sources-file",
+		Description: "archives and git repositories of the --additional-sources-file flags",
+		Generate:    true,
+		Run:         additionalsources.AdditionalSourcesProvider,
+	},
+	{
+		Name:         "os-release",
+		Description:  "base operating system of the image",
+		Requirements: []Requirement{ImageFiles},
+		Generate:     true,
+		Inspect:      true,
+		Run:          osrelease.Provider,
+	},
+	{
+		Name:         "kpack",
+		Description:  "source repository of images built by kpack",
+		Requirements: []Requirement{ImageConfig},
+		Inspect:      true,
+		Run:          kpack.Provider,
+	},
+	{
+		Name:         "base-image",
+		Description:  "packages of the base image apart from the application, with --base-image, --base-image-tar or the base image annotations",
+		Requirements: []Requirement{ImageFiles, ImageConfig},
+		Generate:     true,
+		Inspect:      true,
+		Run:          baseimage.Provider,
+	},
+	{
+		Name:        "provenance",
+		Description: "version of deplab",
+		Generate:    true,
+		Inspect:     true,
+		Run:         ProvenanceProvider,
+	},
+	{
+		Name:         "existing-label",
+		Description:  "metadata of an existing deplab label, merged with the inspected metadata",
+		Requirements: []Requirement{ImageConfig},
+		Inspect:      true,
+		Run:          ExistingLabelProvider,
+	},
+	{
+		Name:         "referrers",
+		Description:  "metadata attached to the image as artifacts, in its repository or the --referrers-oci-layout",
+		Requirements: []Requirement{ImageReferrers},
+		Inspect:      true,
+		Run:          artifact.Provider,
+	},
+}
+
+// runProviders runs the providers in order against the image, each with the
+// params and the warnings of its name. The files read by the providers are
+// visited in a single walk of the image beforehand.
+func runProviders(dli image.Image, providers []NamedProvider, params common.RunParams, collector *warnings.Collector, md metadata.Metadata) (metadata.Metadata, error) {
+	paramsOf := func(p NamedProvider) common.RunParams {
+		providerParams := params
+		providerParams.Warnings = collector.ForProvider(p.Name)
+		return providerParams
+	}
+
+	runs := make([]provider, len(providers))
+	var visitors []image.FileVisitor
+	for i, p := range providers {
+		runs[i] = p.Run
+		if p.Scan != nil {
+			visitor, run := p.Scan(dli, paramsOf(p))
+			visitors = append(visitors, visitor)
+			runs[i] = run
+		}
+	}
+
+	if err := image.WalkVisitors(dli, visitors...); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not walk the image files: %w", err)
+	}
+
+	for i, p := range providers {
+		var err error
+		md, err = runs[i](dli, paramsOf(p), md)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+	}
+	return md, nil
+}
+
+// SelectProviders returns the providers of the command, in order, restricted
+// to the names given, if any, and without the skipped names
+func SelectProviders(inspect bool, names, skip []string) ([]NamedProvider, error) {
+	err := validateProviderNames(append(append([]string{}, names...), skip...))
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []NamedProvider
+	for _, p := range Providers {
+		if inspect && !p.Inspect || !inspect && !p.Generate {
+			continue
+		}
+		if len(names) > 0 && !contains(names, p.Name) {
+			continue
+		}
+		if contains(skip, p.Name) {
+			continue
+		}
+		selected = append(selected, p)
+	}
+
+	return selected, nil
+}
+
+func ProviderNames() []string {
+	var names []string
+	for _, p := range Providers {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func validateProviderNames(names []string) error {
+	for _, name := range names {
+		if !contains(ProviderNames(), name) {
+			return fmt.Errorf("unknown provider %s, must be one of: %s", name, strings.Join(ProviderNames(), ", "))
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
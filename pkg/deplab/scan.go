@@ -0,0 +1,59 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+const TableOutput = "table"
+
+var ScanOutputFormats = []string{TableOutput, JSONOutput}
+
+func RunScan(params common.ScanParams) error {
+	collector := warnings.NewCollector()
+
+	md, _, err := readLabelledMetadata(params.Image, params.ImageTarPath, params.MetadataFilePath, collector)
+	if err != nil {
+		return fmt.Errorf("scan cannot read the image: %w", err)
+	}
+
+	db, err := scan.LoadDatabase(params.DatabasePath)
+	if err != nil {
+		return fmt.Errorf("scan cannot load the advisories: %w", err)
+	}
+
+	report, err := scan.Scan(md, db)
+	if err != nil {
+		return fmt.Errorf("scan cannot match the packages: %w", err)
+	}
+
+	if params.OutputFormat == JSONOutput {
+		err = printJSON(report)
+	} else {
+		err = scan.WriteTable(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = reportWarnings(collector, "")
+	if err != nil {
+		return err
+	}
+
+	if params.FailOn != "" {
+		threshold := scan.ParseSeverity(params.FailOn)
+		if found := report.AtLeast(threshold); len(found) > 0 {
+			return fmt.Errorf("found %d vulnerabilities of severity %s or higher", len(found), threshold)
+		}
+	}
+
+	return nil
+}
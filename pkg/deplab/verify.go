@@ -0,0 +1,61 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package deplab
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/policy"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+var VerifyOutputFormats = []string{TextOutput, JSONOutput}
+
+func RunVerify(params common.VerifyParams) error {
+	collector := warnings.NewCollector()
+
+	p, err := policy.ReadPolicyFile(params.PolicyFilePath)
+	if err != nil {
+		return fmt.Errorf("verify cannot read the policy: %w", err)
+	}
+
+	md, config, err := readLabelledMetadata(params.Image, params.ImageTarPath, params.MetadataFilePath, collector)
+	if err != nil {
+		return fmt.Errorf("verify cannot read the image: %w", err)
+	}
+
+	// metadata files do not record when the image was created
+	var created time.Time
+	if config != nil {
+		created = config.Created.Time
+	}
+
+	report, err := policy.Verify(p, md, created, time.Now())
+	if err != nil {
+		return fmt.Errorf("verify cannot evaluate the policy: %w", err)
+	}
+
+	if params.OutputFormat == JSONOutput {
+		err = printJSON(report)
+	} else {
+		err = policy.WriteText(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = reportWarnings(collector, "")
+	if err != nil {
+		return err
+	}
+
+	if len(report.Violations) > 0 {
+		return fmt.Errorf("found %d policy violations", len(report.Violations))
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// Diff lists what changed between the metadata of two images
+type Diff struct {
+	Base     []Change          `json:"base"`
+	Packages []PackageListDiff `json:"packages"`
+	Git      ListDiff          `json:"git"`
+	Archives ListDiff          `json:"archives"`
+}
+
+// PackageListDiff lists the changes of the packages of one ecosystem, named
+// after the type of its dependency
+type PackageListDiff struct {
+	Type string `json:"type"`
+	ListDiff
+}
+
+type ListDiff struct {
+	Added   []Item   `json:"added"`
+	Removed []Item   `json:"removed"`
+	Changed []Change `json:"changed"`
+}
+
+type Item struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type Change struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PackageListTypes are the dependency types whose packages are compared, in
+// the order they are reported
+var PackageListTypes = []string{
+	metadata.DebianPackageListSourceType,
+	metadata.RPMPackageListSourceType,
+	metadata.ApkPackageListSourceType,
+	metadata.PythonPackageListSourceType,
+	metadata.NpmPackageListSourceType,
+	metadata.MavenPackageListSourceType,
+	metadata.GoBinariesSourceType,
+	metadata.BuildpackMetadataType,
+}
+
+func Compare(from, to metadata.Metadata) (Diff, error) {
+	d := Diff{
+		Base:     compareBase(from.Base, to.Base),
+		Packages: []PackageListDiff{},
+	}
+
+	for _, packageListType := range PackageListTypes {
+		fromPackages, err := packages(from, packageListType)
+		if err != nil {
+			return Diff{}, fmt.Errorf("could not read %s of the first image: %w", packageListType, err)
+		}
+		toPackages, err := packages(to, packageListType)
+		if err != nil {
+			return Diff{}, fmt.Errorf("could not read %s of the second image: %w", packageListType, err)
+		}
+
+		listDiff := compare(fromPackages, toPackages)
+		if !listDiff.IsEmpty() {
+			d.Packages = append(d.Packages, PackageListDiff{Type: packageListType, ListDiff: listDiff})
+		}
+	}
+
+	fromGit, err := gitSources(from)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not read git sources of the first image: %w", err)
+	}
+	toGit, err := gitSources(to)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not read git sources of the second image: %w", err)
+	}
+	d.Git = compare(fromGit, toGit)
+
+	fromArchives, err := archives(from)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not read archives of the first image: %w", err)
+	}
+	toArchives, err := archives(to)
+	if err != nil {
+		return Diff{}, fmt.Errorf("could not read archives of the second image: %w", err)
+	}
+	d.Archives = compare(fromArchives, toArchives)
+
+	return d, nil
+}
+
+func (d Diff) IsEmpty() bool {
+	return len(d.Base) == 0 && len(d.Packages) == 0 && d.Git.IsEmpty() && d.Archives.IsEmpty()
+}
+
+func (l ListDiff) IsEmpty() bool {
+	return len(l.Added) == 0 && len(l.Removed) == 0 && len(l.Changed) == 0
+}
+
+func compareBase(from, to metadata.Base) []Change {
+	changes := []Change{}
+	for key, value := range from {
+		if to[key] != value {
+			changes = append(changes, Change{Name: key, From: value, To: to[key]})
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes = append(changes, Change{Name: key, To: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// compare matches the items by name; the version of an item without one is
+// not compared
+func compare(from, to []Item) ListDiff {
+	l := ListDiff{Added: []Item{}, Removed: []Item{}, Changed: []Change{}}
+
+	fromVersions := versions(from)
+	toVersions := versions(to)
+
+	for _, item := range from {
+		version, ok := toVersions[item.Name]
+		if !ok {
+			l.Removed = append(l.Removed, item)
+		} else if version != item.Version {
+			l.Changed = append(l.Changed, Change{Name: item.Name, From: item.Version, To: version})
+		}
+	}
+	for _, item := range to {
+		if _, ok := fromVersions[item.Name]; !ok {
+			l.Added = append(l.Added, item)
+		}
+	}
+
+	sort.Slice(l.Added, func(i, j int) bool { return l.Added[i].Name < l.Added[j].Name })
+	sort.Slice(l.Removed, func(i, j int) bool { return l.Removed[i].Name < l.Removed[j].Name })
+	sort.Slice(l.Changed, func(i, j int) bool { return l.Changed[i].Name < l.Changed[j].Name })
+	return l
+}
+
+func versions(items []Item) map[string]string {
+	versions := map[string]string{}
+	for _, item := range items {
+		versions[item.Name] = item.Version
+	}
+	return versions
+}
+
+type installedPackage struct {
+	name    string
+	version string
+	// architecture, or location of packages which have none
+	architecture string
+}
+
+func packages(md metadata.Metadata, dependencyType string) ([]Item, error) {
+	dependency, ok := metadata.SelectDependency(md.Dependencies, dependencyType)
+	if !ok {
+		return nil, nil
+	}
+
+	var installed []installedPackage
+	switch dependencyType {
+	case metadata.DebianPackageListSourceType:
+		var sourceMetadata metadata.DebianPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			installed = append(installed, installedPackage{p.Package, p.Version, p.Architecture})
+		}
+	case metadata.RPMPackageListSourceType:
+		var sourceMetadata metadata.RpmPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			installed = append(installed, installedPackage{p.Package, p.Version, p.Architecture})
+		}
+	case metadata.ApkPackageListSourceType:
+		var sourceMetadata metadata.ApkPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			installed = append(installed, installedPackage{p.Package, p.Version, p.Architecture})
+		}
+	case metadata.PythonPackageListSourceType:
+		var sourceMetadata metadata.PythonPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			installed = append(installed, installedPackage{p.Package, p.Version, p.Location})
+		}
+	case metadata.NpmPackageListSourceType:
+		var sourceMetadata metadata.NpmPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			installed = append(installed, installedPackage{p.Package, p.Version, p.Location})
+		}
+	case metadata.MavenPackageListSourceType:
+		var sourceMetadata metadata.MavenPackageListSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.Packages {
+			name := p.ArtifactID
+			if p.GroupID != "" {
+				name = p.GroupID + ":" + p.ArtifactID
+			}
+			installed = append(installed, installedPackage{name, p.Version, p.Path})
+		}
+	case metadata.GoBinariesSourceType:
+		var sourceMetadata metadata.GoBinariesSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		// modules built into more than one binary are told apart by the
+		// path of the binary when their versions differ
+		seen := map[metadata.GoModule]bool{}
+		for _, b := range sourceMetadata.Binaries {
+			for _, m := range append([]metadata.GoModule{b.Module}, b.Modules...) {
+				resolved := m.Resolved()
+				key := metadata.GoModule{Path: resolved.Path, Version: resolved.Version}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				installed = append(installed, installedPackage{resolved.Path, resolved.Version, b.Path})
+			}
+		}
+	case metadata.BuildpackMetadataType:
+		var sourceMetadata metadata.BuildpackBOMSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		for _, p := range sourceMetadata.BillOfMaterials {
+			installed = append(installed, installedPackage{name: p.Name, version: p.Version})
+		}
+	}
+
+	// packages installed more than once are told apart by their architecture,
+	// or by where they are installed
+	count := map[string]int{}
+	for _, p := range installed {
+		count[p.name]++
+	}
+
+	var items []Item
+	for _, p := range installed {
+		name := p.name
+		if count[name] > 1 && p.architecture != "" {
+			name = name + ":" + p.architecture
+		}
+		items = append(items, Item{Name: name, Version: p.version})
+	}
+	return items, nil
+}
+
+func gitSources(md metadata.Metadata) ([]Item, error) {
+	var items []Item
+	for _, dependency := range md.Dependencies {
+		if dependency.Source.Type != metadata.GitSourceType {
+			continue
+		}
+
+		var sourceMetadata metadata.GitSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		commit, _ := dependency.Source.Version["commit"].(string)
+		items = append(items, Item{Name: sourceMetadata.URL, Version: commit})
+	}
+	return items, nil
+}
+
+func archives(md metadata.Metadata) ([]Item, error) {
+	var items []Item
+	for _, dependency := range md.Dependencies {
+		if dependency.Source.Type != metadata.ArchiveType {
+			continue
+		}
+
+		var sourceMetadata metadata.ArchiveSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			return nil, err
+		}
+		items = append(items, Item{Name: sourceMetadata.URL})
+	}
+	return items, nil
+}
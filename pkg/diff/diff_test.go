@@ -0,0 +1,176 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/diff"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("Compare", func() {
+	var from, to metadata.Metadata
+
+	BeforeEach(func() {
+		from = metadata.Metadata{
+			Base: metadata.Base{"name": "Ubuntu", "version_id": "18.04", "version_codename": "bionic"},
+			Dependencies: []metadata.Dependency{
+				debianPackages(
+					metadata.DpkgPackage{Package: "curl", Version: "7.58.0", Architecture: "amd64"},
+					metadata.DpkgPackage{Package: "libc6", Version: "2.27-3ubuntu1", Architecture: "amd64"},
+					metadata.DpkgPackage{Package: "wget", Version: "1.19.4", Architecture: "amd64"},
+				),
+				gitSource("https://example.com/app.git", "1111"),
+				archive("https://example.com/old.tgz"),
+			},
+		}
+		to = metadata.Metadata{
+			Base: metadata.Base{"name": "Ubuntu", "version_id": "20.04", "id": "ubuntu"},
+			Dependencies: []metadata.Dependency{
+				debianPackages(
+					metadata.DpkgPackage{Package: "curl", Version: "7.58.0", Architecture: "amd64"},
+					metadata.DpkgPackage{Package: "libc6", Version: "2.31-0ubuntu9", Architecture: "amd64"},
+					metadata.DpkgPackage{Package: "zlib1g", Version: "1.2.11", Architecture: "amd64"},
+				),
+				gitSource("https://example.com/app.git", "2222"),
+				archive("https://example.com/new.tgz"),
+			},
+		}
+	})
+
+	It("reports the changes of the base", func() {
+		d, err := diff.Compare(from, to)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Base).To(Equal([]diff.Change{
+			{Name: "id", From: "", To: "ubuntu"},
+			{Name: "version_codename", From: "bionic", To: ""},
+			{Name: "version_id", From: "18.04", To: "20.04"},
+		}))
+	})
+
+	It("reports the added, removed and changed packages", func() {
+		d, err := diff.Compare(from, to)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Packages).To(Equal([]diff.PackageListDiff{{
+			Type: metadata.DebianPackageListSourceType,
+			ListDiff: diff.ListDiff{
+				Added:   []diff.Item{{Name: "zlib1g", Version: "1.2.11"}},
+				Removed: []diff.Item{{Name: "wget", Version: "1.19.4"}},
+				Changed: []diff.Change{{Name: "libc6", From: "2.27-3ubuntu1", To: "2.31-0ubuntu9"}},
+			},
+		}}))
+	})
+
+	It("reports the changes of git sources and archives", func() {
+		d, err := diff.Compare(from, to)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Git.Changed).To(Equal([]diff.Change{{Name: "https://example.com/app.git", From: "1111", To: "2222"}}))
+		Expect(d.Archives.Added).To(Equal([]diff.Item{{Name: "https://example.com/new.tgz"}}))
+		Expect(d.Archives.Removed).To(Equal([]diff.Item{{Name: "https://example.com/old.tgz"}}))
+	})
+
+	It("reads the metadata of a label the same as the metadata of providers", func() {
+		label, err := json.Marshal(to)
+		Expect(err).ToNot(HaveOccurred())
+
+		var decoded metadata.Metadata
+		Expect(json.Unmarshal(label, &decoded)).To(Succeed())
+
+		d, err := diff.Compare(to, decoded)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d.IsEmpty()).To(BeTrue())
+	})
+
+	It("tells apart packages installed for more than one architecture", func() {
+		to.Dependencies[0] = debianPackages(
+			metadata.DpkgPackage{Package: "curl", Version: "7.58.0", Architecture: "amd64"},
+			metadata.DpkgPackage{Package: "libc6", Version: "2.27-3ubuntu1", Architecture: "amd64"},
+			metadata.DpkgPackage{Package: "libc6", Version: "2.27-3ubuntu1", Architecture: "i386"},
+			metadata.DpkgPackage{Package: "wget", Version: "1.19.4", Architecture: "amd64"},
+		)
+
+		d, err := diff.Compare(from, to)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(d.Packages[0].Added).To(Equal([]diff.Item{
+			{Name: "libc6:amd64", Version: "2.27-3ubuntu1"},
+			{Name: "libc6:i386", Version: "2.27-3ubuntu1"},
+		}))
+		Expect(d.Packages[0].Removed).To(Equal([]diff.Item{{Name: "libc6", Version: "2.27-3ubuntu1"}}))
+	})
+
+	Describe("WriteText", func() {
+		It("writes one line per change", func() {
+			d, err := diff.Compare(from, to)
+			Expect(err).ToNot(HaveOccurred())
+
+			buffer := bytes.Buffer{}
+			Expect(diff.WriteText(&buffer, d)).To(Succeed())
+
+			Expect(buffer.String()).To(Equal(`base
+  + id: ubuntu
+  - version_codename: bionic
+  ~ version_id: 18.04 -> 20.04
+debian_package_list
+  + zlib1g 1.2.11
+  - wget 1.19.4
+  ~ libc6 2.27-3ubuntu1 -> 2.31-0ubuntu9
+git
+  ~ https://example.com/app.git 1111 -> 2222
+archives
+  + https://example.com/new.tgz
+  - https://example.com/old.tgz
+`))
+		})
+
+		It("says when there is no difference", func() {
+			d, err := diff.Compare(from, from)
+			Expect(err).ToNot(HaveOccurred())
+
+			buffer := bytes.Buffer{}
+			Expect(diff.WriteText(&buffer, d)).To(Succeed())
+
+			Expect(buffer.String()).To(Equal("no differences found\n"))
+		})
+	})
+})
+
+func debianPackages(packages ...metadata.DpkgPackage) metadata.Dependency {
+	return metadata.Dependency{
+		Type: metadata.DebianPackageListSourceType,
+		Source: metadata.Source{
+			Type:     "inline",
+			Metadata: metadata.DebianPackageListSourceMetadata{Packages: packages},
+		},
+	}
+}
+
+func gitSource(url, commit string) metadata.Dependency {
+	return metadata.Dependency{
+		Type: "package",
+		Source: metadata.Source{
+			Type:     metadata.GitSourceType,
+			Version:  map[string]interface{}{"commit": commit},
+			Metadata: metadata.GitSourceMetadata{URL: url},
+		},
+	}
+}
+
+func archive(url string) metadata.Dependency {
+	return metadata.Dependency{
+		Type: "package",
+		Source: metadata.Source{
+			Type:     metadata.ArchiveType,
+			Metadata: metadata.ArchiveSourceMetadata{URL: url},
+		},
+	}
+}
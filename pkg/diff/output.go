@@ -0,0 +1,62 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteText writes the diff one line per change, grouped by section, with
+// the added, removed and changed entries marked by +, - and ~
+func WriteText(w io.Writer, d Diff) error {
+	if d.IsEmpty() {
+		_, err := fmt.Fprintln(w, "no differences found")
+		return err
+	}
+
+	var lines []string
+
+	if len(d.Base) > 0 {
+		lines = append(lines, "base")
+		for _, change := range d.Base {
+			switch {
+			case change.From == "":
+				lines = append(lines, fmt.Sprintf("  + %s: %s", change.Name, change.To))
+			case change.To == "":
+				lines = append(lines, fmt.Sprintf("  - %s: %s", change.Name, change.From))
+			default:
+				lines = append(lines, fmt.Sprintf("  ~ %s: %s -> %s", change.Name, change.From, change.To))
+			}
+		}
+	}
+
+	for _, packageList := range d.Packages {
+		lines = append(lines, listLines(packageList.Type, packageList.ListDiff)...)
+	}
+	lines = append(lines, listLines("git", d.Git)...)
+	lines = append(lines, listLines("archives", d.Archives)...)
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func listLines(title string, l ListDiff) []string {
+	if l.IsEmpty() {
+		return nil
+	}
+
+	lines := []string{title}
+	for _, item := range l.Added {
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("  + %s %s", item.Name, item.Version), " "))
+	}
+	for _, item := range l.Removed {
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("  - %s %s", item.Name, item.Version), " "))
+	}
+	for _, change := range l.Changed {
+		lines = append(lines, fmt.Sprintf("  ~ %s %s -> %s", change.Name, change.From, change.To))
+	}
+	return lines
+}
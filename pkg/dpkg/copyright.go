@@ -0,0 +1,174 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package dpkg
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const DocPath = "/usr/share/doc"
+
+var (
+	// common licenses are shipped in /usr/share/common-licenses, which
+	// free-form copyright files refer to
+	commonLicenseRef = regexp.MustCompile(`/usr/share/common-licenses/([A-Za-z0-9.+_-]*[A-Za-z0-9+])`)
+
+	licensePhrases = []struct {
+		pattern *regexp.Regexp
+		license string
+	}{
+		{regexp.MustCompile(`(?i)Apache License,?\s+Version 2\.0`), "Apache-2.0"},
+		{regexp.MustCompile(`(?i)Permission is hereby granted, free of charge`), "MIT"},
+		{regexp.MustCompile(`(?i)Redistribution and use in source and binary forms`), "BSD"},
+		{regexp.MustCompile(`(?i)GNU (Library|Lesser) General Public License`), "LGPL"},
+		{regexp.MustCompile(`(?i)GNU General Public License`), "GPL"},
+		{regexp.MustCompile(`(?i)Mozilla Public License,?\s+(v\.?|version)\s*2\.0`), "MPL-2.0"},
+		{regexp.MustCompile(`(?i)\bpublic domain\b`), "public-domain"},
+	}
+)
+
+// copyrightVisitor reads the copyright files of /usr/share/doc into
+// copyrights, by the name of the package they belong to
+func copyrightVisitor(copyrights map[string]string) image.FileVisitor {
+	return image.FileVisitor{
+		Match: func(filePath string, _ os.FileInfo) bool {
+			dir, fileName := path.Split(filePath)
+			return fileName == "copyright" && path.Dir(path.Clean(dir)) == DocPath
+		},
+		Visit: func(filePath string, _ os.FileInfo, content io.Reader) error {
+			b, err := ioutil.ReadAll(content)
+			if err != nil {
+				return err
+			}
+			copyrights[path.Base(path.Dir(filePath))] = string(b)
+			return nil
+		},
+	}
+}
+
+// addLicenses sets the licenses of the packages from their copyright files,
+// by package name
+func addLicenses(dli image.Image, packages []metadata.DpkgPackage, copyrights map[string]string) {
+	for i, pkg := range packages {
+		content, ok := copyrights[pkg.Package]
+		if !ok {
+			// the doc directory of a package may be a link to the one of
+			// another package built from the same source
+			var err error
+			content, err = dli.GetFileContent(path.Join(DocPath, pkg.Package, "copyright"))
+			if err != nil {
+				continue
+			}
+		}
+		packages[i].Licenses = ParseCopyright(content)
+	}
+}
+
+// ParseCopyright lists the licenses of a debian copyright file. The License
+// fields of the machine-readable format are used when present, see
+// https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/,
+// otherwise licenses are guessed from the common licenses referred to and
+// the wording of the file.
+func ParseCopyright(content string) []string {
+	var licenses []string
+	if isMachineReadable(content) {
+		licenses = machineReadableLicenses(content)
+	}
+	if len(licenses) == 0 {
+		licenses = freeFormLicenses(content)
+	}
+
+	return sortedUnique(licenses)
+}
+
+func isMachineReadable(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Format:") || strings.HasPrefix(line, "Format-Specification:") {
+			return true
+		}
+	}
+	return false
+}
+
+// machineReadableLicenses reads the License fields of the header and Files
+// paragraphs, or those of the standalone License paragraphs if there are none
+func machineReadableLicenses(content string) []string {
+	var filesLicenses, standaloneLicenses []string
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		var license string
+		var hasFiles bool
+		for _, line := range strings.Split(paragraph, "\n") {
+			if strings.HasPrefix(line, "Files:") {
+				hasFiles = true
+			}
+			if strings.HasPrefix(line, "License:") {
+				license = strings.TrimSpace(strings.TrimPrefix(line, "License:"))
+			}
+		}
+
+		if license == "" {
+			continue
+		}
+		if hasFiles || isMachineReadable(paragraph) {
+			filesLicenses = append(filesLicenses, license)
+		} else {
+			standaloneLicenses = append(standaloneLicenses, license)
+		}
+	}
+
+	if len(filesLicenses) != 0 {
+		return filesLicenses
+	}
+	return standaloneLicenses
+}
+
+func freeFormLicenses(content string) []string {
+	var licenses []string
+	for _, match := range commonLicenseRef.FindAllStringSubmatch(content, -1) {
+		licenses = append(licenses, match[1])
+	}
+	if len(licenses) != 0 {
+		return licenses
+	}
+
+	for _, phrase := range licensePhrases {
+		if phrase.pattern.MatchString(content) {
+			licenses = append(licenses, phrase.license)
+		}
+	}
+	return licenses
+}
+
+func sortedUnique(licenses []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, license := range licenses {
+		if !seen[license] {
+			seen[license] = true
+			unique = append(unique, license)
+		}
+	}
+
+	collator := collate.New(language.BritishEnglish)
+	sort.Slice(unique, func(i, j int) bool {
+		return collator.CompareString(unique[i], unique[j]) < 0
+	})
+	return unique
+}
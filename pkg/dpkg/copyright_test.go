@@ -0,0 +1,124 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package dpkg_test
+
+import (
+	"github.com/onsi/ginkgo/extensions/table"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
+)
+
+const machineReadableCopyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: zlib
+Source: http://zlib.net/
+
+Files: *
+Copyright: 1995-2013 Jean-loup Gailly and Mark Adler
+License: Zlib
+
+Files: debian/*
+Copyright: 2007 Mark Brown
+License: GPL-2+ or Artistic
+ This program is free software; you can redistribute it and/or modify
+ it under the terms of the GNU General Public License.
+
+License: Zlib
+ This software is provided 'as-is', without any express or implied
+ warranty.
+`
+
+const status = `Package: zlib1g
+Status: install ok installed
+Architecture: amd64
+Version: 1:1.2.11.dfsg-2
+
+Package: libgcc1
+Status: install ok installed
+Architecture: amd64
+Version: 1:8.3.0-6
+Source: gcc-8 (8.3.0-6)
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2020a-0+deb10u1
+`
+
+var _ = Describe("copyright", func() {
+	table.DescribeTable("ParseCopyright", func(content string, expected []string) {
+		Expect(ParseCopyright(content)).To(Equal(expected))
+	},
+		table.Entry("the License fields of the machine-readable format", machineReadableCopyright,
+			[]string{"GPL-2+ or Artistic", "Zlib"}),
+		table.Entry("the standalone License paragraphs when no Files paragraph has a license", `Format-Specification: http://svn.debian.org/wsvn/dep/web/deps/dep5.mdwn?op=file&rev=135
+Name: tzdata
+
+License: public-domain
+ This database is in the public domain.
+`, []string{"public-domain"}),
+		table.Entry("the common licenses referred to by free-form files", `This is the Debian GNU/Linux prepackaged version of the GNU C library.
+
+On Debian systems, the complete text of the GNU Library General Public
+License can be found in /usr/share/common-licenses/LGPL-2 and the GPL in
+/usr/share/common-licenses/GPL-2.
+`, []string{"GPL-2", "LGPL-2"}),
+		table.Entry("the wording of free-form files", `Copyright (c) 2006 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software.
+
+Licensed under the Apache License, Version 2.0 (the "License").
+`, []string{"Apache-2.0", "MIT"}),
+		table.Entry("no license", "Copyright (c) 2006 Example\n", []string{}),
+	)
+
+	Describe("Provider", func() {
+		It("reads the licenses of the copyright files when asked to", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/var/lib/dpkg/status":                 status,
+					"/usr/share/doc/zlib1g/copyright":      machineReadableCopyright,
+					"/usr/share/doc/gcc-8-base/copyright":  "see /usr/share/common-licenses/GPL-3\n",
+					"/usr/share/doc/zlib1g/changelog.gz":   "",
+					"/usr/share/doc-base/zlib1g/copyright": "see /usr/share/common-licenses/BSD\n",
+				},
+				Links: map[string]string{
+					"/usr/share/doc/libgcc1": "/usr/share/doc/gcc-8-base",
+				},
+			}, common.RunParams{DpkgLicenses: true}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			licenses := map[string][]string{}
+			for _, pkg := range sourceMetadata.Packages {
+				licenses[pkg.Package] = pkg.Licenses
+			}
+			Expect(licenses).To(Equal(map[string][]string{
+				"libgcc1": {"GPL-3"},
+				"tzdata":  nil,
+				"zlib1g":  {"GPL-2+ or Artistic", "Zlib"},
+			}))
+		})
+
+		It("does not read the licenses by default", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/var/lib/dpkg/status":            status,
+					"/usr/share/doc/zlib1g/copyright": machineReadableCopyright,
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			for _, pkg := range sourceMetadata.Packages {
+				Expect(pkg.Licenses).To(BeNil())
+			}
+		})
+	})
+})
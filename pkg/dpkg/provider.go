@@ -18,6 +18,11 @@ import (
 	"golang.org/x/text/language"
 )
 
+const (
+	StatusPath  = "/var/lib/dpkg/status"
+	StatusDPath = "/var/lib/dpkg/status.d"
+)
+
 //func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
 //	dependency, err := BuildDependencyMetadata(dli)
 //	if err != nil {
@@ -28,8 +33,51 @@ import (
 //}
 
 func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not read dpkg files: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the copyright files of the image, with
+// --dpkg-licenses, and hashing the files listed in the md5sums files, with
+// --verify-packages, in a walk shared with other providers, and the provider
+// adding the debian packages once it is done
+func Scan(dli image.Image, params common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	copyrights := map[string]string{}
+	var v verification
+
+	var visitors []image.FileVisitor
+	if params.DpkgLicenses {
+		visitors = append(visitors, copyrightVisitor(copyrights))
+	}
+	if params.VerifyPackages {
+		v = readVerification(dli)
+		visitors = append(visitors, v.visitor())
+	}
+
+	provider := func(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		return addPackages(dli, params, md, copyrights, v)
+	}
+
+	return image.CombineVisitors(visitors...), provider
+}
+
+// addPackages adds the debian packages of the image, with the licenses of the
+// copyright files, by package name, and the files verified against the
+// md5sums files
+func addPackages(dli image.Image, params common.RunParams, md metadata.Metadata, copyrights map[string]string, v verification) (metadata.Metadata, error) {
 	packages := getDebianPackages(dli)
 
+	if len(packages) != 0 && params.DpkgLicenses {
+		addLicenses(dli, packages, copyrights)
+	}
+
+	if len(packages) != 0 && params.VerifyPackages {
+		v.verify(packages)
+	}
+
 	if len(packages) != 0 {
 		sources, err := getAptSources(dli)
 		if err != nil {
@@ -158,7 +206,7 @@ func ParseStatDBEntry(content string) (metadata.DpkgPackage, error) {
 }
 
 func listPackagesFromStatusD(dli image.Image) (packages []metadata.DpkgPackage) {
-	fileList, err := dli.GetDirContents("/var/lib/dpkg/status.d")
+	fileList, err := dli.GetDirContents(StatusDPath)
 	if err != nil {
 		// in this case an empty or non-existent directory is not an error
 		fileList = []string{}
@@ -175,7 +223,7 @@ func listPackagesFromStatusD(dli image.Image) (packages []metadata.DpkgPackage)
 }
 
 func listPackagesFromStatus(dli image.Image) (packages []metadata.DpkgPackage) {
-	statDBString, err := dli.GetFileContent("/var/lib/dpkg/status")
+	statDBString, err := dli.GetFileContent(StatusPath)
 	if err != nil {
 		// in this case an empty or non-existent file is not an error
 		statDBString = ""
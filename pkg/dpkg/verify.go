@@ -0,0 +1,297 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package dpkg
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+const (
+	InfoPath    = "/var/lib/dpkg/info"
+	ConfigPath  = "/etc/dpkg/dpkg.cfg"
+	ConfigDPath = "/etc/dpkg/dpkg.cfg.d"
+)
+
+var (
+	// mergedUsrDirs are linked to their /usr counterpart on merged /usr
+	// systems, where packages may still list their files at the root
+	mergedUsrDirs = []string{"/bin/", "/sbin/", "/lib/", "/lib32/", "/lib64/", "/libx32/"}
+
+	// dpkg only reads the configuration files of dpkg.cfg.d with these names
+	configFileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	// the characters of a glob class which are special in a regexp class
+	classEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `^`, `\^`)
+)
+
+// pathFilter is a path-exclude or path-include option of dpkg, deciding
+// whether the files of packages matching its glob are installed
+type pathFilter struct {
+	glob    *regexp.Regexp
+	include bool
+}
+
+type pathFilters []pathFilter
+
+// excludes tells whether dpkg did not install the file, when the last filter
+// matching it is a path-exclude
+func (filters pathFilters) excludes(filePath string) bool {
+	for i := len(filters) - 1; i >= 0; i-- {
+		if filters[i].glob.MatchString(filePath) {
+			return !filters[i].include
+		}
+	}
+	return false
+}
+
+// verification holds the md5 sums the packages are verified against, by the
+// name of their md5sums file, the filters of the files dpkg installed, and the
+// md5 sums of the files of the image listed by the packages, by path
+type verification struct {
+	md5sums map[string]map[string]string
+	filters pathFilters
+	hashes  map[string]string
+}
+
+// readVerification reads the md5sums files and the dpkg configuration of the
+// image. Missing files are not an error, there is then nothing to verify.
+func readVerification(dli image.Image) verification {
+	v := verification{
+		md5sums: map[string]map[string]string{},
+		hashes:  map[string]string{},
+	}
+
+	names, contents := readDir(dli, InfoPath)
+	for i, name := range names {
+		if path.Ext(name) == ".md5sums" {
+			v.md5sums[strings.TrimSuffix(name, ".md5sums")] = ParseMD5Sums(contents[i])
+		}
+	}
+
+	// dpkg reads the files of dpkg.cfg.d, in order, before dpkg.cfg
+	var configs []string
+	names, contents = readDir(dli, ConfigDPath)
+	for i, name := range names {
+		if configFileName.MatchString(name) {
+			configs = append(configs, contents[i])
+		}
+	}
+	if content, err := dli.GetFileContent(ConfigPath); err == nil {
+		configs = append(configs, content)
+	}
+	v.filters = parsePathFilters(configs)
+
+	return v
+}
+
+// readDir returns the names of the files of a directory of the image and
+// their contents, or nothing when it cannot be read
+func readDir(dli image.Image, dir string) ([]string, []string) {
+	names, err := dli.GetDirFileNames(dir, false)
+	if err != nil {
+		return nil, nil
+	}
+	contents, err := dli.GetDirContents(dir)
+	if err != nil || len(contents) != len(names) {
+		return nil, nil
+	}
+	return names, contents
+}
+
+// visitor hashes the files listed in the md5sums files which dpkg installed,
+// at their path and, for files listed at the root, in /usr on merged /usr
+// systems
+func (v verification) visitor() image.FileVisitor {
+	listed := map[string]bool{}
+	for _, sums := range v.md5sums {
+		for filePath := range sums {
+			if v.filters.excludes(filePath) {
+				continue
+			}
+			listed[filePath] = true
+			listed[mergedUsrPath(filePath)] = true
+		}
+	}
+
+	return image.FileVisitor{
+		Match: func(filePath string, _ os.FileInfo) bool {
+			return listed[filePath]
+		},
+		Visit: func(filePath string, _ os.FileInfo, content io.Reader) error {
+			sum, err := md5Sum(content)
+			if err != nil {
+				return err
+			}
+			v.hashes[filePath] = sum
+			return nil
+		},
+	}
+}
+
+// verify sets the missing and modified files of the packages, each against
+// the md5 sums of its own md5sums file. The files excluded from installation
+// by the dpkg configuration are not missing.
+func (v verification) verify(packages []metadata.DpkgPackage) {
+	for i, pkg := range packages {
+		// the md5sums file is qualified with the architecture for packages
+		// that can be co-installed for several of them, and missing for
+		// packages which ship no files
+		sums, ok := v.md5sums[pkg.Package]
+		if !ok {
+			sums = v.md5sums[pkg.Package+":"+pkg.Architecture]
+		}
+
+		for filePath, sum := range sums {
+			if v.filters.excludes(filePath) {
+				continue
+			}
+
+			actual, ok := v.hashes[filePath]
+			if !ok {
+				// the file may not be found at the path it was installed
+				// to, but through a link, e.g. /bin on merged /usr
+				actual, ok = v.hashes[mergedUsrPath(filePath)]
+			}
+
+			if !ok {
+				packages[i].MissingFiles = append(packages[i].MissingFiles, filePath)
+			} else if actual != sum {
+				packages[i].ModifiedFiles = append(packages[i].ModifiedFiles, filePath)
+			}
+		}
+
+		sort.Strings(packages[i].ModifiedFiles)
+		sort.Strings(packages[i].MissingFiles)
+	}
+}
+
+// parsePathFilters reads the path-exclude and path-include options of dpkg
+// configuration files, in the order they are read. Options may be written
+// with or without their leading dashes, and their value after an equal sign
+// or a space.
+func parsePathFilters(configs []string) pathFilters {
+	var filters pathFilters
+	for _, config := range configs {
+		for _, line := range strings.Split(config, "\n") {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "--")
+			idx := strings.IndexAny(line, "= \t")
+			if idx == -1 {
+				continue
+			}
+
+			var include bool
+			switch line[:idx] {
+			case "path-include":
+				include = true
+			case "path-exclude":
+			default:
+				continue
+			}
+
+			glob, err := globRegexp(strings.TrimSpace(strings.TrimLeft(line[idx:], "= \t")))
+			if err != nil {
+				continue
+			}
+			filters = append(filters, pathFilter{glob: glob, include: include})
+		}
+	}
+	return filters
+}
+
+// globRegexp translates a glob of dpkg to a regular expression. dpkg matches
+// paths with fnmatch(3) without FNM_PATHNAME, so * also matches slashes.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			// a ] right after the opening bracket, or its negation, is part
+			// of the class
+			start := i + 1
+			if start < len(glob) && glob[start] == '!' {
+				start++
+			}
+			if start < len(glob) && glob[start] == ']' {
+				start++
+			}
+			end := strings.IndexByte(glob[start:], ']')
+			if end == -1 {
+				expr.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : start+end]
+			expr.WriteString("[")
+			if strings.HasPrefix(class, "!") {
+				expr.WriteString("^")
+				class = class[1:]
+			}
+			expr.WriteString(classEscaper.Replace(class))
+			expr.WriteString("]")
+			i = start + end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// mergedUsrPath is the path of a file of the root directories linked to /usr
+// on merged /usr systems, or the path itself
+func mergedUsrPath(filePath string) string {
+	for _, dir := range mergedUsrDirs {
+		if strings.HasPrefix(filePath, dir) {
+			return "/usr" + filePath
+		}
+	}
+	return filePath
+}
+
+// ParseMD5Sums reads the md5 sums of a dpkg md5sums file, by the absolute
+// path of the file they belong to
+func ParseMD5Sums(content string) map[string]string {
+	sums := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		filePath := strings.TrimSpace(fields[1])
+		if filePath == "" {
+			continue
+		}
+		sums[path.Join("/", filePath)] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+func md5Sum(content io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package dpkg_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
+)
+
+func md5Hex(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("verify", func() {
+	Describe("ParseMD5Sums", func() {
+		It("reads the sums by absolute path", func() {
+			Expect(ParseMD5Sums(`d41d8cd98f00b204e9800998ecf8427e  usr/lib/libz.so.1
+9E107D9D372BB6826BD81D3542A419D6  usr/share/doc/zlib1g/file with spaces
+
+invalid
+`)).To(Equal(map[string]string{
+				"/usr/lib/libz.so.1":                     "d41d8cd98f00b204e9800998ecf8427e",
+				"/usr/share/doc/zlib1g/file with spaces": "9e107d9d372bb6826bd81d3542a419d6",
+			}))
+		})
+	})
+
+	Describe("Provider", func() {
+		var image test_utils.FakeImage
+
+		BeforeEach(func() {
+			image = test_utils.FakeImage{
+				Files: map[string]string{
+					"/var/lib/dpkg/status": status,
+					"/var/lib/dpkg/info/zlib1g:amd64.md5sums": md5Hex("libz") + "  usr/lib/libz.so.1\n" +
+						md5Hex("changelog") + "  usr/share/doc/zlib1g/changelog.gz\n",
+					"/var/lib/dpkg/info/libgcc1.md5sums": md5Hex("libgcc") + "  lib/libgcc_s.so.1\n" +
+						md5Hex("copyright") + "  usr/share/doc/gcc-8-base/copyright\n",
+					"/usr/lib/libz.so.1":                  "tampered",
+					"/usr/lib/libgcc_s.so.1":              "libgcc",
+					"/usr/share/doc/gcc-8-base/copyright": "copyright",
+				},
+				Links: map[string]string{
+					"/lib": "/usr/lib",
+				},
+			}
+		})
+
+		It("reports the missing and modified files when asked to", func() {
+			md, err := Provider(image, common.RunParams{VerifyPackages: true}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			Expect(sourceMetadata.Packages).To(HaveLen(3))
+
+			libgcc1 := sourceMetadata.Packages[0]
+			Expect(libgcc1.Package).To(Equal("libgcc1"))
+			Expect(libgcc1.ModifiedFiles).To(BeNil())
+			Expect(libgcc1.MissingFiles).To(BeNil())
+
+			tzdata := sourceMetadata.Packages[1]
+			Expect(tzdata.Package).To(Equal("tzdata"))
+			Expect(tzdata.ModifiedFiles).To(BeNil())
+			Expect(tzdata.MissingFiles).To(BeNil())
+
+			zlib1g := sourceMetadata.Packages[2]
+			Expect(zlib1g.Package).To(Equal("zlib1g"))
+			Expect(zlib1g.ModifiedFiles).To(Equal([]string{"/usr/lib/libz.so.1"}))
+			Expect(zlib1g.MissingFiles).To(Equal([]string{"/usr/share/doc/zlib1g/changelog.gz"}))
+		})
+
+		It("finds the files listed at the root in /usr on merged /usr systems", func() {
+			image.Files["/var/lib/dpkg/info/tzdata.md5sums"] = md5Hex("zic") + "  usr/sbin/zic\n" +
+				md5Hex("tzconfig") + "  sbin/tzconfig\n"
+			image.Files["/usr/sbin/tzconfig"] = "changed"
+
+			md, err := Provider(image, common.RunParams{VerifyPackages: true}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			tzdata := sourceMetadata.Packages[1]
+			Expect(tzdata.Package).To(Equal("tzdata"))
+			Expect(tzdata.ModifiedFiles).To(Equal([]string{"/sbin/tzconfig"}))
+			Expect(tzdata.MissingFiles).To(Equal([]string{"/usr/sbin/zic"}))
+		})
+
+		It("does not report the files excluded by the dpkg configuration as missing", func() {
+			image.Files["/etc/dpkg/dpkg.cfg.d/excludes"] = "# drop the docs\n" +
+				"path-exclude /usr/share/doc/*\n" +
+				"path-include=/usr/share/doc/*/copyright\n"
+			image.Files["/etc/dpkg/dpkg.cfg.d/ignored.dpkg-old"] = "path-exclude=/usr/lib/*\n"
+			delete(image.Files, "/usr/share/doc/gcc-8-base/copyright")
+
+			md, err := Provider(image, common.RunParams{VerifyPackages: true}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			libgcc1 := sourceMetadata.Packages[0]
+			Expect(libgcc1.Package).To(Equal("libgcc1"))
+			Expect(libgcc1.MissingFiles).To(Equal([]string{"/usr/share/doc/gcc-8-base/copyright"}))
+
+			zlib1g := sourceMetadata.Packages[2]
+			Expect(zlib1g.Package).To(Equal("zlib1g"))
+			Expect(zlib1g.ModifiedFiles).To(Equal([]string{"/usr/lib/libz.so.1"}))
+			Expect(zlib1g.MissingFiles).To(BeNil())
+		})
+
+		It("verifies each package against its own md5 sums", func() {
+			image.Files["/var/lib/dpkg/info/tzdata.md5sums"] = md5Hex("shared") + "  usr/lib/libz.so.1\n"
+			image.Files["/usr/lib/libz.so.1"] = "shared"
+
+			md, err := Provider(image, common.RunParams{VerifyPackages: true}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			tzdata := sourceMetadata.Packages[1]
+			Expect(tzdata.Package).To(Equal("tzdata"))
+			Expect(tzdata.ModifiedFiles).To(BeNil())
+
+			zlib1g := sourceMetadata.Packages[2]
+			Expect(zlib1g.Package).To(Equal("zlib1g"))
+			Expect(zlib1g.ModifiedFiles).To(Equal([]string{"/usr/lib/libz.so.1"}))
+		})
+
+		It("only reads the files listed in the md5sums files", func() {
+			visitor, _ := Scan(image, common.RunParams{VerifyPackages: true})
+
+			Expect(visitor.Match("/usr/lib/libz.so.1", nil)).To(BeTrue())
+			Expect(visitor.Match("/usr/lib/libgcc_s.so.1", nil)).To(BeTrue())
+			Expect(visitor.Match("/usr/bin/unlisted", nil)).To(BeFalse())
+			Expect(visitor.Match("/var/lib/dpkg/info/libgcc1.md5sums", nil)).To(BeFalse())
+		})
+
+		It("does not verify the packages by default", func() {
+			md, err := Provider(image, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.DebianPackageListSourceMetadata)
+			for _, pkg := range sourceMetadata.Packages {
+				Expect(pkg.ModifiedFiles).To(BeNil())
+				Expect(pkg.MissingFiles).To(BeNil())
+			}
+		})
+	})
+})
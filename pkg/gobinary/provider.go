@@ -0,0 +1,136 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package gobinary
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"sort"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+)
+
+var elfMagic = []byte("\x7fELF")
+
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not find go binaries: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the go binaries of the image in a walk
+// shared with other providers, and the provider adding them once it is done
+func Scan(image.Image, common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	var binaries []metadata.GoBinary
+
+	visitor := image.FileVisitor{
+		Match: func(_ string, info os.FileInfo) bool {
+			return info.Mode()&0111 != 0
+		},
+		Visit: func(path string, _ os.FileInfo, content io.Reader) error {
+			binary, ok, err := ReadGoBinary(path, content)
+			if err != nil {
+				return err
+			}
+			if ok {
+				binaries = append(binaries, binary)
+			}
+			return nil
+		},
+	}
+
+	provider := func(_ image.Image, _ common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		if len(binaries) == 0 {
+			return md, nil
+		}
+
+		sort.Slice(binaries, func(i, j int) bool {
+			return binaries[i].Path < binaries[j].Path
+		})
+
+		sourceMetadata := metadata.GoBinariesSourceMetadata{
+			Binaries: binaries,
+		}
+
+		version, err := common.Digest(sourceMetadata)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not get digest for source metadata: %w", err)
+		}
+
+		md.Dependencies = append(md.Dependencies, metadata.Dependency{
+			Type: metadata.GoBinariesSourceType,
+			Source: metadata.Source{
+				Type: "inline",
+				Version: map[string]interface{}{
+					"sha256": version,
+				},
+				Metadata: sourceMetadata,
+			},
+		})
+		return md, nil
+	}
+
+	return visitor, provider
+}
+
+// ReadGoBinary reads the build information of the executable at path, and
+// whether it is an ELF executable built by go with module support
+func ReadGoBinary(path string, content io.Reader) (metadata.GoBinary, bool, error) {
+	magic := make([]byte, len(elfMagic))
+	_, err := io.ReadFull(content, magic)
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == nil && !bytes.Equal(magic, elfMagic) {
+		return metadata.GoBinary{}, false, nil
+	}
+	if err != nil {
+		return metadata.GoBinary{}, false, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	rest, err := ioutil.ReadAll(content)
+	if err != nil {
+		return metadata.GoBinary{}, false, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	info, err := buildinfo.Read(bytes.NewReader(append(magic, rest...)))
+	if err != nil {
+		// not a go binary, or one built without module support
+		return metadata.GoBinary{}, false, nil
+	}
+
+	binary := metadata.GoBinary{
+		Path:      path,
+		GoVersion: info.GoVersion,
+		Package:   info.Path,
+		Module:    goModule(&info.Main),
+		Modules:   []metadata.GoModule{},
+	}
+	for _, dep := range info.Deps {
+		binary.Modules = append(binary.Modules, goModule(dep))
+	}
+
+	return binary, true, nil
+}
+
+func goModule(module *debug.Module) metadata.GoModule {
+	m := metadata.GoModule{
+		Path:    module.Path,
+		Version: module.Version,
+		Sum:     module.Sum,
+	}
+	if module.Replace != nil {
+		replace := goModule(module.Replace)
+		m.Replace = &replace
+	}
+	return m
+}
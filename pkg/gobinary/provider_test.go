@@ -0,0 +1,91 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package gobinary_test
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/gobinary"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("gobinary", func() {
+	var goBinary []byte
+
+	BeforeEach(func() {
+		// the test binary is itself built by go with module support
+		executable, err := os.Executable()
+		Expect(err).ToNot(HaveOccurred())
+
+		goBinary, err = ioutil.ReadFile(executable)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("Provider", func() {
+		It("adds the build information of the go binaries, sorted by path", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/usr/local/bin/server": string(goBinary),
+					"/app/cli":              string(goBinary),
+				},
+				Modes: map[string]os.FileMode{
+					"/usr/local/bin/server": 0755,
+					"/app/cli":              0755,
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(HaveLen(1))
+			dependency := md.Dependencies[0]
+			Expect(dependency.Type).To(Equal(metadata.GoBinariesSourceType))
+			Expect(dependency.Source.Type).To(Equal("inline"))
+			Expect(dependency.Source.Version["sha256"]).ToNot(BeEmpty())
+
+			sourceMetadata := dependency.Source.Metadata.(metadata.GoBinariesSourceMetadata)
+			Expect(sourceMetadata.Binaries).To(HaveLen(2))
+			Expect(sourceMetadata.Binaries[0].Path).To(Equal("/app/cli"))
+			Expect(sourceMetadata.Binaries[1].Path).To(Equal("/usr/local/bin/server"))
+
+			binary := sourceMetadata.Binaries[0]
+			Expect(binary.GoVersion).To(Equal(runtime.Version()))
+			Expect(binary.Package).To(Equal("github.com/vmware-tanzu/dependency-labeler/pkg/gobinary.test"))
+			Expect(binary.Module.Path).To(Equal("github.com/vmware-tanzu/dependency-labeler"))
+
+			var gomega metadata.GoModule
+			for _, module := range binary.Modules {
+				if module.Path == "github.com/onsi/gomega" {
+					gomega = module
+				}
+			}
+			Expect(gomega.Version).To(HavePrefix("v"))
+			Expect(gomega.Sum).To(HavePrefix("h1:"))
+		})
+
+		It("skips files which are not executable go binaries", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/app/not-executable": string(goBinary),
+					"/bin/not-go":         "\x7fELF\x02\x01\x01 not a go binary",
+					"/bin/script":         "#!/bin/sh\necho hello\n",
+					"/bin/empty":          "",
+				},
+				Modes: map[string]os.FileMode{
+					"/bin/not-go": 0755,
+					"/bin/script": 0755,
+					"/bin/empty":  0755,
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(BeEmpty())
+		})
+	})
+})
@@ -6,126 +6,513 @@ package image
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
 	"path"
 	"path/filepath"
+	"sync"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
 
 	"github.com/containerd/containerd/reference/docker"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/match"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 )
 
 type Image interface {
 	GetFileContent(string) (string, error)
 	GetDirContents(string) ([]string, error)
 	GetDirFileNames(string, bool) ([]string, error)
+	WalkFiles(WalkFilesFunc) error
+	Layers() ([]Layer, error)
+	FileHistory(string) ([]int, error)
+	GetLayerFileContent(string, int) (string, error)
 	AbsolutePath(string) (string, error)
 	GetConfig() (*v1.ConfigFile, error)
+	GetManifest() (*v1.Manifest, error)
 	ExportWithMetadata(metadata.Metadata, string, string) error
+	PushWithMetadata(metadata.Metadata, string) error
+	WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error
+}
+
+// WalkFilesFunc is called with the absolute path, the file info and the
+// content of a regular file of the image
+type WalkFilesFunc func(path string, info os.FileInfo, content io.Reader) error
+
+// Layer describes a layer of an image, with the command of the history of
+// the image config which created it, when known
+type Layer struct {
+	Digest    string
+	CreatedBy string
 }
 
 type ExportableImage interface {
 	ExportWithMetadata(metadata.Metadata, string, string) error
+	PushWithMetadata(metadata.Metadata, string) error
+	WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error
 	Cleanup()
 }
 
+// RefNameAnnotation names an image in the index of an OCI image layout
+const RefNameAnnotation = "org.opencontainers.image.ref.name"
+
 type RootFSImage struct {
 	rootFS RootFS
 	image  v1.Image
 }
 
+// NewRootFSImage extracts the whole file system of the image into a temporary
+// directory, for the providers which need a real path to its files
+func NewRootFSImage(image v1.Image) (RootFSImage, error) {
+	// this folder is unnecessary and may contain folders with bad permissions
+	rootFS, err := NewRootFS(image, []string{"usr/share/doc/"})
+	if err != nil {
+		return RootFSImage{}, fmt.Errorf("could not create new image: %w", err)
+	}
+
+	return RootFSImage{image: image, rootFS: rootFS}, nil
+}
+
 func (dli RootFSImage) GetConfig() (*v1.ConfigFile, error) {
 	return dli.image.ConfigFile()
 }
 
-func NewDeplabImage(inputImage, inputImageTarPath string) (RootFSImage, error) {
+func (dli RootFSImage) GetManifest() (*v1.Manifest, error) {
+	return dli.image.Manifest()
+}
+
+func (dli *RootFSImage) Cleanup() {
+	dli.rootFS.Cleanup()
+}
+
+func (dli RootFSImage) ExportWithMetadata(metadata metadata.Metadata, path string, tag string) error {
+	return exportWithMetadata(dli.image, metadata, nil, path, tag)
+}
+
+func (dli RootFSImage) PushWithMetadata(metadata metadata.Metadata, ref string) error {
+	return pushWithMetadata(dli.image, metadata, nil, ref)
+}
+
+func (dli RootFSImage) WriteOCILayoutWithMetadata(metadata metadata.Metadata, path string, tag string) error {
+	return writeOCILayoutWithMetadata(dli.image, metadata, nil, path, tag)
+}
+
+func (dli RootFSImage) GetFileContent(s string) (string, error) {
+	return dli.rootFS.GetFileContent(s)
+}
+
+func (dli RootFSImage) GetDirContents(s string) ([]string, error) {
+	return dli.rootFS.GetDirContents(s)
+}
+
+func (dli RootFSImage) GetDirFileNames(s string, i bool) ([]string, error) {
+	return dli.rootFS.GetDirFileNames(s, i)
+}
+
+func (dli RootFSImage) WalkFiles(fn WalkFilesFunc) error {
+	return dli.rootFS.WalkFiles(fn)
+}
+
+func (dli RootFSImage) AbsolutePath(absPath string) (string, error) {
+	joinedPath := path.Join(dli.rootFS.rootfsLocation, absPath)
+	patheee, err := filepath.Abs(joinedPath)
+	if err != nil {
+		return "", fmt.Errorf("could not create image absolute path, %s: %w", absPath, err)
+	}
+	return patheee, nil
+}
+
+// LayerFSImage reads the files of the image from its layers, without
+// extracting them. The file system is only extracted, into a RootFSImage, the
+// first time a provider asks for an AbsolutePath.
+type LayerFSImage struct {
+	image   v1.Image
+	layerFS *LayerFS
+	rootFS  *lazyRootFS
+	labels  *labeller
+}
+
+type lazyRootFS struct {
+	image    v1.Image
+	cacheDir string
+	once     sync.Once
+	rootFS   RootFSImage
+	err      error
+}
+
+func NewDeplabImage(inputImage, inputImageTarPath string) (LayerFSImage, error) {
 	var (
-		image v1.Image
-		err   error
+		image    v1.Image
+		layers   v1.Image
+		cacheDir string
+		err      error
 	)
 
 	if inputImage != "" {
 		image, err = crane.Pull(inputImage)
 		if err != nil {
-			return RootFSImage{}, fmt.Errorf("failed to pull %s: %w", inputImage, err)
+			return LayerFSImage{}, fmt.Errorf("failed to pull %s: %w", inputImage, err)
+		}
+
+		// the layers are read more than once, so keep them on disk rather
+		// than downloading them again
+		cacheDir, err = ioutil.TempDir("", LayerCachePrefix)
+		if err != nil {
+			return LayerFSImage{}, fmt.Errorf("could not create layer cache directory: %w", err)
 		}
+		layers = cache.Image(image, cache.NewFilesystemCache(cacheDir))
 	} else if inputImageTarPath != "" {
 		image, err = crane.Load(inputImageTarPath)
 		if err != nil {
-			return RootFSImage{}, fmt.Errorf("failed to load %s: %w", inputImageTarPath, err)
+			return LayerFSImage{}, fmt.Errorf("failed to load %s: %w", inputImageTarPath, err)
 		}
+		layers = image
 	} else {
-		return RootFSImage{}, fmt.Errorf("you must provide either an inputImage or inputImageTarPath parameter")
+		return LayerFSImage{}, fmt.Errorf("you must provide either an inputImage or inputImageTarPath parameter")
 	}
 
-	// this folder is unnecessary and may contain folders with bad permissions
-	rootFS, err := NewRootFS(image, []string{"usr/share/doc/"})
+	layerFS, err := NewLayerFS(layers)
 	if err != nil {
-		return RootFSImage{}, fmt.Errorf("could not create new image: %w", err)
+		removeLayerCache(cacheDir)
+		return LayerFSImage{}, fmt.Errorf("could not create new image: %w", err)
 	}
 
-	return RootFSImage{image: image, rootFS: rootFS}, nil
+	return LayerFSImage{
+		image:   image,
+		layerFS: layerFS,
+		rootFS:  &lazyRootFS{image: layers, cacheDir: cacheDir},
+		labels:  &labeller{},
+	}, nil
 }
 
-func (dli *RootFSImage) Cleanup() {
-	dli.rootFS.Cleanup()
+func (dli LayerFSImage) GetConfig() (*v1.ConfigFile, error) {
+	return dli.image.ConfigFile()
 }
 
-func (dli RootFSImage) ExportWithMetadata(metadata metadata.Metadata, path string, tag string) error {
-	err := dli.setMetadata(metadata)
+func (dli LayerFSImage) GetManifest() (*v1.Manifest, error) {
+	return dli.image.Manifest()
+}
+
+func (dli LayerFSImage) Cleanup() {
+	if dli.rootFS == nil {
+		return
+	}
+	dli.rootFS.rootFS.Cleanup()
+	removeLayerCache(dli.rootFS.cacheDir)
+}
+
+// WithSigner returns the image, whose labelled outputs carry a signature of
+// the deplab label made by signer
+func (dli LayerFSImage) WithSigner(signer *signature.Signer) LayerFSImage {
+	dli.labels = &labeller{signer: signer, compress: dli.labels.compress, threshold: dli.labels.threshold}
+	return dli
+}
+
+// WithCompressedLabel returns the image, whose labelled outputs carry the
+// metadata gzipped in the compressed label when its JSON is longer than
+// threshold bytes
+func (dli LayerFSImage) WithCompressedLabel(threshold int) LayerFSImage {
+	dli.labels = &labeller{signer: dli.labels.signer, compress: true, threshold: threshold}
+	return dli
+}
+
+// Digest is the digest of the manifest of the input image
+func (dli LayerFSImage) Digest() (string, error) {
+	digest, err := dli.image.Digest()
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve image digest: %w", err)
+	}
+	return digest.String(), nil
+}
+
+// Descriptor describes the manifest of the input image
+func (dli LayerFSImage) Descriptor() (*v1.Descriptor, error) {
+	descriptor, err := partial.Descriptor(dli.image)
+	if err != nil {
+		return nil, fmt.Errorf("could not describe image manifest: %w", err)
+	}
+	return descriptor, nil
+}
+
+// LabelledDigest is the digest of the manifest of the image labelled with the
+// metadata, as exported, pushed or written to an OCI image layout
+func (dli LayerFSImage) LabelledDigest(metadata metadata.Metadata) (string, error) {
+	image, err := dli.labels.setMetadata(dli.image, metadata)
+	if err != nil {
+		return "", fmt.Errorf("error setting metadata: %w", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve image digest: %w", err)
+	}
+	return digest.String(), nil
+}
+
+// the image itself rather than its cached layers is exported, so that pushing
+// it can still mount the layers from their original repository
+func (dli LayerFSImage) ExportWithMetadata(metadata metadata.Metadata, path string, tag string) error {
+	return exportWithMetadata(dli.image, metadata, dli.labels, path, tag)
+}
+
+func (dli LayerFSImage) PushWithMetadata(metadata metadata.Metadata, ref string) error {
+	return pushWithMetadata(dli.image, metadata, dli.labels, ref)
+}
+
+func (dli LayerFSImage) WriteOCILayoutWithMetadata(metadata metadata.Metadata, path string, tag string) error {
+	return writeOCILayoutWithMetadata(dli.image, metadata, dli.labels, path, tag)
+}
+
+func (dli LayerFSImage) GetFileContent(s string) (string, error) {
+	return dli.layerFS.GetFileContent(s)
+}
+
+func (dli LayerFSImage) GetDirContents(s string) ([]string, error) {
+	return dli.layerFS.GetDirContents(s)
+}
+
+func (dli LayerFSImage) GetDirFileNames(s string, i bool) ([]string, error) {
+	return dli.layerFS.GetDirFileNames(s, i)
+}
+
+func (dli LayerFSImage) WalkFiles(fn WalkFilesFunc) error {
+	return dli.layerFS.WalkFiles(fn)
+}
+
+func (dli LayerFSImage) Layers() ([]Layer, error) {
+	return imageLayers(dli.image)
+}
+
+func (dli LayerFSImage) FileHistory(s string) ([]int, error) {
+	return dli.layerFS.FileHistory(s)
+}
+
+func (dli LayerFSImage) GetLayerFileContent(s string, layer int) (string, error) {
+	return dli.layerFS.GetLayerFileContent(s, layer)
+}
+
+func (dli LayerFSImage) AbsolutePath(absPath string) (string, error) {
+	dli.rootFS.once.Do(func() {
+		dli.rootFS.rootFS, dli.rootFS.err = NewRootFSImage(dli.rootFS.image)
+	})
+	if dli.rootFS.err != nil {
+		return "", fmt.Errorf("could not extract image file system: %w", dli.rootFS.err)
+	}
+
+	return dli.rootFS.rootFS.AbsolutePath(absPath)
+}
+
+// imageLayers lists the layers of the image. The history entries of the config
+// which did not create an empty layer are matched with the layers in order,
+// unless there are not as many of them as layers.
+func imageLayers(image v1.Image) ([]Layer, error) {
+	v1Layers, err := image.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not get image layers: %w", err)
+	}
+
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not get image config: %w", err)
+	}
+
+	var history []v1.History
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			history = append(history, h)
+		}
+	}
+
+	var layers []Layer
+	for i, v1Layer := range v1Layers {
+		digest, err := v1Layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("could not get digest of layer %d: %w", i, err)
+		}
+
+		layer := Layer{Digest: digest.String()}
+		if len(history) == len(v1Layers) {
+			layer.CreatedBy = history[i].CreatedBy
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+const LayerCachePrefix = "deplab-layers-"
+
+func removeLayerCache(cacheDir string) {
+	err := os.RemoveAll(cacheDir)
+	if err != nil {
+		log.Printf("could not clean up layer cache location: %s. %s\n", cacheDir, err)
+	}
+}
+
+func exportWithMetadata(image v1.Image, metadata metadata.Metadata, labels *labeller, path string, tag string) error {
+	image, err := labels.setMetadata(image, metadata)
 	if err != nil {
 		return fmt.Errorf("error setting metadata: %w", err)
 	}
 
-	err = dli.export(path, tag)
+	err = export(image, path, tag)
 	if err != nil {
 		return fmt.Errorf("error exporting tar to %s: %w", path, err)
 	}
 	return nil
 }
 
-func (dli RootFSImage) GetFileContent(s string) (string, error) {
-	return dli.rootFS.GetFileContent(s)
-}
+// pushWithMetadata pushes the labelled image to the registry reference ref.
+// Only the config is new, so layers already present in the registry, or in
+// another repository of the same registry, are not uploaded again.
+func pushWithMetadata(image v1.Image, metadata metadata.Metadata, labels *labeller, ref string) error {
+	image, err := labels.setMetadata(image, metadata)
+	if err != nil {
+		return fmt.Errorf("error setting metadata: %w", err)
+	}
 
-func (dli RootFSImage) GetDirContents(s string) ([]string, error) {
-	return dli.rootFS.GetDirContents(s)
+	err = crane.Push(image, ref)
+	if err != nil {
+		return fmt.Errorf("could not push to %s: %w", ref, err)
+	}
+	return nil
 }
 
-func (dli RootFSImage) GetDirFileNames(s string, i bool) ([]string, error) {
-	return dli.rootFS.GetDirFileNames(s, i)
+// writeOCILayoutWithMetadata writes the labelled image into the OCI image
+// layout at path, creating the layout if needed. When a tag is given, it is
+// set as the ref name annotation and replaces any image with the same ref name.
+func writeOCILayoutWithMetadata(image v1.Image, metadata metadata.Metadata, labels *labeller, path string, tag string) error {
+	image, err := labels.setMetadata(image, metadata)
+	if err != nil {
+		return fmt.Errorf("error setting metadata: %w", err)
+	}
+
+	ociLayout, err := OpenOCILayout(path)
+	if err != nil {
+		return err
+	}
+
+	if tag == "" {
+		err = ociLayout.AppendImage(image)
+	} else {
+		err = ociLayout.ReplaceImage(image,
+			match.Annotation(RefNameAnnotation, tag),
+			layout.WithAnnotations(map[string]string{RefNameAnnotation: tag}))
+	}
+	if err != nil {
+		return fmt.Errorf("could not write image to oci layout at %s: %w", path, err)
+	}
+
+	return nil
 }
 
-func (dli *RootFSImage) setMetadata(metadata metadata.Metadata) error {
-	config, err := dli.image.ConfigFile()
+// OpenOCILayout opens the OCI image layout at path, creating it when it has no
+// index.json yet
+func OpenOCILayout(path string) (layout.Path, error) {
+	ociLayout, err := layout.FromPath(path)
+	if err == nil {
+		return ociLayout, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("could not open oci layout at %s: %w", path, err)
+	}
+
+	ociLayout, err = layout.Write(path, empty.Index)
 	if err != nil {
-		return fmt.Errorf("could not find config file in image: %w", err)
+		return "", fmt.Errorf("could not create oci layout at %s: %w", path, err)
+	}
+	return ociLayout, nil
+}
+
+// labeller labels an image, signing the label with signer when it is not nil
+// and compressing it when compress is set and it is longer than threshold.
+// The last labelled image is kept, so that all the outputs of an image share
+// the same digest even though ECDSA signatures differ every time.
+type labeller struct {
+	signer    *signature.Signer
+	compress  bool
+	threshold int
+	label     string
+	image     v1.Image
+}
+
+// setMetadata sets the deplab label of the image, along with its signature
+// when the labeller has a signer. Any previous label and its signature are
+// removed. A nil labeller sets an unsigned, uncompressed label.
+func (l *labeller) setMetadata(image v1.Image, metadata metadata.Metadata) (v1.Image, error) {
+	if l == nil {
+		return setMetadata(image, metadata, nil, false, 0)
 	}
+
 	md, err := json.Marshal(metadata)
 	if err != nil {
-		return fmt.Errorf("could not marshal json: %w", err)
+		return nil, fmt.Errorf("could not marshal json: %w", err)
+	}
+	if l.image != nil && l.label == string(md) {
+		return l.image, nil
+	}
+
+	labelled, err := setMetadata(image, metadata, l.signer, l.compress, l.threshold)
+	if err != nil {
+		return nil, err
+	}
+	l.label, l.image = string(md), labelled
+	return labelled, nil
+}
+
+func setMetadata(image v1.Image, md metadata.Metadata, signer *signature.Signer, compress bool, threshold int) (v1.Image, error) {
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not find config file in image: %w", err)
+	}
+	label, err := json.Marshal(md)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal json: %w", err)
 	}
 	if config.Config.Labels == nil {
 		config.Config.Labels = map[string]string{}
 	}
 
-	config.Config.Labels["io.deplab.metadata"] = string(md)
+	labels, err := metadata.Labels(string(label), compress, threshold)
+	if err != nil {
+		return nil, err
+	}
+	delete(config.Config.Labels, metadata.LabelName)
+	delete(config.Config.Labels, metadata.CompressedLabelName)
+	for name, value := range labels {
+		config.Config.Labels[name] = value
+	}
+
+	delete(config.Config.Labels, signature.LabelName)
+	if signer != nil {
+		signatureLabel, err := signer.Sign(string(label))
+		if err != nil {
+			return nil, err
+		}
+		config.Config.Labels[signature.LabelName] = signatureLabel
+	}
 
-	dli.image, err = mutate.Config(dli.image, config.Config)
+	image, err = mutate.Config(image, config.Config)
 	if err != nil {
-		return fmt.Errorf("could not mutate config in image: %w", err)
+		return nil, fmt.Errorf("could not mutate config in image: %w", err)
 	}
 
-	return nil
+	return image, nil
 }
 
-func (dli *RootFSImage) export(path string, tag string) error {
+func export(image v1.Image, path string, tag string) error {
 	var actualTag string
 	if tag == "" {
-		h, err := dli.image.Digest()
+		h, err := image.Digest()
 		if err != nil {
 			return fmt.Errorf("could not retrieve image digest: %w", err)
 		}
@@ -139,19 +526,10 @@ func (dli *RootFSImage) export(path string, tag string) error {
 		return fmt.Errorf("tag %s is invalid: %w", actualTag, err)
 	}
 
-	err = crane.Save(dli.image, name.String(), path)
+	err = crane.Save(image, name.String(), path)
 	if err != nil {
 		return fmt.Errorf("could not export to %s: %w", path, err)
 	}
 
 	return nil
 }
-
-func (dli RootFSImage) AbsolutePath(absPath string) (string, error) {
-	joinedPath := path.Join(dli.rootFS.rootfsLocation, absPath)
-	patheee, err := filepath.Abs(joinedPath)
-	if err != nil {
-		return "", fmt.Errorf("could not create image absolute path, %s: %w", absPath, err)
-	}
-	return patheee, nil
-}
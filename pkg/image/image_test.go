@@ -5,12 +5,17 @@ package image_test
 
 import (
 	"io/ioutil"
+	"log"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
 
 	"github.com/google/go-containerregistry/pkg/crane"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/vmware-tanzu/dependency-labeler/pkg/image"
@@ -21,7 +26,7 @@ var _ = Describe("Image", func() {
 	Describe("NewDeplabImage", func() {
 		Context("with valid inputs", func() {
 			var (
-				image RootFSImage
+				image LayerFSImage
 				err   error
 			)
 
@@ -73,7 +78,7 @@ var _ = Describe("Image", func() {
 	Describe("ExportWithMetadata", func() {
 		Context("when saving the image to a tar", func() {
 			var (
-				image RootFSImage
+				image LayerFSImage
 				dir   string
 			)
 
@@ -126,9 +131,239 @@ var _ = Describe("Image", func() {
 		})
 	})
 
+	Describe("PushWithMetadata", func() {
+		var (
+			image    LayerFSImage
+			registry *httptest.Server
+		)
+
+		BeforeEach(func() {
+			registry = httptest.NewServer(ggcrregistry.New(ggcrregistry.Logger(log.New(ioutil.Discard, "", 0))))
+
+			inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
+			Expect(err).ToNot(HaveOccurred())
+
+			image, err = NewDeplabImage("", inputTarPath)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			image.Cleanup()
+			registry.Close()
+		})
+
+		It("pushes the image with the metadata in the label", func() {
+			ref := strings.TrimPrefix(registry.URL, "http://") + "/deplab/output-image:latest"
+
+			err := image.PushWithMetadata(metadata.Metadata{}, ref)
+			Expect(err).ToNot(HaveOccurred())
+
+			labelledImage, err := crane.Pull(ref)
+			Expect(err).ToNot(HaveOccurred())
+
+			cf, err := labelledImage.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cf.Config.Labels["io.deplab.metadata"]).To(MatchJSON(`{
+				"base": null,
+				"provenance": null,
+				"dependencies": null
+			}`))
+
+			By("keeping the existing labels")
+			Expect(cf.Config.Labels["foo"]).To(Equal("bar"))
+		})
+
+		It("returns an error if the reference is invalid", func() {
+			err := image.PushWithMetadata(metadata.Metadata{}, "not a valid reference")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("WriteOCILayoutWithMetadata", func() {
+		var (
+			image LayerFSImage
+			dir   string
+		)
+
+		BeforeEach(func() {
+			inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
+			Expect(err).ToNot(HaveOccurred())
+
+			image, err = NewDeplabImage("", inputTarPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			dir, err = ioutil.TempDir("", "deplab-oci-layout-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			image.Cleanup()
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("creates the layout with the labelled image, annotated with the tag", func() {
+			ociLayout := filepath.Join(dir, "layout")
+
+			err := image.WriteOCILayoutWithMetadata(metadata.Metadata{}, ociLayout, "example.com/image:tag")
+			Expect(err).ToNot(HaveOccurred())
+
+			index, err := layout.ImageIndexFromPath(ociLayout)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest, err := index.IndexManifest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.Manifests).To(HaveLen(1))
+			Expect(manifest.Manifests[0].Annotations).To(Equal(map[string]string{
+				RefNameAnnotation: "example.com/image:tag",
+			}))
+
+			labelledImage, err := index.Image(manifest.Manifests[0].Digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			cf, err := labelledImage.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cf.Config.Labels["io.deplab.metadata"]).To(MatchJSON(`{
+				"base": null,
+				"provenance": null,
+				"dependencies": null
+			}`))
+		})
+
+		It("appends to an existing layout, replacing the image with the same tag", func() {
+			Expect(image.WriteOCILayoutWithMetadata(metadata.Metadata{}, dir, "first")).To(Succeed())
+			Expect(image.WriteOCILayoutWithMetadata(metadata.Metadata{}, dir, "second")).To(Succeed())
+			Expect(image.WriteOCILayoutWithMetadata(metadata.Metadata{
+				Base: metadata.Base{"name": "replaced"},
+			}, dir, "first")).To(Succeed())
+
+			index, err := layout.ImageIndexFromPath(dir)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest, err := index.IndexManifest()
+			Expect(err).ToNot(HaveOccurred())
+
+			var refNames []string
+			for _, descriptor := range manifest.Manifests {
+				refNames = append(refNames, descriptor.Annotations[RefNameAnnotation])
+			}
+			Expect(refNames).To(ConsistOf("first", "second"))
+		})
+
+		It("returns an error, without creating a layout, when the existing one cannot be opened", func() {
+			path := filepath.Join(dir, "file")
+			Expect(ioutil.WriteFile(path, []byte("not a layout"), 0644)).To(Succeed())
+
+			err := image.WriteOCILayoutWithMetadata(metadata.Metadata{}, path, "tag")
+			Expect(err).To(MatchError(ContainSubstring("could not open oci layout at " + path)))
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("not a layout"))
+		})
+	})
+
+	Describe("LabelledDigest", func() {
+		var (
+			image LayerFSImage
+			dir   string
+		)
+
+		BeforeEach(func() {
+			inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
+			Expect(err).ToNot(HaveOccurred())
+
+			image, err = NewDeplabImage("", inputTarPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			dir, err = ioutil.TempDir("", "deplab-digest-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			image.Cleanup()
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("is the digest of the exported image", func() {
+			md := metadata.Metadata{Base: metadata.Base{"id": "debian"}}
+
+			digest, err := image.LabelledDigest(md)
+			Expect(err).ToNot(HaveOccurred())
+
+			destinationImage := filepath.Join(dir, "output-image.tar")
+			Expect(image.ExportWithMetadata(md, destinationImage, "")).To(Succeed())
+
+			labelledImage, err := crane.Load(destinationImage)
+			Expect(err).ToNot(HaveOccurred())
+			exportedDigest, err := labelledImage.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(exportedDigest.String()))
+
+			By("differing from the digest of the input image")
+			inputDigest, err := image.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).ToNot(Equal(inputDigest))
+		})
+	})
+
+	Describe("WithCompressedLabel", func() {
+		var (
+			image LayerFSImage
+			dir   string
+		)
+
+		BeforeEach(func() {
+			inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
+			Expect(err).ToNot(HaveOccurred())
+
+			image, err = NewDeplabImage("", inputTarPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			dir, err = ioutil.TempDir("", "deplab-compressed-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			image.Cleanup()
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		exportedLabels := func(image LayerFSImage, md metadata.Metadata) map[string]string {
+			destinationImage := filepath.Join(dir, "output-image.tar")
+			Expect(image.ExportWithMetadata(md, destinationImage, "")).To(Succeed())
+
+			labelledImage, err := crane.Load(destinationImage)
+			Expect(err).ToNot(HaveOccurred())
+			config, err := labelledImage.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+			return config.Config.Labels
+		}
+
+		It("compresses the label above the threshold", func() {
+			md := metadata.Metadata{Base: metadata.Base{"id": "debian"}}
+
+			labels := exportedLabels(image.WithCompressedLabel(0), md)
+			Expect(labels).ToNot(HaveKey(metadata.LabelName))
+
+			label, ok, err := metadata.ReadLabel(labels)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(label).To(MatchJSON(`{"base":{"id":"debian"},"provenance":null,"dependencies":null}`))
+		})
+
+		It("keeps the label as JSON up to the threshold", func() {
+			md := metadata.Metadata{Base: metadata.Base{"id": "debian"}}
+
+			labels := exportedLabels(image.WithCompressedLabel(1024), md)
+			Expect(labels).To(HaveKey(metadata.LabelName))
+			Expect(labels).ToNot(HaveKey(metadata.CompressedLabelName))
+		})
+	})
+
 	Describe("AbsolutePath", func() {
 		Context("relative path to rootFS location", func() {
-			var image RootFSImage
+			var image LayerFSImage
 			It("provides the absolute path", func() {
 				inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
 				Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,485 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+
+	// same limit as the linux kernel
+	maxSymlinks = 40
+)
+
+// LayerFS serves the files of an image straight from its layer tarballs.
+// The layers are indexed once, applying whiteouts and opaque directories the
+// way the overlay filesystem of a container runtime would, and the content of
+// a file is then read from the topmost layer which has it.
+type LayerFS struct {
+	layers   []v1.Layer
+	entries  map[string]layerEntry
+	children map[string]map[string]struct{}
+	// history holds every version of the regular files, oldest first
+	history map[string][]layerEntry
+}
+
+type layerEntry struct {
+	layer    int
+	index    int
+	typeflag byte
+	linkname string
+	info     os.FileInfo
+}
+
+func (e layerEntry) isDir() bool {
+	return e.typeflag == tar.TypeDir
+}
+
+func NewLayerFS(image v1.Image) (*LayerFS, error) {
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not get image layers: %w", err)
+	}
+
+	fs := &LayerFS{
+		layers:   layers,
+		entries:  map[string]layerEntry{"/": {typeflag: tar.TypeDir}},
+		children: map[string]map[string]struct{}{},
+		history:  map[string][]layerEntry{},
+	}
+
+	for i, layer := range layers {
+		err := fs.indexLayer(i, layer)
+		if err != nil {
+			return nil, fmt.Errorf("could not index layer %d: %w", i, err)
+		}
+	}
+
+	return fs, nil
+}
+
+func (fs *LayerFS) indexLayer(i int, layer v1.Layer) error {
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("could not read layer: %w", err)
+	}
+	defer rc.Close()
+
+	var (
+		paths     []string
+		entries   = map[string]layerEntry{}
+		whiteouts []string
+		opaques   []string
+	)
+
+	tr := tar.NewReader(rc)
+	for index := 0; ; index++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read layer tar: %w", err)
+		}
+
+		p := cleanPath(hdr.Name)
+		dir, base := path.Split(p)
+
+		switch {
+		case base == opaqueWhiteout:
+			opaques = append(opaques, path.Clean(dir))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			whiteouts = append(whiteouts, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		}
+
+		entry := layerEntry{layer: i, index: index, typeflag: hdr.Typeflag, linkname: hdr.Linkname, info: hdr.FileInfo()}
+		if hdr.Typeflag == tar.TypeLink {
+			// the content of a hard link is held by the entry it links to
+			target := cleanPath(hdr.Linkname)
+			if linked, ok := entries[target]; ok {
+				entry = linked
+			} else if linked, ok := fs.entries[target]; ok {
+				entry = linked
+			} else {
+				continue
+			}
+		}
+
+		if _, seen := entries[p]; !seen {
+			paths = append(paths, p)
+		}
+		entries[p] = entry
+	}
+
+	// whiteouts only hide the content of the layers below
+	for _, dir := range opaques {
+		fs.removeChildren(dir)
+	}
+	for _, p := range whiteouts {
+		fs.remove(p)
+	}
+
+	for _, p := range paths {
+		fs.add(p, entries[p])
+
+		if entries[p].typeflag == tar.TypeReg || entries[p].typeflag == tar.TypeRegA {
+			fs.history[p] = append(fs.history[p], entries[p])
+		}
+	}
+
+	return nil
+}
+
+func (fs *LayerFS) add(p string, entry layerEntry) {
+	if existing, ok := fs.entries[p]; ok && existing.isDir() && !entry.isDir() {
+		fs.removeChildren(p)
+	}
+	fs.entries[p] = entry
+
+	for p != "/" {
+		parent, name := path.Dir(p), path.Base(p)
+
+		if existing, ok := fs.entries[parent]; !ok || !existing.isDir() {
+			fs.removeChildren(parent)
+			fs.entries[parent] = layerEntry{typeflag: tar.TypeDir}
+		}
+
+		children, ok := fs.children[parent]
+		if !ok {
+			children = map[string]struct{}{}
+			fs.children[parent] = children
+		}
+		if _, ok := children[name]; ok {
+			return
+		}
+		children[name] = struct{}{}
+
+		p = parent
+	}
+}
+
+func (fs *LayerFS) remove(p string) {
+	if p == "/" {
+		fs.removeChildren(p)
+		return
+	}
+
+	fs.removeChildren(p)
+	delete(fs.entries, p)
+	delete(fs.children[path.Dir(p)], path.Base(p))
+}
+
+func (fs *LayerFS) removeChildren(dir string) {
+	for name := range fs.children[dir] {
+		child := path.Join(dir, name)
+		fs.removeChildren(child)
+		delete(fs.entries, child)
+	}
+	delete(fs.children, dir)
+}
+
+// resolve follows the symbolic links of every component of p, as if the
+// image was the root of the filesystem
+func (fs *LayerFS) resolve(p string) (string, layerEntry, error) {
+	resolved := "/"
+	remaining := strings.Split(cleanPath(p), "/")
+	links := 0
+
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		entry, ok := fs.entries[next]
+		if !ok {
+			return "", layerEntry{}, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+		}
+
+		if entry.typeflag == tar.TypeSymlink {
+			links++
+			if links > maxSymlinks {
+				return "", layerEntry{}, &os.PathError{Op: "open", Path: p, Err: fmt.Errorf("too many levels of symbolic links")}
+			}
+			if path.IsAbs(entry.linkname) {
+				resolved = "/"
+			}
+			remaining = append(strings.Split(entry.linkname, "/"), remaining...)
+			continue
+		}
+
+		if len(remaining) > 0 && !entry.isDir() {
+			return "", layerEntry{}, &os.PathError{Op: "open", Path: p, Err: fmt.Errorf("not a directory")}
+		}
+		resolved = next
+	}
+
+	return resolved, fs.entries[resolved], nil
+}
+
+func (fs *LayerFS) GetFileContent(p string) (string, error) {
+	_, entry, err := fs.resolve(p)
+	if err != nil {
+		return "", fmt.Errorf("could not find file in image layers: %w", err)
+	}
+	if entry.isDir() {
+		return "", fmt.Errorf("could not find file in image layers: %s is a directory", p)
+	}
+
+	contents, err := fs.read([]layerEntry{entry})
+	if err != nil {
+		return "", fmt.Errorf("could not read file %s from image layers: %w", p, err)
+	}
+	return string(contents[0]), nil
+}
+
+// FileHistory returns the indexes of the layers which added or changed the
+// file at p, oldest first. Unlike GetFileContent, p is not resolved through
+// symbolic links.
+func (fs *LayerFS) FileHistory(p string) ([]int, error) {
+	versions, ok := fs.history[cleanPath(p)]
+	if !ok {
+		return nil, fmt.Errorf("could not find file in image layers: %s", p)
+	}
+
+	var layers []int
+	for _, entry := range versions {
+		layers = append(layers, entry.layer)
+	}
+	return layers, nil
+}
+
+// GetLayerFileContent returns the content of the file at p as added or
+// changed by the layer, see FileHistory
+func (fs *LayerFS) GetLayerFileContent(p string, layer int) (string, error) {
+	for _, entry := range fs.history[cleanPath(p)] {
+		if entry.layer != layer {
+			continue
+		}
+
+		contents, err := fs.read([]layerEntry{entry})
+		if err != nil {
+			return "", fmt.Errorf("could not read file %s from image layer %d: %w", p, layer, err)
+		}
+		return string(contents[0]), nil
+	}
+	return "", fmt.Errorf("could not find file in image layer %d: %s", layer, p)
+}
+
+func (fs *LayerFS) GetDirContents(p string) ([]string, error) {
+	dir, names, err := fs.list(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []layerEntry
+	for _, name := range names {
+		if fs.entries[path.Join(dir, name)].isDir() {
+			continue
+		}
+
+		_, entry, err := fs.resolve(path.Join(dir, name))
+		if err != nil || entry.isDir() {
+			return nil, fmt.Errorf("could not find file in directory in image layers: %s", path.Join(dir, name))
+		}
+		files = append(files, entry)
+	}
+
+	contents, err := fs.read(files)
+	if err != nil {
+		return nil, fmt.Errorf("could not read files of directory %s from image layers: %w", p, err)
+	}
+
+	var fileContents []string
+	for _, content := range contents {
+		fileContents = append(fileContents, string(content))
+	}
+	return fileContents, nil
+}
+
+func (fs *LayerFS) GetDirFileNames(p string, includeDir bool) ([]string, error) {
+	dir, names, err := fs.list(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileNames []string
+	for _, name := range names {
+		if fs.entries[path.Join(dir, name)].isDir() && !includeDir {
+			continue
+		}
+		fileNames = append(fileNames, name)
+	}
+	return fileNames, nil
+}
+
+// WalkFiles calls fn with the content of every regular file of the image,
+// reading each layer once. The files are not walked in any particular order.
+func (fs *LayerFS) WalkFiles(fn WalkFilesFunc) error {
+	paths := map[int]map[int][]string{}
+	for p, entry := range fs.entries {
+		if entry.typeflag != tar.TypeReg && entry.typeflag != tar.TypeRegA {
+			continue
+		}
+		if paths[entry.layer] == nil {
+			paths[entry.layer] = map[int][]string{}
+		}
+		paths[entry.layer][entry.index] = append(paths[entry.layer][entry.index], p)
+	}
+
+	for layer := range fs.layers {
+		if len(paths[layer]) == 0 {
+			continue
+		}
+
+		err := fs.walkLayer(layer, paths[layer], fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *LayerFS) walkLayer(layer int, paths map[int][]string, fn WalkFilesFunc) error {
+	rc, err := fs.layers[layer].Uncompressed()
+	if err != nil {
+		return fmt.Errorf("could not read layer %d: %w", layer, err)
+	}
+	defer rc.Close()
+
+	remaining := len(paths)
+	tr := tar.NewReader(rc)
+	for index := 0; remaining > 0; index++ {
+		_, err := tr.Next()
+		if err != nil {
+			return fmt.Errorf("could not read layer %d tar: %w", layer, err)
+		}
+
+		targets, ok := paths[index]
+		if !ok {
+			continue
+		}
+		remaining--
+
+		if len(targets) == 1 {
+			err := fn(targets[0], fs.entries[targets[0]].info, tr)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// hard links share the content of the entry they link to
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("could not read layer %d tar: %w", layer, err)
+		}
+		for _, p := range targets {
+			err := fn(p, fs.entries[p].info, bytes.NewReader(content))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (fs *LayerFS) list(p string) (string, []string, error) {
+	dir, entry, err := fs.resolve(p)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not find directory in image layers: %w", err)
+	}
+	if !entry.isDir() {
+		return "", nil, fmt.Errorf("could not find directory in image layers: %s is not a directory", p)
+	}
+
+	var names []string
+	for name := range fs.children[dir] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return dir, names, nil
+}
+
+// read returns the content of the entries, reading each layer only once
+func (fs *LayerFS) read(entries []layerEntry) ([][]byte, error) {
+	wanted := map[int]map[int][]int{}
+	for i, entry := range entries {
+		if wanted[entry.layer] == nil {
+			wanted[entry.layer] = map[int][]int{}
+		}
+		wanted[entry.layer][entry.index] = append(wanted[entry.layer][entry.index], i)
+	}
+
+	contents := make([][]byte, len(entries))
+	for layer, indexes := range wanted {
+		err := fs.readLayer(layer, indexes, contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+func (fs *LayerFS) readLayer(layer int, indexes map[int][]int, contents [][]byte) error {
+	rc, err := fs.layers[layer].Uncompressed()
+	if err != nil {
+		return fmt.Errorf("could not read layer %d: %w", layer, err)
+	}
+	defer rc.Close()
+
+	remaining := len(indexes)
+	tr := tar.NewReader(rc)
+	for index := 0; remaining > 0; index++ {
+		_, err := tr.Next()
+		if err != nil {
+			return fmt.Errorf("could not read layer %d tar: %w", layer, err)
+		}
+
+		targets, ok := indexes[index]
+		if !ok {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("could not read layer %d tar: %w", layer, err)
+		}
+		for _, i := range targets {
+			contents[i] = content
+		}
+		remaining--
+	}
+
+	return nil
+}
+
+func cleanPath(name string) string {
+	return path.Clean("/" + name)
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package image_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/image"
+)
+
+var _ = Describe("LayerFS", func() {
+	Context("when there is a valid image archive", func() {
+		var lfs *LayerFS
+
+		BeforeEach(func() {
+			inputTarPath, err := filepath.Abs("../../test/integration/assets/image-archives/all-file-types.tgz")
+			Expect(err).ToNot(HaveOccurred())
+
+			image, err := crane.Load(inputTarPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			lfs, err = NewLayerFS(image)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("retrieves the content of a file", func() {
+			aFile, err := lfs.GetFileContent("/all-files/start-file")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(aFile).To(ContainSubstring("hello world"))
+		})
+
+		It("retrieves the content of all files of a directory", func() {
+			statusFiles, err := lfs.GetDirContents("/all-files/folder")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusFiles).To(ConsistOf(
+				ContainSubstring("foo"),
+				ContainSubstring("bar"),
+			))
+		})
+
+		It("retrieves the file names, following links and ignoring subdirectories", func() {
+			fileNames, err := lfs.GetDirFileNames("/all-files", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileNames).To(ConsistOf("hard-link-file", "start-file", "symbolic-link-file"))
+
+			fileNames, err = lfs.GetDirFileNames("/all-files", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileNames).To(ConsistOf("folder", "hard-link-file", "start-file", "symbolic-link-file"))
+
+			contents, err := lfs.GetDirContents("/all-files")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ConsistOf(
+				ContainSubstring("hello world"),
+				ContainSubstring("hello world"),
+				ContainSubstring("hello world"),
+			))
+		})
+
+		It("returns an error when the path does not exist", func() {
+			_, err := lfs.GetFileContent("/this/file/does/not/exist.txt")
+			Expect(err).To(MatchError(ContainSubstring("could not find file in image layers")))
+
+			_, err = lfs.GetDirContents("/this/directory/does/not/exist")
+			Expect(err).To(MatchError(ContainSubstring("could not find directory in image layers")))
+
+			_, err = lfs.GetDirFileNames("/this/directory/does/not/exist", false)
+			Expect(err).To(MatchError(ContainSubstring("could not find directory in image layers")))
+		})
+	})
+
+	Context("when the image has several layers", func() {
+		var lfs *LayerFS
+
+		BeforeEach(func() {
+			var err error
+			lfs, err = NewLayerFS(layeredImage(
+				[]tarEntry{
+					{name: "usr/lib/os-release", content: "ID=lower"},
+					{name: "lib", link: "usr/lib", typeflag: tar.TypeSymlink},
+					{name: "etc/os-release", link: "../lib/os-release", typeflag: tar.TypeSymlink},
+					{name: "etc/removed", content: "removed"},
+					{name: "etc/removed-dir/file", content: "removed"},
+					{name: "etc/opaque/lower", content: "lower"},
+					{name: "var/replaced/file", content: "lower"},
+					{name: "var/absolute", link: "/usr/lib", typeflag: tar.TypeSymlink},
+				},
+				[]tarEntry{
+					{name: "usr/lib/os-release", content: "ID=upper"},
+					{name: "etc/.wh.removed"},
+					{name: "etc/.wh.removed-dir"},
+					{name: "etc/opaque/.wh..wh..opq"},
+					{name: "etc/opaque/upper", content: "upper"},
+					{name: "var/replaced", content: "upper"},
+					{name: "var/hard-link", link: "usr/lib/os-release", typeflag: tar.TypeLink},
+				},
+			))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reads the file from the topmost layer", func() {
+			Expect(lfs.GetFileContent("/usr/lib/os-release")).To(Equal("ID=upper"))
+			Expect(lfs.GetFileContent("/var/hard-link")).To(Equal("ID=upper"))
+		})
+
+		It("keeps every version of a file", func() {
+			Expect(lfs.FileHistory("/usr/lib/os-release")).To(Equal([]int{0, 1}))
+			Expect(lfs.FileHistory("/etc/opaque/upper")).To(Equal([]int{1}))
+
+			Expect(lfs.GetLayerFileContent("/usr/lib/os-release", 0)).To(Equal("ID=lower"))
+			Expect(lfs.GetLayerFileContent("/usr/lib/os-release", 1)).To(Equal("ID=upper"))
+
+			_, err := lfs.GetLayerFileContent("/etc/opaque/upper", 0)
+			Expect(err).To(HaveOccurred())
+
+			_, err = lfs.FileHistory("/etc/os-release")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("follows symbolic links inside the image", func() {
+			Expect(lfs.GetFileContent("/etc/os-release")).To(Equal("ID=upper"))
+			Expect(lfs.GetFileContent("/lib/os-release")).To(Equal("ID=upper"))
+			Expect(lfs.GetFileContent("/var/absolute/os-release")).To(Equal("ID=upper"))
+			Expect(lfs.GetDirFileNames("/lib", false)).To(ConsistOf("os-release"))
+		})
+
+		It("hides the files removed by whiteouts", func() {
+			_, err := lfs.GetFileContent("/etc/removed")
+			Expect(err).To(HaveOccurred())
+
+			_, err = lfs.GetDirFileNames("/etc/removed-dir", true)
+			Expect(err).To(HaveOccurred())
+
+			Expect(lfs.GetDirFileNames("/etc", true)).To(ConsistOf("opaque", "os-release"))
+		})
+
+		It("hides the lower content of opaque directories", func() {
+			Expect(lfs.GetDirContents("/etc/opaque")).To(ConsistOf("upper"))
+		})
+
+		It("replaces a directory by a file", func() {
+			Expect(lfs.GetFileContent("/var/replaced")).To(Equal("upper"))
+
+			_, err := lfs.GetFileContent("/var/replaced/file")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("walks the content of the regular files which are visible", func() {
+			files := map[string]string{}
+			err := lfs.WalkFiles(func(path string, info os.FileInfo, content io.Reader) error {
+				Expect(info.Mode().IsRegular()).To(BeTrue())
+
+				b, err := ioutil.ReadAll(content)
+				Expect(err).ToNot(HaveOccurred())
+				files[path] = string(b)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(files).To(Equal(map[string]string{
+				"/usr/lib/os-release": "ID=upper",
+				"/etc/opaque/upper":   "upper",
+				"/var/replaced":       "upper",
+				"/var/hard-link":      "ID=upper",
+			}))
+		})
+	})
+})
+
+type tarEntry struct {
+	name     string
+	content  string
+	link     string
+	typeflag byte
+}
+
+func layeredImage(layers ...[]tarEntry) v1.Image {
+	image := empty.Image
+	for _, entries := range layers {
+		buffer := bytes.Buffer{}
+		tw := tar.NewWriter(&buffer)
+		for _, entry := range entries {
+			typeflag := entry.typeflag
+			if typeflag == 0 {
+				typeflag = tar.TypeReg
+			}
+			Expect(tw.WriteHeader(&tar.Header{
+				Name:     entry.name,
+				Linkname: entry.link,
+				Typeflag: typeflag,
+				Mode:     0644,
+				Size:     int64(len(entry.content)),
+			})).To(Succeed())
+			_, err := tw.Write([]byte(entry.content))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(tw.Close()).To(Succeed())
+
+		content := buffer.Bytes()
+		layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		image, err = mutate.AppendLayers(image, layer)
+		Expect(err).ToNot(HaveOccurred())
+	}
+	return image
+}
@@ -77,6 +77,30 @@ func (rfs *RootFS) GetFileContent(path string) (string, error) {
 	return string(fileBytes), nil
 }
 
+// WalkFiles calls fn with the content of every regular file of the rootFS
+func (rfs *RootFS) WalkFiles(fn WalkFilesFunc) error {
+	return filepath.Walk(rfs.rootfsLocation, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("could not walk rootFS: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not open file in rootFS: %w", err)
+		}
+		defer f.Close()
+
+		rel, err := filepath.Rel(rfs.rootfsLocation, path)
+		if err != nil {
+			return fmt.Errorf("could not find path in rootFS: %w", err)
+		}
+		return fn(filepath.Join("/", rel), info, f)
+	})
+}
+
 func NewRootFS(image v1.Image, excludePatterns []string) (RootFS, error) {
 	var (
 		rootFS string
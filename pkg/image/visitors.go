@@ -0,0 +1,94 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// FileWalker walks the regular files of an image, as an Image does
+type FileWalker interface {
+	WalkFiles(WalkFilesFunc) error
+}
+
+// FileVisitor is called with the content of the files it matches, in a walk
+// of the image shared with other visitors. A visitor without Match visits no
+// file.
+type FileVisitor struct {
+	Match func(path string, info os.FileInfo) bool
+	Visit WalkFilesFunc
+}
+
+// WalkVisitors walks the files of the image once for all the visitors, as
+// combined by CombineVisitors. The image is not walked when no visitor
+// matches files.
+func WalkVisitors(dli FileWalker, visitors ...FileVisitor) error {
+	visitor := CombineVisitors(visitors...)
+	if visitor.Match == nil {
+		return nil
+	}
+
+	return dli.WalkFiles(func(path string, info os.FileInfo, content io.Reader) error {
+		if !visitor.Match(path, info) {
+			return nil
+		}
+		return visitor.Visit(path, info, content)
+	})
+}
+
+// CombineVisitors returns the visitor of the files matched by any of the
+// visitors. A file matched by several visitors is read once, and each of them
+// is called with its content in turn.
+func CombineVisitors(visitors ...FileVisitor) FileVisitor {
+	var matching []FileVisitor
+	for _, visitor := range visitors {
+		if visitor.Match != nil {
+			matching = append(matching, visitor)
+		}
+	}
+
+	switch len(matching) {
+	case 0:
+		return FileVisitor{}
+	case 1:
+		return matching[0]
+	}
+
+	return FileVisitor{
+		Match: func(path string, info os.FileInfo) bool {
+			for _, visitor := range matching {
+				if visitor.Match(path, info) {
+					return true
+				}
+			}
+			return false
+		},
+		Visit: func(path string, info os.FileInfo, content io.Reader) error {
+			var matched []FileVisitor
+			for _, visitor := range matching {
+				if visitor.Match(path, info) {
+					matched = append(matched, visitor)
+				}
+			}
+			if len(matched) == 1 {
+				return matched[0].Visit(path, info, content)
+			}
+
+			b, err := ioutil.ReadAll(content)
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", path, err)
+			}
+			for _, visitor := range matched {
+				if err := visitor.Visit(path, info, bytes.NewReader(b)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
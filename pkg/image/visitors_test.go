@@ -0,0 +1,87 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package image_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/image"
+)
+
+type failingWalker struct{}
+
+func (failingWalker) WalkFiles(WalkFilesFunc) error {
+	return errors.New("walked")
+}
+
+var _ = Describe("WalkVisitors", func() {
+	var lfs *LayerFS
+
+	BeforeEach(func() {
+		var err error
+		lfs, err = NewLayerFS(layeredImage(
+			[]tarEntry{
+				{name: "etc/os-release", content: "ID=test"},
+				{name: "usr/bin/tool", content: "tool"},
+				{name: "usr/share/doc/tool/copyright", content: "MIT"},
+			},
+		))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	collect := func(prefix string, files map[string]string) FileVisitor {
+		return FileVisitor{
+			Match: func(path string, _ os.FileInfo) bool {
+				return strings.HasPrefix(path, prefix)
+			},
+			Visit: func(path string, _ os.FileInfo, content io.Reader) error {
+				b, err := ioutil.ReadAll(content)
+				if err != nil {
+					return err
+				}
+				files[path] = string(b)
+				return nil
+			},
+		}
+	}
+
+	It("calls each visitor with the content of the files it matches", func() {
+		etc, usr, share := map[string]string{}, map[string]string{}, map[string]string{}
+		Expect(WalkVisitors(lfs, collect("/etc/", etc), collect("/usr/", usr), collect("/usr/share/", share))).To(Succeed())
+
+		Expect(etc).To(Equal(map[string]string{"/etc/os-release": "ID=test"}))
+		Expect(usr).To(Equal(map[string]string{"/usr/bin/tool": "tool", "/usr/share/doc/tool/copyright": "MIT"}))
+		Expect(share).To(Equal(map[string]string{"/usr/share/doc/tool/copyright": "MIT"}))
+	})
+
+	It("combines visitors into one visitor of the files matched by any of them", func() {
+		etc, usr := map[string]string{}, map[string]string{}
+		combined := CombineVisitors(collect("/etc/", etc), FileVisitor{}, collect("/usr/bin/", usr))
+		Expect(WalkVisitors(lfs, combined)).To(Succeed())
+
+		Expect(etc).To(Equal(map[string]string{"/etc/os-release": "ID=test"}))
+		Expect(usr).To(Equal(map[string]string{"/usr/bin/tool": "tool"}))
+		Expect(CombineVisitors(FileVisitor{}).Match).To(BeNil())
+	})
+
+	It("returns the error of a visitor", func() {
+		err := WalkVisitors(lfs, FileVisitor{
+			Match: func(string, os.FileInfo) bool { return true },
+			Visit: func(string, os.FileInfo, io.Reader) error { return errors.New("visit failed") },
+		})
+		Expect(err).To(MatchError("visit failed"))
+	})
+
+	It("does not walk the image when no visitor matches files", func() {
+		Expect(WalkVisitors(failingWalker{}, FileVisitor{}, FileVisitor{})).To(Succeed())
+		Expect(WalkVisitors(failingWalker{})).To(Succeed())
+	})
+})
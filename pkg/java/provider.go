@@ -0,0 +1,282 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package java
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	manifestPath = "META-INF/MANIFEST.MF"
+	mavenDir     = "META-INF/maven/"
+	// NestedSeparator separates the path of an archive from the path of an
+	// archive nested in it, as in jar urls
+	NestedSeparator = "!/"
+	// maxDepth bounds the nesting of archives which are read, e.g. a war in
+	// an ear holding jars in WEB-INF/lib
+	maxDepth = 3
+)
+
+var (
+	archiveExtensions = []string{".jar", ".war", ".ear"}
+	fileNameVersion   = regexp.MustCompile(`^(.+?)-(\d[^-]*(-.+)?)$`)
+)
+
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not find java archives: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the java archives of the image in a walk
+// shared with other providers, and the provider adding their packages once it
+// is done
+func Scan(_ image.Image, params common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	collector := params.Warnings
+	var packages []metadata.MavenPackage
+
+	visitor := image.FileVisitor{
+		Match: func(filePath string, _ os.FileInfo) bool {
+			return IsArchive(filePath)
+		},
+		Visit: func(filePath string, _ os.FileInfo, content io.Reader) error {
+			b, err := ioutil.ReadAll(content)
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", filePath, err)
+			}
+
+			archivePackages, err := ReadArchive(filePath, b, 0, collector)
+			if err != nil {
+				collector.Add(warnings.InvalidPackageEntry, filePath, fmt.Sprintf("skipping archive: %s", err))
+				return nil
+			}
+			packages = append(packages, archivePackages...)
+			return nil
+		},
+	}
+
+	provider := func(_ image.Image, _ common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		if len(packages) == 0 {
+			return md, nil
+		}
+
+		collator := collate.New(language.BritishEnglish)
+		sort.Slice(packages, func(i, j int) bool {
+			if c := collator.CompareString(coordinates(packages[i]), coordinates(packages[j])); c != 0 {
+				return c < 0
+			}
+			return packages[i].Path < packages[j].Path
+		})
+
+		sourceMetadata := metadata.MavenPackageListSourceMetadata{
+			Packages: packages,
+		}
+
+		version, err := common.Digest(sourceMetadata)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not get digest for source metadata: %w", err)
+		}
+
+		md.Dependencies = append(md.Dependencies, metadata.Dependency{
+			Type: metadata.MavenPackageListSourceType,
+			Source: metadata.Source{
+				Type: "inline",
+				Version: map[string]interface{}{
+					"sha256": version,
+				},
+				Metadata: sourceMetadata,
+			},
+		})
+		return md, nil
+	}
+
+	return visitor, provider
+}
+
+// IsArchive tells whether the file is a jar, war or ear archive
+func IsArchive(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(lower, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadArchive lists the maven packages of an archive, found in the
+// pom.properties files of its META-INF/maven directory or else in its
+// manifest, followed by the packages of the archives nested in it, such as
+// the BOOT-INF/lib jars of spring boot applications. Nested archives which
+// cannot be read are reported to the collector and skipped.
+func ReadArchive(archivePath string, content []byte, depth int, collector *warnings.Collector) ([]metadata.MavenPackage, error) {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []metadata.MavenPackage
+	var manifest map[string]string
+	var nested []*zip.File
+
+	for _, f := range r.File {
+		switch {
+		case strings.HasPrefix(f.Name, mavenDir) && path.Base(f.Name) == "pom.properties":
+			properties, err := readEntry(f)
+			if err != nil {
+				return nil, err
+			}
+			pkg := parsePomProperties(string(properties))
+			if pkg.ArtifactID != "" {
+				pkg.Path = archivePath
+				packages = append(packages, pkg)
+			}
+		case f.Name == manifestPath:
+			m, err := readEntry(f)
+			if err != nil {
+				return nil, err
+			}
+			manifest = parseManifest(string(m))
+		case IsArchive(f.Name) && !f.FileInfo().IsDir() && depth < maxDepth:
+			nested = append(nested, f)
+		}
+	}
+
+	if len(packages) == 0 {
+		packages = append(packages, fromManifest(archivePath, manifest))
+	}
+
+	for _, f := range nested {
+		nestedPath := archivePath + NestedSeparator + f.Name
+
+		var nestedPackages []metadata.MavenPackage
+		b, err := readEntry(f)
+		if err == nil {
+			nestedPackages, err = ReadArchive(nestedPath, b, depth+1, collector)
+		}
+		if err != nil {
+			collector.Add(warnings.InvalidPackageEntry, nestedPath, fmt.Sprintf("skipping nested archive: %s", err))
+			continue
+		}
+		packages = append(packages, nestedPackages...)
+	}
+
+	return packages, nil
+}
+
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+func parsePomProperties(content string) metadata.MavenPackage {
+	pkg := metadata.MavenPackage{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "groupId":
+			pkg.GroupID = value
+		case "artifactId":
+			pkg.ArtifactID = value
+		case "version":
+			pkg.Version = value
+		}
+	}
+	return pkg
+}
+
+// parseManifest reads the main section of a manifest, whose lines are
+// wrapped with a leading space
+func parseManifest(content string) map[string]string {
+	attributes := map[string]string{}
+
+	var lastKey string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if line == "" {
+			break
+		}
+
+		if line[0] == ' ' {
+			attributes[lastKey] += line[1:]
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		lastKey = strings.TrimSpace(parts[0])
+		attributes[lastKey] = strings.TrimSpace(parts[1])
+	}
+	return attributes
+}
+
+// fromManifest names the package of an archive without pom.properties after
+// its file name, taking the version and group from its manifest when set
+func fromManifest(archivePath string, manifest map[string]string) metadata.MavenPackage {
+	name := path.Base(archivePath)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	version := firstAttribute(manifest, "Implementation-Version", "Bundle-Version", "Specification-Version")
+	if version != "" {
+		name = strings.TrimSuffix(name, "-"+version)
+	} else if matches := fileNameVersion.FindStringSubmatch(name); matches != nil {
+		name, version = matches[1], matches[2]
+	}
+
+	return metadata.MavenPackage{
+		GroupID:    firstAttribute(manifest, "Implementation-Vendor-Id"),
+		ArtifactID: name,
+		Version:    version,
+		Path:       archivePath,
+	}
+}
+
+func firstAttribute(attributes map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := attributes[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func coordinates(pkg metadata.MavenPackage) string {
+	return pkg.GroupID + ":" + pkg.ArtifactID + ":" + pkg.Version
+}
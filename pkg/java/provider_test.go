@@ -0,0 +1,183 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package java_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"sort"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/java"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+func archive(entries map[string]string) string {
+	buffer := bytes.Buffer{}
+	w := zip.NewWriter(&buffer)
+
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := w.Create(name)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write([]byte(entries[name]))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+
+	return buffer.String()
+}
+
+func pomProperties(groupID, artifactID, version string) string {
+	return "#Generated by Maven\ngroupId=" + groupID + "\nartifactId=" + artifactID + "\nversion=" + version + "\n"
+}
+
+var _ = Describe("java", func() {
+	Describe("Provider", func() {
+		var collector *warnings.Collector
+
+		BeforeEach(func() {
+			collector = warnings.NewCollector()
+		})
+
+		getPackages := func(files map[string]string) []metadata.MavenPackage {
+			md, err := Provider(test_utils.FakeImage{Files: files}, common.RunParams{Warnings: collector.ForProvider("java")}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(HaveLen(1))
+			dependency := md.Dependencies[0]
+			Expect(dependency.Type).To(Equal(metadata.MavenPackageListSourceType))
+			Expect(dependency.Source.Type).To(Equal("inline"))
+			Expect(dependency.Source.Version["sha256"]).ToNot(BeEmpty())
+
+			return dependency.Source.Metadata.(metadata.MavenPackageListSourceMetadata).Packages
+		}
+
+		It("reads the maven coordinates of the pom.properties of archives, sorted by coordinates", func() {
+			packages := getPackages(map[string]string{
+				"/usr/share/java/jackson-core-2.11.2.jar": archive(map[string]string{
+					"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\nImplementation-Version: 0.0.0\n",
+					"META-INF/maven/com.fasterxml.jackson.core/jackson-core/pom.properties": pomProperties("com.fasterxml.jackson.core", "jackson-core", "2.11.2"),
+				}),
+				"/opt/lib/shaded.JAR": archive(map[string]string{
+					"META-INF/maven/org.slf4j/slf4j-api/pom.properties":         pomProperties("org.slf4j", "slf4j-api", "1.7.30"),
+					"META-INF/maven/ch.qos.logback/logback-core/pom.properties": pomProperties("ch.qos.logback", "logback-core", "1.2.3"),
+				}),
+				"/opt/lib/readme.txt": "not an archive",
+			})
+			Expect(collector.Warnings()).To(BeEmpty())
+
+			Expect(packages).To(Equal([]metadata.MavenPackage{
+				{GroupID: "ch.qos.logback", ArtifactID: "logback-core", Version: "1.2.3", Path: "/opt/lib/shaded.JAR"},
+				{GroupID: "com.fasterxml.jackson.core", ArtifactID: "jackson-core", Version: "2.11.2", Path: "/usr/share/java/jackson-core-2.11.2.jar"},
+				{GroupID: "org.slf4j", ArtifactID: "slf4j-api", Version: "1.7.30", Path: "/opt/lib/shaded.JAR"},
+			}))
+		})
+
+		It("falls back to the manifest and the file name of archives without pom.properties", func() {
+			packages := getPackages(map[string]string{
+				"/opt/lib/commons-lang3-3.11.jar": archive(map[string]string{
+					"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\r\nImplementation-Vendor-Id: org.apache.commons\r\nImplementation-Version: 3.1\r\n 1\r\n\r\nName: org/apache/\r\nImplementation-Version: 0.0\r\n",
+				}),
+				"/opt/lib/legacy-1.2.jar": archive(map[string]string{
+					"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n",
+				}),
+				"/opt/lib/unversioned.jar": archive(map[string]string{}),
+			})
+
+			Expect(packages).To(Equal([]metadata.MavenPackage{
+				{ArtifactID: "legacy", Version: "1.2", Path: "/opt/lib/legacy-1.2.jar"},
+				{ArtifactID: "unversioned", Path: "/opt/lib/unversioned.jar"},
+				{GroupID: "org.apache.commons", ArtifactID: "commons-lang3", Version: "3.11", Path: "/opt/lib/commons-lang3-3.11.jar"},
+			}))
+		})
+
+		It("reads the archives nested in spring boot jars and wars", func() {
+			packages := getPackages(map[string]string{
+				"/app/app.jar": archive(map[string]string{
+					"META-INF/maven/com.example/app/pom.properties": pomProperties("com.example", "app", "1.0.0"),
+					"BOOT-INF/lib/spring-core-5.2.9.RELEASE.jar": archive(map[string]string{
+						"META-INF/maven/org.springframework/spring-core/pom.properties": pomProperties("org.springframework", "spring-core", "5.2.9.RELEASE"),
+					}),
+					"BOOT-INF/lib/jul-to-slf4j-1.7.30.jar": archive(map[string]string{}),
+				}),
+				"/usr/local/tomcat/webapps/shop.war": archive(map[string]string{
+					"WEB-INF/lib/gson-2.8.6.jar": archive(map[string]string{
+						"META-INF/maven/com.google.code.gson/gson/pom.properties": pomProperties("com.google.code.gson", "gson", "2.8.6"),
+					}),
+				}),
+			})
+
+			Expect(packages).To(Equal([]metadata.MavenPackage{
+				{ArtifactID: "jul-to-slf4j", Version: "1.7.30", Path: "/app/app.jar!/BOOT-INF/lib/jul-to-slf4j-1.7.30.jar"},
+				{ArtifactID: "shop", Path: "/usr/local/tomcat/webapps/shop.war"},
+				{GroupID: "com.example", ArtifactID: "app", Version: "1.0.0", Path: "/app/app.jar"},
+				{GroupID: "com.google.code.gson", ArtifactID: "gson", Version: "2.8.6", Path: "/usr/local/tomcat/webapps/shop.war!/WEB-INF/lib/gson-2.8.6.jar"},
+				{GroupID: "org.springframework", ArtifactID: "spring-core", Version: "5.2.9.RELEASE", Path: "/app/app.jar!/BOOT-INF/lib/spring-core-5.2.9.RELEASE.jar"},
+			}))
+		})
+
+		It("warns about archives which cannot be read", func() {
+			packages := getPackages(map[string]string{
+				"/opt/lib/broken.jar": "not a zip",
+				"/opt/lib/gson-2.8.6.jar": archive(map[string]string{
+					"META-INF/maven/com.google.code.gson/gson/pom.properties": pomProperties("com.google.code.gson", "gson", "2.8.6"),
+				}),
+			})
+			Expect(packages).To(HaveLen(1))
+
+			Expect(collector.Warnings()).To(ConsistOf(warnings.Warning{
+				Code:     warnings.InvalidPackageEntry,
+				Provider: "java",
+				Subject:  "/opt/lib/broken.jar",
+				Message:  "skipping archive: zip: not a valid zip file",
+			}))
+		})
+
+		It("warns about nested archives which cannot be read, keeping the rest of the archive", func() {
+			packages := getPackages(map[string]string{
+				"/app/app.jar": archive(map[string]string{
+					"META-INF/maven/com.example/app/pom.properties": pomProperties("com.example", "app", "1.0.0"),
+					"BOOT-INF/lib/broken.jar":                       "not a zip",
+					"BOOT-INF/lib/gson-2.8.6.jar": archive(map[string]string{
+						"META-INF/maven/com.google.code.gson/gson/pom.properties": pomProperties("com.google.code.gson", "gson", "2.8.6"),
+					}),
+				}),
+			})
+
+			Expect(packages).To(Equal([]metadata.MavenPackage{
+				{GroupID: "com.example", ArtifactID: "app", Version: "1.0.0", Path: "/app/app.jar"},
+				{GroupID: "com.google.code.gson", ArtifactID: "gson", Version: "2.8.6", Path: "/app/app.jar!/BOOT-INF/lib/gson-2.8.6.jar"},
+			}))
+
+			Expect(collector.Warnings()).To(ConsistOf(warnings.Warning{
+				Code:     warnings.InvalidPackageEntry,
+				Provider: "java",
+				Subject:  "/app/app.jar!/BOOT-INF/lib/broken.jar",
+				Message:  "skipping nested archive: zip: not a valid zip file",
+			}))
+		})
+
+		It("does not add a dependency when there is no java archive", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/etc/os-release": "ID=scratch",
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(BeEmpty())
+		})
+	})
+})
@@ -4,6 +4,7 @@
 package kpack_test
 
 import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -25,6 +26,10 @@ func (m MockImage) GetConfig() (*v1.ConfigFile, error) {
 	return config, nil
 }
 
+func (m MockImage) GetManifest() (*v1.Manifest, error) {
+	panic("implement me")
+}
+
 func (m MockImage) Cleanup() {
 	panic("implement me")
 }
@@ -41,6 +46,22 @@ func (m MockImage) GetDirContents(string) ([]string, error) {
 	panic("implement me")
 }
 
+func (m MockImage) WalkFiles(image.WalkFilesFunc) error {
+	panic("implement me")
+}
+
+func (m MockImage) Layers() ([]image.Layer, error) {
+	panic("implement me")
+}
+
+func (m MockImage) FileHistory(string) ([]int, error) {
+	panic("implement me")
+}
+
+func (m MockImage) GetLayerFileContent(string, int) (string, error) {
+	panic("implement me")
+}
+
 func (m MockImage) AbsolutePath(string) (string, error) {
 	panic("implement me")
 }
@@ -49,6 +70,14 @@ func (m MockImage) ExportWithMetadata(metadata.Metadata, string, string) error {
 	panic("implement me")
 }
 
+func (m MockImage) PushWithMetadata(metadata.Metadata, string) error {
+	panic("implement me")
+}
+
+func (m MockImage) WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
+}
+
 var _ = Describe("Kpack", func() {
 	Describe("Provider", func() {
 		Context("when the image has no kpack label", func() {
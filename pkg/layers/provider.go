@@ -0,0 +1,398 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package layers
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/apk"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/java"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/rpm"
+)
+
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// Provider records on the packages reported by the providers run before it
+// the layer of the image which introduced them. The package databases are
+// evaluated as of each layer which changed them, a package being introduced
+// by the oldest layer from which on every version of the database has it.
+// The packages described by a file of their own, e.g. go binaries, are
+// introduced by the topmost layer which has that file.
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	if !params.AttributeLayers {
+		return md, nil
+	}
+
+	return Attribute(dli, md)
+}
+
+// Attribute records on the packages of md the layer which introduced them,
+// as the Provider does
+func Attribute(dli image.Image, md metadata.Metadata) (metadata.Metadata, error) {
+	layers, err := dli.Layers()
+	if err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not list the image layers: %w", err)
+	}
+
+	a := attribution{dli: dli, layers: layers}
+
+	dependencies := make([]metadata.Dependency, 0, len(md.Dependencies))
+	for _, dependency := range md.Dependencies {
+		var err error
+		switch dependency.Type {
+		case metadata.DebianPackageListSourceType:
+			dependency, err = a.dpkg(dependency)
+		case metadata.RPMPackageListSourceType:
+			dependency, err = a.rpm(dependency)
+		case metadata.ApkPackageListSourceType:
+			dependency, err = a.apk(dependency)
+		case metadata.GoBinariesSourceType:
+			dependency, err = a.goBinaries(dependency)
+		case metadata.PythonPackageListSourceType:
+			dependency, err = a.python(dependency)
+		case metadata.NpmPackageListSourceType:
+			dependency, err = a.npm(dependency)
+		case metadata.MavenPackageListSourceType:
+			dependency, err = a.maven(dependency)
+		}
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not attribute %s to the image layers: %w", dependency.Type, err)
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	md.Dependencies = dependencies
+	return md, nil
+}
+
+type attribution struct {
+	dli    image.Image
+	layers []image.Layer
+}
+
+// databaseVersion holds the keys of the packages of a package database as
+// of a layer which changed it
+type databaseVersion struct {
+	layer int
+	keys  map[string]bool
+}
+
+func (a attribution) dpkg(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.DebianPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	versions, err := a.fileVersions(dpkg.StatusPath, func(content string) map[string]bool {
+		keys := map[string]bool{}
+		for _, entry := range strings.Split(content, "\n\n") {
+			if pkg, err := dpkg.ParseStatDBEntry(entry); err == nil {
+				keys[packageKey(pkg.Package, pkg.Architecture, pkg.Version)] = true
+			}
+		}
+		return keys
+	})
+	if err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		layer, ok := introducedBy(versions, packageKey(pkg.Package, pkg.Architecture, pkg.Version))
+		if !ok {
+			// distroless images have a file per package instead
+			layer, ok = a.fileLayer(path.Join(dpkg.StatusDPath, pkg.Package))
+		}
+		sourceMetadata.Packages[i].Layer = a.layer(layer, ok)
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) rpm(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.RpmPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	versions, err := a.rpmVersions()
+	if err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		layer, ok := introducedBy(versions, packageKey(pkg.Package, pkg.Architecture, pkg.Version, pkg.SourceRpm))
+		sourceMetadata.Packages[i].Layer = a.layer(layer, ok)
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) apk(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.ApkPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	versions, err := a.fileVersions(apk.InstalledDBPath, func(content string) map[string]bool {
+		keys := map[string]bool{}
+		for _, entry := range strings.Split(content, "\n\n") {
+			if pkg, err := apk.ParseInstalledDBEntry(entry); err == nil {
+				keys[packageKey(pkg.Package, pkg.Architecture, pkg.Version)] = true
+			}
+		}
+		return keys
+	})
+	if err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		layer, ok := introducedBy(versions, packageKey(pkg.Package, pkg.Architecture, pkg.Version))
+		sourceMetadata.Packages[i].Layer = a.layer(layer, ok)
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) goBinaries(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.GoBinariesSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	for i, binary := range sourceMetadata.Binaries {
+		sourceMetadata.Binaries[i].Layer = a.layer(a.fileLayer(binary.Path))
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) python(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.PythonPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		sourceMetadata.Packages[i].Layer = a.layer(a.pythonLayer(pkg))
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) npm(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.NpmPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		sourceMetadata.Packages[i].Layer = a.layer(a.fileLayer(path.Join(pkg.Location, "package.json")))
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+func (a attribution) maven(dependency metadata.Dependency) (metadata.Dependency, error) {
+	var sourceMetadata metadata.MavenPackageListSourceMetadata
+	if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+		return dependency, err
+	}
+
+	for i, pkg := range sourceMetadata.Packages {
+		// nested archives are introduced with the archive holding them
+		archivePath := strings.Split(pkg.Path, java.NestedSeparator)[0]
+		sourceMetadata.Packages[i].Layer = a.layer(a.fileLayer(archivePath))
+	}
+
+	return WithSourceMetadata(dependency, sourceMetadata)
+}
+
+// fileVersions parses every version of the file at p with keys
+func (a attribution) fileVersions(p string, keys func(string) map[string]bool) ([]databaseVersion, error) {
+	history, err := a.dli.FileHistory(p)
+	if err != nil {
+		// in this case a non-existent file is not an error
+		return nil, nil
+	}
+
+	var versions []databaseVersion
+	for _, layer := range history {
+		content, err := a.dli.GetLayerFileContent(p, layer)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, databaseVersion{layer: layer, keys: keys(content)})
+	}
+	return versions, nil
+}
+
+// rpmVersions reads the rpm database as of each layer which changed one of
+// its files, the rpm database being made of several files
+func (a attribution) rpmVersions() ([]databaseVersion, error) {
+	fileNames, err := a.dli.GetDirFileNames(rpm.RPMDbPath, false)
+	if err != nil {
+		// in this case a non-existent directory is not an error
+		return nil, nil
+	}
+
+	histories := map[string][]int{}
+	changed := map[int]bool{}
+	for _, fileName := range fileNames {
+		history, err := a.dli.FileHistory(path.Join(rpm.RPMDbPath, fileName))
+		if err != nil {
+			continue
+		}
+		histories[fileName] = history
+		for _, layer := range history {
+			changed[layer] = true
+		}
+	}
+
+	var layers []int
+	for layer := range changed {
+		layers = append(layers, layer)
+	}
+	sort.Ints(layers)
+
+	var versions []databaseVersion
+	for _, layer := range layers {
+		keys, err := a.rpmKeys(histories, layer)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, databaseVersion{layer: layer, keys: keys})
+	}
+	return versions, nil
+}
+
+func (a attribution) rpmKeys(histories map[string][]int, layer int) (map[string]bool, error) {
+	files := map[string][]byte{}
+	for fileName, history := range histories {
+		fileLayer, ok := latest(history, layer)
+		if !ok {
+			continue
+		}
+
+		content, err := a.dli.GetLayerFileContent(path.Join(rpm.RPMDbPath, fileName), fileLayer)
+		if err != nil {
+			return nil, err
+		}
+		files[fileName] = []byte(content)
+	}
+
+	keys := map[string]bool{}
+	headers, _, err := rpm.ReadHeaders(files)
+	if err != nil {
+		// a layer may leave the database in a state only a later one fixes
+		return keys, nil
+	}
+	for _, header := range headers {
+		pkg, err := rpm.UnmarshalPackage(header)
+		if err != nil {
+			return nil, err
+		}
+		keys[packageKey(pkg.Package, pkg.Architecture, pkg.Version, pkg.SourceRpm)] = true
+	}
+	return keys, nil
+}
+
+// pythonLayer finds the layer of the metadata of a distribution, in the
+// .dist-info or .egg-info of its location named after its name and version
+func (a attribution) pythonLayer(pkg metadata.PythonPackage) (int, bool) {
+	names, err := a.dli.GetDirFileNames(pkg.Location, true)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, name := range names {
+		ext := path.Ext(name)
+		if ext != ".dist-info" && ext != ".egg-info" {
+			continue
+		}
+
+		parts := strings.Split(strings.TrimSuffix(name, ext), "-")
+		if len(parts) < 2 || normalizePythonName(parts[0]) != normalizePythonName(pkg.Package) || parts[1] != pkg.Version {
+			continue
+		}
+
+		infoPath := path.Join(pkg.Location, name)
+		for _, p := range []string{path.Join(infoPath, "METADATA"), path.Join(infoPath, "PKG-INFO"), infoPath} {
+			if layer, ok := a.fileLayer(p); ok {
+				return layer, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// fileLayer returns the topmost layer which has the file at p
+func (a attribution) fileLayer(p string) (int, bool) {
+	history, err := a.dli.FileHistory(p)
+	if err != nil || len(history) == 0 {
+		return 0, false
+	}
+	return history[len(history)-1], true
+}
+
+func (a attribution) layer(i int, ok bool) *metadata.Layer {
+	if !ok || i < 0 || i >= len(a.layers) {
+		return nil
+	}
+	return &metadata.Layer{
+		Digest:    a.layers[i].Digest,
+		CreatedBy: a.layers[i].CreatedBy,
+	}
+}
+
+// introducedBy returns the layer of the oldest version of a package database
+// from which on every version has the package
+func introducedBy(versions []databaseVersion, key string) (int, bool) {
+	layer, found := 0, false
+	for i := len(versions) - 1; i >= 0 && versions[i].keys[key]; i-- {
+		layer, found = versions[i].layer, true
+	}
+	return layer, found
+}
+
+// latest returns the newest layer of the history up to the given layer
+func latest(history []int, layer int) (int, bool) {
+	found, ok := 0, false
+	for _, l := range history {
+		if l <= layer {
+			found, ok = l, true
+		}
+	}
+	return found, ok
+}
+
+func packageKey(fields ...string) string {
+	return strings.Join(fields, "\x00")
+}
+
+func normalizePythonName(name string) string {
+	return strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "_"))
+}
+
+// WithSourceMetadata replaces the metadata of the dependency, and its digest
+func WithSourceMetadata(dependency metadata.Dependency, sourceMetadata interface{}) (metadata.Dependency, error) {
+	version, err := common.Digest(sourceMetadata)
+	if err != nil {
+		return dependency, fmt.Errorf("could not get digest for source metadata: %w", err)
+	}
+
+	dependency.Source.Metadata = sourceMetadata
+	dependency.Source.Version = map[string]interface{}{
+		"sha256": version,
+	}
+	return dependency, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package layers_test
+
+import (
+	"io/ioutil"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/layers"
+)
+
+var imageLayers = []image.Layer{
+	{Digest: "sha256:base", CreatedBy: "/bin/sh -c #(nop) ADD file:rootfs.tar.gz in / "},
+	{Digest: "sha256:app", CreatedBy: "/bin/sh -c #(nop) COPY dir:app in /app "},
+	{Digest: "sha256:packages", CreatedBy: "/bin/sh -c apt-get install -y curl"},
+}
+
+func layer(i int) *metadata.Layer {
+	return &metadata.Layer{Digest: imageLayers[i].Digest, CreatedBy: imageLayers[i].CreatedBy}
+}
+
+func dpkgDependency(packages ...metadata.DpkgPackage) metadata.Dependency {
+	return metadata.Dependency{
+		Type: metadata.DebianPackageListSourceType,
+		Source: metadata.Source{
+			Type:     "inline",
+			Version:  map[string]interface{}{"sha256": "unattributed"},
+			Metadata: metadata.DebianPackageListSourceMetadata{Packages: packages},
+		},
+	}
+}
+
+var _ = Describe("layers", func() {
+	Describe("Provider", func() {
+		It("attributes the debian packages to the oldest layer from which on the status has them", func() {
+			dli := test_utils.FakeImage{
+				ImageLayers: imageLayers,
+				History: map[string][]test_utils.FakeVersion{
+					"/var/lib/dpkg/status": {
+						{Layer: 0, Content: "Package: libc6\nArchitecture: amd64\nVersion: 2.28-10\n\nPackage: curl\nArchitecture: amd64\nVersion: 7.64.0-4\n"},
+						{Layer: 2, Content: "Package: libc6\nArchitecture: amd64\nVersion: 2.28-10\n\nPackage: curl\nArchitecture: amd64\nVersion: 7.64.0-4+deb10u1\n\nPackage: zlib1g\nArchitecture: amd64\nVersion: 1:1.2.11.dfsg-1\n"},
+					},
+					"/var/lib/dpkg/status.d/tzdata": {{Layer: 1, Content: "Package: tzdata\n"}},
+				},
+			}
+
+			md, err := Provider(dli, common.RunParams{AttributeLayers: true}, metadata.Metadata{
+				Dependencies: []metadata.Dependency{dpkgDependency(
+					metadata.DpkgPackage{Package: "curl", Architecture: "amd64", Version: "7.64.0-4+deb10u1"},
+					metadata.DpkgPackage{Package: "libc6", Architecture: "amd64", Version: "2.28-10"},
+					metadata.DpkgPackage{Package: "tzdata", Architecture: "all", Version: "2020a-0+deb10u1"},
+					metadata.DpkgPackage{Package: "unknown", Architecture: "amd64", Version: "1.0"},
+					metadata.DpkgPackage{Package: "zlib1g", Architecture: "amd64", Version: "1:1.2.11.dfsg-1"},
+				)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			dependency := md.Dependencies[0]
+			Expect(dependency.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+
+			attributed := map[string]*metadata.Layer{}
+			for _, pkg := range dependency.Source.Metadata.(metadata.DebianPackageListSourceMetadata).Packages {
+				attributed[pkg.Package] = pkg.Layer
+			}
+			Expect(attributed).To(Equal(map[string]*metadata.Layer{
+				"curl":    layer(2),
+				"libc6":   layer(0),
+				"tzdata":  layer(1),
+				"unknown": nil,
+				"zlib1g":  layer(2),
+			}))
+		})
+
+		It("attributes the rpm packages to the layer of the rpm database", func() {
+			packages, err := ioutil.ReadFile("../../test/integration/assets/rpm/Packages")
+			Expect(err).ToNot(HaveOccurred())
+
+			dli := test_utils.FakeImage{
+				ImageLayers: imageLayers,
+				History: map[string][]test_utils.FakeVersion{
+					"/var/lib/rpm/Packages": {{Layer: 1, Content: string(packages)}},
+				},
+			}
+
+			md, err := Provider(dli, common.RunParams{AttributeLayers: true}, metadata.Metadata{
+				Dependencies: []metadata.Dependency{{
+					Type: metadata.RPMPackageListSourceType,
+					Source: metadata.Source{Metadata: metadata.RpmPackageListSourceMetadata{Packages: []metadata.RpmPackage{{
+						Package:      "bash",
+						Version:      "4.4.18",
+						Architecture: "x86_64",
+						License:      "GPLv3",
+						SourceRpm:    "bash-4.4.18-1.ph3.src.rpm",
+					}}}},
+				}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			packageList := md.Dependencies[0].Source.Metadata.(metadata.RpmPackageListSourceMetadata).Packages
+			Expect(packageList[0].Layer).To(Equal(layer(1)))
+		})
+
+		It("attributes the packages of their own file to the topmost layer which has it", func() {
+			dli := test_utils.FakeImage{
+				ImageLayers: imageLayers,
+				History: map[string][]test_utils.FakeVersion{
+					"/app/server": {{Layer: 0, Content: ""}, {Layer: 1, Content: ""}},
+					"/app/node_modules/left-pad/package.json":                                {{Layer: 1, Content: ""}},
+					"/usr/lib/python3/dist-packages/PyYAML-5.3.1.egg-info":                   {{Layer: 2, Content: ""}},
+					"/usr/lib/python3/dist-packages/zope.interface-5.1.0.dist-info/METADATA": {{Layer: 0, Content: ""}},
+					"/usr/lib/python3/dist-packages/yaml/__init__.py":                        {{Layer: 2, Content: ""}},
+				},
+			}
+
+			md, err := Provider(dli, common.RunParams{AttributeLayers: true}, metadata.Metadata{
+				Dependencies: []metadata.Dependency{
+					{
+						Type: metadata.GoBinariesSourceType,
+						Source: metadata.Source{Metadata: metadata.GoBinariesSourceMetadata{
+							Binaries: []metadata.GoBinary{{Path: "/app/server"}},
+						}},
+					},
+					{
+						// as read back from an existing label
+						Type: metadata.NpmPackageListSourceType,
+						Source: metadata.Source{Metadata: map[string]interface{}{
+							"packages": []interface{}{
+								map[string]interface{}{"package": "left-pad", "location": "/app/node_modules/left-pad"},
+							},
+						}},
+					},
+					{
+						Type: metadata.PythonPackageListSourceType,
+						Source: metadata.Source{Metadata: metadata.PythonPackageListSourceMetadata{
+							Packages: []metadata.PythonPackage{
+								{Package: "PyYAML", Version: "5.3.1", Location: "/usr/lib/python3/dist-packages"},
+								{Package: "zope-interface", Version: "5.1.0", Location: "/usr/lib/python3/dist-packages"},
+							},
+						}},
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			binaries := md.Dependencies[0].Source.Metadata.(metadata.GoBinariesSourceMetadata).Binaries
+			Expect(binaries[0].Layer).To(Equal(layer(1)))
+
+			npmPackages := md.Dependencies[1].Source.Metadata.(metadata.NpmPackageListSourceMetadata).Packages
+			Expect(npmPackages[0].Layer).To(Equal(layer(1)))
+
+			pythonPackages := md.Dependencies[2].Source.Metadata.(metadata.PythonPackageListSourceMetadata).Packages
+			Expect(pythonPackages[0].Layer).To(Equal(layer(2)))
+			Expect(pythonPackages[1].Layer).To(Equal(layer(0)))
+		})
+
+		It("does nothing by default", func() {
+			md := metadata.Metadata{Dependencies: []metadata.Dependency{dpkgDependency(
+				metadata.DpkgPackage{Package: "curl", Architecture: "amd64", Version: "7.64.0-4"},
+			)}}
+
+			Expect(Provider(test_utils.FakeImage{}, common.RunParams{}, md)).To(Equal(md))
+		})
+	})
+})
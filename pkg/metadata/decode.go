@@ -0,0 +1,30 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DecodeSourceMetadata copies the metadata of a source into target.
+// Metadata generated by a provider holds the typed struct, while metadata
+// read back from a label or a file holds a map; both are handled the same way.
+func DecodeSourceMetadata(source Source, target interface{}) error {
+	if source.Metadata == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(source.Metadata)
+	if err != nil {
+		return fmt.Errorf("could not encode source metadata: %w", err)
+	}
+
+	err = json.Unmarshal(raw, target)
+	if err != nil {
+		return fmt.Errorf("could not decode source metadata: %w", err)
+	}
+
+	return nil
+}
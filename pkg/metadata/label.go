@@ -0,0 +1,74 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package metadata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// LabelName is the label holding the metadata as JSON
+	LabelName = "io.deplab.metadata"
+	// CompressedLabelName is the label holding the metadata as gzipped JSON,
+	// encoded in base64, in place of LabelName
+	CompressedLabelName = "io.deplab.metadata.gzip.v1"
+	// MaxLabelSize is the size in bytes of the largest JSON read from the
+	// label of CompressedLabelName, which would otherwise be decompressed
+	// whatever its size
+	MaxLabelSize = 32 << 20
+)
+
+// Labels returns the labels holding the JSON of the metadata: the label of
+// LabelName, or the label of CompressedLabelName when compress is set and the
+// JSON is longer than threshold bytes
+func Labels(label string, compress bool, threshold int) (map[string]string, error) {
+	if !compress || len(label) <= threshold {
+		return map[string]string{LabelName: label}, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
+	writer := gzip.NewWriter(encoder)
+	if _, err := writer.Write([]byte(label)); err != nil {
+		return nil, fmt.Errorf("could not compress label: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("could not compress label: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("could not encode label: %w", err)
+	}
+
+	return map[string]string{CompressedLabelName: buf.String()}, nil
+}
+
+// ReadLabel returns the JSON of the metadata held by the labels, in either
+// encoding, and whether there is one
+func ReadLabel(labels map[string]string) (string, bool, error) {
+	compressed, ok := labels[CompressedLabelName]
+	if !ok {
+		label, ok := labels[LabelName]
+		return label, ok, nil
+	}
+
+	reader, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, bytes.NewBufferString(compressed)))
+	if err != nil {
+		return "", false, fmt.Errorf("cannot decode the %s label: %w", CompressedLabelName, err)
+	}
+	defer reader.Close()
+
+	label, err := ioutil.ReadAll(io.LimitReader(reader, MaxLabelSize+1))
+	if err != nil {
+		return "", false, fmt.Errorf("cannot decode the %s label: %w", CompressedLabelName, err)
+	}
+	if len(label) > MaxLabelSize {
+		return "", false, fmt.Errorf("cannot decode the %s label: it is larger than %d bytes", CompressedLabelName, MaxLabelSize)
+	}
+	return string(label), true, nil
+}
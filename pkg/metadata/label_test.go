@@ -0,0 +1,85 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package metadata_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("Label", func() {
+	label := `{"dependencies":[` + strings.Repeat(`{"type":"debian_package"},`, 100) + `{"type":"debian_package"}]}`
+
+	Describe("Labels", func() {
+		It("keeps the label as JSON without compression", func() {
+			labels, err := metadata.Labels(label, false, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{metadata.LabelName: label}))
+		})
+
+		It("keeps the label as JSON up to the threshold", func() {
+			labels, err := metadata.Labels(label, true, len(label))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{metadata.LabelName: label}))
+		})
+
+		It("compresses the label above the threshold", func() {
+			labels, err := metadata.Labels(label, true, len(label)-1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(labels).To(HaveLen(1))
+			Expect(labels).To(HaveKey(metadata.CompressedLabelName))
+			Expect(len(labels[metadata.CompressedLabelName])).To(BeNumerically("<", len(label)))
+		})
+	})
+
+	Describe("ReadLabel", func() {
+		It("reads the compressed label", func() {
+			labels, err := metadata.Labels(label, true, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			read, ok, err := metadata.ReadLabel(labels)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(read).To(Equal(label))
+		})
+
+		It("reads the JSON label", func() {
+			read, ok, err := metadata.ReadLabel(map[string]string{metadata.LabelName: label})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(read).To(Equal(label))
+		})
+
+		It("reports a missing label", func() {
+			_, ok, err := metadata.ReadLabel(map[string]string{"other": "label"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("fails on a compressed label which cannot be decoded", func() {
+			_, _, err := metadata.ReadLabel(map[string]string{metadata.CompressedLabelName: "not gzip"})
+			Expect(err).To(MatchError(ContainSubstring("cannot decode the " + metadata.CompressedLabelName + " label")))
+		})
+
+		It("fails on a compressed label larger than the maximum size once decompressed", func() {
+			labels, err := metadata.Labels(strings.Repeat(" ", metadata.MaxLabelSize+1), true, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, err = metadata.ReadLabel(labels)
+			Expect(err).To(MatchError(ContainSubstring("larger than")))
+		})
+
+		It("reads a compressed label of the maximum size", func() {
+			labels, err := metadata.Labels(strings.Repeat(" ", metadata.MaxLabelSize), true, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			read, _, err := metadata.ReadLabel(labels)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(read).To(HaveLen(metadata.MaxLabelSize))
+		})
+	})
+})
@@ -13,12 +13,17 @@ func Merge(original, current Metadata) (Metadata, []Warning) {
 	var warnings []Warning
 	newDependencies := make([]Dependency, 0)
 
-	if len(original.Base) > 0 && !reflect.DeepEqual(original.Base, current.Base) {
+	if len(original.Base) > 0 && !reflect.DeepEqual(withoutBaseImage(original.Base), withoutBaseImage(current.Base)) {
 		warnings = append(warnings, "base")
 	}
 
 	newDependencies, warnings = selectAdditionalDependencies(DebianPackageListSourceType, newDependencies, warnings, original, current)
 	newDependencies, warnings = selectAdditionalDependencies(RPMPackageListSourceType, newDependencies, warnings, original, current)
+	newDependencies, warnings = selectAdditionalDependencies(ApkPackageListSourceType, newDependencies, warnings, original, current)
+	newDependencies, warnings = selectAdditionalDependencies(GoBinariesSourceType, newDependencies, warnings, original, current)
+	newDependencies, warnings = selectAdditionalDependencies(PythonPackageListSourceType, newDependencies, warnings, original, current)
+	newDependencies, warnings = selectAdditionalDependencies(NpmPackageListSourceType, newDependencies, warnings, original, current)
+	newDependencies, warnings = selectAdditionalDependencies(MavenPackageListSourceType, newDependencies, warnings, original, current)
 	newDependencies, warnings = selectAdditionalDependencies(BuildpackMetadataType, newDependencies, warnings, original, current)
 	newDependencies, warnings = selectAdditionalDependencies(PackageType, newDependencies, warnings, original, current)
 
@@ -30,13 +35,67 @@ func Merge(original, current Metadata) (Metadata, []Warning) {
 		}
 	}
 
+	// the base image, and the dependencies found in it, are only known when
+	// the base image is given, so the ones of the original are kept otherwise
+	base := current.Base
+	baseDependencies := current.BaseDependencies
+	if len(current.BaseDependencies) == 0 {
+		base = withBaseImageOf(current.Base, original.Base)
+		baseDependencies = original.BaseDependencies
+	}
+
+	// unaccounted files are only listed when asked for, so the ones of the
+	// original are kept otherwise
+	unaccountedFiles := current.UnaccountedFiles
+	if current.UnaccountedFiles == nil {
+		unaccountedFiles = original.UnaccountedFiles
+	}
+
 	return Metadata{
-		Provenance:   append(original.Provenance, current.Provenance...),
-		Base:         current.Base,
-		Dependencies: newDependencies,
+		Provenance:       append(original.Provenance, current.Provenance...),
+		Base:             base,
+		Dependencies:     newDependencies,
+		BaseDependencies: baseDependencies,
+		UnaccountedFiles: unaccountedFiles,
 	}, warnings
 }
 
+// withoutBaseImage returns the keys of the base which do not record the base
+// image
+func withoutBaseImage(base Base) Base {
+	without := Base{}
+	for k, v := range base {
+		if k != BaseImageKey && k != BaseImageDigestKey {
+			without[k] = v
+		}
+	}
+	return without
+}
+
+// withBaseImageOf returns base recording the base image of other, unless
+// base records one already
+func withBaseImageOf(base, other Base) Base {
+	_, hasImage := base[BaseImageKey]
+	_, hasDigest := base[BaseImageDigestKey]
+	if hasImage || hasDigest {
+		return base
+	}
+	if _, ok := other[BaseImageDigestKey]; !ok {
+		return base
+	}
+
+	with := Base{}
+	for k, v := range base {
+		with[k] = v
+	}
+	for _, k := range []string{BaseImageKey, BaseImageDigestKey} {
+		if v, ok := other[k]; ok {
+			with[k] = v
+		}
+	}
+	return with
+}
+
 func selectAdditionalDependencies(sourceType string, dependencies []Dependency, warnings []Warning, original Metadata, current Metadata) ([]Dependency, []Warning) {
 	originalDependency, presentInSource := SelectDependency(original.Dependencies, sourceType)
 	currentDependency, presentInAdditional := SelectDependency(current.Dependencies, sourceType)
@@ -106,6 +106,77 @@ var _ = Describe("Merge", func() {
 		})
 	})
 
+	Describe("base image", func() {
+		debianBase := metadata.Base{"id": "debian", "version_id": "10"}
+		baseDependency := metadata.Dependency{
+			Type:   metadata.DebianPackageListSourceType,
+			Source: metadata.Source{Type: "inline", Version: map[string]interface{}{"sha256": "base"}},
+		}
+		original := metadata.Metadata{
+			Base: metadata.Base{
+				"id":                        "debian",
+				"version_id":                "10",
+				metadata.BaseImageKey:       "debian:10",
+				metadata.BaseImageDigestKey: "sha256:abc",
+			},
+			BaseDependencies: []metadata.Dependency{baseDependency},
+		}
+
+		Context("when the current metadata has no base image", func() {
+			It("keeps the base image and its dependencies from the original metadata without a warning", func() {
+				result, warnings := metadata.Merge(original, metadata.Metadata{Base: debianBase})
+				Expect(warnings).To(BeEmpty())
+				Expect(result.Base).To(Equal(original.Base))
+				Expect(result.BaseDependencies).To(Equal(original.BaseDependencies))
+				Expect(debianBase).ToNot(HaveKey(metadata.BaseImageKey))
+			})
+		})
+
+		Context("when the current metadata has a base image", func() {
+			It("retains the base image and its dependencies from the current metadata", func() {
+				current := metadata.Metadata{
+					Base: metadata.Base{
+						"id":                        "debian",
+						"version_id":                "10",
+						metadata.BaseImageKey:       "debian:10-slim",
+						metadata.BaseImageDigestKey: "sha256:def",
+					},
+					BaseDependencies: []metadata.Dependency{{Type: metadata.RPMPackageListSourceType}},
+				}
+
+				result, warnings := metadata.Merge(original, current)
+				Expect(warnings).To(BeEmpty())
+				Expect(result.Base).To(Equal(current.Base))
+				Expect(result.BaseDependencies).To(Equal(current.BaseDependencies))
+			})
+		})
+	})
+
+	Describe("unaccounted files", func() {
+		original := metadata.Metadata{
+			UnaccountedFiles: []metadata.UnaccountedFile{{Path: "/opt/app/bin/app", Size: 10, SHA256: "abc"}},
+		}
+
+		It("keeps the unaccounted files of the original metadata when the current metadata has none", func() {
+			result, _ := metadata.Merge(original, metadata.Metadata{})
+			Expect(result.UnaccountedFiles).To(Equal(original.UnaccountedFiles))
+		})
+
+		It("retains the unaccounted files of the current metadata", func() {
+			current := metadata.Metadata{
+				UnaccountedFiles: []metadata.UnaccountedFile{{Path: "/opt/app/bin/other", Size: 20, SHA256: "def"}},
+			}
+
+			result, _ := metadata.Merge(original, current)
+			Expect(result.UnaccountedFiles).To(Equal(current.UnaccountedFiles))
+		})
+
+		It("retains the empty list of unaccounted files of the current metadata", func() {
+			result, _ := metadata.Merge(original, metadata.Metadata{UnaccountedFiles: []metadata.UnaccountedFile{}})
+			Expect(result.UnaccountedFiles).To(BeEmpty())
+		})
+	})
+
 	Describe("git", func() {
 		Context("git dependencies on original", func() {
 			It("retains the git dependencies from the original metadata", func() {
@@ -376,6 +447,46 @@ var _ = Describe("Merge", func() {
 		})
 	})
 
+	Describe("apk", func() {
+		Context("apk list dependencies on both original and current", func() {
+			Context("when original and current don't match", func() {
+				It("retains only the apk list dependencies from the current metadata and emits a warning", func() {
+					originalApk := metadata.Dependency{
+						Type: metadata.ApkPackageListSourceType,
+						Source: metadata.Source{
+							Type: "inline",
+							Version: map[string]interface{}{
+								"sha256": "original",
+							},
+						},
+					}
+
+					currentApk := metadata.Dependency{
+						Type: metadata.ApkPackageListSourceType,
+						Source: metadata.Source{
+							Type: "inline",
+							Version: map[string]interface{}{
+								"sha256": "current",
+							},
+						},
+					}
+
+					result, warnings := metadata.Merge(metadata.Metadata{
+						Dependencies: []metadata.Dependency{originalApk},
+					}, metadata.Metadata{
+						Dependencies: []metadata.Dependency{currentApk},
+					})
+
+					Expect(warnings).To(ConsistOf(metadata.Warning(metadata.ApkPackageListSourceType)))
+
+					apk, ok := test_utils.SelectApkDependency(result.Dependencies)
+					Expect(ok).To(BeTrue())
+					Expect(apk).To(Equal(currentApk))
+				})
+			})
+		})
+	})
+
 	Describe("archive", func() {
 		Context("archive dependencies on original", func() {
 			It("retains the archives dependencies from the original metadata", func() {
@@ -21,3 +21,18 @@ func WriteMetadataFile(md Metadata, metadataFilePath string) error {
 	}
 	return nil
 }
+
+func ReadMetadataFile(metadataFilePath string) (Metadata, error) {
+	metadataFile, err := os.Open(metadataFilePath)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("could not open file %s: %w", metadataFilePath, err)
+	}
+	defer metadataFile.Close()
+
+	var md Metadata
+	err = json.NewDecoder(metadataFile).Decode(&md)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("could not read metadata file %s: %w", metadataFilePath, err)
+	}
+	return md, nil
+}
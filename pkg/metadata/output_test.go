@@ -57,4 +57,25 @@ var _ = Describe("outputs", func() {
 			})
 		})
 	})
+
+	Describe("ReadMetadataFile", func() {
+		It("reads back a written metadata file", func() {
+			path := test_utils.NonExistingFileName()
+			defer test_utils.CleanupFile(path)
+
+			err := WriteMetadataFile(test_utils.MetadataSample, path)
+			Expect(err).ToNot(HaveOccurred())
+
+			md, err := ReadMetadataFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(md.Base).To(Equal(test_utils.MetadataSample.Base))
+			Expect(md.Dependencies).To(HaveLen(len(test_utils.MetadataSample.Dependencies)))
+		})
+
+		It("returns an error if the file does not exist", func() {
+			_, err := ReadMetadataFile("a-path-that-does-not-exist/metadata.json")
+
+			Expect(err).To(MatchError(ContainSubstring("a-path-that-does-not-exist/metadata.json")))
+		})
+	})
 })
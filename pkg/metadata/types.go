@@ -7,6 +7,11 @@ const (
 	DebianPackageListSourceType = "debian_package_list"
 	GitSourceType               = "git"
 	RPMPackageListSourceType    = "rpm_package_list"
+	ApkPackageListSourceType    = "apk_package_list"
+	GoBinariesSourceType        = "go_binaries"
+	PythonPackageListSourceType = "python_package_list"
+	NpmPackageListSourceType    = "npm_package_list"
+	MavenPackageListSourceType  = "maven_package_list"
 	ArchiveType                 = "archive"
 	PackageType                 = "package"
 	BuildpackMetadataType       = "buildpack_metadata"
@@ -16,6 +21,18 @@ type Metadata struct {
 	Base         Base         `json:"base"`
 	Provenance   []Provenance `json:"provenance"`
 	Dependencies []Dependency `json:"dependencies"`
+	// BaseDependencies are the dependencies of the base image, when told
+	// apart from the ones of the application in Dependencies
+	BaseDependencies []Dependency `json:"base_dependencies,omitempty"`
+	// UnaccountedFiles lists the executables, shared libraries and archives
+	// which no package manager or provider claims
+	UnaccountedFiles []UnaccountedFile `json:"unaccounted_files,omitempty"`
+}
+
+// AllDependencies returns the dependencies of the base image followed by the
+// ones of the application
+func (md Metadata) AllDependencies() []Dependency {
+	return append(append([]Dependency{}, md.BaseDependencies...), md.Dependencies...)
 }
 
 type Provenance struct {
@@ -26,6 +43,13 @@ type Provenance struct {
 
 type Base map[string]string
 
+// Keys of the Base recording the image which the image is built on, next to
+// the keys of its os-release file
+const (
+	BaseImageKey       = "image"
+	BaseImageDigestKey = "image_digest"
+)
+
 type Dependency struct {
 	Type   string `json:"type"`
 	Source Source `json:"source"`
@@ -46,6 +70,27 @@ type RpmPackageListSourceMetadata struct {
 	Packages []RpmPackage `json:"packages"`
 }
 
+type ApkPackageListSourceMetadata struct {
+	Packages     []ApkPackage `json:"packages"`
+	Repositories []string     `json:"repositories"`
+}
+
+type PythonPackageListSourceMetadata struct {
+	Packages []PythonPackage `json:"packages"`
+}
+
+type NpmPackageListSourceMetadata struct {
+	Packages []NpmPackage `json:"packages"`
+}
+
+type MavenPackageListSourceMetadata struct {
+	Packages []MavenPackage `json:"packages"`
+}
+
+type GoBinariesSourceMetadata struct {
+	Binaries []GoBinary `json:"binaries"`
+}
+
 type BuildpackBOMSourceMetadata struct {
 	Buildpacks      []Buildpack            `json:"buildpacks"`
 	BillOfMaterials []BuildpackBOM         `json:"bom"`
@@ -67,10 +112,14 @@ type ArchiveSourceMetadata struct {
 }
 
 type DpkgPackage struct {
-	Package      string        `json:"package"`
-	Version      string        `json:"version"`
-	Architecture string        `json:"architecture"`
-	Source       PackageSource `json:"source"`
+	Package       string        `json:"package"`
+	Version       string        `json:"version"`
+	Architecture  string        `json:"architecture"`
+	Source        PackageSource `json:"source"`
+	Licenses      []string      `json:"licenses,omitempty"`
+	ModifiedFiles []string      `json:"modified_files,omitempty"`
+	MissingFiles  []string      `json:"missing_files,omitempty"`
+	Layer         *Layer        `json:"layer,omitempty"`
 }
 
 type RpmPackage struct {
@@ -79,6 +128,78 @@ type RpmPackage struct {
 	Architecture string `json:"architecture" rpm:"ARCH"`
 	License      string `json:"license" rpm:"LICENSE"`
 	SourceRpm    string `json:"source_rpm" rpm:"SOURCERPM"`
+	Layer        *Layer `json:"layer,omitempty"`
+}
+
+type ApkPackage struct {
+	Package      string `json:"package"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture"`
+	License      string `json:"license"`
+	Origin       string `json:"origin"`
+	Commit       string `json:"commit"`
+	Maintainer   string `json:"maintainer"`
+	Layer        *Layer `json:"layer,omitempty"`
+}
+
+type PythonPackage struct {
+	Package     string `json:"package"`
+	Version     string `json:"version"`
+	License     string `json:"license"`
+	Installer   string `json:"installer"`
+	Interpreter string `json:"interpreter"`
+	Location    string `json:"location"`
+	Layer       *Layer `json:"layer,omitempty"`
+}
+
+type NpmPackage struct {
+	Package   string `json:"package"`
+	Version   string `json:"version"`
+	License   string `json:"license"`
+	Resolved  string `json:"resolved"`
+	Integrity string `json:"integrity"`
+	Location  string `json:"location"`
+	Layer     *Layer `json:"layer,omitempty"`
+}
+
+type MavenPackage struct {
+	GroupID    string `json:"group_id"`
+	ArtifactID string `json:"artifact_id"`
+	Version    string `json:"version"`
+	Path       string `json:"path"`
+	Layer      *Layer `json:"layer,omitempty"`
+}
+
+type GoBinary struct {
+	Path      string     `json:"path"`
+	GoVersion string     `json:"go_version"`
+	Package   string     `json:"package"`
+	Module    GoModule   `json:"module"`
+	Modules   []GoModule `json:"modules"`
+	Layer     *Layer     `json:"layer,omitempty"`
+}
+
+// Layer is the layer of the image which introduced a package, and the
+// command of the image history which created it
+type Layer struct {
+	Digest    string `json:"digest"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+type GoModule struct {
+	Path    string    `json:"path"`
+	Version string    `json:"version"`
+	Sum     string    `json:"sum,omitempty"`
+	Replace *GoModule `json:"replace,omitempty"`
+}
+
+// Resolved is the module whose code was built into the binary, following
+// replace directives which point to another module version
+func (m GoModule) Resolved() GoModule {
+	if m.Replace != nil && m.Replace.Version != "" {
+		return *m.Replace
+	}
+	return m
 }
 
 type Buildpack struct {
@@ -120,3 +241,9 @@ var ScratchBase = Base{
 	"version_codename": "unknown",
 	"version_id":       "unknown",
 }
+
+type UnaccountedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
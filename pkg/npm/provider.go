@@ -0,0 +1,301 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package npm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	nodeModules = "node_modules"
+	// PackageLockFile is the lockfile of an application
+	PackageLockFile = "package-lock.json"
+	// HiddenLockFile is the lockfile npm keeps of what is installed in a
+	// node_modules directory
+	HiddenLockFile = ".package-lock.json"
+)
+
+type packageJSON struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	License   json.RawMessage `json:"license"`
+	Licenses  licenseList     `json:"licenses"`
+	Resolved  string          `json:"_resolved"`
+	Integrity string          `json:"_integrity"`
+}
+
+type licenseObject struct {
+	Type string `json:"type"`
+}
+
+// UnmarshalJSON reads a license object, or a license given as a string in the
+// deprecated list of licenses
+func (l *licenseObject) UnmarshalJSON(b []byte) error {
+	var expression string
+	if err := json.Unmarshal(b, &expression); err == nil {
+		l.Type = expression
+		return nil
+	}
+
+	type object licenseObject
+	return json.Unmarshal(b, (*object)(l))
+}
+
+// licenseList is the deprecated list of licenses, which packages also set to
+// a single license. Licenses which cannot be read are left out, as they are
+// not worth skipping the package for.
+type licenseList []licenseObject
+
+func (l *licenseList) UnmarshalJSON(b []byte) error {
+	var licenses []licenseObject
+	if err := json.Unmarshal(b, &licenses); err == nil {
+		*l = licenses
+		return nil
+	}
+
+	var single licenseObject
+	if err := json.Unmarshal(b, &single); err == nil {
+		*l = licenseList{single}
+	}
+	return nil
+}
+
+type lockfile struct {
+	// lockfileVersion 2 and later
+	Packages map[string]lockedPackage `json:"packages"`
+	// lockfileVersion 1
+	Dependencies map[string]lockedDependency `json:"dependencies"`
+}
+
+type lockedPackage struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	Resolved  string          `json:"resolved"`
+	Integrity string          `json:"integrity"`
+	License   json.RawMessage `json:"license"`
+	Link      bool            `json:"link"`
+}
+
+type lockedDependency struct {
+	Version      string                      `json:"version"`
+	Resolved     string                      `json:"resolved"`
+	Integrity    string                      `json:"integrity"`
+	Dependencies map[string]lockedDependency `json:"dependencies"`
+}
+
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not find npm packages: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the package.json and lockfiles of the
+// image in a walk shared with other providers, and the provider adding the
+// packages once it is done
+func Scan(_ image.Image, params common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	collector := params.Warnings
+	installed := map[string]*metadata.NpmPackage{}
+	locked := map[string]metadata.NpmPackage{}
+	hiddenLocked := map[string]metadata.NpmPackage{}
+
+	visitor := image.FileVisitor{
+		Match: func(filePath string, _ os.FileInfo) bool {
+			switch path.Base(filePath) {
+			case "package.json", PackageLockFile, HiddenLockFile:
+				return true
+			}
+			return false
+		},
+		Visit: func(filePath string, _ os.FileInfo, content io.Reader) error {
+			dir, fileName := path.Split(filePath)
+			dir = path.Clean(dir)
+
+			switch {
+			case fileName == "package.json" && isPackageDir(dir):
+				var p packageJSON
+				if err := json.NewDecoder(content).Decode(&p); err != nil {
+					collector.Add(warnings.InvalidPackageEntry, filePath, fmt.Sprintf("skipping package: %s", err))
+					return nil
+				}
+				if p.Name == "" {
+					collector.Add(warnings.InvalidPackageEntry, filePath, "skipping package: missing name")
+					return nil
+				}
+				installed[dir] = &metadata.NpmPackage{
+					Package:   p.Name,
+					Version:   p.Version,
+					License:   license(p.License, p.Licenses),
+					Resolved:  p.Resolved,
+					Integrity: p.Integrity,
+					Location:  dir,
+				}
+			case fileName == PackageLockFile && !strings.Contains(filePath, "/"+nodeModules+"/"):
+				return readLockfile(filePath, dir, content, locked, collector)
+			case fileName == HiddenLockFile && path.Base(dir) == nodeModules:
+				return readLockfile(filePath, path.Dir(dir), content, hiddenLocked, collector)
+			}
+			return nil
+		},
+	}
+
+	provider := func(_ image.Image, _ common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		packages := getNpmPackages(installed, locked, hiddenLocked)
+		if len(packages) == 0 {
+			return md, nil
+		}
+
+		sourceMetadata := metadata.NpmPackageListSourceMetadata{
+			Packages: packages,
+		}
+
+		version, err := common.Digest(sourceMetadata)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not get digest for source metadata: %w", err)
+		}
+
+		md.Dependencies = append(md.Dependencies, metadata.Dependency{
+			Type: metadata.NpmPackageListSourceType,
+			Source: metadata.Source{
+				Type: "inline",
+				Version: map[string]interface{}{
+					"sha256": version,
+				},
+				Metadata: sourceMetadata,
+			},
+		})
+		return md, nil
+	}
+
+	return visitor, provider
+}
+
+// getNpmPackages lists the packages installed in node_modules directories,
+// as described by their package.json, preferring the version, resolved url
+// and integrity recorded by a lockfile of the same node_modules directory
+func getNpmPackages(installed map[string]*metadata.NpmPackage, locked, hiddenLocked map[string]metadata.NpmPackage) []metadata.NpmPackage {
+	var packages []metadata.NpmPackage
+	for location, pkg := range installed {
+		for _, lock := range []map[string]metadata.NpmPackage{locked, hiddenLocked} {
+			if l, ok := lock[location]; ok && l.Version != "" {
+				pkg.Version = l.Version
+				pkg.Resolved = l.Resolved
+				pkg.Integrity = l.Integrity
+				if l.License != "" {
+					pkg.License = l.License
+				}
+			}
+		}
+		packages = append(packages, *pkg)
+	}
+
+	collator := collate.New(language.BritishEnglish)
+	sort.Slice(packages, func(i, j int) bool {
+		if c := collator.CompareString(packages[i].Package, packages[j].Package); c != 0 {
+			return c < 0
+		}
+		return packages[i].Location < packages[j].Location
+	})
+
+	return packages
+}
+
+// isPackageDir tells whether the directory is a package installed in a
+// node_modules directory, possibly under a scope
+func isPackageDir(dir string) bool {
+	parent := path.Dir(dir)
+	if strings.HasPrefix(path.Base(parent), "@") {
+		parent = path.Dir(parent)
+	}
+	return path.Base(parent) == nodeModules && !strings.HasPrefix(path.Base(dir), ".")
+}
+
+// readLockfile adds the packages of the lockfile, by the directory they are
+// installed in relative to root
+func readLockfile(filePath, root string, content io.Reader, packages map[string]metadata.NpmPackage, collector *warnings.Collector) error {
+	var l lockfile
+	if err := json.NewDecoder(content).Decode(&l); err != nil {
+		collector.Add(warnings.InvalidPackageEntry, filePath, fmt.Sprintf("skipping lockfile: %s", err))
+		return nil
+	}
+
+	if len(l.Packages) != 0 {
+		for key, p := range l.Packages {
+			if p.Link || !strings.HasPrefix(key, nodeModules+"/") && !strings.Contains(key, "/"+nodeModules+"/") {
+				continue
+			}
+			location := path.Join(root, key)
+			packages[location] = metadata.NpmPackage{
+				Package:   p.Name,
+				Version:   p.Version,
+				License:   license(p.License, nil),
+				Resolved:  p.Resolved,
+				Integrity: p.Integrity,
+				Location:  location,
+			}
+		}
+		return nil
+	}
+
+	addLockedDependencies(root, l.Dependencies, packages)
+	return nil
+}
+
+func addLockedDependencies(dir string, dependencies map[string]lockedDependency, packages map[string]metadata.NpmPackage) {
+	for name, d := range dependencies {
+		location := path.Join(dir, nodeModules, name)
+		packages[location] = metadata.NpmPackage{
+			Package:   name,
+			Version:   d.Version,
+			Resolved:  d.Resolved,
+			Integrity: d.Integrity,
+			Location:  location,
+		}
+		addLockedDependencies(location, d.Dependencies, packages)
+	}
+}
+
+// license reads the license of a package, either an SPDX expression, an
+// object with a type, or the deprecated list of licenses
+func license(raw json.RawMessage, licenses licenseList) string {
+	if len(raw) != 0 {
+		var expression string
+		if err := json.Unmarshal(raw, &expression); err == nil {
+			return expression
+		}
+
+		var object licenseObject
+		if err := json.Unmarshal(raw, &object); err == nil {
+			return object.Type
+		}
+	}
+
+	var types []string
+	for _, l := range licenses {
+		if l.Type != "" {
+			types = append(types, l.Type)
+		}
+	}
+	if len(types) > 1 {
+		return "(" + strings.Join(types, " OR ") + ")"
+	}
+	return strings.Join(types, "")
+}
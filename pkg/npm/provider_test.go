@@ -0,0 +1,217 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package npm_test
+
+import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/npm"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+var _ = Describe("npm", func() {
+	Describe("Provider", func() {
+		var collector *warnings.Collector
+
+		BeforeEach(func() {
+			collector = warnings.NewCollector()
+		})
+
+		getPackages := func(files map[string]string) []metadata.NpmPackage {
+			md, err := Provider(test_utils.FakeImage{Files: files}, common.RunParams{Warnings: collector.ForProvider("npm")}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(HaveLen(1))
+			dependency := md.Dependencies[0]
+			Expect(dependency.Type).To(Equal(metadata.NpmPackageListSourceType))
+			Expect(dependency.Source.Type).To(Equal("inline"))
+			Expect(dependency.Source.Version["sha256"]).ToNot(BeEmpty())
+
+			return dependency.Source.Metadata.(metadata.NpmPackageListSourceMetadata).Packages
+		}
+
+		It("adds the packages installed in node_modules directories, sorted by name", func() {
+			packages := getPackages(map[string]string{
+				"/usr/local/lib/node_modules/npm/package.json":                     `{"name": "npm", "version": "6.14.8", "license": "Artistic-2.0"}`,
+				"/usr/local/lib/node_modules/npm/node_modules/abbrev/package.json": `{"name": "abbrev", "version": "1.1.1", "license": {"type": "ISC"}, "_resolved": "https://registry.npmjs.org/abbrev/-/abbrev-1.1.1.tgz", "_integrity": "sha512-nne9"}`,
+				"/usr/local/lib/node_modules/npm/lib/package.json":                 `{"name": "not-a-package"}`,
+				"/app/node_modules/@types/node/package.json":                       `{"name": "@types/node", "version": "14.11.2", "licenses": [{"type": "MIT"}, {"type": "Apache-2.0"}]}`,
+				"/app/package.json": `{"name": "app", "version": "1.0.0"}`,
+			})
+			Expect(collector.Warnings()).To(BeEmpty())
+
+			Expect(packages).To(Equal([]metadata.NpmPackage{
+				{
+					Package:  "@types/node",
+					Version:  "14.11.2",
+					License:  "(MIT OR Apache-2.0)",
+					Location: "/app/node_modules/@types/node",
+				},
+				{
+					Package:   "abbrev",
+					Version:   "1.1.1",
+					License:   "ISC",
+					Resolved:  "https://registry.npmjs.org/abbrev/-/abbrev-1.1.1.tgz",
+					Integrity: "sha512-nne9",
+					Location:  "/usr/local/lib/node_modules/npm/node_modules/abbrev",
+				},
+				{
+					Package:  "npm",
+					Version:  "6.14.8",
+					License:  "Artistic-2.0",
+					Location: "/usr/local/lib/node_modules/npm",
+				},
+			}))
+		})
+
+		It("reads the licenses lists of strings or of a single license", func() {
+			packages := getPackages(map[string]string{
+				"/app/node_modules/a/package.json": `{"name": "a", "licenses": ["MIT", "Apache-2.0"]}`,
+				"/app/node_modules/b/package.json": `{"name": "b", "licenses": "BSD-3-Clause"}`,
+				"/app/node_modules/c/package.json": `{"name": "c", "licenses": {"type": "ISC"}}`,
+				"/app/node_modules/d/package.json": `{"name": "d", "licenses": 1}`,
+			})
+			Expect(collector.Warnings()).To(BeEmpty())
+
+			var licenses []string
+			for _, pkg := range packages {
+				licenses = append(licenses, pkg.License)
+			}
+			Expect(licenses).To(Equal([]string{"(MIT OR Apache-2.0)", "BSD-3-Clause", "ISC", ""}))
+		})
+
+		It("prefers the package-lock.json of the application", func() {
+			packages := getPackages(map[string]string{
+				"/app/package-lock.json": `{
+					"lockfileVersion": 2,
+					"packages": {
+						"": {"name": "app", "version": "1.0.0"},
+						"node_modules/express": {"version": "4.17.1", "resolved": "https://registry.npmjs.org/express/-/express-4.17.1.tgz", "integrity": "sha512-mHJ9", "license": "MIT"},
+						"node_modules/express/node_modules/debug": {"version": "2.6.9", "resolved": "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz", "integrity": "sha512-bC7E"},
+						"node_modules/mocha": {"version": "8.1.3", "dev": true}
+					}
+				}`,
+				"/app/node_modules/express/package.json":                    `{"name": "express", "version": "4.17.0"}`,
+				"/app/node_modules/express/node_modules/debug/package.json": `{"name": "debug", "version": "2.6.9", "license": "MIT"}`,
+			})
+
+			Expect(packages).To(Equal([]metadata.NpmPackage{
+				{
+					Package:   "debug",
+					Version:   "2.6.9",
+					License:   "MIT",
+					Resolved:  "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz",
+					Integrity: "sha512-bC7E",
+					Location:  "/app/node_modules/express/node_modules/debug",
+				},
+				{
+					Package:   "express",
+					Version:   "4.17.1",
+					License:   "MIT",
+					Resolved:  "https://registry.npmjs.org/express/-/express-4.17.1.tgz",
+					Integrity: "sha512-mHJ9",
+					Location:  "/app/node_modules/express",
+				},
+			}))
+		})
+
+		It("reads the nested dependencies of a version 1 lockfile", func() {
+			packages := getPackages(map[string]string{
+				"/app/package-lock.json": `{
+					"lockfileVersion": 1,
+					"dependencies": {
+						"express": {
+							"version": "4.17.1",
+							"resolved": "https://registry.npmjs.org/express/-/express-4.17.1.tgz",
+							"integrity": "sha512-mHJ9",
+							"dependencies": {
+								"debug": {"version": "2.6.9", "resolved": "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz", "integrity": "sha512-bC7E"}
+							}
+						}
+					}
+				}`,
+				"/app/node_modules/express/package.json":                    `{"name": "express", "license": "MIT"}`,
+				"/app/node_modules/express/node_modules/debug/package.json": `{"name": "debug"}`,
+			})
+
+			Expect(packages).To(ConsistOf(
+				metadata.NpmPackage{
+					Package:   "debug",
+					Version:   "2.6.9",
+					Resolved:  "https://registry.npmjs.org/debug/-/debug-2.6.9.tgz",
+					Integrity: "sha512-bC7E",
+					Location:  "/app/node_modules/express/node_modules/debug",
+				},
+				metadata.NpmPackage{
+					Package:   "express",
+					Version:   "4.17.1",
+					License:   "MIT",
+					Resolved:  "https://registry.npmjs.org/express/-/express-4.17.1.tgz",
+					Integrity: "sha512-mHJ9",
+					Location:  "/app/node_modules/express",
+				},
+			))
+		})
+
+		It("reads the hidden lockfile of a node_modules directory", func() {
+			packages := getPackages(map[string]string{
+				"/app/node_modules/.package-lock.json": `{
+					"lockfileVersion": 2,
+					"packages": {
+						"node_modules/ms": {"version": "2.1.2", "resolved": "https://registry.npmjs.org/ms/-/ms-2.1.2.tgz", "integrity": "sha512-sGkP"}
+					}
+				}`,
+				"/app/node_modules/ms/package.json": `{"name": "ms", "version": "2.1.2", "license": "MIT"}`,
+			})
+
+			Expect(packages).To(Equal([]metadata.NpmPackage{{
+				Package:   "ms",
+				Version:   "2.1.2",
+				License:   "MIT",
+				Resolved:  "https://registry.npmjs.org/ms/-/ms-2.1.2.tgz",
+				Integrity: "sha512-sGkP",
+				Location:  "/app/node_modules/ms",
+			}}))
+		})
+
+		It("warns about invalid package.json files", func() {
+			packages := getPackages(map[string]string{
+				"/app/node_modules/broken/package.json":   `{"name": `,
+				"/app/node_modules/nameless/package.json": `{"version": "1.0.0"}`,
+				"/app/node_modules/ms/package.json":       `{"name": "ms", "version": "2.1.2"}`,
+			})
+			Expect(packages).To(HaveLen(1))
+
+			Expect(collector.Warnings()).To(ConsistOf(
+				warnings.Warning{
+					Code:     warnings.InvalidPackageEntry,
+					Provider: "npm",
+					Subject:  "/app/node_modules/broken/package.json",
+					Message:  "skipping package: unexpected EOF",
+				},
+				warnings.Warning{
+					Code:     warnings.InvalidPackageEntry,
+					Provider: "npm",
+					Subject:  "/app/node_modules/nameless/package.json",
+					Message:  "skipping package: missing name",
+				},
+			))
+		})
+
+		It("does not add a dependency when there is no npm package", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/app/package.json": `{"name": "app", "version": "1.0.0"}`,
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(BeEmpty())
+		})
+	})
+})
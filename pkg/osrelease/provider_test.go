@@ -4,6 +4,7 @@
 package osrelease_test
 
 import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
 	"fmt"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	. "github.com/onsi/ginkgo"
@@ -22,6 +23,10 @@ func (m MockImage) GetConfig() (*v1.ConfigFile, error) {
 	panic("implement me")
 }
 
+func (m MockImage) GetManifest() (*v1.Manifest, error) {
+	panic("implement me")
+}
+
 func (m MockImage) Cleanup() {
 	panic("implement me")
 }
@@ -38,6 +43,22 @@ func (m MockImage) GetDirContents(string) ([]string, error) {
 	panic("implement me")
 }
 
+func (m MockImage) WalkFiles(image.WalkFilesFunc) error {
+	panic("implement me")
+}
+
+func (m MockImage) Layers() ([]image.Layer, error) {
+	panic("implement me")
+}
+
+func (m MockImage) FileHistory(string) ([]int, error) {
+	panic("implement me")
+}
+
+func (m MockImage) GetLayerFileContent(string, int) (string, error) {
+	panic("implement me")
+}
+
 func (m MockImage) AbsolutePath(string) (string, error) {
 	panic("implement me")
 }
@@ -46,6 +67,14 @@ func (m MockImage) ExportWithMetadata(metadata.Metadata, string, string) error {
 	panic("implement me")
 }
 
+func (m MockImage) PushWithMetadata(metadata.Metadata, string) error {
+	panic("implement me")
+}
+
+func (m MockImage) WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
+}
+
 var _ = Describe("OsRelease", func() {
 	Describe("BuildOSMetadata", func() {
 		Context("when the image has os-release", func() {
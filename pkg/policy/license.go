@@ -0,0 +1,94 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy
+
+import (
+	"regexp"
+	"strings"
+)
+
+var licenseTokens = regexp.MustCompile(`\(|\)|[^\s()]+`)
+
+// licenseExpression is a license expression, e.g. GPL-2.0-or-later OR MIT,
+// read as alternatives of licenses which all apply
+type licenseExpression struct {
+	tokens []string
+	next   int
+}
+
+// satisfies tells whether the license expression can be complied with using
+// only the licenses accepted by ok: any alternative of an OR, and every
+// license of an AND. Licenses with an exception are read as the license.
+// Expressions which cannot be parsed are read as a single license.
+func satisfies(license string, ok func(string) bool) bool {
+	e := &licenseExpression{tokens: licenseTokens.FindAllString(license, -1)}
+	result, parsed := e.or(ok)
+	if !parsed || e.next != len(e.tokens) {
+		return ok(strings.TrimSpace(license))
+	}
+	return result
+}
+
+func (e *licenseExpression) or(ok func(string) bool) (bool, bool) {
+	result, parsed := e.and(ok)
+	for parsed && e.accept("or") {
+		alternative, p := e.and(ok)
+		result, parsed = result || alternative, p
+	}
+	return result, parsed
+}
+
+func (e *licenseExpression) and(ok func(string) bool) (bool, bool) {
+	result, parsed := e.license(ok)
+	for parsed && e.accept("and") {
+		other, p := e.license(ok)
+		result, parsed = result && other, p
+	}
+	return result, parsed
+}
+
+func (e *licenseExpression) license(ok func(string) bool) (bool, bool) {
+	if e.next == len(e.tokens) {
+		return false, false
+	}
+
+	if e.accept("(") {
+		result, parsed := e.or(ok)
+		if !parsed || !e.accept(")") {
+			return false, false
+		}
+		return result, true
+	}
+
+	token := e.tokens[e.next]
+	if token == ")" || isOperator(token) {
+		return false, false
+	}
+	e.next++
+
+	if e.accept("with") {
+		if e.next == len(e.tokens) {
+			return false, false
+		}
+		e.next++
+	}
+	return ok(token), true
+}
+
+func (e *licenseExpression) accept(token string) bool {
+	if e.next < len(e.tokens) && strings.EqualFold(e.tokens[e.next], token) {
+		e.next++
+		return true
+	}
+	return false
+}
+
+func isOperator(token string) bool {
+	for _, operator := range []string{"and", "or", "with"} {
+		if strings.EqualFold(token, operator) {
+			return true
+		}
+	}
+	return false
+}
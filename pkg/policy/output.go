@@ -0,0 +1,25 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteText writes the violations one per line, after the rule which they
+// break
+func WriteText(w io.Writer, r Report) error {
+	if len(r.Violations) == 0 {
+		_, err := fmt.Fprintln(w, "no policy violations found")
+		return err
+	}
+
+	for _, violation := range r.Violations {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", violation.Rule, violation.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
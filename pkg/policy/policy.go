@@ -0,0 +1,189 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/purl"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Policy is the set of rules the dependencies of an image have to follow
+type Policy struct {
+	DeniedPackages []PackageRule `yaml:"denied_packages"`
+	Licenses       LicenseRule   `yaml:"licenses"`
+	RequiredGit    *GitRule      `yaml:"required_git"`
+	RequiredBase   *BaseRule     `yaml:"required_base"`
+	// MaxAge is the maximum time since the image was created, as a go
+	// duration or a number of days, e.g. 720h or 30d
+	MaxAge string `yaml:"max_age"`
+
+	maxAge time.Duration
+}
+
+// PackageRule denies the versions of a package of an ecosystem, named by the
+// type of its package url: deb, rpm, apk, pypi, npm, maven or golang
+type PackageRule struct {
+	Ecosystem string `yaml:"ecosystem"`
+	// Name may use * wildcards. Maven packages are named group:artifact, and
+	// the go version of go binaries is the stdlib package.
+	Name string `yaml:"name"`
+	// Versions are ranges of comma separated comparisons, e.g. ">= 1.0, <
+	// 1.2", any of which denies the package. All versions are denied when
+	// empty.
+	Versions []string `yaml:"versions"`
+	Reason   string   `yaml:"reason"`
+
+	name     *regexp.Regexp
+	versions []versionRange
+}
+
+// LicenseRule lists the licenses the packages may or may not declare, with *
+// wildcards
+type LicenseRule struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// GitRule requires a git dependency, from a repository matching the url with
+// * wildcards, or any repository when empty
+type GitRule struct {
+	URL string `yaml:"url"`
+
+	url *regexp.Regexp
+}
+
+// BaseRule requires the distribution of the image, by the id and version of
+// its os-release
+type BaseRule struct {
+	ID        string   `yaml:"id"`
+	VersionID []string `yaml:"version_id"`
+
+	versionID []versionRange
+}
+
+// packageTypes are the dependency types of the packages of each ecosystem
+var packageTypes = map[string]string{
+	purl.DebType:    metadata.DebianPackageListSourceType,
+	purl.RPMType:    metadata.RPMPackageListSourceType,
+	purl.ApkType:    metadata.ApkPackageListSourceType,
+	purl.PyPIType:   metadata.PythonPackageListSourceType,
+	purl.NpmType:    metadata.NpmPackageListSourceType,
+	purl.MavenType:  metadata.MavenPackageListSourceType,
+	purl.GolangType: metadata.GoBinariesSourceType,
+}
+
+// ReadPolicyFile reads a YAML policy, rejecting unknown fields and invalid
+// rules
+func ReadPolicyFile(path string) (Policy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("could not read policy file: %w", err)
+	}
+
+	var p Policy
+	if err := yaml.UnmarshalStrict(content, &p); err != nil {
+		return Policy{}, fmt.Errorf("could not parse policy file %s: %w", path, err)
+	}
+
+	if err := p.compile(); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+
+	return p, nil
+}
+
+func (p *Policy) compile() error {
+	for i := range p.DeniedPackages {
+		rule := &p.DeniedPackages[i]
+		if _, ok := packageTypes[rule.Ecosystem]; !ok {
+			return fmt.Errorf("denied_packages[%d]: unknown ecosystem %q", i, rule.Ecosystem)
+		}
+		if rule.Name == "" {
+			return fmt.Errorf("denied_packages[%d]: missing name", i)
+		}
+		// python package names are case insensitive
+		rule.name = glob(rule.Name, rule.Ecosystem == purl.PyPIType)
+
+		versions, err := parseVersionRanges(rule.Versions)
+		if err != nil {
+			return fmt.Errorf("denied_packages[%d]: %w", i, err)
+		}
+		rule.versions = versions
+	}
+
+	for _, license := range p.Licenses.Allow {
+		p.Licenses.allow = append(p.Licenses.allow, glob(license, true))
+	}
+	for _, license := range p.Licenses.Deny {
+		p.Licenses.deny = append(p.Licenses.deny, glob(license, true))
+	}
+
+	if p.RequiredGit != nil {
+		pattern := p.RequiredGit.URL
+		if pattern == "" {
+			pattern = "*"
+		}
+		p.RequiredGit.url = glob(pattern, false)
+	}
+
+	if p.RequiredBase != nil {
+		versions, err := parseVersionRanges(p.RequiredBase.VersionID)
+		if err != nil {
+			return fmt.Errorf("required_base: %w", err)
+		}
+		p.RequiredBase.versionID = versions
+	}
+
+	if p.MaxAge != "" {
+		maxAge, err := parseAge(p.MaxAge)
+		if err != nil {
+			return fmt.Errorf("max_age: %w", err)
+		}
+		p.maxAge = maxAge
+	}
+
+	return nil
+}
+
+// parseAge reads a go duration, or a number of days
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// glob matches the whole string against a pattern, where * matches any
+// characters
+func glob(pattern string, ignoreCase bool) *regexp.Regexp {
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	if ignoreCase {
+		expression = "(?i)" + expression
+	}
+	return regexp.MustCompile(expression)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+)
+
+// Report lists the violations of a policy by an image
+type Report struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Violation is a rule of the policy the image does not follow
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Verify evaluates the metadata of an image against the rules of the policy.
+// created is the creation time of the image, zero when unknown, and now the
+// time its age is measured at.
+func Verify(p Policy, md metadata.Metadata, created, now time.Time) (Report, error) {
+	packages, err := scan.Packages(md)
+	if err != nil {
+		return Report{}, fmt.Errorf("could not read the packages of the metadata: %w", err)
+	}
+
+	violations := []Violation{}
+	add := func(rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, rule := range p.DeniedPackages {
+		for _, pkg := range packages {
+			if pkg.Type != packageTypes[rule.Ecosystem] || !rule.name.MatchString(pkg.Name) {
+				continue
+			}
+			if !matchesAny(rule.versions, pkg.Version, scan.Comparator(pkg.Ecosystem)) {
+				continue
+			}
+
+			message := fmt.Sprintf("%s is denied", describe(pkg))
+			if rule.Reason != "" {
+				message += ": " + rule.Reason
+			}
+			add(fmt.Sprintf("denied_packages[%d]", i), "%s", message)
+		}
+	}
+
+	for _, pkg := range packages {
+		for _, license := range pkg.Licenses {
+			if len(p.Licenses.allow) != 0 && !satisfies(license, matcher(p.Licenses.allow, true)) {
+				add("licenses.allow", "%s declares license %q, which is not allowed", describe(pkg), license)
+			}
+			if len(p.Licenses.deny) != 0 && !satisfies(license, matcher(p.Licenses.deny, false)) {
+				add("licenses.deny", "%s declares license %q, which is denied", describe(pkg), license)
+			}
+		}
+	}
+
+	if p.RequiredGit != nil && !hasGitDependency(md, p.RequiredGit.url) {
+		if p.RequiredGit.URL == "" {
+			add("required_git", "no git dependency")
+		} else {
+			add("required_git", "no git dependency from a repository matching %q", p.RequiredGit.URL)
+		}
+	}
+
+	if rule := p.RequiredBase; rule != nil {
+		if rule.ID != "" && md.Base["id"] != rule.ID {
+			add("required_base.id", "base id is %q, expected %q", md.Base["id"], rule.ID)
+		}
+
+		versionID := md.Base["version_id"]
+		if len(rule.versionID) != 0 {
+			if versionID == "" || versionID == "unknown" {
+				add("required_base.version_id", "base version_id is unknown, expected %s", strings.Join(rule.VersionID, " or "))
+			} else if !matchesAny(rule.versionID, versionID, scan.CompareRpm) {
+				add("required_base.version_id", "base version_id is %q, expected %s", versionID, strings.Join(rule.VersionID, " or "))
+			}
+		}
+	}
+
+	if p.maxAge != 0 {
+		if created.IsZero() {
+			add("max_age", "the creation time of the image is unknown")
+		} else if now.Sub(created) > p.maxAge {
+			add("max_age", "the image was created at %s, more than %s ago", created.UTC().Format(time.RFC3339), p.MaxAge)
+		}
+	}
+
+	return Report{Violations: violations}, nil
+}
+
+// matcher accepts the licenses matching one of the patterns, or when match
+// is false the ones matching none of them
+func matcher(patterns []*regexp.Regexp, match bool) func(string) bool {
+	return func(license string) bool {
+		for _, pattern := range patterns {
+			if pattern.MatchString(license) {
+				return match
+			}
+		}
+		return !match
+	}
+}
+
+func hasGitDependency(md metadata.Metadata, url *regexp.Regexp) bool {
+	for _, dependency := range md.AllDependencies() {
+		if dependency.Source.Type != metadata.GitSourceType {
+			continue
+		}
+
+		var sourceMetadata metadata.GitSourceMetadata
+		if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+			continue
+		}
+		if url.MatchString(sourceMetadata.URL) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names a package by the type of its package url, its name and its
+// version, e.g. deb package openssl 1.1.1n-0+deb11u1
+func describe(pkg scan.Package) string {
+	for ecosystem, packageType := range packageTypes {
+		if packageType == pkg.Type {
+			return fmt.Sprintf("%s package %s %s", ecosystem, pkg.Name, pkg.Version)
+		}
+	}
+	return fmt.Sprintf("package %s %s", pkg.Name, pkg.Version)
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/policy"
+)
+
+var _ = Describe("Verify", func() {
+	var (
+		dir string
+		md  metadata.Metadata
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "deplab-policy-")
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+		md = metadata.Metadata{
+			Base: metadata.Base{"id": "debian", "version_id": "10"},
+			Dependencies: []metadata.Dependency{
+				{
+					Type: metadata.DebianPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.DebianPackageListSourceMetadata{Packages: []metadata.DpkgPackage{
+							{Package: "bash", Version: "5.0-4", Licenses: []string{"GPL-3+"}},
+							{Package: "openssl", Version: "1.1.1d-0+deb10u7", Licenses: []string{"OpenSSL"}},
+						}},
+					},
+				},
+				{
+					Type: metadata.NpmPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.NpmPackageListSourceMetadata{Packages: []metadata.NpmPackage{
+							{Package: "left-pad", Version: "1.3.0", License: "WTFPL OR MIT"},
+							{Package: "lodash", Version: "4.17.15", License: "(MIT AND CC0-1.0)"},
+						}},
+					},
+				},
+				{
+					Type: "package",
+					Source: metadata.Source{
+						Type:     metadata.GitSourceType,
+						Version:  map[string]interface{}{"commit": "abc123"},
+						Metadata: metadata.GitSourceMetadata{URL: "https://github.com/example/app.git"},
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	readPolicy := func(content string) policy.Policy {
+		path := filepath.Join(dir, "policy.yml")
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+
+		p, err := policy.ReadPolicyFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		return p
+	}
+
+	It("reports the denied packages and versions, comparing versions with the rules of their ecosystem", func() {
+		p := readPolicy(`
+denied_packages:
+- ecosystem: deb
+  name: openssl
+  versions: ["< 1.1.1d-0+deb10u8"]
+  reason: CVE-2022-0778
+- ecosystem: deb
+  name: openssl
+  versions: [">= 1.1.1n"]
+- ecosystem: npm
+  name: left-*
+`)
+
+		report, err := policy.Verify(p, md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "denied_packages[0]", Message: "deb package openssl 1.1.1d-0+deb10u7 is denied: CVE-2022-0778"},
+			{Rule: "denied_packages[2]", Message: "npm package left-pad 1.3.0 is denied"},
+		}))
+	})
+
+	It("reports the licenses which are not allowed, accepting any alternative of a license expression", func() {
+		p := readPolicy(`
+licenses:
+  allow: [MIT, GPL-*, OpenSSL]
+`)
+
+		report, err := policy.Verify(p, md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "licenses.allow", Message: `npm package lodash 4.17.15 declares license "(MIT AND CC0-1.0)", which is not allowed`},
+		}))
+	})
+
+	It("reports the denied licenses", func() {
+		p := readPolicy(`
+licenses:
+  deny: [gpl-3*, WTFPL]
+`)
+
+		report, err := policy.Verify(p, md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "licenses.deny", Message: `deb package bash 5.0-4 declares license "GPL-3+", which is denied`},
+		}))
+	})
+
+	It("reports a missing git dependency", func() {
+		report, err := policy.Verify(readPolicy(`
+required_git:
+  url: https://github.com/example/*
+`), md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Violations).To(BeEmpty())
+
+		report, err = policy.Verify(readPolicy(`
+required_git:
+  url: https://github.com/other/*
+`), md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "required_git", Message: `no git dependency from a repository matching "https://github.com/other/*"`},
+		}))
+	})
+
+	It("reports a base distribution other than the required one", func() {
+		p := readPolicy(`
+required_base:
+  id: ubuntu
+  version_id: [">= 11"]
+`)
+
+		report, err := policy.Verify(p, md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "required_base.id", Message: `base id is "debian", expected "ubuntu"`},
+			{Rule: "required_base.version_id", Message: `base version_id is "10", expected >= 11`},
+		}))
+	})
+
+	It("reports an image older than the maximum age", func() {
+		p := readPolicy(`max_age: 30d`)
+
+		report, err := policy.Verify(p, md, now.Add(-29*24*time.Hour), now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Violations).To(BeEmpty())
+
+		report, err = policy.Verify(p, md, now.Add(-31*24*time.Hour), now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "max_age", Message: "the image was created at 2022-01-29T00:00:00Z, more than 30d ago"},
+		}))
+
+		report, err = policy.Verify(p, md, time.Time{}, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "max_age", Message: "the creation time of the image is unknown"},
+		}))
+	})
+
+	It("prints the violations after their rule", func() {
+		buffer := &bytes.Buffer{}
+		Expect(policy.WriteText(buffer, policy.Report{Violations: []policy.Violation{
+			{Rule: "required_git", Message: "no git dependency"},
+		}})).To(Succeed())
+		Expect(buffer.String()).To(Equal("required_git: no git dependency\n"))
+
+		buffer.Reset()
+		Expect(policy.WriteText(buffer, policy.Report{})).To(Succeed())
+		Expect(buffer.String()).To(Equal("no policy violations found\n"))
+	})
+
+	Context("with an invalid policy", func() {
+		It("rejects unknown fields", func() {
+			path := filepath.Join(dir, "policy.yml")
+			Expect(ioutil.WriteFile(path, []byte("denied_package: []\n"), 0644)).To(Succeed())
+
+			_, err := policy.ReadPolicyFile(path)
+			Expect(err).To(MatchError(ContainSubstring("field denied_package not found")))
+		})
+
+		It("rejects unknown ecosystems", func() {
+			path := filepath.Join(dir, "policy.yml")
+			Expect(ioutil.WriteFile(path, []byte("denied_packages: [{ecosystem: debian, name: bash}]\n"), 0644)).To(Succeed())
+
+			_, err := policy.ReadPolicyFile(path)
+			Expect(err).To(MatchError(ContainSubstring(`denied_packages[0]: unknown ecosystem "debian"`)))
+		})
+
+		It("rejects invalid ages", func() {
+			path := filepath.Join(dir, "policy.yml")
+			Expect(ioutil.WriteFile(path, []byte("max_age: a month\n"), 0644)).To(Succeed())
+
+			_, err := policy.ReadPolicyFile(path)
+			Expect(err).To(MatchError(ContainSubstring("max_age")))
+		})
+	})
+})
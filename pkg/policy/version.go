@@ -0,0 +1,92 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+)
+
+// versionRange holds the versions satisfying all its comparisons
+type versionRange []comparison
+
+type comparison struct {
+	operator string
+	version  string
+}
+
+// operators are the comparison operators, the longest first so that <= is
+// not read as <
+var operators = []string{"==", "!=", "<=", ">=", "<", ">", "="}
+
+func parseVersionRanges(ranges []string) ([]versionRange, error) {
+	var parsed []versionRange
+	for _, r := range ranges {
+		var vr versionRange
+		for _, c := range strings.Split(r, ",") {
+			c = strings.TrimSpace(c)
+
+			operator := "="
+			for _, op := range operators {
+				if strings.HasPrefix(c, op) {
+					operator, c = op, strings.TrimSpace(strings.TrimPrefix(c, op))
+					break
+				}
+			}
+			if operator == "==" {
+				operator = "="
+			}
+
+			if c == "" {
+				return nil, fmt.Errorf("invalid version range %q", r)
+			}
+			vr = append(vr, comparison{operator: operator, version: c})
+		}
+		parsed = append(parsed, vr)
+	}
+	return parsed, nil
+}
+
+// matchesAny tells whether the version is in one of the ranges, or whether
+// there are no ranges
+func matchesAny(ranges []versionRange, version string, compare scan.CompareFunc) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	for _, r := range ranges {
+		if r.matches(version, compare) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r versionRange) matches(version string, compare scan.CompareFunc) bool {
+	for _, c := range r {
+		result := compare(version, c.version)
+
+		var ok bool
+		switch c.operator {
+		case "!=":
+			ok = result != 0
+		case "<":
+			ok = result < 0
+		case "<=":
+			ok = result <= 0
+		case ">":
+			ok = result > 0
+		case ">=":
+			ok = result >= 0
+		default:
+			ok = result == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
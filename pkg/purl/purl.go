@@ -0,0 +1,112 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package purl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DebType     = "deb"
+	RPMType     = "rpm"
+	ApkType     = "apk"
+	GolangType  = "golang"
+	PyPIType    = "pypi"
+	NpmType     = "npm"
+	MavenType   = "maven"
+	GenericType = "generic"
+)
+
+// Qualifiers are the key/value pairs appended to a package url after the "?"
+type Qualifiers map[string]string
+
+// New builds a package url as described in https://github.com/package-url/purl-spec
+func New(purlType, namespace, name, version string, qualifiers Qualifiers) string {
+	var b strings.Builder
+
+	b.WriteString("pkg:")
+	b.WriteString(purlType)
+	b.WriteString("/")
+
+	if namespace != "" {
+		for _, segment := range strings.Split(namespace, "/") {
+			b.WriteString(escape(segment))
+			b.WriteString("/")
+		}
+	}
+
+	b.WriteString(escape(name))
+
+	if version != "" {
+		b.WriteString("@")
+		b.WriteString(escape(version))
+	}
+
+	var keys []string
+	for k, v := range qualifiers {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i == 0 {
+			b.WriteString("?")
+		} else {
+			b.WriteString("&")
+		}
+		b.WriteString(strings.ToLower(k))
+		b.WriteString("=")
+		b.WriteString(escape(qualifiers[k]))
+	}
+
+	return b.String()
+}
+
+// Golang builds the package url of a go module, whose path is split into the
+// namespace and the name
+func Golang(modulePath, version string) string {
+	namespace, name := "", modulePath
+	if i := strings.LastIndex(modulePath, "/"); i != -1 {
+		namespace, name = modulePath[:i], modulePath[i+1:]
+	}
+	return New(GolangType, namespace, name, version, nil)
+}
+
+// PyPI builds the package url of a python package, whose name is normalized
+// to lower case with dashes
+func PyPI(name, version string) string {
+	return New(PyPIType, "", strings.ReplaceAll(strings.ToLower(name), "_", "-"), version, nil)
+}
+
+// Npm builds the package url of an npm package, whose scope is the namespace
+func Npm(name, version string) string {
+	namespace := ""
+	if i := strings.Index(name, "/"); strings.HasPrefix(name, "@") && i != -1 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	return New(NpmType, namespace, name, version, nil)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
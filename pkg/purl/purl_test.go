@@ -0,0 +1,62 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package purl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/purl"
+)
+
+var _ = Describe("Purl", func() {
+	DescribeTable("New", func(purlType, namespace, name, version string, qualifiers Qualifiers, expected string) {
+		Expect(New(purlType, namespace, name, version, qualifiers)).To(Equal(expected))
+	},
+		Entry("a debian package", DebType, "ubuntu", "libgcc1", "1:8.3.0-6ubuntu1~18.04.1", Qualifiers{"arch": "amd64"},
+			"pkg:deb/ubuntu/libgcc1@1%3A8.3.0-6ubuntu1~18.04.1?arch=amd64"),
+		Entry("a package without namespace", GenericType, "", "foo", "1.0", nil,
+			"pkg:generic/foo@1.0"),
+		Entry("a package without version", RPMType, "photon", "bash", "", nil,
+			"pkg:rpm/photon/bash"),
+		Entry("special characters in the name", DebType, "debian", "libstdc++6", "8.3.0", nil,
+			"pkg:deb/debian/libstdc%2B%2B6@8.3.0"),
+		Entry("sorted qualifiers, skipping empty values", RPMType, "fedora", "curl", "7.50.3-1.fc25", Qualifiers{"distro": "fedora-25", "arch": "i386", "epoch": ""},
+			"pkg:rpm/fedora/curl@7.50.3-1.fc25?arch=i386&distro=fedora-25"),
+	)
+
+	DescribeTable("Golang", func(modulePath, version, expected string) {
+		Expect(Golang(modulePath, version)).To(Equal(expected))
+	},
+		Entry("a module hosted on github", "github.com/spf13/cobra", "v0.0.5",
+			"pkg:golang/github.com/spf13/cobra@v0.0.5"),
+		Entry("a module without namespace", "example", "v1.0.0",
+			"pkg:golang/example@v1.0.0"),
+		Entry("a major version suffix", "gopkg.in/yaml.v2", "v2.2.4",
+			"pkg:golang/gopkg.in/yaml.v2@v2.2.4"),
+	)
+
+	DescribeTable("PyPI", func(name, version, expected string) {
+		Expect(PyPI(name, version)).To(Equal(expected))
+	},
+		Entry("a lower case name", "requests", "2.24.0",
+			"pkg:pypi/requests@2.24.0"),
+		Entry("a name to normalize", "Django_Rest", "3.11.1",
+			"pkg:pypi/django-rest@3.11.1"),
+	)
+
+	DescribeTable("Npm", func(name, version, expected string) {
+		Expect(Npm(name, version)).To(Equal(expected))
+	},
+		Entry("an unscoped package", "express", "4.17.1",
+			"pkg:npm/express@4.17.1"),
+		Entry("a scoped package", "@babel/core", "7.11.6",
+			"pkg:npm/%40babel/core@7.11.6"),
+	)
+
+	It("uses the group id as the namespace of maven packages", func() {
+		Expect(New(MavenType, "org.springframework", "spring-core", "5.2.9.RELEASE", nil)).
+			To(Equal("pkg:maven/org.springframework/spring-core@5.2.9.RELEASE"))
+	})
+})
@@ -0,0 +1,264 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package python
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const licenseClassifierPrefix = "License :: "
+
+// distribution is an installed python distribution, described by the files of
+// its .dist-info or .egg-info directory
+type distribution struct {
+	location  string
+	metadata  string
+	installer string
+}
+
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not find python packages: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the distributions of the image in a walk
+// shared with other providers, and the provider adding their packages once it
+// is done
+func Scan(image.Image, common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	distributions := map[string]*distribution{}
+
+	visitor := image.FileVisitor{
+		Match: func(filePath string, _ os.FileInfo) bool {
+			_, _, ok := distributionFile(filePath)
+			return ok
+		},
+		Visit: func(filePath string, _ os.FileInfo, content io.Reader) error {
+			infoPath, fileName, _ := distributionFile(filePath)
+
+			b, err := ioutil.ReadAll(content)
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", filePath, err)
+			}
+
+			d, ok := distributions[infoPath]
+			if !ok {
+				d = &distribution{location: path.Dir(infoPath)}
+				distributions[infoPath] = d
+			}
+
+			if fileName == "INSTALLER" {
+				d.installer = strings.TrimSpace(string(b))
+			} else {
+				d.metadata = string(b)
+			}
+			return nil
+		},
+	}
+
+	provider := func(_ image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		packages := getPythonPackages(distributions, params.Warnings)
+		if len(packages) == 0 {
+			return md, nil
+		}
+
+		sourceMetadata := metadata.PythonPackageListSourceMetadata{
+			Packages: packages,
+		}
+
+		version, err := common.Digest(sourceMetadata)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not get digest for source metadata: %w", err)
+		}
+
+		md.Dependencies = append(md.Dependencies, metadata.Dependency{
+			Type: metadata.PythonPackageListSourceType,
+			Source: metadata.Source{
+				Type: "inline",
+				Version: map[string]interface{}{
+					"sha256": version,
+				},
+				Metadata: sourceMetadata,
+			},
+		})
+		return md, nil
+	}
+
+	return visitor, provider
+}
+
+// getPythonPackages parses the distributions, by the path of their .dist-info
+// or .egg-info
+func getPythonPackages(distributions map[string]*distribution, collector *warnings.Collector) []metadata.PythonPackage {
+	var packages []metadata.PythonPackage
+	for infoPath, d := range distributions {
+		if d.metadata == "" {
+			// an INSTALLER file alone does not describe a distribution
+			continue
+		}
+
+		pkg, err := ParseMetadata(d.metadata)
+		if err != nil {
+			collector.Add(warnings.InvalidPackageEntry, infoPath, fmt.Sprintf("skipping distribution: %s", err))
+			continue
+		}
+		pkg.Installer = d.installer
+		pkg.Location = d.location
+		pkg.Interpreter = interpreter(d.location)
+
+		packages = append(packages, pkg)
+	}
+
+	collator := collate.New(language.BritishEnglish)
+	sort.Slice(packages, func(i, j int) bool {
+		if c := collator.CompareString(packages[i].Package, packages[j].Package); c != 0 {
+			return c < 0
+		}
+		return packages[i].Location < packages[j].Location
+	})
+
+	return packages
+}
+
+// distributionFile tells whether the file describes a distribution installed
+// in a site-packages or dist-packages directory, returning the path of its
+// .dist-info or .egg-info and the name of the file
+func distributionFile(filePath string) (string, string, bool) {
+	dir, fileName := path.Split(filePath)
+	dir = path.Clean(dir)
+
+	if strings.HasSuffix(fileName, ".egg-info") && isSitePackages(dir) {
+		// distributions installed by distutils have a single PKG-INFO file
+		return filePath, "PKG-INFO", true
+	}
+
+	if !isSitePackages(path.Dir(dir)) {
+		return "", "", false
+	}
+
+	switch {
+	case strings.HasSuffix(dir, ".dist-info") && (fileName == "METADATA" || fileName == "INSTALLER"):
+		return dir, fileName, true
+	case strings.HasSuffix(dir, ".egg-info") && fileName == "PKG-INFO":
+		return dir, fileName, true
+	}
+	return "", "", false
+}
+
+func isSitePackages(dir string) bool {
+	base := path.Base(dir)
+	return base == "site-packages" || base == "dist-packages"
+}
+
+// interpreter is the path of the python interpreter owning the packages of a
+// site-packages directory, such as /opt/venv/bin/python3.8 for
+// /opt/venv/lib/python3.8/site-packages
+func interpreter(location string) string {
+	libDir := path.Dir(location)
+	version := path.Base(libDir)
+	if !strings.HasPrefix(version, "python") {
+		return ""
+	}
+
+	prefix := path.Dir(libDir)
+	if base := path.Base(prefix); base != "lib" && base != "lib64" {
+		return ""
+	}
+
+	return path.Join(path.Dir(prefix), "bin", version)
+}
+
+// ParseMetadata parses the headers of the METADATA or PKG-INFO file of a
+// distribution, see https://packaging.python.org/specifications/core-metadata/
+func ParseMetadata(content string) (metadata.PythonPackage, error) {
+	headers := map[string][]string{}
+
+	var lastKey string
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if line == "" {
+			// the description follows the headers
+			break
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if values := headers[lastKey]; len(values) != 0 {
+				values[len(values)-1] += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		lastKey = strings.ToLower(strings.TrimSpace(parts[0]))
+		headers[lastKey] = append(headers[lastKey], strings.TrimSpace(parts[1]))
+	}
+
+	pkg := metadata.PythonPackage{
+		Package: first(headers["name"]),
+		Version: first(headers["version"]),
+		License: license(headers),
+	}
+
+	if pkg.Package == "" {
+		return pkg, fmt.Errorf("invalid distribution metadata")
+	}
+
+	return pkg, nil
+}
+
+// license prefers the license expression, then the license field, unless it
+// holds the text of the license, and then the license classifiers
+func license(headers map[string][]string) string {
+	if expression := first(headers["license-expression"]); expression != "" {
+		return expression
+	}
+
+	license := first(headers["license"])
+	if license != "" && license != "UNKNOWN" && !strings.Contains(license, "\n") {
+		return license
+	}
+
+	var classifiers []string
+	for _, classifier := range headers["classifier"] {
+		if strings.HasPrefix(classifier, licenseClassifierPrefix) {
+			segments := strings.Split(classifier, " :: ")
+			classifiers = append(classifiers, segments[len(segments)-1])
+		}
+	}
+	if len(classifiers) != 0 {
+		return strings.Join(classifiers, ", ")
+	}
+
+	if license != "UNKNOWN" {
+		return strings.SplitN(license, "\n", 2)[0]
+	}
+	return ""
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
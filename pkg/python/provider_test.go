@@ -0,0 +1,146 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package python_test
+
+import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/python"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+const requestsMetadata = `Metadata-Version: 2.1
+Name: requests
+Version: 2.24.0
+Summary: Python HTTP for Humans.
+License: Apache 2.0
+Classifier: License :: OSI Approved :: Apache Software License
+
+Requests is an elegant and simple HTTP library for Python.
+License: not a header
+`
+
+var _ = Describe("python", func() {
+	Describe("Provider", func() {
+		It("adds the distributions of all site-packages and dist-packages, sorted by name", func() {
+			collector := warnings.NewCollector()
+
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/opt/venv/lib/python3.8/site-packages/requests-2.24.0.dist-info/METADATA":  requestsMetadata,
+					"/opt/venv/lib/python3.8/site-packages/requests-2.24.0.dist-info/INSTALLER": "pip\n",
+					"/opt/venv/lib/python3.8/site-packages/requests/__init__.py":                "",
+					"/usr/local/lib/python3.8/site-packages/Flask-1.1.2.dist-info/METADATA":     "Name: Flask\nVersion: 1.1.2\nLicense: BSD-3-Clause\n",
+					"/usr/lib/python3/dist-packages/six-1.14.0.egg-info/PKG-INFO":               "Name: six\nVersion: 1.14.0\nLicense: MIT\n",
+					"/usr/lib/python3/dist-packages/distro_info-0.23.egg-info":                  "Name: distro-info\nVersion: 0.23\nLicense: ISC\n",
+					"/usr/local/lib/python3.8/site-packages/requests-2.22.0.dist-info/METADATA": "Name: requests\nVersion: 2.22.0\n",
+					"/src/app/app.egg-info/PKG-INFO":                                            "Name: app\nVersion: 0.1\n",
+				},
+			}, common.RunParams{Warnings: collector.ForProvider("python")}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(collector.Warnings()).To(BeEmpty())
+
+			Expect(md.Dependencies).To(HaveLen(1))
+			dependency := md.Dependencies[0]
+			Expect(dependency.Type).To(Equal(metadata.PythonPackageListSourceType))
+			Expect(dependency.Source.Type).To(Equal("inline"))
+			Expect(dependency.Source.Version["sha256"]).ToNot(BeEmpty())
+
+			sourceMetadata := dependency.Source.Metadata.(metadata.PythonPackageListSourceMetadata)
+			Expect(sourceMetadata.Packages).To(Equal([]metadata.PythonPackage{
+				{
+					Package:     "distro-info",
+					Version:     "0.23",
+					License:     "ISC",
+					Interpreter: "/usr/bin/python3",
+					Location:    "/usr/lib/python3/dist-packages",
+				},
+				{
+					Package:     "Flask",
+					Version:     "1.1.2",
+					License:     "BSD-3-Clause",
+					Interpreter: "/usr/local/bin/python3.8",
+					Location:    "/usr/local/lib/python3.8/site-packages",
+				},
+				{
+					Package:     "requests",
+					Version:     "2.24.0",
+					License:     "Apache 2.0",
+					Installer:   "pip",
+					Interpreter: "/opt/venv/bin/python3.8",
+					Location:    "/opt/venv/lib/python3.8/site-packages",
+				},
+				{
+					Package:     "requests",
+					Version:     "2.22.0",
+					Interpreter: "/usr/local/bin/python3.8",
+					Location:    "/usr/local/lib/python3.8/site-packages",
+				},
+				{
+					Package:     "six",
+					Version:     "1.14.0",
+					License:     "MIT",
+					Interpreter: "/usr/bin/python3",
+					Location:    "/usr/lib/python3/dist-packages",
+				},
+			}))
+		})
+
+		It("warns about distributions without a name", func() {
+			collector := warnings.NewCollector()
+
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/usr/lib/python3/dist-packages/broken-1.0.dist-info/METADATA": "Version: 1.0\n",
+					"/usr/lib/python3/dist-packages/six-1.14.0.egg-info/PKG-INFO":  "Name: six\nVersion: 1.14.0\n",
+				},
+			}, common.RunParams{Warnings: collector.ForProvider("python")}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			sourceMetadata := md.Dependencies[0].Source.Metadata.(metadata.PythonPackageListSourceMetadata)
+			Expect(sourceMetadata.Packages).To(HaveLen(1))
+
+			Expect(collector.Warnings()).To(ConsistOf(warnings.Warning{
+				Code:     warnings.InvalidPackageEntry,
+				Provider: "python",
+				Subject:  "/usr/lib/python3/dist-packages/broken-1.0.dist-info",
+				Message:  "skipping distribution: invalid distribution metadata",
+			}))
+		})
+
+		It("does not add a dependency when there is no python distribution", func() {
+			md, err := Provider(test_utils.FakeImage{
+				Files: map[string]string{
+					"/etc/os-release": "ID=scratch",
+				},
+			}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("ParseMetadata license", func(content, expected string) {
+		pkg, err := ParseMetadata("Name: foo\nVersion: 1.0\n" + content)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pkg.License).To(Equal(expected))
+	},
+		Entry("the license expression", "License-Expression: MIT OR Apache-2.0\nLicense: MIT\n", "MIT OR Apache-2.0"),
+		Entry("the license field", "License: MIT\n", "MIT"),
+		Entry("the classifiers for an unknown license",
+			"License: UNKNOWN\nClassifier: License :: OSI Approved :: MIT License\nClassifier: License :: OSI Approved :: Apache Software License\n",
+			"MIT License, Apache Software License"),
+		Entry("the classifiers for the text of a license",
+			"License: Copyright (c) 2020\n        Permission is hereby granted\nClassifier: License :: OSI Approved :: MIT License\n",
+			"MIT License"),
+		Entry("the first line of the text of a license without classifiers",
+			"License: Copyright (c) 2020\n        Permission is hereby granted\n", "Copyright (c) 2020"),
+		Entry("no license", "License: UNKNOWN\n", ""),
+	)
+})
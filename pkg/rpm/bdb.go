@@ -0,0 +1,187 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package rpm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BerkeleyDB hash database, as used for the Packages file by rpm < 4.16,
+// see https://github.com/berkeleydb/libdb/blob/master/src/dbinc/db_page.h
+const (
+	bdbHashMagic = 0x061561
+
+	bdbPageHeaderSize = 26
+
+	bdbHashUnsortedPageType = 2
+	bdbOverflowPageType     = 7
+	bdbHashPageType         = 13
+
+	bdbKeyDataEntryType  = 1
+	bdbOffPageEntryType  = 3
+	bdbOffPageEntrySize  = 12
+	bdbMaxOverflowChains = 1 << 20
+)
+
+// ReadBerkeleyDB returns the header blobs stored as values of the BerkeleyDB
+// hash database content, without going through the BerkeleyDB library
+func ReadBerkeleyDB(content []byte) ([][]byte, error) {
+	if len(content) < bdbPageHeaderSize+12 {
+		return nil, fmt.Errorf("berkeleydb file is too short")
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(content[12:16]) == bdbHashMagic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(content[12:16]) == bdbHashMagic:
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a berkeleydb hash database")
+	}
+
+	db := bdb{
+		content:  content,
+		order:    order,
+		pageSize: order.Uint32(content[20:24]),
+	}
+
+	if db.pageSize < bdbPageHeaderSize || db.pageSize > 64*1024 {
+		return nil, fmt.Errorf("berkeleydb file has an invalid page size of %d", db.pageSize)
+	}
+
+	lastPage := order.Uint32(content[32:36])
+
+	var blobs [][]byte
+	for pageNo := uint32(1); pageNo <= lastPage; pageNo++ {
+		page, err := db.page(pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		pageType := page[25]
+		if pageType != bdbHashPageType && pageType != bdbHashUnsortedPageType {
+			continue
+		}
+
+		values, err := db.hashValues(page)
+		if err != nil {
+			return nil, fmt.Errorf("could not read berkeleydb page %d: %w", pageNo, err)
+		}
+		blobs = append(blobs, values...)
+	}
+
+	return blobs, nil
+}
+
+type bdb struct {
+	content  []byte
+	order    binary.ByteOrder
+	pageSize uint32
+}
+
+func (db bdb) page(pageNo uint32) ([]byte, error) {
+	start := uint64(pageNo) * uint64(db.pageSize)
+	end := start + uint64(db.pageSize)
+	if end > uint64(len(db.content)) {
+		return nil, fmt.Errorf("berkeleydb page %d is out of bounds", pageNo)
+	}
+	return db.content[start:end], nil
+}
+
+// hashValues returns the values stored on a hash page: entries alternate
+// between keys and values, and large values live on a chain of overflow pages.
+// rpm keeps the next header instance number under key 0, which is skipped.
+func (db bdb) hashValues(page []byte) ([][]byte, error) {
+	numEntries := int(db.order.Uint16(page[20:22]))
+	if bdbPageHeaderSize+2*numEntries > len(page) {
+		return nil, fmt.Errorf("too many entries: %d", numEntries)
+	}
+
+	var values [][]byte
+	for i := 1; i < numEntries; i += 2 {
+		key, err := db.entry(page, i-1)
+		if err != nil {
+			return nil, err
+		}
+		if isInstanceCounter(key) {
+			continue
+		}
+
+		entry, err := db.entry(page, i)
+		if err != nil {
+			return nil, err
+		}
+
+		switch entry[0] {
+		case bdbKeyDataEntryType:
+			values = append(values, entry[1:])
+		case bdbOffPageEntryType:
+			if len(entry) < bdbOffPageEntrySize {
+				return nil, fmt.Errorf("entry %d is too short", i)
+			}
+			value, err := db.overflow(db.order.Uint32(entry[4:8]), db.order.Uint32(entry[8:12]))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		default:
+			return nil, fmt.Errorf("unsupported entry type %d", entry[0])
+		}
+	}
+
+	return values, nil
+}
+
+// entry returns the item at index i of a hash page; items are stored from the
+// end of the page downwards so each one ends where the previous one starts
+func (db bdb) entry(page []byte, i int) ([]byte, error) {
+	offset := int(db.order.Uint16(page[bdbPageHeaderSize+2*i:]))
+	end := len(page)
+	if i > 0 {
+		end = int(db.order.Uint16(page[bdbPageHeaderSize+2*(i-1):]))
+	}
+	if offset < bdbPageHeaderSize || offset >= end || end > len(page) {
+		return nil, fmt.Errorf("entry %d is out of bounds", i)
+	}
+	return page[offset:end], nil
+}
+
+func isInstanceCounter(key []byte) bool {
+	return len(key) == 5 && key[0] == bdbKeyDataEntryType &&
+		key[1] == 0 && key[2] == 0 && key[3] == 0 && key[4] == 0
+}
+
+func (db bdb) overflow(pageNo, length uint32) ([]byte, error) {
+	value := make([]byte, 0, length)
+
+	for i := 0; pageNo != 0; i++ {
+		if i > bdbMaxOverflowChains {
+			return nil, fmt.Errorf("overflow chain starting at page %d is too long", pageNo)
+		}
+
+		page, err := db.page(pageNo)
+		if err != nil {
+			return nil, err
+		}
+		if page[25] != bdbOverflowPageType {
+			return nil, fmt.Errorf("page %d is not an overflow page", pageNo)
+		}
+
+		used := int(db.order.Uint16(page[22:24]))
+		if bdbPageHeaderSize+used > len(page) {
+			return nil, fmt.Errorf("overflow page %d is out of bounds", pageNo)
+		}
+		value = append(value, page[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+
+		pageNo = db.order.Uint32(page[16:20])
+	}
+
+	if uint32(len(value)) != length {
+		return nil, fmt.Errorf("expected %d bytes of overflow data, found %d", length, len(value))
+	}
+
+	return value, nil
+}
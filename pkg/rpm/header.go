@@ -0,0 +1,215 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package rpm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// rpm header tags, see https://github.com/rpm-software-management/rpm/blob/master/include/rpm/rpmtag.h
+const (
+	TagName        = 1000
+	TagVersion     = 1001
+	TagRelease     = 1002
+	TagEpoch       = 1003
+	TagLicense     = 1014
+	TagFileSizes   = 1028
+	TagFileModes   = 1030
+	TagFileDigests = 1035
+	TagArch        = 1022
+	TagSourceRpm   = 1044
+	TagDirIndexes  = 1116
+	TagBaseNames   = 1117
+	TagDirNames    = 1118
+)
+
+// Tags maps the names used in the rpm struct tags of metadata.RpmPackage,
+// which follow the rpm queryformat names, to header tags
+var Tags = map[string]int32{
+	"NAME":      TagName,
+	"VERSION":   TagVersion,
+	"RELEASE":   TagRelease,
+	"EPOCH":     TagEpoch,
+	"ARCH":      TagArch,
+	"LICENSE":   TagLicense,
+	"SOURCERPM": TagSourceRpm,
+}
+
+const (
+	typeNull        = 0
+	typeChar        = 1
+	typeInt8        = 2
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBin         = 7
+	typeStringArray = 8
+	typeI18NString  = 9
+
+	indexEntrySize = 16
+
+	// rpm itself refuses headers bigger than this
+	maxHeaderSize = 256 * 1024 * 1024
+)
+
+// None is what rpm prints for a tag missing from the header
+const None = "(none)"
+
+type indexEntry struct {
+	Tag    int32
+	Type   uint32
+	Offset int32
+	Count  uint32
+}
+
+// Header is a parsed rpm header blob as stored in the rpm database
+type Header struct {
+	entries map[int32]indexEntry
+	data    []byte
+}
+
+// ParseHeader parses the header blob stored for each package in the rpm
+// database: a count of index entries and the size of the data store, followed
+// by the index entries and the data store itself, all big endian.
+func ParseHeader(blob []byte) (Header, error) {
+	if len(blob) < 8 {
+		return Header{}, fmt.Errorf("rpm header is too short: %d bytes", len(blob))
+	}
+
+	il := binary.BigEndian.Uint32(blob[0:4])
+	dl := binary.BigEndian.Uint32(blob[4:8])
+
+	if uint64(il)*indexEntrySize+uint64(dl) > maxHeaderSize {
+		return Header{}, fmt.Errorf("rpm header is too big: %d entries, %d bytes of data", il, dl)
+	}
+
+	dataStart := 8 + uint64(il)*indexEntrySize
+	dataEnd := dataStart + uint64(dl)
+	if uint64(len(blob)) < dataEnd {
+		return Header{}, fmt.Errorf("rpm header is truncated: expected %d bytes, found %d", dataEnd, len(blob))
+	}
+
+	h := Header{
+		entries: make(map[int32]indexEntry, il),
+		data:    blob[dataStart:dataEnd],
+	}
+
+	for i := uint64(0); i < uint64(il); i++ {
+		var entry indexEntry
+		err := binary.Read(bytes.NewReader(blob[8+i*indexEntrySize:8+(i+1)*indexEntrySize]), binary.BigEndian, &entry)
+		if err != nil {
+			return Header{}, fmt.Errorf("could not read rpm header index entry: %w", err)
+		}
+
+		if entry.Offset < 0 || uint64(entry.Offset) > uint64(dl) {
+			return Header{}, fmt.Errorf("rpm header tag %d points outside of the data store", entry.Tag)
+		}
+
+		if _, ok := h.entries[entry.Tag]; !ok {
+			h.entries[entry.Tag] = entry
+		}
+	}
+
+	return h, nil
+}
+
+// String returns the value of a string tag, or of the first element of a
+// string array tag, the way rpm's queryformat prints it
+func (h Header) String(tag int32) string {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return None
+	}
+
+	switch entry.Type {
+	case typeString, typeStringArray, typeI18NString:
+		strings := h.strings(entry, 1)
+		if len(strings) == 0 {
+			return None
+		}
+		return strings[0]
+	case typeInt32:
+		values := h.Int32Array(tag)
+		if len(values) == 0 {
+			return None
+		}
+		return fmt.Sprintf("%d", values[0])
+	default:
+		return None
+	}
+}
+
+// StringArray returns all the values of a string array tag
+func (h Header) StringArray(tag int32) []string {
+	entry, ok := h.entries[tag]
+	if !ok {
+		return nil
+	}
+
+	switch entry.Type {
+	case typeString, typeStringArray, typeI18NString:
+		return h.strings(entry, entry.Count)
+	default:
+		return nil
+	}
+}
+
+// Int32Array returns all the values of an int32 tag
+func (h Header) Int32Array(tag int32) []int32 {
+	entry, ok := h.entries[tag]
+	if !ok || entry.Type != typeInt32 {
+		return nil
+	}
+
+	start := uint64(entry.Offset)
+	end := start + 4*uint64(entry.Count)
+	if end > uint64(len(h.data)) {
+		return nil
+	}
+
+	values := make([]int32, entry.Count)
+	for i := range values {
+		values[i] = int32(binary.BigEndian.Uint32(h.data[start+4*uint64(i):]))
+	}
+	return values
+}
+
+// Int16Array returns all the values of an int16 tag
+func (h Header) Int16Array(tag int32) []uint16 {
+	entry, ok := h.entries[tag]
+	if !ok || entry.Type != typeInt16 {
+		return nil
+	}
+
+	start := uint64(entry.Offset)
+	end := start + 2*uint64(entry.Count)
+	if end > uint64(len(h.data)) {
+		return nil
+	}
+
+	values := make([]uint16, entry.Count)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(h.data[start+2*uint64(i):])
+	}
+	return values
+}
+
+func (h Header) strings(entry indexEntry, count uint32) []string {
+	var result []string
+
+	data := h.data[entry.Offset:]
+	for i := uint32(0); i < count; i++ {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 {
+			break
+		}
+		result = append(result, string(data[:end]))
+		data = data[end+1:]
+	}
+
+	return result
+}
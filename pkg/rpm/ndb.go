@@ -0,0 +1,96 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package rpm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ndb database, as used for the Packages.db file by SUSE's rpm >= 4.16,
+// see https://github.com/rpm-software-management/rpm/blob/master/lib/backend/ndb/rpmpkg.c
+const (
+	ndbHeaderMagic = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic   = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic   = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+
+	ndbVersion = 0
+
+	ndbHeaderSize      = 32
+	ndbSlotSize        = 16
+	ndbSlotsPerPage    = 4096 / ndbSlotSize
+	ndbBlockSize       = 16
+	ndbBlobHeaderSize  = 16
+	ndbMaxSlotNumPages = 1 << 16
+)
+
+// ReadNDB returns the header blobs stored in the ndb database content
+func ReadNDB(content []byte) ([][]byte, error) {
+	if len(content) < ndbHeaderSize {
+		return nil, fmt.Errorf("ndb file is too short")
+	}
+
+	if binary.LittleEndian.Uint32(content[0:4]) != ndbHeaderMagic {
+		return nil, fmt.Errorf("not an ndb database")
+	}
+	if version := binary.LittleEndian.Uint32(content[4:8]); version != ndbVersion {
+		return nil, fmt.Errorf("unsupported ndb version %d", version)
+	}
+
+	slotNPages := binary.LittleEndian.Uint32(content[12:16])
+	if slotNPages == 0 || slotNPages > ndbMaxSlotNumPages {
+		return nil, fmt.Errorf("ndb file has an invalid number of slot pages: %d", slotNPages)
+	}
+
+	// the first two slots of the first page are taken up by the header
+	numSlots := int(slotNPages)*ndbSlotsPerPage - 2
+	if ndbHeaderSize+numSlots*ndbSlotSize > len(content) {
+		return nil, fmt.Errorf("ndb file is truncated")
+	}
+
+	var blobs [][]byte
+	for i := 0; i < numSlots; i++ {
+		slot := content[ndbHeaderSize+i*ndbSlotSize:]
+
+		if binary.LittleEndian.Uint32(slot[0:4]) != ndbSlotMagic {
+			return nil, fmt.Errorf("ndb file has a corrupt slot %d", i)
+		}
+
+		pkgIndex := binary.LittleEndian.Uint32(slot[4:8])
+		if pkgIndex == 0 {
+			continue
+		}
+
+		blob, err := ndbBlob(content, pkgIndex, binary.LittleEndian.Uint32(slot[8:12]))
+		if err != nil {
+			return nil, fmt.Errorf("could not read package %d: %w", pkgIndex, err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	return blobs, nil
+}
+
+func ndbBlob(content []byte, pkgIndex, blockOffset uint32) ([]byte, error) {
+	start := uint64(blockOffset) * ndbBlockSize
+	if start+ndbBlobHeaderSize > uint64(len(content)) {
+		return nil, fmt.Errorf("blob is out of bounds")
+	}
+
+	header := content[start : start+ndbBlobHeaderSize]
+	if binary.LittleEndian.Uint32(header[0:4]) != ndbBlobMagic {
+		return nil, fmt.Errorf("blob has an invalid magic")
+	}
+	if binary.LittleEndian.Uint32(header[4:8]) != pkgIndex {
+		return nil, fmt.Errorf("blob belongs to package %d", binary.LittleEndian.Uint32(header[4:8]))
+	}
+
+	length := uint64(binary.LittleEndian.Uint32(header[12:16]))
+	end := start + ndbBlobHeaderSize + length
+	if end > uint64(len(content)) {
+		return nil, fmt.Errorf("blob is truncated")
+	}
+
+	return content[start+ndbBlobHeaderSize : end], nil
+}
@@ -4,38 +4,47 @@
 package rpm
 
 import (
+	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
 )
 
-const sep = "\t"
-
-// UnmarshalPackage matches the line items to the index of the struct field
-func UnmarshalPackage(packageLine string) metadata.RpmPackage {
-	rpmPackage := metadata.RpmPackage{}
-	rpmPackageValue := reflect.ValueOf(&rpmPackage).Elem()
-
-	values := strings.Split(packageLine, sep)
-	for i, value := range values {
-		rpmPackageValue.Field(i).SetString(value)
-	}
-	return rpmPackage
-}
-
-// QueryFormat uses the struct definition to create a queryformat for rpm
-func QueryFormat() string {
-	var fields []string
-
+// UnmarshalPackage fills the struct fields from the header tags named in their rpm struct tag,
+// leaving the fields without one
+func UnmarshalPackage(header Header) (metadata.RpmPackage, error) {
 	rpmPackage := metadata.RpmPackage{}
 	rpmPackageValue := reflect.ValueOf(&rpmPackage).Elem()
 	rpmPackageType := rpmPackageValue.Type()
 
 	for i := 0; i < rpmPackageValue.NumField(); i++ {
-		field := rpmPackageType.Field(i).Tag.Get("rpm")
-		fields = append(fields, "%{"+field+"}")
+		name, ok := rpmPackageType.Field(i).Tag.Lookup("rpm")
+		if !ok {
+			// not read from the header
+			continue
+		}
+		tag, ok := Tags[name]
+		if !ok {
+			return metadata.RpmPackage{}, fmt.Errorf("unknown rpm tag %s", name)
+		}
+		rpmPackageValue.Field(i).SetString(header.String(tag))
 	}
+	return rpmPackage, nil
+}
 
-	return strings.Join(fields, sep) + "\n"
+// FileNames lists the paths of the files installed by a package, which the
+// header stores as base names indexing into a list of directories
+func FileNames(header Header) []string {
+	baseNames := header.StringArray(TagBaseNames)
+	dirNames := header.StringArray(TagDirNames)
+	dirIndexes := header.Int32Array(TagDirIndexes)
+
+	var fileNames []string
+	for i, baseName := range baseNames {
+		if i >= len(dirIndexes) || dirIndexes[i] < 0 || int(dirIndexes[i]) >= len(dirNames) {
+			continue
+		}
+		fileNames = append(fileNames, dirNames[dirIndexes[i]]+baseName)
+	}
+	return fileNames
 }
@@ -5,11 +5,8 @@ package rpm
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path"
 	"sort"
-	"strings"
 
 	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
 
@@ -23,54 +20,45 @@ import (
 
 const RPMDbPath = "/var/lib/rpm"
 
-func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
-
-	absPath, err := dli.AbsolutePath(RPMDbPath)
-	if err != nil {
-		return metadata.Metadata{}, fmt.Errorf("absolute path for rpm database: %w", err)
-	}
+// databases lists the package database of each rpm backend, newest first, as
+// a distribution which migrated from one backend to another may leave the old
+// database behind. The databases are read from the files of the rpm database
+// directory, by name.
+var databases = []struct {
+	fileName string
+	read     func(files map[string][]byte) ([][]byte, error)
+}{
+	{"rpmdb.sqlite", func(files map[string][]byte) ([][]byte, error) {
+		return ReadSQLite(files["rpmdb.sqlite"], files["rpmdb.sqlite-wal"])
+	}},
+	{"Packages.db", func(files map[string][]byte) ([][]byte, error) {
+		return ReadNDB(files["Packages.db"])
+	}},
+	{"Packages", func(files map[string][]byte) ([][]byte, error) {
+		return ReadBerkeleyDB(files["Packages"])
+	}},
+}
 
-	exists, err := exists(path.Join(absPath, "Packages"))
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	headers, found, err := readHeaders(dli)
 	if err != nil {
-		return metadata.Metadata{}, fmt.Errorf("rpm could not find existance of path: %w", err)
+		return metadata.Metadata{}, err
 	}
-	if !exists {
+	if !found {
 		return md, nil
 	}
 
-	if !isRPMInstalled() {
-		return metadata.Metadata{}, fmt.Errorf("an rpm database exists at %s but rpm is not installed and available on your path: %w", RPMDbPath, err)
-	}
-
-	query := QueryFormat()
-	cmd := exec.Command("rpm",
-		"-qa",
-		"--dbpath", absPath,
-		"--queryformat", query,
-	)
-	stdOutBuffer := &strings.Builder{}
-	cmd.Stdout = stdOutBuffer
-
-	err = cmd.Run()
-
-	if err != nil {
-		return metadata.Metadata{},
-			fmt.Errorf("failed to execute rpm at path, %s, with query, %s: %w", absPath, query, err)
-	}
-
-	if strings.TrimSpace(stdOutBuffer.String()) == "" {
-		return metadata.Metadata{}, fmt.Errorf("no rpm packages data found")
-	}
-
-	allPackagesDetails := strings.Split(strings.TrimSpace(stdOutBuffer.String()), "\n")
-
 	var packages []metadata.RpmPackage
 
-	for _, line := range allPackagesDetails {
-		packages = append(packages, UnmarshalPackage(line))
+	for _, header := range headers {
+		rpmPackage, err := UnmarshalPackage(header)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+		packages = append(packages, rpmPackage)
 	}
 	collator := collate.New(language.BritishEnglish)
-	sort.Slice(packages, func(i, j int) bool {
+	sort.SliceStable(packages, func(i, j int) bool {
 		return collator.CompareString(packages[i].Package, packages[j].Package) < 0
 	})
 
@@ -97,26 +85,95 @@ func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (m
 	return md, nil
 }
 
-func isRPMInstalled() bool {
-	stdOutBuffer := &strings.Builder{}
-	cmd := exec.Command("rpm",
-		"--version",
-	)
+// OwnedFiles lists the paths of the files installed by the packages of the
+// rpm database, if any
+func OwnedFiles(dli image.Image) ([]string, error) {
+	headers, _, err := readHeaders(dli)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, header := range headers {
+		files = append(files, FileNames(header)...)
+	}
+	return files, nil
+}
+
+// readHeaders parses the package headers of the newest rpm database of the
+// image, and tells whether one was found
+func readHeaders(dli image.Image) ([]Header, bool, error) {
+	fileNames, err := dli.GetDirFileNames(RPMDbPath, false)
+	if err != nil {
+		// in this case a non-existent directory is not an error
+		return nil, false, nil
+	}
+
+	files := map[string][]byte{}
+	for _, fileName := range fileNames {
+		if !isDatabaseFile(fileName) {
+			continue
+		}
+
+		content, err := dli.GetFileContent(path.Join(RPMDbPath, fileName))
+		if err != nil {
+			return nil, false, fmt.Errorf("could not read rpm database file %s: %w", path.Join(RPMDbPath, fileName), err)
+		}
+		files[fileName] = []byte(content)
+	}
+
+	return ReadHeaders(files)
+}
+
+// ReadHeaders parses the package headers of the newest rpm database among the
+// files of the rpm database directory, by name, and tells whether there was
+// one
+func ReadHeaders(files map[string][]byte) ([]Header, bool, error) {
+	var blobs [][]byte
+	found := false
+	for _, database := range databases {
+		if _, ok := files[database.fileName]; !ok {
+			continue
+		}
+
+		var err error
+		blobs, err = database.read(files)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read rpm database at path, %s: %w", path.Join(RPMDbPath, database.fileName), err)
+		}
+		found = true
+		break
+	}
+	if !found {
+		return nil, false, nil
+	}
 
-	cmd.Stdout = stdOutBuffer
+	if len(blobs) == 0 {
+		return nil, false, fmt.Errorf("no rpm packages data found")
+	}
 
-	err := cmd.Run()
+	var headers []Header
+	for _, blob := range blobs {
+		header, err := ParseHeader(blob)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse rpm package header: %w", err)
+		}
+		headers = append(headers, header)
+	}
 
-	return err == nil && strings.Contains(stdOutBuffer.String(), "RPM version")
+	return headers, true, nil
 }
 
-func exists(path string) (bool, error) {
-	_, err := os.Stat(path)
-	if err == nil {
-		return true, nil
+// isDatabaseFile tells whether the file of the rpm database directory is read
+// by one of the backends, as its database or the sqlite write-ahead log
+func isDatabaseFile(fileName string) bool {
+	if fileName == "rpmdb.sqlite-wal" {
+		return true
 	}
-	if os.IsNotExist(err) {
-		return false, nil
+	for _, database := range databases {
+		if fileName == database.fileName {
+			return true
+		}
 	}
-	return true, err
+	return false
 }
@@ -5,15 +5,18 @@ package rpm_test
 
 import (
 	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
 	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/onsi/gomega/gstruct"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/rpm"
@@ -29,47 +32,72 @@ func (m MockImage) GetConfig() (*v1.ConfigFile, error) {
 	panic("implement me")
 }
 
-func (m MockImage) Cleanup() {
+func (m MockImage) GetManifest() (*v1.Manifest, error) {
 	panic("implement me")
 }
 
-func (m MockImage) GetFileContent(string) (string, error) {
+func (m MockImage) Cleanup() {
 	panic("implement me")
 }
 
+func (m MockImage) GetFileContent(p string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(m.path, strings.TrimPrefix(p, rpm.RPMDbPath)))
+	return string(content), err
+}
+
 func (m MockImage) GetDirFileNames(string, bool) ([]string, error) {
-	panic("implement me")
+	files, err := ioutil.ReadDir(m.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileNames []string
+	for _, file := range files {
+		fileNames = append(fileNames, file.Name())
+	}
+	return fileNames, nil
 }
 
 func (m MockImage) GetDirContents(string) ([]string, error) {
 	panic("implement me")
 }
 
-func (m MockImage) AbsolutePath(string) (string, error) {
-	path, err := filepath.Abs(m.path)
+func (m MockImage) WalkFiles(image.WalkFilesFunc) error {
+	panic("implement me")
+}
+
+func (m MockImage) Layers() ([]image.Layer, error) {
+	panic("implement me")
+}
+
+func (m MockImage) FileHistory(string) ([]int, error) {
+	panic("implement me")
+}
+
+func (m MockImage) GetLayerFileContent(string, int) (string, error) {
+	panic("implement me")
+}
 
-	Expect(err).ToNot(HaveOccurred())
-	return path, err
+func (m MockImage) AbsolutePath(string) (string, error) {
+	panic("implement me")
 }
 
 func (m MockImage) ExportWithMetadata(metadata.Metadata, string, string) error {
 	panic("implement me")
 }
 
-var _ = Describe("Pkg/Rpm/Provider", func() {
+func (m MockImage) PushWithMetadata(metadata.Metadata, string) error {
+	panic("implement me")
+}
 
-	//rpm leaves __db.001 etc. files in the folder when it runs; we should try to clean those up
-	AfterEach(func() {
-		files, err := filepath.Glob("../../test/integration/assets/rpm/__db.*")
-		Expect(err).ToNot(HaveOccurred())
-		for _, f := range files {
-			err := os.Remove(f)
-			Expect(err).ToNot(HaveOccurred())
-		}
-	})
+func (m MockImage) WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
+}
+
+var _ = Describe("Pkg/Rpm/Provider", func() {
 
-	It("should generate list of dependencies", func() {
-		md, err := rpm.Provider(MockImage{"../../test/integration/assets/rpm"}, common.RunParams{}, metadata.Metadata{})
+	DescribeTable("should generate list of dependencies", func(dbPath string) {
+		md, err := rpm.Provider(MockImage{dbPath}, common.RunParams{}, metadata.Metadata{})
 
 		Expect(err).ToNot(HaveOccurred())
 		packages := md.Dependencies[0].Source.Metadata.(metadata.RpmPackageListSourceMetadata).Packages
@@ -85,6 +113,70 @@ var _ = Describe("Pkg/Rpm/Provider", func() {
 				"SourceRpm":    Not(BeEmpty()),
 			}))
 		}
+
+		Expect(packages).To(ContainElement(metadata.RpmPackage{
+			Package:      "bash",
+			Version:      "4.4.18",
+			Architecture: "x86_64",
+			License:      "GPLv3",
+			SourceRpm:    "bash-4.4.18-1.ph3.src.rpm",
+		}))
+
+		By("reporting tags missing from the header the way rpm does")
+		Expect(packages).To(ContainElement(metadata.RpmPackage{
+			Package:      "gpg-pubkey",
+			Version:      "66fd4949",
+			Architecture: "(none)",
+			License:      "pubkey",
+			SourceRpm:    "(none)",
+		}))
+	},
+		Entry("berkeleydb", "../../test/integration/assets/rpm"),
+		Entry("ndb", "../../test/integration/assets/rpm-ndb"),
+		Entry("sqlite", "../../test/integration/assets/rpm-sqlite"),
+	)
+
+	It("generates the same digest whatever the database backend", func() {
+		var digests []interface{}
+		for _, dbPath := range []string{
+			"../../test/integration/assets/rpm",
+			"../../test/integration/assets/rpm-ndb",
+			"../../test/integration/assets/rpm-sqlite",
+		} {
+			md, err := rpm.Provider(MockImage{dbPath}, common.RunParams{}, metadata.Metadata{})
+			Expect(err).ToNot(HaveOccurred())
+			digests = append(digests, md.Dependencies[0].Source.Version["sha256"])
+		}
+
+		Expect(digests[1]).To(Equal(digests[0]))
+		Expect(digests[2]).To(Equal(digests[0]))
+	})
+
+	It("includes the changes in the sqlite write-ahead log", func() {
+		md, err := rpm.Provider(MockImage{"../../test/integration/assets/rpm-sqlite-wal"}, common.RunParams{}, metadata.Metadata{})
+
+		Expect(err).ToNot(HaveOccurred())
+		packages := md.Dependencies[0].Source.Metadata.(metadata.RpmPackageListSourceMetadata).Packages
+		Expect(packages).To(HaveLen(2))
+		Expect(packages[0].Package).To(Equal("bash"))
+		Expect(packages[1].Package).To(Equal("libgcc"))
+	})
+
+	It("returns an error if the rpm database is corrupt", func() {
+		tempDirPath, err := ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tempDirPath)
+
+		err = ioutil.WriteFile(filepath.Join(tempDirPath, "Packages"), []byte("not a berkeleydb database"), 0644)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = rpm.Provider(MockImage{tempDirPath}, common.RunParams{}, metadata.Metadata{})
+		Expect(err).To(MatchError(ContainSubstring("failed to read rpm database at path, /var/lib/rpm/Packages")))
+	})
+
+	It("returns an error if a payload of the sqlite database is larger than the database", func() {
+		_, err := rpm.Provider(MockImage{"../../test/integration/assets/rpm-sqlite-corrupt"}, common.RunParams{}, metadata.Metadata{})
+		Expect(err).To(MatchError(ContainSubstring("payload is out of bounds")))
 	})
 
 	It("does not modify the metadata if no rpm database folder is found", func() {
@@ -103,6 +195,17 @@ var _ = Describe("Pkg/Rpm/Provider", func() {
 
 	})
 
+	DescribeTable("lists the files owned by the packages", func(dbPath string) {
+		files, err := rpm.OwnedFiles(MockImage{dbPath})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(files).To(ContainElement("/bin/bash"))
+		Expect(files).ToNot(ContainElement(""))
+	},
+		Entry("berkeleydb", "../../test/integration/assets/rpm"),
+		Entry("sqlite", "../../test/integration/assets/rpm-sqlite"),
+	)
+
 	It("returns nil if no Package file is found in the rpm database folder", func() {
 		tempDirPath, err := ioutil.TempDir("", "")
 		Expect(err).NotTo(HaveOccurred())
@@ -120,20 +223,4 @@ var _ = Describe("Pkg/Rpm/Provider", func() {
 			Type: "Do not touch this one!!!!!",
 		}}}))
 	})
-
-	It("returns an error if rpm is not in the PATH", func() {
-		PATH := os.Getenv("PATH")
-		Expect(os.Setenv("PATH", "")).ToNot(HaveOccurred())
-
-		defer func() {
-			Expect(os.Setenv("PATH", PATH)).ToNot(HaveOccurred())
-		}()
-
-		_, err := rpm.Provider(MockImage{"../../test/integration/assets/rpm"}, common.RunParams{}, metadata.Metadata{})
-
-		Expect(err).To(MatchError(SatisfyAll(
-			ContainSubstring("an rpm database exists at"),
-			ContainSubstring("but rpm is not installed and available on your path"))))
-
-	})
 })
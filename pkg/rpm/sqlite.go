@@ -0,0 +1,401 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package rpm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// sqlite database, as used for the rpmdb.sqlite file by rpm >= 4.16. Only the
+// parts of the file format needed to read the blobs of the Packages table are
+// implemented, see https://www.sqlite.org/fileformat2.html
+const (
+	sqliteMagic      = "SQLite format 3\x00"
+	sqliteHeaderSize = 100
+
+	sqliteTableInteriorPage = 0x05
+	sqliteTableLeafPage     = 0x0d
+
+	sqliteWALHeaderSize      = 32
+	sqliteWALFrameHeaderSize = 24
+
+	sqlitePackagesTable = "Packages"
+
+	sqliteMaxDepth = 64
+)
+
+// ReadSQLite returns the header blobs stored in the Packages table of the
+// sqlite database content, including the changes committed to its
+// write-ahead log, if any, which rpm may not have checkpointed yet
+func ReadSQLite(content, wal []byte) ([][]byte, error) {
+	if len(content) < sqliteHeaderSize || string(content[0:16]) != sqliteMagic {
+		return nil, fmt.Errorf("not an sqlite database")
+	}
+
+	pageSize := uint32(binary.BigEndian.Uint16(content[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("sqlite file has an invalid page size of %d", pageSize)
+	}
+
+	db := sqlite{
+		content:    content,
+		pageSize:   pageSize,
+		usableSize: pageSize - uint32(content[20]),
+	}
+
+	db.walPages = readWAL(wal, pageSize)
+
+	rootPage, err := db.tableRootPage(sqlitePackagesTable)
+	if err != nil {
+		return nil, fmt.Errorf("could not find the %s table: %w", sqlitePackagesTable, err)
+	}
+
+	var blobs [][]byte
+	err = db.walkTable(rootPage, 0, func(record []interface{}) error {
+		for _, value := range record {
+			if blob, ok := value.([]byte); ok {
+				blobs = append(blobs, blob)
+				return nil
+			}
+		}
+		return fmt.Errorf("package row without a blob")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read the %s table: %w", sqlitePackagesTable, err)
+	}
+
+	return blobs, nil
+}
+
+type sqlite struct {
+	content    []byte
+	pageSize   uint32
+	usableSize uint32
+	walPages   map[uint32][]byte
+}
+
+// readWAL returns the latest committed version of each page in the
+// write-ahead log content, if there is one
+func readWAL(content []byte, pageSize uint32) map[uint32][]byte {
+	if len(content) < sqliteWALHeaderSize || binary.BigEndian.Uint32(content[8:12]) != pageSize {
+		return nil
+	}
+	salt := content[16:24]
+
+	committed := map[uint32][]byte{}
+	pending := map[uint32][]byte{}
+
+	frameSize := sqliteWALFrameHeaderSize + int(pageSize)
+	for offset := sqliteWALHeaderSize; offset+frameSize <= len(content); offset += frameSize {
+		frame := content[offset : offset+frameSize]
+		if !bytes.Equal(frame[8:16], salt) {
+			break
+		}
+
+		pending[binary.BigEndian.Uint32(frame[0:4])] = frame[sqliteWALFrameHeaderSize:]
+
+		// a non-zero database size marks the last frame of a transaction
+		if binary.BigEndian.Uint32(frame[4:8]) != 0 {
+			for pageNo, page := range pending {
+				committed[pageNo] = page
+			}
+			pending = map[uint32][]byte{}
+		}
+	}
+
+	return committed
+}
+
+func (db sqlite) page(pageNo uint32) ([]byte, error) {
+	if page, ok := db.walPages[pageNo]; ok {
+		return page, nil
+	}
+
+	start := uint64(pageNo-1) * uint64(db.pageSize)
+	end := start + uint64(db.pageSize)
+	if pageNo == 0 || end > uint64(len(db.content)) {
+		return nil, fmt.Errorf("page %d is out of bounds", pageNo)
+	}
+	return db.content[start:end], nil
+}
+
+// pageCount is the number of pages of the database file, and of its
+// write-ahead log
+func (db sqlite) pageCount() uint64 {
+	return uint64(len(db.content))/uint64(db.pageSize) + uint64(len(db.walPages))
+}
+
+// tableRootPage looks up the root page of a table in the sqlite_master table,
+// which is rooted at page 1
+func (db sqlite) tableRootPage(name string) (uint32, error) {
+	var rootPage uint32
+
+	err := db.walkTable(1, 0, func(record []interface{}) error {
+		if len(record) < 4 {
+			return nil
+		}
+
+		recordType, _ := record[0].(string)
+		recordName, _ := record[1].(string)
+		if recordType != "table" || !strings.EqualFold(recordName, name) {
+			return nil
+		}
+
+		if page, ok := record[3].(int64); ok {
+			rootPage = uint32(page)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if rootPage == 0 {
+		return 0, fmt.Errorf("table not found")
+	}
+	return rootPage, nil
+}
+
+// walkTable calls fn with the record of each row of the table b-tree rooted at pageNo
+func (db sqlite) walkTable(pageNo uint32, depth int, fn func([]interface{}) error) error {
+	if depth > sqliteMaxDepth {
+		return fmt.Errorf("table b-tree is too deep")
+	}
+
+	page, err := db.page(pageNo)
+	if err != nil {
+		return err
+	}
+
+	// page 1 starts with the database header
+	headerOffset := 0
+	if pageNo == 1 {
+		headerOffset = sqliteHeaderSize
+	}
+	header := page[headerOffset:]
+
+	numCells := int(binary.BigEndian.Uint16(header[3:5]))
+
+	switch header[0] {
+	case sqliteTableInteriorPage:
+		pointers := header[12:]
+		if 2*numCells > len(pointers) {
+			return fmt.Errorf("page %d has too many cells", pageNo)
+		}
+
+		for i := 0; i < numCells; i++ {
+			cell := int(binary.BigEndian.Uint16(pointers[2*i:]))
+			if cell+4 > len(page) {
+				return fmt.Errorf("cell %d of page %d is out of bounds", i, pageNo)
+			}
+
+			err := db.walkTable(binary.BigEndian.Uint32(page[cell:]), depth+1, fn)
+			if err != nil {
+				return err
+			}
+		}
+
+		return db.walkTable(binary.BigEndian.Uint32(header[8:12]), depth+1, fn)
+
+	case sqliteTableLeafPage:
+		pointers := header[8:]
+		if 2*numCells > len(pointers) {
+			return fmt.Errorf("page %d has too many cells", pageNo)
+		}
+
+		for i := 0; i < numCells; i++ {
+			cell := int(binary.BigEndian.Uint16(pointers[2*i:]))
+			if cell >= len(page) {
+				return fmt.Errorf("cell %d of page %d is out of bounds", i, pageNo)
+			}
+
+			payload, err := db.payload(page[cell:])
+			if err != nil {
+				return fmt.Errorf("could not read cell %d of page %d: %w", i, pageNo, err)
+			}
+
+			record, err := parseRecord(payload)
+			if err != nil {
+				return fmt.Errorf("could not parse cell %d of page %d: %w", i, pageNo, err)
+			}
+
+			err = fn(record)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("page %d is not a table b-tree page", pageNo)
+	}
+}
+
+// payload returns the full payload of a table leaf cell, following the
+// overflow pages for payloads which do not fit on the leaf page
+func (db sqlite) payload(cell []byte) ([]byte, error) {
+	size, n := readVarint(cell)
+	if n == 0 {
+		return nil, fmt.Errorf("invalid payload size")
+	}
+	cell = cell[n:]
+
+	_, n = readVarint(cell)
+	if n == 0 {
+		return nil, fmt.Errorf("invalid rowid")
+	}
+	cell = cell[n:]
+
+	usable := uint64(db.usableSize)
+	maxLocal := usable - 35
+	if size <= maxLocal {
+		if size > uint64(len(cell)) {
+			return nil, fmt.Errorf("payload is out of bounds")
+		}
+		return cell[:size], nil
+	}
+
+	minLocal := (usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if local+4 > uint64(len(cell)) {
+		return nil, fmt.Errorf("payload is out of bounds")
+	}
+
+	// the rest of the payload is held by overflow pages, of which there are
+	// no more than pages in the database
+	if size-local > db.pageCount()*(usable-4) {
+		return nil, fmt.Errorf("payload is out of bounds")
+	}
+
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+
+	overflowPage := binary.BigEndian.Uint32(cell[local:])
+	for overflowPage != 0 && uint64(len(payload)) < size {
+		page, err := db.page(overflowPage)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := page[4:db.usableSize]
+		if remaining := size - uint64(len(payload)); remaining < uint64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+
+		overflowPage = binary.BigEndian.Uint32(page[0:4])
+	}
+
+	if uint64(len(payload)) != size {
+		return nil, fmt.Errorf("expected %d bytes of payload, found %d", size, len(payload))
+	}
+
+	return payload, nil
+}
+
+// parseRecord decodes a record into nil, int64, float64 (returned as raw
+// bits), string or []byte values
+func parseRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := readVarint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("invalid record header")
+	}
+
+	var serialTypes []uint64
+	for offset := uint64(n); offset < headerSize; {
+		serialType, n := readVarint(payload[offset:headerSize])
+		if n == 0 {
+			return nil, fmt.Errorf("invalid record header")
+		}
+		serialTypes = append(serialTypes, serialType)
+		offset += uint64(n)
+	}
+
+	var values []interface{}
+	body := payload[headerSize:]
+	for _, serialType := range serialTypes {
+		size := serialTypeSize(serialType)
+		if size > uint64(len(body)) {
+			return nil, fmt.Errorf("record is truncated")
+		}
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType >= 1 && serialType <= 6:
+			values = append(values, readInt(data))
+		case serialType == 7:
+			values = append(values, binary.BigEndian.Uint64(data))
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, data)
+		case serialType >= 13:
+			values = append(values, string(data))
+		default:
+			return nil, fmt.Errorf("unsupported serial type %d", serialType)
+		}
+	}
+
+	return values, nil
+}
+
+func serialTypeSize(serialType uint64) uint64 {
+	switch serialType {
+	case 1, 2, 3, 4:
+		return serialType
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	}
+	if serialType >= 12 {
+		return (serialType - 12) / 2
+	}
+	return 0
+}
+
+func readInt(data []byte) int64 {
+	var value int64
+	if len(data) > 0 && data[0]&0x80 != 0 {
+		value = -1
+	}
+	for _, b := range data {
+		value = value<<8 | int64(b)
+	}
+	return value
+}
+
+// readVarint decodes an sqlite variable-length integer, returning the value
+// and the number of bytes read, or 0 bytes read if it is truncated
+func readVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+		if i == 8 {
+			return value<<8 | uint64(data[i]), 9
+		}
+		value = value<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return value, 9
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Directories of an advisory database, holding the OSV records, the JSON
+// exports of the debian security tracker and the OVAL definitions
+const (
+	OSVDir    = "osv"
+	DebianDir = "debian"
+	OVALDir   = "oval"
+)
+
+// Database holds the advisories read from a local directory, so that images
+// can be scanned without network access
+type Database struct {
+	osv    map[string][]osvEntry
+	debian map[string]map[string]debianIssue
+	oval   []*ovalDefinitions
+}
+
+// LoadDatabase reads the advisories of the directory at dir:
+//
+//	osv/**/*.json and osv/**/*.zip  OSV records, e.g. the all.zip exports
+//	debian/*.json                   the debian security tracker JSON export
+//	oval/*.xml and oval/*.xml.bz2   OVAL definitions, e.g. of Red Hat
+func LoadDatabase(dir string) (Database, error) {
+	db := Database{
+		osv:    map[string][]osvEntry{},
+		debian: map[string]map[string]debianIssue{},
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return Database{}, fmt.Errorf("could not find advisory database: %w", err)
+	}
+
+	files := 0
+	err := walkDir(filepath.Join(dir, OSVDir), func(path string) error {
+		switch {
+		case strings.HasSuffix(path, ".json"):
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files++
+			return db.addOSVFile(path, content)
+		case strings.HasSuffix(path, ".zip"):
+			files++
+			return db.addOSVArchive(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return Database{}, err
+	}
+
+	err = walkDir(filepath.Join(dir, DebianDir), func(path string) error {
+		if !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files++
+		if err := db.addDebian(content); err != nil {
+			return fmt.Errorf("could not parse debian security tracker export %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Database{}, err
+	}
+
+	err = walkDir(filepath.Join(dir, OVALDir), func(path string) error {
+		if !strings.HasSuffix(path, ".xml") && !strings.HasSuffix(path, ".xml.bz2") {
+			return nil
+		}
+		content, err := readOVALFile(path)
+		if err != nil {
+			return err
+		}
+		files++
+		oval, err := parseOVAL(content)
+		if err != nil {
+			return fmt.Errorf("could not parse OVAL definitions %s: %w", path, err)
+		}
+		db.oval = append(db.oval, oval)
+		return nil
+	})
+	if err != nil {
+		return Database{}, err
+	}
+
+	if files == 0 {
+		return Database{}, fmt.Errorf("could not find any advisories in %s, expected %s, %s or %s directories", dir, OSVDir, DebianDir, OVALDir)
+	}
+	return db, nil
+}
+
+func (db *Database) addOSVFile(path string, content []byte) error {
+	vulnerabilities, err := parseOSV(content)
+	if err != nil {
+		return fmt.Errorf("could not parse OSV record %s: %w", path, err)
+	}
+	db.addOSV(vulnerabilities)
+	return nil
+}
+
+func (db *Database) addOSVArchive(path string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("could not open OSV archive %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		if !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("could not open %s of OSV archive %s: %w", f.Name, path, err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("could not read %s of OSV archive %s: %w", f.Name, path, err)
+		}
+
+		if err := db.addOSVFile(path+"/"+f.Name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readOVALFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open OVAL definitions %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".bz2") {
+		return ioutil.ReadAll(bzip2.NewReader(f))
+	}
+	return ioutil.ReadAll(f)
+}
+
+// walkDir calls fn with the path of each file under dir, if it exists
+func walkDir(dir string, fn func(path string) error) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return fn(path)
+	})
+}
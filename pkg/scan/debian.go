@@ -0,0 +1,99 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// DebianSource names the findings of the debian security tracker
+const DebianSource = "debian"
+
+// debianTracker is the JSON export of the debian security tracker, the
+// issues of each source package by id
+type debianTracker map[string]map[string]debianIssue
+
+type debianIssue struct {
+	Description string                  `json:"description"`
+	Releases    map[string]debianStatus `json:"releases"`
+}
+
+type debianStatus struct {
+	Status       string `json:"status"`
+	FixedVersion string `json:"fixed_version"`
+	Urgency      string `json:"urgency"`
+}
+
+func (db *Database) addDebian(content []byte) error {
+	var tracker debianTracker
+	if err := json.Unmarshal(content, &tracker); err != nil {
+		return err
+	}
+
+	for sourcePackage, issues := range tracker {
+		if db.debian[sourcePackage] == nil {
+			db.debian[sourcePackage] = map[string]debianIssue{}
+		}
+		for id, issue := range issues {
+			db.debian[sourcePackage][id] = issue
+		}
+	}
+	return nil
+}
+
+// matchDebian reports the issues of the source packages which are open, or
+// resolved by a later version, in the release of the image, named by the
+// codename of its os-release
+func (db Database) matchDebian(packages []Package, base metadata.Base) []Finding {
+	codename := base["version_codename"]
+
+	var findings []Finding
+	for _, pkg := range packages {
+		if pkg.Ecosystem != Debian {
+			continue
+		}
+
+		issues := db.debian[pkg.SourceName]
+		var ids []string
+		for id := range issues {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			status, ok := issues[id].Releases[codename]
+			if !ok {
+				continue
+			}
+
+			fixed := ""
+			switch status.Status {
+			case "resolved":
+				// a fixed version of 0 means the release was never affected
+				if status.FixedVersion == "" || status.FixedVersion == "0" || CompareDpkg(pkg.SourceVersion, status.FixedVersion) >= 0 {
+					continue
+				}
+				fixed = status.FixedVersion
+			case "open", "undetermined":
+			default:
+				continue
+			}
+
+			findings = append(findings, Finding{
+				ID:           id,
+				Source:       DebianSource,
+				Type:         pkg.Type,
+				Package:      pkg.Name,
+				Version:      pkg.Version,
+				FixedVersion: fixed,
+				Severity:     ParseSeverity(status.Urgency),
+				Summary:      firstLine(issues[id].Description),
+			})
+		}
+	}
+	return findings
+}
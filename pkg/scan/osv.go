@@ -0,0 +1,276 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// OSVSource names the findings of the OSV advisories
+const OSVSource = "osv"
+
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+type osvVulnerability struct {
+	ID               string                 `json:"id"`
+	Aliases          []string               `json:"aliases"`
+	Summary          string                 `json:"summary"`
+	Details          string                 `json:"details"`
+	Withdrawn        string                 `json:"withdrawn"`
+	Severity         []osvSeverity          `json:"severity"`
+	Affected         []osvAffected          `json:"affected"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges            []osvRange             `json:"ranges"`
+	Versions          []string               `json:"versions"`
+	Severity          []osvSeverity          `json:"severity"`
+	EcosystemSpecific map[string]interface{} `json:"ecosystem_specific"`
+	DatabaseSpecific  map[string]interface{} `json:"database_specific"`
+}
+
+type osvRange struct {
+	Type   string     `json:"type"`
+	Events []osvEvent `json:"events"`
+}
+
+type osvEvent struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+}
+
+// osvEntry is a package affected by an advisory, in the release of the
+// distribution named after its ecosystem, if any
+type osvEntry struct {
+	vulnerability *osvVulnerability
+	affected      osvAffected
+	release       string
+}
+
+// parseOSV reads an OSV record, or an array of them
+func parseOSV(content []byte) ([]osvVulnerability, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		var vulnerabilities []osvVulnerability
+		err := json.Unmarshal(content, &vulnerabilities)
+		return vulnerabilities, err
+	}
+
+	var vulnerability osvVulnerability
+	err := json.Unmarshal(content, &vulnerability)
+	return []osvVulnerability{vulnerability}, err
+}
+
+func (db *Database) addOSV(vulnerabilities []osvVulnerability) {
+	for i := range vulnerabilities {
+		vulnerability := &vulnerabilities[i]
+		if vulnerability.Withdrawn != "" {
+			continue
+		}
+
+		for _, affected := range vulnerability.Affected {
+			ecosystem, release := splitEcosystem(affected.Package.Ecosystem)
+			key := osvKey(ecosystem, affected.Package.Name)
+			db.osv[key] = append(db.osv[key], osvEntry{vulnerability: vulnerability, affected: affected, release: release})
+		}
+	}
+}
+
+func (db Database) matchOSV(packages []Package, base metadata.Base) []Finding {
+	var findings []Finding
+	for _, pkg := range packages {
+		// the advisories of the distributions are about source packages
+		name, version := pkg.Name, pkg.Version
+		if pkg.SourceName != "" {
+			name, version = pkg.SourceName, pkg.SourceVersion
+		}
+
+		compare := Comparator(pkg.Ecosystem)
+		for _, entry := range db.osv[osvKey(pkg.Ecosystem, name)] {
+			if entry.release != "" && !releaseMatches(pkg.Ecosystem, entry.release, base) {
+				continue
+			}
+
+			affected, fixed := entry.affects(version, compare)
+			if !affected {
+				continue
+			}
+
+			summary := entry.vulnerability.Summary
+			if summary == "" {
+				summary = firstLine(entry.vulnerability.Details)
+			}
+			findings = append(findings, Finding{
+				ID:           entry.vulnerability.ID,
+				Aliases:      entry.vulnerability.Aliases,
+				Source:       OSVSource,
+				Type:         pkg.Type,
+				Package:      pkg.Name,
+				Version:      pkg.Version,
+				FixedVersion: fixed,
+				Severity:     entry.severity(),
+				Summary:      summary,
+			})
+		}
+	}
+	return findings
+}
+
+// affects tells whether the version is one of the affected versions or in
+// one of the affected ranges, and the version fixing it, if any
+func (e osvEntry) affects(version string, compare CompareFunc) (bool, string) {
+	for _, v := range e.affected.Versions {
+		if v == version {
+			return true, ""
+		}
+	}
+
+	for _, r := range e.affected.Ranges {
+		rangeCompare := compare
+		switch r.Type {
+		case "SEMVER":
+			rangeCompare = CompareSemver
+		case "ECOSYSTEM":
+		default:
+			continue
+		}
+
+		if affected, fixed := rangeAffects(r.Events, version, rangeCompare); affected {
+			return true, fixed
+		}
+	}
+	return false, ""
+}
+
+// rangeAffects walks the events of the range in version order, the version
+// being affected from an introduced event until a fixed event or past a last
+// affected event
+func rangeAffects(events []osvEvent, version string, compare CompareFunc) (bool, string) {
+	sorted := append([]osvEvent{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Introduced == "0" || sorted[j].Introduced == "0" {
+			return sorted[i].Introduced == "0" && sorted[j].Introduced != "0"
+		}
+		return compare(sorted[i].version(), sorted[j].version()) < 0
+	})
+
+	affected, fixed := false, ""
+	for _, event := range sorted {
+		switch {
+		case event.Introduced != "":
+			if event.Introduced == "0" || compare(version, event.Introduced) >= 0 {
+				affected = true
+			}
+		case event.Fixed != "":
+			if compare(version, event.Fixed) >= 0 {
+				affected = false
+			} else if affected && fixed == "" {
+				fixed = event.Fixed
+			}
+		case event.LastAffected != "":
+			if compare(version, event.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+
+	if !affected {
+		return false, ""
+	}
+	return true, fixed
+}
+
+func (e osvEvent) version() string {
+	switch {
+	case e.Introduced != "":
+		return e.Introduced
+	case e.Fixed != "":
+		return e.Fixed
+	default:
+		return e.LastAffected
+	}
+}
+
+// severity reads the severity given by the database of the advisory, or else
+// rates its CVSS v3 vector
+func (e osvEntry) severity() Severity {
+	for _, fields := range []map[string]interface{}{
+		e.affected.EcosystemSpecific,
+		e.affected.DatabaseSpecific,
+		e.vulnerability.DatabaseSpecific,
+	} {
+		for _, key := range []string{"severity", "urgency", "priority"} {
+			if s, ok := fields[key].(string); ok && ParseSeverity(s) != Unknown {
+				return ParseSeverity(s)
+			}
+		}
+	}
+
+	for _, severity := range append(append([]osvSeverity{}, e.affected.Severity...), e.vulnerability.Severity...) {
+		if severity.Type == "CVSS_V3" {
+			return CVSS3Severity(severity.Score)
+		}
+	}
+	return Unknown
+}
+
+// splitEcosystem splits the release of the distribution from the name of its
+// ecosystem, e.g. Debian:10 or Alpine:v3.16
+func splitEcosystem(ecosystem string) (string, string) {
+	parts := strings.SplitN(ecosystem, ":", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// releaseMatches tells whether the release of an ecosystem is the one of the
+// os-release of the image, e.g. 10 for debian, v3.16 for alpine 3.16.2 and
+// 20.04:LTS for ubuntu
+func releaseMatches(ecosystem, release string, base metadata.Base) bool {
+	versionID := base["version_id"]
+	if versionID == "" || versionID == "unknown" {
+		return false
+	}
+
+	parts := strings.Split(versionID, ".")
+	switch ecosystem {
+	case Debian:
+		return release == parts[0]
+	case Alpine:
+		return len(parts) > 1 && release == "v"+parts[0]+"."+parts[1]
+	case Ubuntu:
+		return release == versionID || strings.HasPrefix(release, versionID+":")
+	default:
+		// e.g. enterprise_linux:8::baseos for red hat
+		return strings.Contains(":"+release+":", ":"+parts[0]+":")
+	}
+}
+
+func osvKey(ecosystem, name string) string {
+	if ecosystem == PyPI {
+		name = strings.ToLower(pythonNameSeparators.ReplaceAllString(name, "-"))
+	}
+	return ecosystem + "\x00" + name
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
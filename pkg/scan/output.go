@@ -0,0 +1,29 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteTable writes the findings one per line, in aligned columns
+func WriteTable(w io.Writer, r Report) error {
+	if len(r.Findings) == 0 {
+		_, err := fmt.Fprintln(w, "no vulnerabilities found")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SEVERITY\tID\tPACKAGE\tVERSION\tFIXED IN\tTYPE")
+	for _, finding := range r.Findings {
+		fixed := finding.FixedVersion
+		if fixed == "" {
+			fixed = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", finding.Severity, finding.ID, finding.Package, finding.Version, fixed, finding.Type)
+	}
+	return tw.Flush()
+}
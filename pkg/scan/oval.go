@@ -0,0 +1,337 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// OVALSource names the findings of the OVAL definitions
+const OVALSource = "oval"
+
+// ovalDefinitions holds the definitions of an OVAL file, along with the rpm
+// tests, objects and states they refer to
+type ovalDefinitions struct {
+	Definitions []ovalDefinition `xml:"definitions>definition"`
+	Tests       []ovalTest       `xml:"tests>rpminfo_test"`
+	Objects     []ovalObject     `xml:"objects>rpminfo_object"`
+	States      []ovalState      `xml:"states>rpminfo_state"`
+
+	definitions map[string]*ovalDefinition
+	tests       map[string]*ovalTest
+	objects     map[string]*ovalObject
+	states      map[string]*ovalState
+}
+
+type ovalDefinition struct {
+	ID          string          `xml:"id,attr"`
+	Class       string          `xml:"class,attr"`
+	Title       string          `xml:"metadata>title"`
+	Description string          `xml:"metadata>description"`
+	References  []ovalReference `xml:"metadata>reference"`
+	Severity    string          `xml:"metadata>advisory>severity"`
+	Criteria    ovalCriteria    `xml:"criteria"`
+}
+
+type ovalReference struct {
+	ID     string `xml:"ref_id,attr"`
+	Source string `xml:"source,attr"`
+}
+
+type ovalCriteria struct {
+	Operator          string               `xml:"operator,attr"`
+	Negate            bool                 `xml:"negate,attr"`
+	Criteria          []ovalCriteria       `xml:"criteria"`
+	Criterions        []ovalCriterion      `xml:"criterion"`
+	ExtendDefinitions []ovalExtendCriteria `xml:"extend_definition"`
+}
+
+type ovalCriterion struct {
+	TestRef string `xml:"test_ref,attr"`
+	Negate  bool   `xml:"negate,attr"`
+}
+
+type ovalExtendCriteria struct {
+	DefinitionRef string `xml:"definition_ref,attr"`
+	Negate        bool   `xml:"negate,attr"`
+}
+
+type ovalTest struct {
+	ID     string `xml:"id,attr"`
+	Check  string `xml:"check,attr"`
+	Object struct {
+		Ref string `xml:"object_ref,attr"`
+	} `xml:"object"`
+	States []struct {
+		Ref string `xml:"state_ref,attr"`
+	} `xml:"state"`
+}
+
+type ovalObject struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name"`
+}
+
+type ovalState struct {
+	ID             string     `xml:"id,attr"`
+	EVR            *ovalValue `xml:"evr"`
+	Version        *ovalValue `xml:"version"`
+	Arch           *ovalValue `xml:"arch"`
+	SignatureKeyID *ovalValue `xml:"signature_keyid"`
+}
+
+type ovalValue struct {
+	Operation string `xml:"operation,attr"`
+	Value     string `xml:",chardata"`
+}
+
+// ovalMatch is an installed package older than the version fixing it
+type ovalMatch struct {
+	pkg   Package
+	fixed string
+}
+
+func parseOVAL(content []byte) (*ovalDefinitions, error) {
+	var oval ovalDefinitions
+	if err := xml.Unmarshal(content, &oval); err != nil {
+		return nil, err
+	}
+
+	oval.definitions = map[string]*ovalDefinition{}
+	for i := range oval.Definitions {
+		oval.definitions[oval.Definitions[i].ID] = &oval.Definitions[i]
+	}
+	oval.tests = map[string]*ovalTest{}
+	for i := range oval.Tests {
+		oval.tests[oval.Tests[i].ID] = &oval.Tests[i]
+	}
+	oval.objects = map[string]*ovalObject{}
+	for i := range oval.Objects {
+		oval.objects[oval.Objects[i].ID] = &oval.Objects[i]
+	}
+	oval.states = map[string]*ovalState{}
+	for i := range oval.States {
+		oval.states[oval.States[i].ID] = &oval.States[i]
+	}
+	return &oval, nil
+}
+
+// matchOVAL reports the patch and vulnerability definitions whose criteria
+// hold for the installed rpm packages. Signature key ids cannot be checked
+// from the metadata, so they are assumed to match.
+func (db Database) matchOVAL(packages []Package) []Finding {
+	installed := map[string][]Package{}
+	for _, pkg := range packages {
+		if pkg.Type == metadata.RPMPackageListSourceType {
+			installed[pkg.Name] = append(installed[pkg.Name], pkg)
+		}
+	}
+	if len(installed) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, oval := range db.oval {
+		for _, definition := range oval.Definitions {
+			if definition.Class != "patch" && definition.Class != "vulnerability" {
+				continue
+			}
+
+			holds, matches := oval.evaluate(definition.Criteria, installed, 0)
+			if !holds {
+				continue
+			}
+
+			id, aliases := definition.ID, []string(nil)
+			for _, reference := range definition.References {
+				switch {
+				case reference.Source == "CVE":
+					aliases = append(aliases, reference.ID)
+				case id == definition.ID && reference.Source != "":
+					id = reference.ID
+				}
+			}
+
+			for _, match := range matches {
+				findings = append(findings, Finding{
+					ID:           id,
+					Aliases:      aliases,
+					Source:       OVALSource,
+					Type:         match.pkg.Type,
+					Package:      match.pkg.Name,
+					Version:      match.pkg.Version,
+					FixedVersion: match.fixed,
+					Severity:     ParseSeverity(definition.Severity),
+					Summary:      strings.TrimSpace(definition.Title),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// maxOVALDepth bounds the definitions extending each other
+const maxOVALDepth = 16
+
+// evaluate tells whether the criteria hold, with the packages found older
+// than their fixed version by the tests which hold
+func (o *ovalDefinitions) evaluate(criteria ovalCriteria, installed map[string][]Package, depth int) (bool, []ovalMatch) {
+	if depth > maxOVALDepth {
+		return false, nil
+	}
+
+	type result struct {
+		holds   bool
+		matches []ovalMatch
+	}
+	var results []result
+
+	for _, c := range criteria.Criteria {
+		holds, matches := o.evaluate(c, installed, depth+1)
+		results = append(results, result{holds, matches})
+	}
+	for _, c := range criteria.Criterions {
+		holds, matches := o.test(c.TestRef, installed)
+		if c.Negate {
+			holds, matches = !holds, nil
+		}
+		results = append(results, result{holds, matches})
+	}
+	for _, c := range criteria.ExtendDefinitions {
+		holds, matches := false, []ovalMatch(nil)
+		if definition, ok := o.definitions[c.DefinitionRef]; ok {
+			holds, matches = o.evaluate(definition.Criteria, installed, depth+1)
+		}
+		if c.Negate {
+			holds, matches = !holds, nil
+		}
+		results = append(results, result{holds, matches})
+	}
+
+	or := strings.EqualFold(criteria.Operator, "OR")
+	holds := !or
+	var matches []ovalMatch
+	for _, r := range results {
+		if or && r.holds {
+			holds = true
+			matches = append(matches, r.matches...)
+		}
+		if !or {
+			holds = holds && r.holds
+			matches = append(matches, r.matches...)
+		}
+	}
+
+	if criteria.Negate {
+		return !holds, nil
+	}
+	if !holds {
+		return false, nil
+	}
+	return true, matches
+}
+
+// test evaluates an rpminfo test against the installed packages named by its
+// object, by default holding when at least one of them has all its states
+func (o *ovalDefinitions) test(ref string, installed map[string][]Package) (bool, []ovalMatch) {
+	test, ok := o.tests[ref]
+	if !ok {
+		return false, nil
+	}
+	object, ok := o.objects[test.Object.Ref]
+	if !ok {
+		return false, nil
+	}
+
+	var states []*ovalState
+	for _, s := range test.States {
+		if state, ok := o.states[s.Ref]; ok {
+			states = append(states, state)
+		}
+	}
+
+	var matches []ovalMatch
+	satisfied := 0
+	for _, pkg := range installed[object.Name] {
+		fixed, ok := satisfies(pkg, states)
+		if !ok {
+			continue
+		}
+		satisfied++
+		if fixed != "" {
+			matches = append(matches, ovalMatch{pkg: pkg, fixed: fixed})
+		}
+	}
+
+	switch test.Check {
+	case "all":
+		return satisfied > 0 && satisfied == len(installed[object.Name]), matches
+	case "none satisfy":
+		return satisfied == 0, nil
+	default:
+		return satisfied > 0, matches
+	}
+}
+
+// satisfies tells whether the package has all the states, and the version
+// fixing it when one of them requires a lower version
+func satisfies(pkg Package, states []*ovalState) (string, bool) {
+	fixed := ""
+	for _, state := range states {
+		if state.EVR != nil {
+			// the metadata does not record the epochs
+			_, version, release := splitRpmVersion(state.EVR.Value)
+			evr := version
+			if release != "" {
+				evr += "-" + release
+			}
+			if !compareOperation(state.EVR.Operation, CompareRpm(pkg.Version, evr)) {
+				return "", false
+			}
+			if strings.HasPrefix(state.EVR.Operation, "less than") {
+				fixed = state.EVR.Value
+			}
+		}
+		if state.Version != nil && !matchValue(state.Version, strings.SplitN(pkg.Version, "-", 2)[0]) {
+			return "", false
+		}
+		if state.Arch != nil && !matchValue(state.Arch, pkg.Architecture) {
+			return "", false
+		}
+	}
+	return fixed, true
+}
+
+func compareOperation(operation string, c int) bool {
+	switch operation {
+	case "less than":
+		return c < 0
+	case "less than or equal":
+		return c <= 0
+	case "greater than":
+		return c > 0
+	case "greater than or equal":
+		return c >= 0
+	case "not equal":
+		return c != 0
+	default:
+		return c == 0
+	}
+}
+
+func matchValue(value *ovalValue, s string) bool {
+	switch value.Operation {
+	case "pattern match":
+		pattern, err := regexp.Compile(value.Value)
+		return err == nil && pattern.MatchString(s)
+	case "not equal":
+		return s != value.Value
+	default:
+		return s == value.Value
+	}
+}
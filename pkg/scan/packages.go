@@ -0,0 +1,187 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// Package is an installed package, named and versioned the way the
+// advisories of its ecosystem refer to it
+type Package struct {
+	// Type is the type of the dependency which lists the package
+	Type         string
+	Ecosystem    string
+	Name         string
+	Version      string
+	Architecture string
+	// SourceName and SourceVersion are the source package of debian and
+	// alpine packages, which their advisories are about
+	SourceName    string
+	SourceVersion string
+	// Licenses are the licenses declared by the package, when recorded
+	Licenses []string
+}
+
+// distroEcosystems are the ecosystems of the rpm packages, by the id of the
+// os-release of the image
+var distroEcosystems = map[string]string{
+	"rhel":      RedHat,
+	"centos":    RedHat,
+	"rocky":     RockyLinux,
+	"almalinux": AlmaLinux,
+}
+
+// Packages lists the packages of the package lists and go binaries of the
+// metadata, of both the base image and the application
+func Packages(md metadata.Metadata) ([]Package, error) {
+	var packages []Package
+	goModules := map[string]bool{}
+
+	for _, dependency := range md.AllDependencies() {
+		switch dependency.Type {
+		case metadata.DebianPackageListSourceType:
+			var sourceMetadata metadata.DebianPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			ecosystem := Debian
+			if md.Base["id"] == "ubuntu" {
+				ecosystem = Ubuntu
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				p := Package{
+					Type:          dependency.Type,
+					Ecosystem:     ecosystem,
+					Name:          pkg.Package,
+					Version:       pkg.Version,
+					Architecture:  pkg.Architecture,
+					SourceName:    pkg.Source.Package,
+					SourceVersion: pkg.Source.Version,
+					Licenses:      pkg.Licenses,
+				}
+				if p.SourceName == "" {
+					p.SourceName = p.Name
+				}
+				if p.SourceVersion == "" {
+					p.SourceVersion = p.Version
+				}
+				packages = append(packages, p)
+			}
+		case metadata.RPMPackageListSourceType:
+			var sourceMetadata metadata.RpmPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			ecosystem, ok := distroEcosystems[md.Base["id"]]
+			if !ok {
+				ecosystem = RedHat
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				packages = append(packages, Package{
+					Type:         dependency.Type,
+					Ecosystem:    ecosystem,
+					Name:         pkg.Package,
+					Version:      rpmVersion(pkg),
+					Architecture: pkg.Architecture,
+					Licenses:     licenses(pkg.License),
+				})
+			}
+		case metadata.ApkPackageListSourceType:
+			var sourceMetadata metadata.ApkPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				p := Package{
+					Type:          dependency.Type,
+					Ecosystem:     Alpine,
+					Name:          pkg.Package,
+					Version:       pkg.Version,
+					Architecture:  pkg.Architecture,
+					SourceName:    pkg.Origin,
+					SourceVersion: pkg.Version,
+					Licenses:      licenses(pkg.License),
+				}
+				if p.SourceName == "" {
+					p.SourceName = p.Name
+				}
+				packages = append(packages, p)
+			}
+		case metadata.PythonPackageListSourceType:
+			var sourceMetadata metadata.PythonPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				packages = append(packages, Package{Type: dependency.Type, Ecosystem: PyPI, Name: pkg.Package, Version: pkg.Version, Licenses: licenses(pkg.License)})
+			}
+		case metadata.NpmPackageListSourceType:
+			var sourceMetadata metadata.NpmPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				packages = append(packages, Package{Type: dependency.Type, Ecosystem: Npm, Name: pkg.Package, Version: pkg.Version, Licenses: licenses(pkg.License)})
+			}
+		case metadata.MavenPackageListSourceType:
+			var sourceMetadata metadata.MavenPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				// the advisories name maven packages by group and artifact
+				if pkg.GroupID == "" {
+					continue
+				}
+				packages = append(packages, Package{Type: dependency.Type, Ecosystem: Maven, Name: pkg.GroupID + ":" + pkg.ArtifactID, Version: pkg.Version})
+			}
+		case metadata.GoBinariesSourceType:
+			var sourceMetadata metadata.GoBinariesSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return nil, err
+			}
+			for _, binary := range sourceMetadata.Binaries {
+				modules := []metadata.GoModule{{Path: "stdlib", Version: strings.TrimPrefix(binary.GoVersion, "go")}}
+				for _, m := range append([]metadata.GoModule{binary.Module}, binary.Modules...) {
+					modules = append(modules, m.Resolved())
+				}
+
+				for _, m := range modules {
+					key := m.Path + "@" + m.Version
+					// the main module of a binary built from its source tree
+					// has no version
+					if m.Path == "" || m.Version == "" || m.Version == "(devel)" || goModules[key] {
+						continue
+					}
+					goModules[key] = true
+					packages = append(packages, Package{Type: dependency.Type, Ecosystem: Go, Name: m.Path, Version: m.Version})
+				}
+			}
+		}
+	}
+
+	return packages, nil
+}
+
+func licenses(license string) []string {
+	if license == "" {
+		return nil
+	}
+	return []string{license}
+}
+
+// rpmVersion appends to the version of the package its release, read from
+// the name of its source rpm, name-version-release.src.rpm, when built from
+// the same version. The label does not record the epoch.
+func rpmVersion(pkg metadata.RpmPackage) string {
+	name := strings.TrimSuffix(pkg.SourceRpm, ".src.rpm")
+	i := strings.LastIndex(name, "-")
+	if i < 0 || !strings.HasSuffix(name[:i], "-"+pkg.Version) {
+		return pkg.Version
+	}
+	return pkg.Version + "-" + name[i+1:]
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Report lists the vulnerabilities found in the packages of an image
+type Report struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Finding is an advisory affecting an installed package
+type Finding struct {
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Source is the kind of database of the advisory, osv, debian or oval
+	Source string `json:"source"`
+	// Type is the type of the dependency which lists the package
+	Type         string   `json:"type"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version"`
+	FixedVersion string   `json:"fixed_version,omitempty"`
+	Severity     Severity `json:"severity"`
+	Summary      string   `json:"summary,omitempty"`
+}
+
+// Scan matches the packages of the metadata against the advisories of the
+// database, comparing their versions with the rules of their ecosystem. The
+// findings are sorted from the most severe.
+func Scan(md metadata.Metadata, db Database) (Report, error) {
+	packages, err := Packages(md)
+	if err != nil {
+		return Report{}, fmt.Errorf("could not read the packages of the metadata: %w", err)
+	}
+
+	var findings []Finding
+	findings = append(findings, db.matchOSV(packages, md.Base)...)
+	findings = append(findings, db.matchDebian(packages, md.Base)...)
+	findings = append(findings, db.matchOVAL(packages)...)
+
+	findings = deduplicate(findings)
+
+	collator := collate.New(language.BritishEnglish)
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity.Rank() > findings[j].Severity.Rank()
+		}
+		if c := collator.CompareString(findings[i].Package, findings[j].Package); c != 0 {
+			return c < 0
+		}
+		return findings[i].ID < findings[j].ID
+	})
+
+	return Report{Findings: findings}, nil
+}
+
+// AtLeast returns the findings of the given severity or higher
+func (r Report) AtLeast(threshold Severity) []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if finding.Severity.Rank() >= threshold.Rank() {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// deduplicate drops the findings of an advisory already found for the same
+// package, and the ones whose id is an alias of another finding, e.g. a CVE
+// of the debian security tracker also reported by a DSA in the OSV records
+func deduplicate(findings []Finding) []Finding {
+	aliased := map[string]bool{}
+	for _, finding := range findings {
+		for _, alias := range finding.Aliases {
+			aliased[findingKey(finding, alias)] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	deduplicated := []Finding{}
+	for _, finding := range findings {
+		key := findingKey(finding, finding.ID)
+		if seen[key] || (aliased[key] && len(finding.Aliases) == 0) {
+			continue
+		}
+		seen[key] = true
+		deduplicated = append(deduplicated, finding)
+	}
+	return deduplicated
+}
+
+func findingKey(finding Finding, id string) string {
+	return finding.Type + "\x00" + finding.Package + "\x00" + finding.Version + "\x00" + id
+}
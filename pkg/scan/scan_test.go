@@ -0,0 +1,230 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+)
+
+const advisories = "../../test/integration/assets/advisories"
+
+var _ = Describe("Scan", func() {
+	var db scan.Database
+
+	BeforeEach(func() {
+		var err error
+		db, err = scan.LoadDatabase(advisories)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("matches apk packages against the OSV records of their alpine release", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Base: metadata.Base{"id": "alpine", "version_id": "3.11.6"},
+			Dependencies: []metadata.Dependency{packageList(metadata.ApkPackageListSourceType, metadata.ApkPackageListSourceMetadata{
+				Packages: []metadata.ApkPackage{
+					{Package: "musl", Version: "1.1.24-r2", Origin: "musl"},
+					{Package: "zlib", Version: "1.2.11-r3", Origin: "zlib"},
+				},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(Equal([]scan.Finding{{
+			ID:           "CVE-2018-25032",
+			Source:       scan.OSVSource,
+			Type:         metadata.ApkPackageListSourceType,
+			Package:      "zlib",
+			Version:      "1.2.11-r3",
+			FixedVersion: "1.2.11-r4",
+			Severity:     scan.High,
+			Summary:      "zlib before 1.2.12 allows memory corruption when deflating if the input has many distant matches.",
+		}}))
+	})
+
+	It("does not match the advisories of another release", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Base: metadata.Base{"id": "alpine", "version_id": "3.13.5"},
+			Dependencies: []metadata.Dependency{packageList(metadata.ApkPackageListSourceType, metadata.ApkPackageListSourceMetadata{
+				Packages: []metadata.ApkPackage{{Package: "zlib", Version: "1.2.11-r3", Origin: "zlib"}},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(BeEmpty())
+	})
+
+	It("matches debian source packages against the security tracker of their release", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Base: metadata.Base{"id": "debian", "version_id": "11", "version_codename": "bullseye"},
+			Dependencies: []metadata.Dependency{packageList(metadata.DebianPackageListSourceType, metadata.DebianPackageListSourceMetadata{
+				Packages: []metadata.DpkgPackage{
+					{Package: "bash", Version: "5.1-2", Source: metadata.PackageSource{Package: "bash", Version: "5.1-2"}},
+					{Package: "libssl1.1", Version: "1.1.1k-1+deb11u1", Source: metadata.PackageSource{Package: "openssl", Version: "1.1.1k-1+deb11u1"}},
+				},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(Equal([]scan.Finding{
+			{
+				ID:           "DSA-5103-1",
+				Aliases:      []string{"CVE-2022-0778"},
+				Source:       scan.OSVSource,
+				Type:         metadata.DebianPackageListSourceType,
+				Package:      "libssl1.1",
+				Version:      "1.1.1k-1+deb11u1",
+				FixedVersion: "1.1.1n-0+deb11u1",
+				Severity:     scan.High,
+				Summary:      "openssl - security update",
+			},
+			{
+				ID:       "CVE-2007-6755",
+				Source:   scan.DebianSource,
+				Type:     metadata.DebianPackageListSourceType,
+				Package:  "libssl1.1",
+				Version:  "1.1.1k-1+deb11u1",
+				Severity: scan.Negligible,
+				Summary:  "The NIST SP 800-90A default statement of the Dual Elliptic Curve Deterministic Random Bit Generation algorithm contains point Q constants with a possible relationship to certain secret values.",
+			},
+		}))
+	})
+
+	It("evaluates the OVAL definitions against the rpm packages", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Base: metadata.Base{"id": "rhel", "version_id": "8.5"},
+			Dependencies: []metadata.Dependency{packageList(metadata.RPMPackageListSourceType, metadata.RpmPackageListSourceMetadata{
+				Packages: []metadata.RpmPackage{
+					{Package: "bash", Version: "4.4.19", Architecture: "x86_64", SourceRpm: "bash-4.4.19-14.el8.src.rpm"},
+					{Package: "openssl-libs", Version: "1.1.1k", Architecture: "x86_64", SourceRpm: "openssl-1.1.1k-4.el8.src.rpm"},
+					{Package: "redhat-release", Version: "8.5", Architecture: "x86_64", SourceRpm: "redhat-release-8.5-0.8.el8.src.rpm"},
+				},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(Equal([]scan.Finding{{
+			ID:           "RHSA-2022:0065",
+			Aliases:      []string{"CVE-2021-3712"},
+			Source:       scan.OVALSource,
+			Type:         metadata.RPMPackageListSourceType,
+			Package:      "openssl-libs",
+			Version:      "1.1.1k-4.el8",
+			FixedVersion: "1:1.1.1k-5.el8_5",
+			Severity:     scan.High,
+			Summary:      "RHSA-2022:0065: openssl security update (Important)",
+		}}))
+	})
+
+	It("does not evaluate the OVAL definitions of another release", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Dependencies: []metadata.Dependency{packageList(metadata.RPMPackageListSourceType, metadata.RpmPackageListSourceMetadata{
+				Packages: []metadata.RpmPackage{
+					{Package: "bash", Version: "4.4.19", Architecture: "x86_64", SourceRpm: "bash-4.4.19-7.el8.src.rpm"},
+					{Package: "redhat-release", Version: "9.0", Architecture: "x86_64", SourceRpm: "redhat-release-9.0-2.17.el9.src.rpm"},
+				},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(BeEmpty())
+	})
+
+	It("matches python packages against the OSV archives, by normalized name", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Dependencies: []metadata.Dependency{packageList(metadata.PythonPackageListSourceType, metadata.PythonPackageListSourceMetadata{
+				Packages: []metadata.PythonPackage{
+					{Package: "Requests", Version: "2.19.1"},
+					{Package: "urllib3", Version: "1.26.5"},
+				},
+			})},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Findings).To(HaveLen(1))
+		Expect(report.Findings[0].ID).To(Equal("PYSEC-2018-28"))
+		Expect(report.Findings[0].Package).To(Equal("Requests"))
+		Expect(report.Findings[0].FixedVersion).To(Equal("2.20.0"))
+		Expect(report.Findings[0].Severity).To(Equal(scan.High))
+	})
+
+	It("sorts the findings from the most severe and filters them by severity", func() {
+		report, err := scan.Scan(metadata.Metadata{
+			Base: metadata.Base{"id": "debian", "version_id": "11", "version_codename": "bullseye"},
+			Dependencies: []metadata.Dependency{
+				packageList(metadata.DebianPackageListSourceType, metadata.DebianPackageListSourceMetadata{
+					Packages: []metadata.DpkgPackage{{Package: "openssl", Version: "1.1.1k-1+deb11u1"}},
+				}),
+				packageList(metadata.PythonPackageListSourceType, metadata.PythonPackageListSourceMetadata{
+					Packages: []metadata.PythonPackage{{Package: "requests", Version: "2.19.1"}},
+				}),
+			},
+		}, db)
+		Expect(err).ToNot(HaveOccurred())
+
+		var ids []string
+		for _, finding := range report.Findings {
+			ids = append(ids, finding.ID)
+		}
+		Expect(ids).To(Equal([]string{"DSA-5103-1", "PYSEC-2018-28", "CVE-2007-6755"}))
+
+		Expect(report.AtLeast(scan.High)).To(HaveLen(2))
+		Expect(report.AtLeast(scan.Critical)).To(BeEmpty())
+	})
+
+	It("prints the findings as a table", func() {
+		buffer := &bytes.Buffer{}
+		Expect(scan.WriteTable(buffer, scan.Report{Findings: []scan.Finding{{
+			ID:       "CVE-2007-6755",
+			Type:     metadata.DebianPackageListSourceType,
+			Package:  "openssl",
+			Version:  "1.1.1k-1+deb11u1",
+			Severity: scan.Negligible,
+		}}})).To(Succeed())
+
+		Expect(buffer.String()).To(Equal(
+			"SEVERITY    ID             PACKAGE  VERSION           FIXED IN  TYPE\n" +
+				"negligible  CVE-2007-6755  openssl  1.1.1k-1+deb11u1  -         debian_package_list\n"))
+	})
+
+	It("prints that no vulnerability was found", func() {
+		buffer := &bytes.Buffer{}
+		Expect(scan.WriteTable(buffer, scan.Report{})).To(Succeed())
+
+		Expect(buffer.String()).To(Equal("no vulnerabilities found\n"))
+	})
+})
+
+var _ = Describe("LoadDatabase", func() {
+	It("fails when the directory does not exist", func() {
+		_, err := scan.LoadDatabase("does-not-exist")
+		Expect(err).To(MatchError(ContainSubstring("could not find advisory database")))
+	})
+
+	It("fails when the directory has no advisories", func() {
+		dir, err := ioutil.TempDir("", "deplab-scan-")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		_, err = scan.LoadDatabase(dir)
+		Expect(err).To(MatchError(ContainSubstring("could not find any advisories")))
+	})
+})
+
+func packageList(dependencyType string, sourceMetadata interface{}) metadata.Dependency {
+	return metadata.Dependency{
+		Type: dependencyType,
+		Source: metadata.Source{
+			Type:     "inline",
+			Metadata: sourceMetadata,
+		},
+	}
+}
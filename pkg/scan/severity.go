@@ -0,0 +1,145 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"math"
+	"strings"
+)
+
+// Severity of a vulnerability, from the lowest to the highest
+type Severity string
+
+const (
+	Unknown    Severity = "unknown"
+	Negligible Severity = "negligible"
+	Low        Severity = "low"
+	Medium     Severity = "medium"
+	High       Severity = "high"
+	Critical   Severity = "critical"
+)
+
+// Severities are the severities, in increasing order
+var Severities = []Severity{Unknown, Negligible, Low, Medium, High, Critical}
+
+// Rank orders the severities, an unknown severity ranking the lowest
+func (s Severity) Rank() int {
+	for i, severity := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return 0
+}
+
+// ParseSeverity reads the severities, urgencies and impacts of the advisory
+// databases, e.g. the moderate severity of GitHub or the important impact of
+// Red Hat
+func ParseSeverity(s string) Severity {
+	switch strings.ToLower(strings.TrimRight(strings.TrimSpace(s), "*")) {
+	case "negligible", "unimportant", "none":
+		return Negligible
+	case "low":
+		return Low
+	case "medium", "moderate":
+		return Medium
+	case "high", "important":
+		return High
+	case "critical":
+		return Critical
+	default:
+		return Unknown
+	}
+}
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// CVSS3Severity rates the base score of a CVSS v3 vector, e.g.
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+func CVSS3Severity(vector string) Severity {
+	score, ok := cvss3BaseScore(vector)
+	switch {
+	case !ok:
+		return Unknown
+	case score == 0:
+		return Negligible
+	case score < 4:
+		return Low
+	case score < 7:
+		return Medium
+	case score < 9:
+		return High
+	default:
+		return Critical
+	}
+}
+
+func cvss3BaseScore(vector string) (float64, bool) {
+	if !strings.HasPrefix(vector, "CVSS:3") {
+		return 0, false
+	}
+
+	metrics := map[string]string{}
+	for _, metric := range strings.Split(vector, "/")[1:] {
+		parts := strings.SplitN(metric, ":", 2)
+		if len(parts) == 2 {
+			metrics[parts[0]] = parts[1]
+		}
+	}
+
+	weights := map[string]float64{}
+	for metric, values := range cvss3Weights {
+		weight, ok := values[metrics[metric]]
+		if !ok {
+			return 0, false
+		}
+		weights[metric] = weight
+	}
+
+	changed := metrics["S"] == "C"
+	if !changed && metrics["S"] != "U" {
+		return 0, false
+	}
+
+	privileges := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if changed {
+		privileges = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	privilege, ok := privileges[metrics["PR"]]
+	if !ok {
+		return 0, false
+	}
+	weights["PR"] = privilege
+
+	iss := 1 - (1-weights["C"])*(1-weights["I"])*(1-weights["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * weights["AV"] * weights["AC"] * weights["PR"] * weights["UI"]
+
+	if impact <= 0 {
+		return 0, true
+	}
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp rounds up to one decimal, as defined by the CVSS v3.1 specification
+func roundUp(score float64) float64 {
+	i := int(math.Round(score * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return (math.Floor(float64(i)/10000) + 1) / 10
+}
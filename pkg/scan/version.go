@@ -0,0 +1,592 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CompareFunc compares two versions of an ecosystem, returning a negative
+// number, zero or a positive number when a is lower than, equal to or
+// greater than b
+type CompareFunc func(a, b string) int
+
+// Ecosystems of the packages, named as in the OSV schema
+const (
+	Debian     = "Debian"
+	Ubuntu     = "Ubuntu"
+	Alpine     = "Alpine"
+	RedHat     = "Red Hat"
+	RockyLinux = "Rocky Linux"
+	AlmaLinux  = "AlmaLinux"
+	PyPI       = "PyPI"
+	Npm        = "npm"
+	Maven      = "Maven"
+	Go         = "Go"
+)
+
+// Comparator returns the version comparison rules of the ecosystem
+func Comparator(ecosystem string) CompareFunc {
+	switch ecosystem {
+	case Debian, Ubuntu:
+		return CompareDpkg
+	case Alpine:
+		return CompareApk
+	case RedHat, RockyLinux, AlmaLinux:
+		return CompareRpm
+	case PyPI:
+		return ComparePython
+	case Maven:
+		return CompareMaven
+	default:
+		return CompareSemver
+	}
+}
+
+// CompareDpkg compares debian versions, [epoch:]upstream[-revision], as
+// dpkg --compare-versions does
+func CompareDpkg(a, b string) int {
+	epochA, upstreamA, revisionA := splitDpkgVersion(a)
+	epochB, upstreamB, revisionB := splitDpkgVersion(b)
+
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareDpkgPart(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareDpkgPart(revisionA, revisionB)
+}
+
+func splitDpkgVersion(v string) (int, string, string) {
+	epoch := 0
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(v[:i])
+		v = v[i+1:]
+	}
+
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, revision = v[:i], v[i+1:]
+	}
+	return epoch, v, revision
+}
+
+// compareDpkgPart compares alternating non-digit and digit runs, the tilde
+// sorting before anything, even the end of the part, and letters before
+// other characters
+func compareDpkgPart(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			orderA, orderB := dpkgOrder(a), dpkgOrder(b)
+			if orderA != orderB {
+				return compareInts(orderA, orderB)
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		var digitsA, digitsB string
+		digitsA, a = leadingDigits(a)
+		digitsB, b = leadingDigits(b)
+		if c := compareNumbers(digitsA, digitsB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func dpkgOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case isLetter(s[0]):
+		return int(s[0])
+	case s[0] == '~':
+		return -1
+	default:
+		return int(s[0]) + 256
+	}
+}
+
+// CompareRpm compares rpm versions, [epoch:]version[-release], as rpmvercmp
+// does. The releases are only compared when both versions have one.
+func CompareRpm(a, b string) int {
+	epochA, versionA, releaseA := splitRpmVersion(a)
+	epochB, versionB, releaseB := splitRpmVersion(b)
+
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := compareRpmPart(versionA, versionB); c != 0 {
+		return c
+	}
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+	return compareRpmPart(releaseA, releaseB)
+}
+
+func splitRpmVersion(v string) (int, string, string) {
+	epoch := 0
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(v[:i])
+		v = v[i+1:]
+	}
+
+	release := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, release = v[:i], v[i+1:]
+	}
+	return epoch, v, release
+}
+
+func compareRpmPart(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for {
+		a = strings.TrimLeftFunc(a, isRpmSeparator)
+		b = strings.TrimLeftFunc(b, isRpmSeparator)
+
+		// a tilde sorts before anything, a caret after the end of the part
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		var segmentA, segmentB string
+		if isDigit(a[0]) {
+			segmentA, a = leadingDigits(a)
+			segmentB, b = leadingDigits(b)
+			// a numeric segment is newer than an alphabetic one
+			if segmentB == "" {
+				return 1
+			}
+			if c := compareNumbers(segmentA, segmentB); c != 0 {
+				return c
+			}
+		} else {
+			segmentA, a = leadingLetters(a)
+			segmentB, b = leadingLetters(b)
+			if segmentB == "" {
+				return -1
+			}
+			if c := strings.Compare(segmentA, segmentB); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+func isRpmSeparator(r rune) bool {
+	return r >= 128 || !isDigit(byte(r)) && !isLetter(byte(r)) && r != '~' && r != '^'
+}
+
+var apkVersionPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)*)([a-z]?)((?:_[a-z]+[0-9]*)*)(?:-r([0-9]+))?$`)
+
+var apkSuffixes = map[string]int{
+	"alpha": -4, "beta": -3, "pre": -2, "rc": -1,
+	"cvs": 1, "svn": 2, "git": 3, "hg": 4, "p": 5,
+}
+
+// CompareApk compares alpine versions, made of numbers, an optional letter,
+// pre- and post-release suffixes and the package revision
+func CompareApk(a, b string) int {
+	matchA, matchB := apkVersionPattern.FindStringSubmatch(a), apkVersionPattern.FindStringSubmatch(b)
+	if matchA == nil || matchB == nil {
+		return strings.Compare(a, b)
+	}
+
+	numbersA, numbersB := strings.Split(matchA[1], "."), strings.Split(matchB[1], ".")
+	for i := 0; i < len(numbersA) && i < len(numbersB); i++ {
+		if c := compareNumbers(numbersA[i], numbersB[i]); c != 0 {
+			return c
+		}
+	}
+	if c := compareInts(len(numbersA), len(numbersB)); c != 0 {
+		return c
+	}
+
+	if c := strings.Compare(matchA[2], matchB[2]); c != 0 {
+		return c
+	}
+
+	suffixesA, suffixesB := apkSuffixList(matchA[3]), apkSuffixList(matchB[3])
+	for i := 0; i < len(suffixesA) || i < len(suffixesB); i++ {
+		var suffixA, suffixB [2]int
+		if i < len(suffixesA) {
+			suffixA = suffixesA[i]
+		}
+		if i < len(suffixesB) {
+			suffixB = suffixesB[i]
+		}
+		if c := compareInts(suffixA[0], suffixB[0]); c != 0 {
+			return c
+		}
+		if c := compareInts(suffixA[1], suffixB[1]); c != 0 {
+			return c
+		}
+	}
+
+	return compareNumbers(matchA[4], matchB[4])
+}
+
+// apkSuffixList returns the rank and number of each suffix, the pre-release
+// suffixes ranking below no suffix at all
+func apkSuffixList(s string) [][2]int {
+	var suffixes [][2]int
+	for _, suffix := range strings.Split(s, "_")[1:] {
+		name, digits := leadingLetters(suffix)
+		number, _ := strconv.Atoi(digits)
+		suffixes = append(suffixes, [2]int{apkSuffixes[name], number})
+	}
+	return suffixes
+}
+
+// CompareSemver compares semantic versions, as used by npm and go modules,
+// with an optional v prefix and missing minor and patch numbers being 0
+func CompareSemver(a, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		numberA, numberB := "0", "0"
+		if i < len(coreA) {
+			numberA = coreA[i]
+		}
+		if i < len(coreB) {
+			numberB = coreB[i]
+		}
+		if c := compareIdentifiers(numberA, numberB); c != 0 {
+			return c
+		}
+	}
+
+	// a pre-release is lower than its release
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+
+	identifiersA, identifiersB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(identifiersA) && i < len(identifiersB); i++ {
+		if c := compareIdentifiers(identifiersA[i], identifiersB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(identifiersA), len(identifiersB))
+}
+
+func splitSemver(v string) ([]string, string) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+
+	pre := ""
+	if i := strings.Index(v, "-"); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+	return strings.Split(v, "."), pre
+}
+
+// compareIdentifiers compares numbers numerically and below any other
+// identifier, which are compared lexically
+func compareIdentifiers(a, b string) int {
+	numericA, numericB := isNumber(a), isNumber(b)
+	switch {
+	case numericA && numericB:
+		return compareNumbers(a, b)
+	case numericA:
+		return -1
+	case numericB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+var pythonVersionPattern = regexp.MustCompile(`^v?(?:([0-9]+)!)?([0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?([0-9]*))?` +
+	`(?:-([0-9]+)|[-_.]?(post|rev|r)[-_.]?([0-9]*))?` +
+	`(?:[-_.]?(dev)[-_.]?([0-9]*))?` +
+	`(?:\+[a-z0-9]+(?:[-_.][a-z0-9]+)*)?$`)
+
+// pythonVersion orders a PEP 440 version, the development releases coming
+// before the pre-releases, which come before the release and its
+// post-releases
+type pythonVersion struct {
+	epoch   int
+	release []string
+	// pre is -1 for a development release only, 0 to 2 for alpha, beta and
+	// release candidates and 3 for none
+	pre, preNumber   int
+	post, postNumber int
+	dev, devNumber   int
+}
+
+// ComparePython compares python distribution versions, following PEP 440
+func ComparePython(a, b string) int {
+	versionA, okA := parsePythonVersion(a)
+	versionB, okB := parsePythonVersion(b)
+	if !okA || !okB {
+		return CompareSemver(a, b)
+	}
+
+	if c := compareInts(versionA.epoch, versionB.epoch); c != 0 {
+		return c
+	}
+	for i := 0; i < len(versionA.release) || i < len(versionB.release); i++ {
+		numberA, numberB := "0", "0"
+		if i < len(versionA.release) {
+			numberA = versionA.release[i]
+		}
+		if i < len(versionB.release) {
+			numberB = versionB.release[i]
+		}
+		if c := compareNumbers(numberA, numberB); c != 0 {
+			return c
+		}
+	}
+
+	for _, pair := range [][2]int{
+		{versionA.pre, versionB.pre},
+		{versionA.preNumber, versionB.preNumber},
+		{versionA.post, versionB.post},
+		{versionA.postNumber, versionB.postNumber},
+		{versionA.dev, versionB.dev},
+		{versionA.devNumber, versionB.devNumber},
+	} {
+		if c := compareInts(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func parsePythonVersion(v string) (pythonVersion, bool) {
+	match := pythonVersionPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if match == nil {
+		return pythonVersion{}, false
+	}
+
+	version := pythonVersion{release: strings.Split(match[2], "."), pre: 3, dev: 1}
+	version.epoch, _ = strconv.Atoi(match[1])
+
+	switch match[3] {
+	case "a", "alpha":
+		version.pre = 0
+	case "b", "beta":
+		version.pre = 1
+	case "c", "rc", "pre", "preview":
+		version.pre = 2
+	}
+	version.preNumber, _ = strconv.Atoi(match[4])
+
+	if match[5] != "" || match[6] != "" {
+		version.post = 1
+		version.postNumber, _ = strconv.Atoi(match[5] + match[7])
+	}
+
+	if match[8] != "" {
+		version.dev = 0
+		version.devNumber, _ = strconv.Atoi(match[9])
+		if match[3] == "" && version.post == 0 {
+			version.pre = -1
+		}
+	}
+	return version, true
+}
+
+var mavenQualifiers = map[string]int{
+	"alpha": 0, "beta": 1, "milestone": 2, "rc": 3, "snapshot": 4, "": 5, "sp": 6,
+}
+
+var mavenAliases = map[string]string{
+	"a": "alpha", "b": "beta", "m": "milestone", "cr": "rc",
+	"ga": "", "final": "", "release": "",
+}
+
+// CompareMaven compares maven versions like maven's ComparableVersion does,
+// numbers ranking above the qualifiers, the known qualifiers being ordered
+// from alpha to sp
+func CompareMaven(a, b string) int {
+	itemsA, itemsB := mavenItems(a), mavenItems(b)
+
+	for i := 0; i < len(itemsA) || i < len(itemsB); i++ {
+		var itemA, itemB string
+		if i < len(itemsA) {
+			itemA = itemsA[i]
+		}
+		if i < len(itemsB) {
+			itemB = itemsB[i]
+		}
+
+		if c := compareMavenItems(itemA, itemB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// mavenItems splits the version at dots, hyphens and the transitions between
+// digits and letters, without its trailing zeros and release qualifiers
+func mavenItems(v string) []string {
+	var items []string
+	for _, part := range strings.FieldsFunc(strings.ToLower(v), func(r rune) bool { return r == '.' || r == '-' }) {
+		for part != "" {
+			var item string
+			if isDigit(part[0]) {
+				item, part = leadingDigits(part)
+				item = strings.TrimLeft(item, "0")
+			} else {
+				item, part = leadingNonDigits(part)
+				if alias, ok := mavenAliases[item]; ok {
+					item = alias
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	for len(items) > 0 && items[len(items)-1] == "" {
+		items = items[:len(items)-1]
+	}
+	return items
+}
+
+// compareMavenItems compares two items, the missing ones being empty, an
+// empty item being a zero next to a number and a release next to a qualifier
+func compareMavenItems(a, b string) int {
+	numericA := a == "" || isDigit(a[0])
+	numericB := b == "" || isDigit(b[0])
+
+	switch {
+	case numericA && numericB:
+		return compareNumbers(a, b)
+	case numericA:
+		if a == "" {
+			return compareMavenQualifiers("", b)
+		}
+		return 1
+	case numericB:
+		if b == "" {
+			return compareMavenQualifiers(a, "")
+		}
+		return -1
+	default:
+		return compareMavenQualifiers(a, b)
+	}
+}
+
+func compareMavenQualifiers(a, b string) int {
+	rankA, knownA := mavenQualifiers[a]
+	rankB, knownB := mavenQualifiers[b]
+	if !knownA {
+		rankA = len(mavenQualifiers)
+	}
+	if !knownB {
+		rankB = len(mavenQualifiers)
+	}
+	if c := compareInts(rankA, rankB); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if c := compareInts(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func leadingDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func leadingLetters(s string) (string, string) {
+	i := 0
+	for i < len(s) && isLetter(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func leadingNonDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && !isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isNumber(s string) bool {
+	digits, rest := leadingDigits(s)
+	return digits != "" && rest == ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package scan_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+)
+
+var _ = Describe("Comparator", func() {
+	DescribeTable("orders the versions with the rules of their ecosystem",
+		func(ecosystem, lower, higher string) {
+			compare := scan.Comparator(ecosystem)
+			Expect(compare(lower, higher)).To(Equal(-1))
+			Expect(compare(higher, lower)).To(Equal(1))
+			Expect(compare(lower, lower)).To(Equal(0))
+		},
+		Entry("dpkg tilde", scan.Debian, "1.0~rc1-1", "1.0-1"),
+		Entry("dpkg epoch", scan.Debian, "2.0-1", "1:1.0-1"),
+		Entry("dpkg revision", scan.Debian, "1.1.1k-1+deb11u1", "1.1.1n-0+deb11u1"),
+		Entry("dpkg letters before symbols", scan.Ubuntu, "1.0a", "1.0+"),
+		Entry("rpm numbers", scan.RedHat, "4.4.9-1", "4.4.19-1"),
+		Entry("rpm release", scan.RedHat, "1.1.1k-4.el8", "1.1.1k-5.el8_5"),
+		Entry("rpm tilde", scan.RockyLinux, "1.0~beta", "1.0"),
+		Entry("rpm caret", scan.AlmaLinux, "1.0", "1.0^git1"),
+		Entry("apk revision", scan.Alpine, "1.2.11-r3", "1.2.11-r4"),
+		Entry("apk suffix", scan.Alpine, "1.0_rc1", "1.0"),
+		Entry("apk patch suffix", scan.Alpine, "1.0", "1.0_p1"),
+		Entry("semver prerelease", scan.Go, "v1.2.0-rc.1", "v1.2.0"),
+		Entry("semver numbers", scan.Npm, "1.9.0", "1.10.0"),
+		Entry("pep 440 release candidate", scan.PyPI, "2.20.0rc1", "2.20.0"),
+		Entry("pep 440 post release", scan.PyPI, "2.20.0", "2.20.0.post1"),
+		Entry("pep 440 dev release", scan.PyPI, "2.20.0.dev1", "2.20.0a1"),
+		Entry("maven qualifier", scan.Maven, "2.13.0-rc1", "2.13.0"),
+		Entry("maven numbers", scan.Maven, "2.9.10", "2.12.1"),
+	)
+
+	It("treats equivalent versions as equal", func() {
+		Expect(scan.Comparator(scan.Debian)("0:1.0-1", "1.0-1")).To(Equal(0))
+		Expect(scan.Comparator(scan.PyPI)("1.0", "1.0.0")).To(Equal(0))
+		Expect(scan.Comparator(scan.Maven)("1.0", "1.0.0")).To(Equal(0))
+	})
+})
+
+var _ = Describe("CVSS3Severity", func() {
+	DescribeTable("rates the base score of the vector",
+		func(vector string, severity scan.Severity) {
+			Expect(scan.CVSS3Severity(vector)).To(Equal(severity))
+		},
+		Entry("critical", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", scan.Critical),
+		Entry("high", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", scan.High),
+		Entry("medium", "CVSS:3.1/AV:L/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", scan.Medium),
+		Entry("none", "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", scan.Negligible),
+		Entry("invalid", "AV:X", scan.Unknown),
+	)
+})
@@ -0,0 +1,259 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LabelName is the label holding the signature of the deplab label
+const LabelName = "io.deplab.signature"
+
+// Algorithms of the signatures
+const (
+	Ed25519 = "ed25519"
+	ECDSA   = "ecdsa"
+)
+
+// ErrInvalidSignature is returned when the signature does not match the label
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signature is the content of the signature label, the signature of the
+// canonical JSON of the deplab label with the key identified by KeyID
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"`
+}
+
+// Signer signs deplab labels with a private key
+type Signer struct {
+	key       crypto.Signer
+	algorithm string
+	keyID     string
+}
+
+// Verifier checks the signatures of deplab labels with a public key
+type Verifier struct {
+	key   crypto.PublicKey
+	keyID string
+}
+
+// LoadSigner reads an ed25519 or ECDSA private key from a PEM file, in PKCS #8
+// or, for ECDSA, SEC 1 form
+func LoadSigner(path string) (*Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s, expected a PRIVATE KEY or an EC PRIVATE KEY", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key %s: %w", path, err)
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		keyID, err := KeyID(k.Public())
+		if err != nil {
+			return nil, err
+		}
+		return &Signer{key: k, algorithm: Ed25519, keyID: keyID}, nil
+	case *ecdsa.PrivateKey:
+		keyID, err := KeyID(k.Public())
+		if err != nil {
+			return nil, err
+		}
+		return &Signer{key: k, algorithm: ECDSA, keyID: keyID}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key %s, expected an ed25519 or ECDSA key", path)
+	}
+}
+
+// LoadVerifier reads an ed25519 or ECDSA public key from a PEM file
+func LoadVerifier(path string) (*Verifier, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q in %s, expected a PUBLIC KEY", block.Type, path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key %s: %w", path, err)
+	}
+
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key %s, expected an ed25519 or ECDSA key", path)
+	}
+
+	keyID, err := KeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{key: key, keyID: keyID}, nil
+}
+
+// KeyID identifies a public key by the SHA-256 digest of its DER encoding
+func KeyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("could not encode public key: %w", err)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(der)), nil
+}
+
+// KeyID identifies the public key of the signer
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Sign signs the canonical JSON of the deplab label, returning the content of
+// the signature label
+func (s *Signer) Sign(label string) (string, error) {
+	content, err := Canonical([]byte(label))
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := s.SignData(content)
+	if err != nil {
+		return "", fmt.Errorf("could not sign label: %w", err)
+	}
+
+	signatureLabel, err := json.Marshal(Signature{
+		Algorithm: s.algorithm,
+		KeyID:     s.keyID,
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not encode signature: %w", err)
+	}
+	return string(signatureLabel), nil
+}
+
+// SignData signs content as is: ed25519 signs it directly and ECDSA signs its
+// digest, with an ASN.1 encoded signature
+func (s *Signer) SignData(content []byte) ([]byte, error) {
+	if s.algorithm == Ed25519 {
+		return s.key.Sign(rand.Reader, content, crypto.Hash(0))
+	}
+	hash := curveHash(s.key.Public().(*ecdsa.PublicKey).Curve)
+	return s.key.Sign(rand.Reader, digest(hash, content), hash)
+}
+
+// Verify checks that the signature label holds a signature of the deplab
+// label made with the key of the verifier
+func (v *Verifier) Verify(label, signatureLabel string) error {
+	var s Signature
+	if err := json.Unmarshal([]byte(signatureLabel), &s); err != nil {
+		return fmt.Errorf("could not parse signature label: %w", err)
+	}
+
+	if s.KeyID != v.keyID {
+		return fmt.Errorf("%w: signed with key %s, expected key %s", ErrInvalidSignature, s.KeyID, v.keyID)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("could not decode signature: %w", err)
+	}
+
+	content, err := Canonical([]byte(label))
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	switch key := v.key.(type) {
+	case ed25519.PublicKey:
+		valid = s.Algorithm == Ed25519 && ed25519.Verify(key, content, sig)
+	case *ecdsa.PublicKey:
+		valid = s.Algorithm == ECDSA && ecdsa.VerifyASN1(key, digest(curveHash(key.Curve), content), sig)
+	}
+	if !valid {
+		return fmt.Errorf("%w: the label does not match its signature", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// Canonical encodes the JSON of a label without insignificant whitespace and
+// with the keys of its objects sorted, so that its signature does not depend
+// on how it was serialized. The label must hold a single JSON value.
+func Canonical(label []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(label))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("could not parse label: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("could not parse label: unexpected data after the JSON value")
+	}
+
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode label: %w", err)
+	}
+	return content, nil
+}
+
+// curveHash is the hash function matching the size of the curve
+func curveHash(curve elliptic.Curve) crypto.Hash {
+	switch curve.Params().BitSize {
+	case 384:
+		return crypto.SHA384
+	case 521:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digest(hash crypto.Hash, content []byte) []byte {
+	h := hash.New()
+	h.Write(content)
+	return h.Sum(nil)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key: %w", err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("could not find a PEM block in %s", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package signature_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+const label = `{"base":{"id":"debian"},"dependencies":[{"type":"debian_package_list","source":{"type":"inline","version":{"sha256":"abc"}}}]}`
+
+var _ = Describe("Signature", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "deplab-signature-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeKeys := func(name string, key crypto.Signer) (string, string) {
+		privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+		Expect(err).ToNot(HaveOccurred())
+
+		privatePath := filepath.Join(dir, name+".key")
+		publicPath := filepath.Join(dir, name+".pub")
+		Expect(ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)).To(Succeed())
+		return privatePath, publicPath
+	}
+
+	ed25519Key := func() crypto.Signer {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		return key
+	}
+
+	ecdsaKey := func(curve elliptic.Curve) func() crypto.Signer {
+		return func() crypto.Signer {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			return key
+		}
+	}
+
+	DescribeTable("verifies the signature of the label, however it is serialized",
+		func(generate func() crypto.Signer, algorithm string) {
+			privatePath, publicPath := writeKeys("key", generate())
+
+			signer, err := signature.LoadSigner(privatePath)
+			Expect(err).ToNot(HaveOccurred())
+			signatureLabel, err := signer.Sign(label)
+			Expect(err).ToNot(HaveOccurred())
+
+			var s signature.Signature
+			Expect(json.Unmarshal([]byte(signatureLabel), &s)).To(Succeed())
+			Expect(s.Algorithm).To(Equal(algorithm))
+			Expect(s.KeyID).To(HavePrefix("sha256:"))
+
+			verifier, err := signature.LoadVerifier(publicPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verifier.Verify(label, signatureLabel)).To(Succeed())
+
+			reordered := `{ "dependencies": [{"source": {"version": {"sha256": "abc"}, "type": "inline"}, "type": "debian_package_list"}],
+				"base": {"id": "debian"} }`
+			Expect(verifier.Verify(reordered, signatureLabel)).To(Succeed())
+		},
+		Entry("ed25519", ed25519Key, signature.Ed25519),
+		Entry("ECDSA P-256", ecdsaKey(elliptic.P256()), signature.ECDSA),
+		Entry("ECDSA P-384", ecdsaKey(elliptic.P384()), signature.ECDSA),
+	)
+
+	It("reads ECDSA keys in SEC 1 form", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+
+		path := filepath.Join(dir, "ec.key")
+		Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+
+		_, err = signature.LoadSigner(path)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("fails when the label was changed", func() {
+		privatePath, publicPath := writeKeys("key", ed25519Key())
+
+		signer, err := signature.LoadSigner(privatePath)
+		Expect(err).ToNot(HaveOccurred())
+		signatureLabel, err := signer.Sign(label)
+		Expect(err).ToNot(HaveOccurred())
+
+		verifier, err := signature.LoadVerifier(publicPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = verifier.Verify(`{"base":{"id":"alpine"}}`, signatureLabel)
+		Expect(err).To(MatchError(signature.ErrInvalidSignature))
+	})
+
+	It("fails when data follows the JSON of the label", func() {
+		privatePath, publicPath := writeKeys("key", ed25519Key())
+
+		signer, err := signature.LoadSigner(privatePath)
+		Expect(err).ToNot(HaveOccurred())
+		signatureLabel, err := signer.Sign(label)
+		Expect(err).ToNot(HaveOccurred())
+
+		verifier, err := signature.LoadVerifier(publicPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(verifier.Verify(label+"\n", signatureLabel)).To(Succeed())
+		Expect(verifier.Verify(label+`{"base":{"id":"alpine"}}`, signatureLabel)).To(MatchError(ContainSubstring("unexpected data after the JSON value")))
+
+		_, err = signer.Sign(label + " trailing")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when the label was signed with another key", func() {
+		privatePath, _ := writeKeys("key", ed25519Key())
+		_, otherPublicPath := writeKeys("other", ed25519Key())
+
+		signer, err := signature.LoadSigner(privatePath)
+		Expect(err).ToNot(HaveOccurred())
+		signatureLabel, err := signer.Sign(label)
+		Expect(err).ToNot(HaveOccurred())
+
+		verifier, err := signature.LoadVerifier(otherPublicPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = verifier.Verify(label, signatureLabel)
+		Expect(err).To(MatchError(signature.ErrInvalidSignature))
+		Expect(err).To(MatchError(ContainSubstring("signed with key sha256:")))
+	})
+
+	It("rejects public keys as signing keys", func() {
+		_, publicPath := writeKeys("key", ed25519Key())
+
+		_, err := signature.LoadSigner(publicPath)
+		Expect(err).To(MatchError(ContainSubstring(`unsupported PEM block "PUBLIC KEY"`)))
+	})
+})
@@ -0,0 +1,555 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/purl"
+)
+
+const NamespacePrefix = "https://github.com/vmware-tanzu/dependency-labeler/spdx/"
+
+var invalidIDCharacters = regexp.MustCompile(`[^a-zA-Z0-9.\-]+`)
+
+// BuildDocument converts the deplab metadata of an image into an SPDX document
+// describing the image, its operating system and every dependency found on it.
+// The tool is a creator of the document, next to the provenance of md.
+func BuildDocument(md metadata.Metadata, name string, tool metadata.Provenance, created time.Time) (Document, error) {
+	digest, err := common.Digest(md)
+	if err != nil {
+		return Document{}, fmt.Errorf("could not get digest for metadata: %w", err)
+	}
+
+	b := builder{
+		ids:      map[string]bool{ImageID: true},
+		licenses: map[string]string{},
+		distro:   md.Base["id"],
+		doc: Document{
+			SPDXVersion:       Version,
+			DataLicense:       DataLicense,
+			SPDXID:            DocumentID,
+			Name:              name,
+			DocumentNamespace: NamespacePrefix + sanitize(name) + "-" + digest,
+			CreationInfo: CreationInfo{
+				Created:  created.UTC().Format(time.RFC3339),
+				Creators: creators(tool, md.Provenance),
+			},
+			Packages: []Package{
+				{
+					Name:                  name,
+					SPDXID:                ImageID,
+					DownloadLocation:      NoAssertion,
+					PrimaryPackagePurpose: "CONTAINER",
+				},
+			},
+			Relationships: []Relationship{
+				{
+					SPDXElementID:      DocumentID,
+					RelationshipType:   "DESCRIBES",
+					RelatedSPDXElement: ImageID,
+				},
+			},
+			DocumentDescribes: []string{ImageID},
+		},
+	}
+
+	if len(md.Base) > 0 {
+		b.addOperatingSystem(md.Base)
+	}
+
+	for _, dependency := range md.AllDependencies() {
+		var err error
+
+		switch {
+		case dependency.Type == metadata.DebianPackageListSourceType:
+			err = b.addDebianPackages(dependency)
+		case dependency.Type == metadata.RPMPackageListSourceType:
+			err = b.addRpmPackages(dependency)
+		case dependency.Type == metadata.ApkPackageListSourceType:
+			err = b.addApkPackages(dependency)
+		case dependency.Type == metadata.PythonPackageListSourceType:
+			err = b.addPythonPackages(dependency)
+		case dependency.Type == metadata.NpmPackageListSourceType:
+			err = b.addNpmPackages(dependency)
+		case dependency.Type == metadata.MavenPackageListSourceType:
+			err = b.addMavenPackages(dependency)
+		case dependency.Type == metadata.GoBinariesSourceType:
+			err = b.addGoBinaries(dependency)
+		case dependency.Type == metadata.BuildpackMetadataType:
+			err = b.addBuildpackBOM(dependency)
+		case dependency.Source.Type == metadata.GitSourceType:
+			err = b.addGitDependency(dependency)
+		case dependency.Source.Type == metadata.ArchiveType:
+			err = b.addArchiveDependency(dependency)
+		}
+
+		if err != nil {
+			return Document{}, fmt.Errorf("could not convert %s dependency: %w", dependency.Type, err)
+		}
+	}
+
+	return b.doc, nil
+}
+
+type builder struct {
+	doc      Document
+	ids      map[string]bool
+	licenses map[string]string
+	distro   string
+}
+
+func (b *builder) add(p Package) {
+	b.doc.Packages = append(b.doc.Packages, p)
+	b.doc.Relationships = append(b.doc.Relationships, Relationship{
+		SPDXElementID:      ImageID,
+		RelationshipType:   "CONTAINS",
+		RelatedSPDXElement: p.SPDXID,
+	})
+}
+
+func (b *builder) id(kind, name string) string {
+	candidate := "SPDXRef-" + kind + "-" + sanitize(name)
+	id := candidate
+	for i := 2; b.ids[id]; i++ {
+		id = fmt.Sprintf("%s-%d", candidate, i)
+	}
+	b.ids[id] = true
+	return id
+}
+
+// license declares the license as found in the package database when it is a
+// valid SPDX license expression, or else registers it as an extracted license
+func (b *builder) license(license string) string {
+	if strings.TrimSpace(license) == "" {
+		return NoAssertion
+	}
+
+	if expression, ok := licenseExpression(license); ok {
+		return expression
+	}
+
+	if ref, ok := b.licenses[license]; ok {
+		return ref
+	}
+
+	ref := "LicenseRef-" + sanitize(license)
+	for i := 2; b.isLicenseRefUsed(ref); i++ {
+		ref = fmt.Sprintf("LicenseRef-%s-%d", sanitize(license), i)
+	}
+
+	b.licenses[license] = ref
+	b.doc.ExtractedLicensingInfos = append(b.doc.ExtractedLicensingInfos, ExtractedLicensingInfo{
+		LicenseID:     ref,
+		ExtractedText: license,
+		Name:          license,
+	})
+
+	return ref
+}
+
+// allLicenses declares the licenses which all apply to a package
+func (b *builder) allLicenses(licenses []string) string {
+	if len(licenses) == 0 {
+		return NoAssertion
+	}
+
+	var expressions []string
+	for _, license := range licenses {
+		expression := b.license(license)
+		if len(licenses) > 1 && strings.Contains(expression, " OR ") {
+			expression = "(" + expression + ")"
+		}
+		expressions = append(expressions, expression)
+	}
+	return strings.Join(expressions, " AND ")
+}
+
+func (b *builder) isLicenseRefUsed(ref string) bool {
+	for _, used := range b.licenses {
+		if used == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) addOperatingSystem(base metadata.Base) {
+	name := base["id"]
+	if name == "" {
+		name = base["name"]
+	}
+
+	b.add(Package{
+		Name:                  name,
+		SPDXID:                b.id("OperatingSystem", name),
+		VersionInfo:           base["version_id"],
+		DownloadLocation:      NoAssertion,
+		PrimaryPackagePurpose: "OPERATING-SYSTEM",
+		Comment:               base["pretty_name"],
+	})
+}
+
+func (b *builder) addDebianPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.DebianPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Package{
+			Name:                  pkg.Package,
+			SPDXID:                b.id("deb", pkg.Package),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			SourceInfo:            fmt.Sprintf("built package from: %s %s", pkg.Source.Package, pkg.Source.Version),
+			LicenseDeclared:       b.allLicenses(pkg.Licenses),
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.New(purl.DebType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+					"arch": pkg.Architecture,
+				})),
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addRpmPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.RpmPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Package{
+			Name:                  pkg.Package,
+			SPDXID:                b.id("rpm", pkg.Package),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			SourceInfo:            fmt.Sprintf("built package from: %s", pkg.SourceRpm),
+			LicenseDeclared:       b.license(pkg.License),
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.New(purl.RPMType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+					"arch": pkg.Architecture,
+				})),
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addApkPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.ApkPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		p := Package{
+			Name:                  pkg.Package,
+			SPDXID:                b.id("apk", pkg.Package),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			SourceInfo:            fmt.Sprintf("built package from: %s %s", pkg.Origin, pkg.Commit),
+			LicenseDeclared:       b.license(pkg.License),
+			PrimaryPackagePurpose: "LIBRARY",
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.New(purl.ApkType, b.distro, pkg.Package, pkg.Version, purl.Qualifiers{
+					"arch": pkg.Architecture,
+				})),
+			},
+		}
+
+		if pkg.Maintainer != "" {
+			p.Supplier = "Person: " + pkg.Maintainer
+		}
+
+		b.add(p)
+	}
+
+	return nil
+}
+
+func (b *builder) addPythonPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.PythonPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Package{
+			Name:                  pkg.Package,
+			SPDXID:                b.id("python", pkg.Package),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			LicenseDeclared:       b.license(pkg.License),
+			PrimaryPackagePurpose: "LIBRARY",
+			Comment:               fmt.Sprintf("installed in %s", pkg.Location),
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.PyPI(pkg.Package, pkg.Version)),
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addNpmPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.NpmPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		p := Package{
+			Name:                  pkg.Package,
+			SPDXID:                b.id("npm", pkg.Package),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			LicenseDeclared:       b.license(pkg.License),
+			PrimaryPackagePurpose: "LIBRARY",
+			Comment:               fmt.Sprintf("installed in %s", pkg.Location),
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.Npm(pkg.Package, pkg.Version)),
+			},
+		}
+
+		if pkg.Resolved != "" {
+			p.DownloadLocation = pkg.Resolved
+		}
+
+		b.add(p)
+	}
+
+	return nil
+}
+
+func (b *builder) addMavenPackages(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.MavenPackageListSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range sourceMetadata.Packages {
+		b.add(Package{
+			Name:                  pkg.ArtifactID,
+			SPDXID:                b.id("maven", pkg.ArtifactID),
+			VersionInfo:           pkg.Version,
+			DownloadLocation:      NoAssertion,
+			LicenseDeclared:       NoAssertion,
+			PrimaryPackagePurpose: "LIBRARY",
+			Comment:               fmt.Sprintf("found in %s", pkg.Path),
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.New(purl.MavenType, pkg.GroupID, pkg.ArtifactID, pkg.Version, nil)),
+			},
+		})
+	}
+
+	return nil
+}
+
+func (b *builder) addGoBinaries(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.GoBinariesSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, binary := range sourceMetadata.Binaries {
+		main := binary.Module.Resolved()
+		b.add(Package{
+			Name:                  binary.Package,
+			SPDXID:                b.id("go", binary.Path),
+			VersionInfo:           main.Version,
+			DownloadLocation:      NoAssertion,
+			LicenseDeclared:       NoAssertion,
+			PrimaryPackagePurpose: "APPLICATION",
+			Comment:               fmt.Sprintf("go binary %s built with %s", binary.Path, binary.GoVersion),
+			ExternalRefs: []ExternalRef{
+				packageManagerRef(purl.Golang(main.Path, main.Version)),
+			},
+		})
+
+		for _, module := range binary.Modules {
+			resolved := module.Resolved()
+			b.add(Package{
+				Name:                  resolved.Path,
+				SPDXID:                b.id("go", resolved.Path),
+				VersionInfo:           resolved.Version,
+				DownloadLocation:      NoAssertion,
+				LicenseDeclared:       NoAssertion,
+				PrimaryPackagePurpose: "LIBRARY",
+				Comment:               fmt.Sprintf("built into go binary %s", binary.Path),
+				ExternalRefs: []ExternalRef{
+					packageManagerRef(purl.Golang(resolved.Path, resolved.Version)),
+				},
+			})
+		}
+	}
+
+	return nil
+}
+
+func (b *builder) addBuildpackBOM(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.BuildpackBOMSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	for _, bom := range sourceMetadata.BillOfMaterials {
+		p := Package{
+			Name:                  bom.Name,
+			SPDXID:                b.id("buildpack", bom.Name),
+			VersionInfo:           bom.Version,
+			DownloadLocation:      NoAssertion,
+			LicenseDeclared:       NoAssertion,
+			PrimaryPackagePurpose: "LIBRARY",
+			Comment:               fmt.Sprintf("contributed by buildpack %s %s", bom.Buildpack.ID, bom.Buildpack.Version),
+		}
+
+		if uri, ok := bom.Metadata["uri"].(string); ok && uri != "" {
+			p.DownloadLocation = uri
+		}
+
+		if sha256, ok := bom.Metadata["sha256"].(string); ok && sha256 != "" {
+			p.Checksums = []Checksum{{Algorithm: "SHA256", ChecksumValue: sha256}}
+		}
+
+		if licenses := buildpackLicenses(bom.Metadata); len(licenses) != 0 {
+			p.LicenseDeclared = b.allLicenses(licenses)
+		}
+
+		if bomPurl, ok := bom.Metadata["purl"].(string); ok && bomPurl != "" {
+			p.ExternalRefs = []ExternalRef{packageManagerRef(bomPurl)}
+		}
+
+		b.add(p)
+	}
+
+	return nil
+}
+
+func (b *builder) addGitDependency(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.GitSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	commit, _ := dependency.Source.Version["commit"].(string)
+
+	b.add(Package{
+		Name:                  sourceMetadata.URL,
+		SPDXID:                b.id("git", sourceMetadata.URL),
+		VersionInfo:           commit,
+		DownloadLocation:      gitDownloadLocation(sourceMetadata.URL, commit),
+		LicenseDeclared:       NoAssertion,
+		PrimaryPackagePurpose: "SOURCE",
+	})
+
+	return nil
+}
+
+func (b *builder) addArchiveDependency(dependency metadata.Dependency) error {
+	var sourceMetadata metadata.ArchiveSourceMetadata
+	err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata)
+	if err != nil {
+		return err
+	}
+
+	b.add(Package{
+		Name:                  sourceMetadata.URL,
+		SPDXID:                b.id("archive", sourceMetadata.URL),
+		DownloadLocation:      sourceMetadata.URL,
+		LicenseDeclared:       NoAssertion,
+		PrimaryPackagePurpose: "ARCHIVE",
+	})
+
+	return nil
+}
+
+func packageManagerRef(locator string) ExternalRef {
+	return ExternalRef{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  locator,
+	}
+}
+
+// gitDownloadLocation follows the VCS location format of the SPDX specification,
+// e.g. git+https://github.com/org/repo.git@commit
+func gitDownloadLocation(url, commit string) string {
+	if url == "" {
+		return NoAssertion
+	}
+
+	if strings.HasPrefix(url, "git@") {
+		url = "ssh://" + strings.Replace(url, ":", "/", 1)
+	}
+
+	location := url
+	if !strings.HasPrefix(location, "git+") && !strings.HasPrefix(location, "git://") {
+		location = "git+" + location
+	}
+
+	if commit != "" {
+		location += "@" + commit
+	}
+
+	return location
+}
+
+func buildpackLicenses(bomMetadata metadata.BuildpackBOMMetadata) []string {
+	var licenses []string
+
+	entries, ok := bomMetadata["licenses"].([]interface{})
+	if !ok {
+		return licenses
+	}
+
+	for _, entry := range entries {
+		if license, ok := entry.(map[string]interface{}); ok {
+			if licenseType, ok := license["type"].(string); ok && licenseType != "" {
+				licenses = append(licenses, licenseType)
+			}
+		}
+	}
+
+	return licenses
+}
+
+func creators(tool metadata.Provenance, provenance []metadata.Provenance) []string {
+	result := []string{fmt.Sprintf("Tool: %s-%s", tool.Name, tool.Version)}
+	for _, p := range provenance {
+		creator := fmt.Sprintf("Tool: %s-%s", p.Name, p.Version)
+		if creator != result[0] {
+			result = append(result, creator)
+		}
+	}
+	return result
+}
+
+func sanitize(s string) string {
+	sanitized := strings.Trim(invalidIDCharacters.ReplaceAllString(s, "-"), "-")
+	if sanitized == "" {
+		return "unknown"
+	}
+	return sanitized
+}
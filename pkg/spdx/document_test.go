@@ -0,0 +1,422 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/spdx"
+)
+
+var _ = Describe("BuildDocument", func() {
+	var (
+		md      metadata.Metadata
+		tool    = metadata.Provenance{Name: "deplab", Version: "0.42.0"}
+		created = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		md = metadata.Metadata{
+			Base: metadata.Base{
+				"id":          "ubuntu",
+				"name":        "Ubuntu",
+				"version_id":  "18.04",
+				"pretty_name": "Ubuntu 18.04.3 LTS",
+			},
+			Provenance: []metadata.Provenance{{Name: "deplab", Version: "0.42.0", URL: "https://example.com"}},
+			Dependencies: []metadata.Dependency{
+				{
+					Type: metadata.DebianPackageListSourceType,
+					Source: metadata.Source{
+						Type:    "inline",
+						Version: map[string]interface{}{"sha256": "some-sha"},
+						Metadata: metadata.DebianPackageListSourceMetadata{
+							Packages: []metadata.DpkgPackage{
+								{
+									Package:      "libstdc++6",
+									Version:      "8.3.0-6ubuntu1",
+									Architecture: "amd64",
+									Source:       metadata.PackageSource{Package: "gcc-8", Version: "8.3.0-6ubuntu1", UpstreamVersion: "8.3.0"},
+								},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.RPMPackageListSourceType,
+					Source: metadata.Source{
+						Type:    "inline",
+						Version: map[string]interface{}{"sha256": "some-sha"},
+						Metadata: metadata.RpmPackageListSourceMetadata{
+							Packages: []metadata.RpmPackage{
+								{Package: "bash", Version: "4.4.18", Architecture: "x86_64", License: "GPLv3+", SourceRpm: "bash-4.4.18-1.src.rpm"},
+								{Package: "bzip2", Version: "1.0.8", Architecture: "x86_64", License: "GPLv3+", SourceRpm: "bzip2-1.0.8-1.src.rpm"},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.BuildpackMetadataType,
+					Source: metadata.Source{
+						Type:    "inline",
+						Version: map[string]interface{}{"sha256": "some-sha"},
+						Metadata: metadata.BuildpackBOMSourceMetadata{
+							BillOfMaterials: []metadata.BuildpackBOM{
+								{
+									Name:    "openjdk-jre",
+									Version: "11.0.6",
+									Metadata: metadata.BuildpackBOMMetadata{
+										"uri":      "https://example.com/openjdk.tar.gz",
+										"sha256":   "abc123",
+										"licenses": []interface{}{map[string]interface{}{"type": "GPL-2.0 WITH Classpath-exception-2.0"}},
+									},
+									Buildpack: metadata.Buildpack{ID: "org.cloudfoundry.openjdk", Version: "1.0.0"},
+								},
+							},
+						},
+					},
+				},
+				{
+					Type: metadata.PackageType,
+					Source: metadata.Source{
+						Type:    metadata.GitSourceType,
+						Version: map[string]interface{}{"commit": "d2c1234"},
+						// as read back from a label
+						Metadata: map[string]interface{}{"url": "git@github.com:vmware-tanzu/dependency-labeler.git", "refs": []interface{}{}},
+					},
+				},
+				{
+					Type: metadata.PackageType,
+					Source: metadata.Source{
+						Type:     metadata.ArchiveType,
+						Metadata: metadata.ArchiveSourceMetadata{URL: "https://example.com/source.tar.gz"},
+					},
+				},
+			},
+		}
+	})
+
+	It("describes the image", func() {
+		doc, err := BuildDocument(md, "example.com/image:tag", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+		Expect(doc.DataLicense).To(Equal("CC0-1.0"))
+		Expect(doc.SPDXID).To(Equal("SPDXRef-DOCUMENT"))
+		Expect(doc.Name).To(Equal("example.com/image:tag"))
+		Expect(doc.DocumentNamespace).To(HavePrefix(NamespacePrefix + "example.com-image-tag-"))
+		Expect(doc.CreationInfo).To(Equal(CreationInfo{
+			Created:  "2020-01-02T03:04:05Z",
+			Creators: []string{"Tool: deplab-0.42.0"},
+		}))
+		Expect(doc.Relationships).To(ContainElement(Relationship{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: "SPDXRef-Image",
+		}))
+	})
+
+	It("lists deplab as a creator without provenance", func() {
+		md.Provenance = nil
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(doc.CreationInfo.Creators).To(Equal([]string{"Tool: deplab-0.42.0"}))
+
+		md.Provenance = []metadata.Provenance{{Name: "kpack", Version: "0.1.0"}}
+		doc, err = BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(doc.CreationInfo.Creators).To(Equal([]string{"Tool: deplab-0.42.0", "Tool: kpack-0.1.0"}))
+	})
+
+	It("generates a different namespace for different metadata", func() {
+		doc1, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		md.Dependencies = md.Dependencies[1:]
+		doc2, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc1.DocumentNamespace).ToNot(Equal(doc2.DocumentNamespace))
+	})
+
+	It("adds one package for the image, the operating system and each dependency", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(HaveLen(8))
+		for _, p := range doc.Packages[1:] {
+			Expect(doc.Relationships).To(ContainElement(Relationship{
+				SPDXElementID:      "SPDXRef-Image",
+				RelationshipType:   "CONTAINS",
+				RelatedSPDXElement: p.SPDXID,
+			}))
+		}
+	})
+
+	It("uses the base as the operating system package", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":                  Equal("ubuntu"),
+			"VersionInfo":           Equal("18.04"),
+			"PrimaryPackagePurpose": Equal("OPERATING-SYSTEM"),
+		})))
+	})
+
+	It("converts debian packages", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":        Equal("libstdc++6"),
+			"SPDXID":      Equal("SPDXRef-deb-libstdc-6"),
+			"VersionInfo": Equal("8.3.0-6ubuntu1"),
+			"SourceInfo":  Equal("built package from: gcc-8 8.3.0-6ubuntu1"),
+			"ExternalRefs": ConsistOf(ExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:deb/ubuntu/libstdc%2B%2B6@8.3.0-6ubuntu1?arch=amd64",
+			}),
+		})))
+	})
+
+	It("declares the licenses of debian packages read from their copyright files", func() {
+		md.Dependencies[0].Source.Metadata = metadata.DebianPackageListSourceMetadata{
+			Packages: []metadata.DpkgPackage{{
+				Package:  "libstdc++6",
+				Version:  "8.3.0-6ubuntu1",
+				Licenses: []string{"GPL-3", "GFDL-1.2"},
+			}},
+		}
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("libstdc++6"),
+			"LicenseDeclared": Equal("LicenseRef-GPL-3 AND GFDL-1.2"),
+		})))
+	})
+
+	It("converts rpm packages, declaring their license as an extracted license", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("bash"),
+			"VersionInfo":     Equal("4.4.18"),
+			"LicenseDeclared": Equal("LicenseRef-GPLv3"),
+		})))
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("bzip2"),
+			"LicenseDeclared": Equal("LicenseRef-GPLv3"),
+		})))
+		Expect(doc.ExtractedLicensingInfos).To(ContainElement(ExtractedLicensingInfo{
+			LicenseID:     "LicenseRef-GPLv3",
+			ExtractedText: "GPLv3+",
+			Name:          "GPLv3+",
+		}))
+	})
+
+	It("declares the licenses which are valid SPDX license expressions as they are", func() {
+		md.Dependencies[0].Source.Metadata = metadata.DebianPackageListSourceMetadata{
+			Packages: []metadata.DpkgPackage{{
+				Package:  "libstdc++6",
+				Version:  "8.3.0-6ubuntu1",
+				Licenses: []string{"mit or apache-2.0", "GPL-3"},
+			}},
+		}
+		md.Dependencies[1].Source.Metadata = metadata.RpmPackageListSourceMetadata{
+			Packages: []metadata.RpmPackage{
+				{Package: "bash", Version: "4.4.18", License: "GPL-3.0-or-later AND (BSD-3-Clause OR LGPL-2.1+)"},
+				{Package: "bzip2", Version: "1.0.8", License: "BSD-3-Clause AND"},
+			},
+		}
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("libstdc++6"),
+			"LicenseDeclared": Equal("(MIT OR Apache-2.0) AND LicenseRef-GPL-3"),
+		})))
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("bash"),
+			"LicenseDeclared": Equal("GPL-3.0-or-later AND (BSD-3-Clause OR LGPL-2.1+)"),
+		})))
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("bzip2"),
+			"LicenseDeclared": Equal("LicenseRef-BSD-3-Clause-AND"),
+		})))
+	})
+
+	It("converts apk packages, with their maintainer as supplier", func() {
+		md = metadata.Metadata{
+			Base: metadata.Base{"id": "alpine", "version_id": "3.11.6"},
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.ApkPackageListSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					// as read back from a label
+					Metadata: map[string]interface{}{
+						"packages": []interface{}{map[string]interface{}{
+							"package":      "musl",
+							"version":      "1.1.24-r2",
+							"architecture": "x86_64",
+							"license":      "MIT",
+							"origin":       "musl",
+							"commit":       "bfe5a5ec",
+							"maintainer":   "Timo Teräs <timo.teras@iki.fi>",
+						}},
+						"repositories": []interface{}{},
+					},
+				},
+			}},
+		}
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":            Equal("musl"),
+			"VersionInfo":     Equal("1.1.24-r2"),
+			"Supplier":        Equal("Person: Timo Teräs <timo.teras@iki.fi>"),
+			"SourceInfo":      Equal("built package from: musl bfe5a5ec"),
+			"LicenseDeclared": Equal("MIT"),
+			"ExternalRefs": ConsistOf(ExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:apk/alpine/musl@1.1.24-r2?arch=x86_64",
+			}),
+		})))
+	})
+
+	It("converts go binaries, with the modules built into them", func() {
+		md = metadata.Metadata{
+			Dependencies: []metadata.Dependency{{
+				Type: metadata.GoBinariesSourceType,
+				Source: metadata.Source{
+					Type:    "inline",
+					Version: map[string]interface{}{"sha256": "some-sha"},
+					// as read back from a label
+					Metadata: map[string]interface{}{
+						"binaries": []interface{}{map[string]interface{}{
+							"path":       "/app/server",
+							"go_version": "go1.15.2",
+							"package":    "example.com/server/cmd/server",
+							"module": map[string]interface{}{
+								"path":    "example.com/server",
+								"version": "v1.2.0",
+							},
+							"modules": []interface{}{
+								map[string]interface{}{
+									"path":    "github.com/spf13/cobra",
+									"version": "v0.0.5",
+									"sum":     "h1:f0B+LdTX/Jhs7XzfRmmKoP/E1J+S29lsHHmbGGrM1so=",
+									"replace": map[string]interface{}{
+										"path":    "github.com/example/cobra",
+										"version": "v0.0.6",
+									},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		}
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":                  Equal("example.com/server/cmd/server"),
+			"VersionInfo":           Equal("v1.2.0"),
+			"PrimaryPackagePurpose": Equal("APPLICATION"),
+			"Comment":               Equal("go binary /app/server built with go1.15.2"),
+			"ExternalRefs": ConsistOf(ExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:golang/example.com/server@v1.2.0",
+			}),
+		})))
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":                  Equal("github.com/example/cobra"),
+			"VersionInfo":           Equal("v0.0.6"),
+			"PrimaryPackagePurpose": Equal("LIBRARY"),
+			"ExternalRefs": ConsistOf(ExternalRef{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  "pkg:golang/github.com/example/cobra@v0.0.6",
+			}),
+		})))
+	})
+
+	It("converts the buildpack bill of materials", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"Name":             Equal("openjdk-jre"),
+			"VersionInfo":      Equal("11.0.6"),
+			"DownloadLocation": Equal("https://example.com/openjdk.tar.gz"),
+			"Checksums":        ConsistOf(Checksum{Algorithm: "SHA256", ChecksumValue: "abc123"}),
+			"LicenseDeclared":  Equal("GPL-2.0 WITH Classpath-exception-2.0"),
+		})))
+	})
+
+	It("converts git and archive dependencies with their download location", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"VersionInfo":      Equal("d2c1234"),
+			"DownloadLocation": Equal("git+ssh://git@github.com/vmware-tanzu/dependency-labeler.git@d2c1234"),
+		})))
+		Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+			"DownloadLocation": Equal("https://example.com/source.tar.gz"),
+		})))
+	})
+
+	It("generates unique and valid SPDX identifiers", func() {
+		md.Dependencies = append(md.Dependencies, md.Dependencies[0])
+
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		ids := map[string]bool{}
+		for _, p := range doc.Packages {
+			Expect(p.SPDXID).To(MatchRegexp(`^SPDXRef-[a-zA-Z0-9.\-]+$`))
+			Expect(ids).ToNot(HaveKey(p.SPDXID))
+			ids[p.SPDXID] = true
+		}
+	})
+
+	It("serializes to the SPDX JSON field names", func() {
+		doc, err := BuildDocument(md, "image", tool, created)
+		Expect(err).ToNot(HaveOccurred())
+
+		raw, err := json.Marshal(doc)
+		Expect(err).ToNot(HaveOccurred())
+
+		var fields map[string]interface{}
+		Expect(json.Unmarshal(raw, &fields)).To(Succeed())
+		Expect(fields).To(SatisfyAll(
+			HaveKeyWithValue("spdxVersion", "SPDX-2.3"),
+			HaveKeyWithValue("SPDXID", "SPDXRef-DOCUMENT"),
+			HaveKey("documentNamespace"),
+			HaveKey("creationInfo"),
+			HaveKey("packages"),
+			HaveKey("relationships"),
+			HaveKey("hasExtractedLicensingInfos"),
+		))
+	})
+})
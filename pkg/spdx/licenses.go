@@ -0,0 +1,182 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx
+
+import (
+	"regexp"
+	"strings"
+)
+
+var licenseTokens = regexp.MustCompile(`\(|\)|[^\s()]+`)
+
+// licenseIDs are the identifiers of the SPDX license list, including the
+// deprecated ones, which package databases use the most
+var licenseIDs = []string{
+	"0BSD", "AFL-1.1", "AFL-1.2", "AFL-2.0", "AFL-2.1", "AFL-3.0", "AGPL-1.0",
+	"AGPL-1.0-only", "AGPL-1.0-or-later", "AGPL-3.0", "AGPL-3.0-only",
+	"AGPL-3.0-or-later", "Apache-1.0", "Apache-1.1", "Apache-2.0", "APSL-1.0",
+	"APSL-1.1", "APSL-1.2", "APSL-2.0", "Artistic-1.0", "Artistic-1.0-cl8",
+	"Artistic-1.0-Perl", "Artistic-2.0", "Beerware", "BlueOak-1.0.0",
+	"BSD-1-Clause", "BSD-2-Clause", "BSD-2-Clause-FreeBSD",
+	"BSD-2-Clause-NetBSD", "BSD-2-Clause-Patent", "BSD-3-Clause",
+	"BSD-3-Clause-Attribution", "BSD-3-Clause-Clear", "BSD-3-Clause-LBNL",
+	"BSD-3-Clause-No-Nuclear-License", "BSD-4-Clause", "BSD-4-Clause-UC",
+	"BSD-Protection", "BSD-Source-Code", "BSL-1.0", "bzip2-1.0.5",
+	"bzip2-1.0.6", "CC-BY-1.0", "CC-BY-2.0", "CC-BY-2.5", "CC-BY-3.0",
+	"CC-BY-4.0", "CC-BY-NC-4.0", "CC-BY-ND-4.0", "CC-BY-SA-1.0",
+	"CC-BY-SA-2.0", "CC-BY-SA-2.5", "CC-BY-SA-3.0", "CC-BY-SA-4.0",
+	"CC-PDDC", "CC0-1.0", "CDDL-1.0", "CDDL-1.1", "CECILL-2.0", "CECILL-2.1",
+	"CECILL-B", "CECILL-C", "ClArtistic", "CPL-1.0", "curl", "ECL-2.0",
+	"EFL-2.0", "EPL-1.0", "EPL-2.0", "EUPL-1.1", "EUPL-1.2", "FSFAP",
+	"FSFUL", "FSFULLR", "FTL", "GFDL-1.1", "GFDL-1.1-only",
+	"GFDL-1.1-or-later", "GFDL-1.2", "GFDL-1.2-only", "GFDL-1.2-or-later",
+	"GFDL-1.3", "GFDL-1.3-only", "GFDL-1.3-or-later", "GPL-1.0", "GPL-1.0+",
+	"GPL-1.0-only", "GPL-1.0-or-later", "GPL-2.0", "GPL-2.0+",
+	"GPL-2.0-only", "GPL-2.0-or-later", "GPL-2.0-with-autoconf-exception",
+	"GPL-2.0-with-bison-exception", "GPL-2.0-with-classpath-exception",
+	"GPL-2.0-with-font-exception", "GPL-2.0-with-GCC-exception", "GPL-3.0",
+	"GPL-3.0+", "GPL-3.0-only", "GPL-3.0-or-later",
+	"GPL-3.0-with-autoconf-exception", "GPL-3.0-with-GCC-exception",
+	"HPND", "IBM-pibs", "ICU", "IJG", "Imlib2", "Info-ZIP", "Intel", "IPA",
+	"IPL-1.0", "ISC", "JasPer-2.0", "JSON", "LGPL-2.0", "LGPL-2.0+",
+	"LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1", "LGPL-2.1+",
+	"LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0", "LGPL-3.0+",
+	"LGPL-3.0-only", "LGPL-3.0-or-later", "LGPLLR", "Libpng",
+	"libpng-2.0", "libtiff", "LPL-1.02", "LPPL-1.3a", "LPPL-1.3c", "MirOS",
+	"MIT", "MIT-0", "MIT-advertising", "MIT-CMU", "MIT-enna", "MIT-feh",
+	"MIT-Modern-Variant", "MIT-open-group", "MITNFA", "MPL-1.0", "MPL-1.1",
+	"MPL-2.0", "MPL-2.0-no-copyleft-exception", "MS-PL", "MS-RL", "NCSA",
+	"Net-SNMP", "NTP", "OFL-1.0", "OFL-1.1", "OLDAP-2.8", "OpenSSL",
+	"OSL-1.0", "OSL-2.0", "OSL-2.1", "OSL-3.0", "PHP-3.0", "PHP-3.01",
+	"PostgreSQL", "PSF-2.0", "Python-2.0", "Python-2.0.1", "QPL-1.0",
+	"Ruby", "Sendmail", "SGI-B-2.0", "SISSL", "Sleepycat", "SMLNJ", "SSPL-1.0",
+	"TCL", "UCL-1.0", "Unicode-DFS-2015", "Unicode-DFS-2016", "Unicode-TOU",
+	"Unlicense", "UPL-1.0", "Vim", "W3C", "W3C-19980720", "W3C-20150513",
+	"WTFPL", "X11", "XFree86-1.1", "Xnet", "xpp", "Zed", "Zend-2.0", "Zlib",
+	"zlib-acknowledgement", "ZPL-1.1", "ZPL-2.0", "ZPL-2.1",
+}
+
+// licenseExceptionIDs are the identifiers of the SPDX license exceptions list
+var licenseExceptionIDs = []string{
+	"389-exception", "Autoconf-exception-2.0", "Autoconf-exception-3.0",
+	"Bison-exception-2.2", "Bootloader-exception", "Classpath-exception-2.0",
+	"CLISP-exception-2.0", "DigiRule-FOSS-exception", "eCos-exception-2.0",
+	"Fawkes-Runtime-exception", "FLTK-exception", "Font-exception-2.0",
+	"freertos-exception-2.0", "GCC-exception-2.0", "GCC-exception-3.1",
+	"gnu-javamail-exception", "GPL-3.0-linking-exception",
+	"GPL-3.0-linking-source-exception", "GPL-CC-1.0", "i2p-gpl-java-exception",
+	"LGPL-3.0-linking-exception", "Libtool-exception", "Linux-syscall-note",
+	"LLVM-exception", "LZMA-exception", "mif-exception", "OCaml-LGPL-linking-exception",
+	"OCCT-exception-1.0", "OpenJDK-assembly-exception-1.0",
+	"openvpn-openssl-exception", "PS-or-PDF-font-exception-20170817",
+	"Qt-GPL-exception-1.0", "Qt-LGPL-exception-1.1", "Qwt-exception-1.0",
+	"u-boot-exception-2.0", "Universal-FOSS-exception-1.0",
+	"WxWindows-exception-3.1",
+}
+
+var (
+	knownLicenses          = byLowerCase(licenseIDs)
+	knownLicenseExceptions = byLowerCase(licenseExceptionIDs)
+)
+
+func byLowerCase(ids []string) map[string]string {
+	known := map[string]string{}
+	for _, id := range ids {
+		known[strings.ToLower(id)] = id
+	}
+	return known
+}
+
+// licenseExpression returns the license as an SPDX license expression, e.g.
+// GPL-2.0-or-later OR MIT, and whether it is one: it must only use the
+// identifiers of the SPDX license list, which are matched case insensitively
+func licenseExpression(license string) (string, bool) {
+	e := &expression{tokens: licenseTokens.FindAllString(license, -1)}
+	if !e.or() || e.next != len(e.tokens) {
+		return "", false
+	}
+
+	result := strings.Join(e.out, " ")
+	result = strings.Replace(result, "( ", "(", -1)
+	result = strings.Replace(result, " )", ")", -1)
+	return result, true
+}
+
+// expression parses the tokens of a license expression, writing them to out
+// with the case of the SPDX lists
+type expression struct {
+	tokens []string
+	next   int
+	out    []string
+}
+
+func (e *expression) or() bool {
+	if !e.and() {
+		return false
+	}
+	for e.accept("OR") {
+		if !e.and() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *expression) and() bool {
+	if !e.license() {
+		return false
+	}
+	for e.accept("AND") {
+		if !e.license() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *expression) license() bool {
+	if e.accept("(") {
+		return e.or() && e.accept(")")
+	}
+
+	if e.next == len(e.tokens) {
+		return false
+	}
+	token := e.tokens[e.next]
+
+	id, ok := knownLicenses[strings.ToLower(token)]
+	if !ok && strings.HasSuffix(token, "+") {
+		id, ok = knownLicenses[strings.ToLower(strings.TrimSuffix(token, "+"))]
+		id += "+"
+	}
+	if !ok {
+		return false
+	}
+	e.next++
+	e.out = append(e.out, id)
+
+	if !e.accept("WITH") {
+		return true
+	}
+	if e.next == len(e.tokens) {
+		return false
+	}
+	exception, ok := knownLicenseExceptions[strings.ToLower(e.tokens[e.next])]
+	if !ok {
+		return false
+	}
+	e.next++
+	e.out = append(e.out, exception)
+	return true
+}
+
+// accept consumes the operator or parenthesis, if it is the next token
+func (e *expression) accept(token string) bool {
+	if e.next < len(e.tokens) && strings.EqualFold(e.tokens[e.next], token) {
+		e.next++
+		e.out = append(e.out, token)
+		return true
+	}
+	return false
+}
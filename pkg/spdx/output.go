@@ -0,0 +1,34 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+func WriteSPDXFile(md metadata.Metadata, name string, tool metadata.Provenance, spdxFilePath string) error {
+	doc, err := BuildDocument(md, name, tool, time.Now())
+	if err != nil {
+		return fmt.Errorf("could not generate spdx document: %w", err)
+	}
+
+	spdxFile, err := os.Create(spdxFilePath)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %w", spdxFilePath, err)
+	}
+	defer spdxFile.Close()
+
+	encoder := json.NewEncoder(spdxFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("could not write spdx file: %w", err)
+	}
+	return nil
+}
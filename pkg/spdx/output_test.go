@@ -0,0 +1,46 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx_test
+
+import (
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/spdx"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+)
+
+var _ = Describe("outputs", func() {
+	Describe("WriteSPDXFile", func() {
+		DescribeTable("when the file can be written", func(path string) {
+			defer test_utils.CleanupFile(path)
+
+			err := WriteSPDXFile(test_utils.MetadataSample, "image", metadata.Provenance{Name: "deplab", Version: "0.42.0"}, path)
+			Expect(err).ToNot(HaveOccurred())
+
+			f, err := os.Open(path)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+
+			doc := Document{}
+			Expect(json.NewDecoder(f).Decode(&doc)).To(Succeed())
+			Expect(doc.Packages).To(HaveLen(2))
+		},
+			Entry("the file exists", test_utils.ExistingFileName()),
+			Entry("the file does not exists", test_utils.NonExistingFileName()),
+		)
+
+		Describe("and the document can't be written", func() {
+			It("returns an error", func() {
+				err := WriteSPDXFile(test_utils.MetadataSample, "image", metadata.Provenance{Name: "deplab", Version: "0.42.0"}, "a-path-that-does-not-exist/foo.spdx.json")
+
+				Expect(err).To(MatchError(ContainSubstring("a-path-that-does-not-exist/foo.spdx.json")))
+			})
+		})
+	})
+})
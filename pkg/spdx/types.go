@@ -0,0 +1,70 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package spdx
+
+const (
+	Version     = "SPDX-2.3"
+	DataLicense = "CC0-1.0"
+	DocumentID  = "SPDXRef-DOCUMENT"
+	ImageID     = "SPDXRef-Image"
+	NoAssertion = "NOASSERTION"
+)
+
+type Document struct {
+	SPDXVersion             string                   `json:"spdxVersion"`
+	DataLicense             string                   `json:"dataLicense"`
+	SPDXID                  string                   `json:"SPDXID"`
+	Name                    string                   `json:"name"`
+	DocumentNamespace       string                   `json:"documentNamespace"`
+	CreationInfo            CreationInfo             `json:"creationInfo"`
+	Packages                []Package                `json:"packages"`
+	Relationships           []Relationship           `json:"relationships"`
+	ExtractedLicensingInfos []ExtractedLicensingInfo `json:"hasExtractedLicensingInfos,omitempty"`
+	DocumentDescribes       []string                 `json:"documentDescribes,omitempty"`
+}
+
+type CreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type Package struct {
+	Name                  string        `json:"name"`
+	SPDXID                string        `json:"SPDXID"`
+	VersionInfo           string        `json:"versionInfo,omitempty"`
+	Supplier              string        `json:"supplier,omitempty"`
+	DownloadLocation      string        `json:"downloadLocation"`
+	FilesAnalyzed         bool          `json:"filesAnalyzed"`
+	SourceInfo            string        `json:"sourceInfo,omitempty"`
+	LicenseConcluded      string        `json:"licenseConcluded,omitempty"`
+	LicenseDeclared       string        `json:"licenseDeclared,omitempty"`
+	CopyrightText         string        `json:"copyrightText,omitempty"`
+	Checksums             []Checksum    `json:"checksums,omitempty"`
+	ExternalRefs          []ExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string        `json:"primaryPackagePurpose,omitempty"`
+	Comment               string        `json:"comment,omitempty"`
+}
+
+type Checksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type ExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type Relationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type ExtractedLicensingInfo struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name,omitempty"`
+}
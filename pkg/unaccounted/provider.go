@@ -0,0 +1,252 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package unaccounted
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/apk"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/dpkg"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/java"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/rpm"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var (
+	sharedLibrary = regexp.MustCompile(`\.so(\.[0-9]+)*$`)
+
+	archiveExtensions = []string{
+		".a", ".ear", ".egg", ".gem", ".jar", ".nupkg", ".tar", ".tar.bz2",
+		".tar.gz", ".tar.xz", ".tgz", ".war", ".whl", ".zip",
+	}
+
+	// mergedUsrDirs are linked from the root directory on merged /usr
+	// systems, where packages may still list their files at the root
+	mergedUsrDirs = []string{"/usr/bin/", "/usr/sbin/", "/usr/lib/", "/usr/lib32/", "/usr/lib64/", "/usr/libx32/"}
+)
+
+// Provider lists the executables, shared libraries and archives of the image
+// which are neither owned by a dpkg, rpm or apk package, nor reported by one
+// of the providers run before it
+func Provider(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+	visitor, provider := Scan(dli, params)
+	if err := image.WalkVisitors(dli, visitor); err != nil {
+		return metadata.Metadata{}, fmt.Errorf("could not walk the image files: %w", err)
+	}
+	return provider(dli, params, md)
+}
+
+// Scan returns the visitor reading the package databases and hashing the
+// candidate files of the image, with --unaccounted-files, in a walk shared
+// with other providers, and the provider listing the candidates which are not
+// accounted for once it is done
+func Scan(_ image.Image, params common.RunParams) (image.FileVisitor, func(image.Image, common.RunParams, metadata.Metadata) (metadata.Metadata, error)) {
+	databases := map[string]string{}
+	var candidates []metadata.UnaccountedFile
+
+	var visitor image.FileVisitor
+	if params.UnaccountedFiles {
+		visitor = image.FileVisitor{
+			Match: func(filePath string, info os.FileInfo) bool {
+				return isPackageDatabase(filePath) || IsCandidate(filePath, info)
+			},
+			Visit: func(filePath string, info os.FileInfo, content io.Reader) error {
+				if isPackageDatabase(filePath) {
+					b, err := ioutil.ReadAll(content)
+					if err != nil {
+						return err
+					}
+					databases[filePath] = string(b)
+					return nil
+				}
+
+				h := sha256.New()
+				if _, err := io.Copy(h, content); err != nil {
+					return err
+				}
+				candidates = append(candidates, metadata.UnaccountedFile{
+					Path:   filePath,
+					Size:   info.Size(),
+					SHA256: hex.EncodeToString(h.Sum(nil)),
+				})
+				return nil
+			},
+		}
+	}
+
+	provider := func(dli image.Image, params common.RunParams, md metadata.Metadata) (metadata.Metadata, error) {
+		if !params.UnaccountedFiles {
+			return md, nil
+		}
+
+		owned := ownership{files: map[string]bool{}}
+		err := owned.addDependencies(md.Dependencies)
+		if err != nil {
+			return metadata.Metadata{}, err
+		}
+
+		rpmFiles, err := rpm.OwnedFiles(dli)
+		if err != nil {
+			return metadata.Metadata{}, fmt.Errorf("could not list rpm files: %w", err)
+		}
+		owned.addFiles(rpmFiles...)
+
+		for filePath, content := range databases {
+			owned.addPackageDatabase(filePath, content)
+		}
+
+		files := []metadata.UnaccountedFile{}
+		for _, candidate := range candidates {
+			if !owned.owns(candidate.Path) {
+				files = append(files, candidate)
+			}
+		}
+
+		collator := collate.New(language.BritishEnglish)
+		sort.Slice(files, func(i, j int) bool {
+			return collator.CompareString(files[i].Path, files[j].Path) < 0
+		})
+
+		md.UnaccountedFiles = files
+		return md, nil
+	}
+
+	return visitor, provider
+}
+
+// IsCandidate tells whether a file is an executable, a shared library or an
+// archive, which needs to be accounted for
+func IsCandidate(filePath string, info os.FileInfo) bool {
+	if info.Mode()&0111 != 0 {
+		return true
+	}
+
+	name := strings.ToLower(path.Base(filePath))
+	if sharedLibrary.MatchString(name) {
+		return true
+	}
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPackageDatabase(filePath string) bool {
+	dir := path.Dir(filePath)
+	return dir == dpkg.InfoPath && path.Ext(filePath) == ".list" ||
+		dir == dpkg.StatusDPath && path.Ext(filePath) == ".md5sums" ||
+		filePath == apk.InstalledDBPath
+}
+
+// ownership holds the files claimed by the package managers and providers,
+// and the directories whose content is claimed as a whole
+type ownership struct {
+	files map[string]bool
+	dirs  []string
+}
+
+func (o *ownership) addFiles(files ...string) {
+	for _, file := range files {
+		o.files[path.Clean(file)] = true
+	}
+}
+
+func (o *ownership) addDirs(dirs ...string) {
+	for _, dir := range dirs {
+		o.dirs = append(o.dirs, path.Clean(dir)+"/")
+	}
+}
+
+func (o *ownership) addPackageDatabase(filePath, content string) {
+	switch {
+	case filePath == apk.InstalledDBPath:
+		o.addFiles(apk.OwnedFiles(content)...)
+	case path.Ext(filePath) == ".md5sums":
+		for file := range dpkg.ParseMD5Sums(content) {
+			o.addFiles(file)
+		}
+	default:
+		for _, line := range strings.Split(content, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				o.addFiles(line)
+			}
+		}
+	}
+}
+
+// addDependencies claims the files reported by the providers, the binaries
+// and archives by path, and the node_modules and site-packages directories
+// as a whole
+func (o *ownership) addDependencies(dependencies []metadata.Dependency) error {
+	for _, dependency := range dependencies {
+		switch dependency.Type {
+		case metadata.GoBinariesSourceType:
+			var sourceMetadata metadata.GoBinariesSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return err
+			}
+			for _, binary := range sourceMetadata.Binaries {
+				o.addFiles(binary.Path)
+			}
+		case metadata.MavenPackageListSourceType:
+			var sourceMetadata metadata.MavenPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				o.addFiles(strings.Split(pkg.Path, java.NestedSeparator)[0])
+			}
+		case metadata.NpmPackageListSourceType:
+			var sourceMetadata metadata.NpmPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				o.addDirs(pkg.Location)
+			}
+		case metadata.PythonPackageListSourceType:
+			var sourceMetadata metadata.PythonPackageListSourceMetadata
+			if err := metadata.DecodeSourceMetadata(dependency.Source, &sourceMetadata); err != nil {
+				return err
+			}
+			for _, pkg := range sourceMetadata.Packages {
+				o.addDirs(pkg.Location)
+			}
+		}
+	}
+	return nil
+}
+
+func (o *ownership) owns(filePath string) bool {
+	if o.files[filePath] {
+		return true
+	}
+	for _, dir := range mergedUsrDirs {
+		if strings.HasPrefix(filePath, dir) && o.files[strings.TrimPrefix(filePath, "/usr")] {
+			return true
+		}
+	}
+	for _, dir := range o.dirs {
+		if strings.HasPrefix(filePath, dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package unaccounted_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/common"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/vmware-tanzu/dependency-labeler/pkg/unaccounted"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("unaccounted", func() {
+	Describe("IsCandidate", func() {
+		It("selects executables, shared libraries and archives", func() {
+			Expect(IsCandidate("/usr/local/bin/app", test_utils.NewFakeFileInfo("app", 0, 0755))).To(BeTrue())
+			Expect(IsCandidate("/usr/lib/libz.so", test_utils.NewFakeFileInfo("libz.so", 0, 0644))).To(BeTrue())
+			Expect(IsCandidate("/usr/lib/libz.so.1.2.11", test_utils.NewFakeFileInfo("libz.so.1.2.11", 0, 0644))).To(BeTrue())
+			Expect(IsCandidate("/opt/app/lib/app.JAR", test_utils.NewFakeFileInfo("app.JAR", 0, 0644))).To(BeTrue())
+			Expect(IsCandidate("/opt/release.tar.gz", test_utils.NewFakeFileInfo("release.tar.gz", 0, 0644))).To(BeTrue())
+
+			Expect(IsCandidate("/etc/passwd", test_utils.NewFakeFileInfo("passwd", 0, 0644))).To(BeFalse())
+			Expect(IsCandidate("/usr/lib/libz.so.conf", test_utils.NewFakeFileInfo("libz.so.conf", 0, 0644))).To(BeFalse())
+		})
+	})
+
+	Describe("Provider", func() {
+		var (
+			dli test_utils.FakeImage
+			md  metadata.Metadata
+		)
+
+		BeforeEach(func() {
+			dli = test_utils.FakeImage{
+				Files: map[string]string{
+					"/var/lib/dpkg/info/bash.list":         "/.\n/bin\n/bin/bash\n/usr/share/doc/bash/README\n",
+					"/var/lib/dpkg/status.d/libc6.md5sums": "d41d8cd98f00b204e9800998ecf8427e  lib/x86_64-linux-gnu/libc.so.6\n",
+					"/lib/apk/db/installed":                "P:musl\nV:1.1.24-r2\nF:lib\nR:libc.musl-x86_64.so.1\n\n",
+					"/usr/bin/bash":                        "bash",
+					"/lib/x86_64-linux-gnu/libc.so.6":      "libc",
+					"/lib/libc.musl-x86_64.so.1":           "musl",
+					"/usr/local/bin/app":                   "app",
+					"/usr/local/bin/entrypoint.sh":         "#!/bin/sh",
+					"/opt/app/app.jar":                     "jar",
+					"/opt/app/vendor.jar":                  "vendor",
+					"/opt/app/node_modules/left-pad/x.so":  "addon",
+					"/usr/local/lib/libfoo.so.1":           "foo",
+					"/etc/passwd":                          "root",
+				},
+				Modes: map[string]os.FileMode{
+					"/usr/bin/bash":                0755,
+					"/usr/local/bin/app":           0755,
+					"/usr/local/bin/entrypoint.sh": 0755,
+				},
+			}
+
+			md = metadata.Metadata{Dependencies: []metadata.Dependency{
+				{
+					Type: metadata.GoBinariesSourceType,
+					Source: metadata.Source{Metadata: metadata.GoBinariesSourceMetadata{
+						Binaries: []metadata.GoBinary{{Path: "/usr/local/bin/app"}},
+					}},
+				},
+				{
+					Type: metadata.MavenPackageListSourceType,
+					Source: metadata.Source{Metadata: metadata.MavenPackageListSourceMetadata{
+						Packages: []metadata.MavenPackage{{ArtifactID: "lib", Path: "/opt/app/app.jar!/lib/lib.jar"}},
+					}},
+				},
+				{
+					// as read back from an existing label
+					Type: metadata.NpmPackageListSourceType,
+					Source: metadata.Source{Metadata: map[string]interface{}{
+						"packages": []interface{}{
+							map[string]interface{}{"package": "left-pad", "location": "/opt/app/node_modules/left-pad"},
+						},
+					}},
+				},
+			}}
+		})
+
+		It("lists the candidates owned by no package nor provider", func() {
+			md, err := Provider(dli, common.RunParams{UnaccountedFiles: true}, md)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(md.Dependencies).To(HaveLen(3))
+			Expect(md.UnaccountedFiles).To(Equal([]metadata.UnaccountedFile{
+				{
+					Path:   "/opt/app/vendor.jar",
+					Size:   6,
+					SHA256: sha256Hex("vendor"),
+				},
+				{
+					Path:   "/usr/local/bin/entrypoint.sh",
+					Size:   9,
+					SHA256: sha256Hex("#!/bin/sh"),
+				},
+				{
+					Path:   "/usr/local/lib/libfoo.so.1",
+					Size:   3,
+					SHA256: sha256Hex("foo"),
+				},
+			}))
+		})
+
+		It("maps only the directories linked from the root on merged /usr systems", func() {
+			dli.Files["/var/lib/dpkg/info/libc6.list"] = "/lib64/ld-linux-x86-64.so.2\n/libexec/helper\n"
+			dli.Files["/usr/lib64/ld-linux-x86-64.so.2"] = "ld"
+			dli.Files["/usr/libexec/helper"] = "helper"
+			dli.Modes["/usr/lib64/ld-linux-x86-64.so.2"] = 0755
+			dli.Modes["/usr/libexec/helper"] = 0755
+
+			md, err := Provider(dli, common.RunParams{UnaccountedFiles: true}, md)
+			Expect(err).ToNot(HaveOccurred())
+
+			var paths []string
+			for _, file := range md.UnaccountedFiles {
+				paths = append(paths, file.Path)
+			}
+			Expect(paths).To(ContainElement("/usr/libexec/helper"))
+			Expect(paths).ToNot(ContainElement("/usr/lib64/ld-linux-x86-64.so.2"))
+		})
+
+		It("does nothing by default", func() {
+			md, err := Provider(dli, common.RunParams{}, md)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(md.UnaccountedFiles).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,122 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package warnings
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Codes of the warnings, stable so that they can be matched on
+const (
+	InvalidSourceURL      = "invalid_source_url"
+	InvalidSourcesFile    = "invalid_sources_file"
+	ExistingLabelMismatch = "existing_label_mismatch"
+	InvalidPackageEntry   = "invalid_package_entry"
+	UnknownBaseImage      = "unknown_base_image"
+	UnreadableArtifact    = "unreadable_artifact"
+	UnmergedArtifact      = "unmerged_artifact"
+)
+
+type Warning struct {
+	Code     string `json:"code"`
+	Provider string `json:"provider"`
+	Message  string `json:"message"`
+	Subject  string `json:"subject,omitempty"`
+}
+
+func (w Warning) String() string {
+	s := fmt.Sprintf("warning [%s] ", w.Code)
+	if w.Provider != "" {
+		s += w.Provider + ": "
+	}
+	s += w.Message
+	if w.Subject != "" {
+		s += fmt.Sprintf(" (%s)", w.Subject)
+	}
+	return s
+}
+
+// Collector gathers the warnings of all the providers of a run. A nil
+// Collector logs the warnings instead.
+type Collector struct {
+	provider string
+	warnings *[]Warning
+}
+
+func NewCollector() *Collector {
+	return &Collector{warnings: &[]Warning{}}
+}
+
+// ForProvider returns a collector adding its warnings to c on behalf of the
+// named provider
+func (c *Collector) ForProvider(name string) *Collector {
+	if c == nil {
+		return nil
+	}
+	return &Collector{provider: name, warnings: c.warnings}
+}
+
+func (c *Collector) Add(code, subject, message string) {
+	if c == nil {
+		log.Println(Warning{Code: code, Message: message, Subject: subject})
+		return
+	}
+	*c.warnings = append(*c.warnings, Warning{
+		Code:     code,
+		Provider: c.provider,
+		Message:  message,
+		Subject:  subject,
+	})
+}
+
+func (c *Collector) Warnings() []Warning {
+	if c == nil {
+		return []Warning{}
+	}
+	return append([]Warning{}, *c.warnings...)
+}
+
+// WriteSummary writes one line per warning, after a count of the warnings
+func WriteSummary(w io.Writer, warnings []Warning) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	plural := "s"
+	if len(warnings) == 1 {
+		plural = ""
+	}
+	_, err := fmt.Fprintf(w, "%d warning%s:\n", len(warnings), plural)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		_, err := fmt.Fprintln(w, warning)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteWarningsFile(warnings []Warning, warningsFilePath string) error {
+	warningsFile, err := os.Create(warningsFilePath)
+	if err != nil {
+		return fmt.Errorf("could not create file %s: %w", warningsFilePath, err)
+	}
+	defer warningsFile.Close()
+
+	encoder := json.NewEncoder(warningsFile)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(warnings)
+	if err != nil {
+		return fmt.Errorf("could not write warnings file: %w", err)
+	}
+	return nil
+}
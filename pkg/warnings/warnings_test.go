@@ -0,0 +1,88 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package warnings_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+var _ = Describe("Collector", func() {
+	It("collects the warnings of each provider", func() {
+		collector := warnings.NewCollector()
+
+		collector.ForProvider("apk").Add(warnings.InvalidPackageEntry, "/lib/apk/db/installed", "skipping entry")
+		collector.ForProvider("existing-label").Add(warnings.ExistingLabelMismatch, "base", "difference identified")
+
+		Expect(collector.Warnings()).To(Equal([]warnings.Warning{
+			{Code: warnings.InvalidPackageEntry, Provider: "apk", Message: "skipping entry", Subject: "/lib/apk/db/installed"},
+			{Code: warnings.ExistingLabelMismatch, Provider: "existing-label", Message: "difference identified", Subject: "base"},
+		}))
+	})
+
+	It("does not panic without a collector", func() {
+		var collector *warnings.Collector
+
+		collector.ForProvider("apk").Add(warnings.InvalidPackageEntry, "", "skipping entry")
+
+		Expect(collector.Warnings()).To(BeEmpty())
+	})
+
+	Describe("WriteSummary", func() {
+		It("writes one line per warning", func() {
+			buffer := bytes.Buffer{}
+
+			err := warnings.WriteSummary(&buffer, []warnings.Warning{
+				{Code: warnings.InvalidSourceURL, Provider: "additional-source-url", Message: "failed to validate additional source url", Subject: "/foo/bar"},
+				{Code: warnings.ExistingLabelMismatch, Provider: "existing-label", Message: "difference identified"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(buffer.String()).To(Equal(`2 warnings:
+warning [invalid_source_url] additional-source-url: failed to validate additional source url (/foo/bar)
+warning [existing_label_mismatch] existing-label: difference identified
+`))
+		})
+
+		It("writes nothing without warnings", func() {
+			buffer := bytes.Buffer{}
+
+			Expect(warnings.WriteSummary(&buffer, nil)).To(Succeed())
+			Expect(buffer.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("WriteWarningsFile", func() {
+		It("writes the warnings as a JSON array", func() {
+			dir, err := ioutil.TempDir("", "deplab-warnings-")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "warnings.json")
+			err = warnings.WriteWarningsFile(warnings.NewCollector().Warnings(), path)
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := ioutil.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(MatchJSON(`[]`))
+
+			var written []warnings.Warning
+			Expect(json.Unmarshal(content, &written)).To(Succeed())
+		})
+
+		It("returns an error if the file cannot be written", func() {
+			err := warnings.WriteWarningsFile(nil, "a-path-that-does-not-exist/warnings.json")
+
+			Expect(err).To(MatchError(ContainSubstring("a-path-that-does-not-exist/warnings.json")))
+		})
+	})
+})
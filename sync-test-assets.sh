@@ -29,6 +29,7 @@ images=( "cloudfoundry/run:tiny" \
           "$DEPLAB_ASSET_REPOSITORY:broken-files" \
           "$DEPLAB_ASSET_REPOSITORY:tiny-with-invalid-label" \
           "$DEPLAB_ASSET_REPOSITORY:os-release-on-scratch" \
+          "$DEPLAB_ASSET_REPOSITORY:apk-on-scratch" \
           "$DEPLAB_ASSET_REPOSITORY:scratch" \
           "$DEPLAB_ASSET_REPOSITORY:scratch-with-buildpack-metadata" \
           "$DEPLAB_ASSET_REPOSITORY:char-device")
@@ -0,0 +1,69 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("[apk] deplab apk", func() {
+	Context("with an image with an apk installed database", func() {
+		It("returns apk metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/apk-on-scratch.tgz"))
+
+			apkPackages := selectApkDependencies(metadataLabel.Dependencies)
+			Expect(apkPackages).To(HaveLen(1))
+			apkPackage := apkPackages[0]
+			Expect(apkPackage.Type).To(Equal(metadata.ApkPackageListSourceType))
+			Expect(apkPackage.Source.Type).To(Equal("inline"))
+
+			sourceMetadata := apkPackage.Source.Metadata.(map[string]interface{})
+			packages := sourceMetadata["packages"].([]interface{})
+			Expect(packages).To(HaveLen(3))
+			Expect(ArePackagesSorted(packages)).To(BeTrue())
+			Expect(packages[1]).To(Equal(map[string]interface{}{
+				"package":      "musl",
+				"version":      "1.1.24-r2",
+				"architecture": "x86_64",
+				"license":      "MIT",
+				"origin":       "musl",
+				"commit":       "bfe5a5ecb0ed2a1bcd4e8a5f0b5a1b1c0f1a6b22",
+				"maintainer":   "Timo Teräs <timo.teras@iki.fi>",
+			}))
+
+			Expect(sourceMetadata["repositories"]).To(Equal([]interface{}{
+				"http://dl-cdn.alpinelinux.org/alpine/v3.11/community",
+				"http://dl-cdn.alpinelinux.org/alpine/v3.11/main",
+			}))
+
+			By("generating a sha256 digest of the metadata content as version")
+			Expect(apkPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+
+			By("reporting the alpine base")
+			Expect(metadataLabel.Base["id"]).To(Equal("alpine"))
+		})
+	})
+
+	Context("image without apk installed database", func() {
+		It("does not return apk metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch.tgz"))
+
+			Expect(selectApkDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		})
+	})
+})
+
+func selectApkDependencies(dependencies []metadata.Dependency) []metadata.Dependency {
+	var apkDependencies []metadata.Dependency
+	for _, dependency := range dependencies {
+		if dependency.Type == metadata.ApkPackageListSourceType {
+			apkDependencies = append(apkDependencies, dependency)
+		}
+	}
+	return apkDependencies
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/artifact"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --attach", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-attach-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		inspect := func(args ...string) metadata.Metadata {
+			stdOut, _ := runDepLab(append([]string{"inspect"}, args...), 0)
+
+			md := metadata.Metadata{}
+			Expect(json.NewDecoder(stdOut).Decode(&md)).To(Succeed())
+			return md
+		}
+
+		gitDependency := func(md metadata.Metadata) metadata.Dependency {
+			for _, dependency := range md.Dependencies {
+				if dependency.Source.Type == metadata.GitSourceType {
+					return dependency
+				}
+			}
+			Fail("no git dependency in the metadata")
+			return metadata.Dependency{}
+		}
+
+		Context("to an image in a registry", func() {
+			var (
+				server     *httptest.Server
+				inputImage string
+			)
+
+			BeforeEach(func() {
+				server = httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+				inputImage = strings.TrimPrefix(server.URL, "http://") + "/deplab/app:latest"
+
+				img, err := crane.Load(getTestAssetPath("image-archives/scratch.tgz"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(crane.Push(img, inputImage)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("leaves the image untouched and inspect discovers the attached metadata", func() {
+				digest, err := crane.Digest(inputImage)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _ = runDepLab([]string{
+					"--image", inputImage,
+					"--git", pathToGitRepo,
+					"--attach", "metadata,spdx",
+				}, 0)
+
+				Expect(crane.Digest(inputImage)).To(Equal(digest))
+
+				tags, err := crane.ListTags(strings.Split(inputImage, ":latest")[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tags).To(ContainElement(strings.Replace(digest, ":", "-", 1)))
+
+				md := inspect("--image", inputImage)
+				Expect(gitDependency(md).Source.Version["commit"]).To(Equal(commitHash))
+			})
+		})
+
+		Context("to an image tarball", func() {
+			It("writes the artifacts to the oci layout, where inspect discovers the metadata", func() {
+				layoutPath := filepath.Join(dir, "layout")
+
+				_, _ = runDepLab([]string{
+					"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+					"--git", pathToGitRepo,
+					"--attach", "metadata,cyclonedx,attestation",
+					"--attach-oci-layout", layoutPath,
+				}, 0)
+
+				index, err := ioutil.ReadFile(filepath.Join(layoutPath, "index.json"))
+				Expect(err).ToNot(HaveOccurred())
+				var manifests artifact.Index
+				Expect(json.Unmarshal(index, &manifests)).To(Succeed())
+				Expect(manifests.Manifests).To(HaveLen(3))
+
+				md := inspect("--image-tar", getTestAssetPath("image-archives/scratch.tgz"), "--referrers-oci-layout", layoutPath)
+				Expect(gitDependency(md).Source.Version["commit"]).To(Equal(commitHash))
+
+				md = inspect("--image-tar", getTestAssetPath("image-archives/scratch.tgz"))
+				Expect(md.Dependencies).To(BeEmpty())
+			})
+
+			It("requires a repository or an oci layout to attach the artifacts to", func() {
+				_, stdErr := runDepLab([]string{
+					"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+					"--attach", "metadata",
+				}, 1)
+
+				Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("ERROR: --attach with --image-tar requires one of --attach-repository or --attach-oci-layout"))
+			})
+		})
+
+		It("rejects unknown artifacts", func() {
+			_, stdErr := runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+				"--attach", "sbom",
+				"--attach-oci-layout", dir,
+			}, 1)
+
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("ERROR: unknown artifact sbom, --attach must be one of: metadata, spdx, cyclonedx, attestation"))
+		})
+	})
+})
@@ -0,0 +1,155 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/attestation"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --attestation-file", func() {
+		var (
+			dir             string
+			imageTarPath    string
+			attestationPath string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-attestation-")
+			Expect(err).ToNot(HaveOccurred())
+
+			imageTarPath = filepath.Join(dir, "image.tar")
+			attestationPath = filepath.Join(dir, "attestation.json")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		imageDigest := func(path string) string {
+			img, err := crane.Load(path)
+			Expect(err).ToNot(HaveOccurred())
+			digest, err := img.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			return digest.Hex
+		}
+
+		readStatement := func(content []byte) (statement struct {
+			attestation.Statement
+			Predicate json.RawMessage `json:"predicate"`
+		}) {
+			Expect(json.Unmarshal(content, &statement)).To(Succeed())
+			return statement
+		}
+
+		It("writes an slsa provenance of the labelled image", func() {
+			runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--attestation-file", attestationPath)
+
+			content, err := ioutil.ReadFile(attestationPath)
+			Expect(err).ToNot(HaveOccurred())
+			statement := readStatement(content)
+
+			Expect(statement.Type).To(Equal(attestation.StatementType))
+			Expect(statement.Subject).To(HaveLen(1))
+			Expect(statement.Subject[0].Digest).To(Equal(map[string]string{"sha256": imageDigest(imageTarPath)}))
+
+			Expect(statement.PredicateType).To(Equal(attestation.SLSAPredicateType))
+			var provenance attestation.Provenance
+			Expect(json.Unmarshal(statement.Predicate, &provenance)).To(Succeed())
+
+			var gitDependencies []attestation.ResourceDescriptor
+			for _, dependency := range provenance.BuildDefinition.ResolvedDependencies {
+				if dependency.URI == "git+https://example.com/example.git" {
+					gitDependencies = append(gitDependencies, dependency)
+				}
+			}
+			Expect(gitDependencies).To(HaveLen(1))
+			Expect(gitDependencies[0].Digest).To(Equal(map[string]string{"gitCommit": commitHash}))
+			Expect(provenance.RunDetails.Builder.ID).To(Equal("https://github.com/vmware-tanzu/dependency-labeler"))
+			Expect(provenance.RunDetails.Metadata.StartedOn).ToNot(BeEmpty())
+		})
+
+		It("writes the deplab metadata as the predicate with --attestation-predicate deplab", func() {
+			metadataLabel := runDeplabAgainstTar(getTestAssetPath("image-archives/os-release-on-scratch.tgz"),
+				"--attestation-file", attestationPath,
+				"--attestation-predicate", "deplab")
+
+			content, err := ioutil.ReadFile(attestationPath)
+			Expect(err).ToNot(HaveOccurred())
+			statement := readStatement(content)
+
+			Expect(statement.PredicateType).To(Equal(attestation.DeplabPredicateType))
+			Expect(string(statement.Predicate)).To(ContainSubstring(metadataLabel.Base["id"]))
+			Expect(string(statement.Predicate)).To(ContainSubstring(commitHash))
+		})
+
+		It("wraps the statement in a DSSE envelope when given a signing key", func() {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			der, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+			keyPath := filepath.Join(dir, "deplab.key")
+			Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+
+			ociLayoutPath := filepath.Join(dir, "layout")
+			runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--output-oci-layout", ociLayoutPath,
+				"--signing-key", keyPath,
+				"--attestation-file", attestationPath)
+
+			content, err := ioutil.ReadFile(attestationPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			var envelope attestation.Envelope
+			Expect(json.Unmarshal(content, &envelope)).To(Succeed())
+			Expect(envelope.PayloadType).To(Equal(attestation.PayloadType))
+			Expect(envelope.Signatures).To(HaveLen(1))
+			Expect(envelope.Signatures[0].KeyID).To(HavePrefix("sha256:"))
+
+			payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+			Expect(err).ToNot(HaveOccurred())
+			statement := readStatement(payload)
+
+			By("attesting the same image as all the outputs, despite ECDSA signatures differing every time")
+			digest := imageDigest(imageTarPath)
+			Expect(statement.Subject[0].Digest).To(Equal(map[string]string{"sha256": digest}))
+
+			index, err := ioutil.ReadFile(filepath.Join(ociLayoutPath, "index.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Contains(string(index), digest)).To(BeTrue())
+		})
+
+		It("exits with an error if the predicate is unknown", func() {
+			_, stdErr := runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+				"--git", pathToGitRepo,
+				"--attestation-file", attestationPath,
+				"--attestation-predicate", "spdx",
+			}, 1)
+
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("ERROR: --attestation-predicate must be one of: slsa, deplab"))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --compress-label", func() {
+		var (
+			dir          string
+			imageTarPath string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-compressed-label-")
+			Expect(err).ToNot(HaveOccurred())
+
+			imageTarPath = filepath.Join(dir, "image.tar")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		labels := func() map[string]string {
+			img, err := crane.Load(imageTarPath)
+			Expect(err).ToNot(HaveOccurred())
+			config, err := img.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+			return config.Config.Labels
+		}
+
+		inspect := func(args ...string) metadata.Metadata {
+			stdOut, _ := runDepLab(append([]string{"inspect", "--image-tar", imageTarPath}, args...), 0)
+
+			md := metadata.Metadata{}
+			Expect(json.NewDecoder(stdOut).Decode(&md)).To(Succeed())
+			return md
+		}
+
+		It("compresses the label above the threshold, which inspect decodes", func() {
+			generated := runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--compress-label",
+				"--compress-label-threshold", "0")
+
+			Expect(labels()).To(HaveKey(metadata.CompressedLabelName))
+			Expect(labels()).ToNot(HaveKey(metadata.LabelName))
+
+			md := inspect()
+			Expect(md.Dependencies).To(Equal(generated.Dependencies))
+		})
+
+		It("keeps the label as JSON below the threshold", func() {
+			runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--compress-label")
+
+			Expect(labels()).To(HaveKey(metadata.LabelName))
+			Expect(labels()).ToNot(HaveKey(metadata.CompressedLabelName))
+		})
+
+		It("signs the JSON of the compressed label", func() {
+			publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+			Expect(err).ToNot(HaveOccurred())
+			publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			privateKeyPath := filepath.Join(dir, "deplab.key")
+			publicKeyPath := filepath.Join(dir, "deplab.pub")
+			Expect(ioutil.WriteFile(privateKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)).To(Succeed())
+			Expect(ioutil.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)).To(Succeed())
+
+			runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--signing-key", privateKeyPath,
+				"--compress-label",
+				"--compress-label-threshold", "0")
+
+			Expect(labels()).To(HaveKey(metadata.CompressedLabelName))
+			inspect("--verify-key", publicKeyPath)
+		})
+
+		It("rejects a negative threshold", func() {
+			_, stdErr := runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--compress-label",
+				"--compress-label-threshold", "-1",
+			}, 1)
+
+			errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+			Expect(errorOutput).To(ContainSubstring("ERROR: --compress-label-threshold cannot be negative"))
+		})
+	})
+})
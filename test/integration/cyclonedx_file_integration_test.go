@@ -0,0 +1,107 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/cyclonedx"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deplab", func() {
+	Describe("when called with --cyclonedx-file", func() {
+		It("writes a json bill of materials by default", func() {
+			bomDestinationPath := test_utils.ExistingFileName()
+			defer test_utils.CleanupFile(bomDestinationPath)
+
+			_ = runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+				"--cyclonedx-file", bomDestinationPath)
+
+			f, err := os.Open(bomDestinationPath)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+
+			bom := cyclonedx.BOM{}
+			Expect(json.NewDecoder(f).Decode(&bom)).To(Succeed())
+
+			Expect(bom.SpecVersion).To(Equal("1.5"))
+			Expect(bom.Metadata.Tools).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name": Equal("deplab"),
+			})))
+			Expect(bom.Components).To(SatisfyAll(
+				ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Name":    Equal("openjdk-jdk"),
+					"Version": Equal("11.0.6"),
+				})),
+				ContainElement(MatchFields(IgnoreExtras, Fields{
+					"Name":    Equal("https://example.com/example.git"),
+					"Version": Equal(commitHash),
+				})),
+			))
+		})
+
+		It("writes an xml bill of materials with --cyclonedx-format xml", func() {
+			bomDestinationPath := test_utils.ExistingFileName()
+			defer test_utils.CleanupFile(bomDestinationPath)
+
+			_ = runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+				"--cyclonedx-file", bomDestinationPath,
+				"--cyclonedx-format", "xml")
+
+			content, err := ioutil.ReadFile(bomDestinationPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(SatisfyAll(
+				ContainSubstring(`<bom xmlns="http://cyclonedx.org/schema/bom/1.5"`),
+				ContainSubstring("<name>openjdk-jdk</name>"),
+			))
+		})
+
+		It("exits with an error if the format is not supported", func() {
+			_, stdErr := runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+				"--cyclonedx-file", test_utils.ExistingFileName(),
+				"--cyclonedx-format", "yaml",
+			}, 1)
+
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("ERROR: --cyclonedx-format must be one of: json, xml"))
+		})
+	})
+
+	Describe("inspect", func() {
+		It("prints a cyclonedx bill of materials with --output cyclonedx", func() {
+			stdOut, _ := runDepLab([]string{
+				"inspect",
+				"--image-tar", getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+				"--output", "cyclonedx",
+			}, 0)
+
+			bom := cyclonedx.BOM{}
+			Expect(json.NewDecoder(stdOut).Decode(&bom)).To(Succeed())
+
+			Expect(bom.BOMFormat).To(Equal("CycloneDX"))
+			Expect(bom.Metadata.Component.Name).To(Equal("scratch-with-buildpack-metadata.tgz"))
+		})
+
+		It("prints xml with --cyclonedx-format xml", func() {
+			stdOut, _ := runDepLab([]string{
+				"inspect",
+				"--image-tar", getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+				"--output", "cyclonedx",
+				"--cyclonedx-format", "xml",
+			}, 0)
+
+			Expect(string(getContentsOfReader(stdOut))).To(ContainSubstring(`<bom xmlns="http://cyclonedx.org/schema/bom/1.5"`))
+		})
+	})
+})
@@ -64,7 +64,7 @@ var _ = Describe("deplab", func() {
 			}, 1)
 
 			errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
-			Expect(errorOutput).To(ContainSubstring("ERROR: requires one of --metadata-file, --dpkg-file, or --output-tar"))
+			Expect(errorOutput).To(ContainSubstring("ERROR: requires one of --metadata-file, --dpkg-file, --spdx-file, --cyclonedx-file, --attestation-file, --attach, --output-tar, --output-image, or --output-oci-layout"))
 		})
 
 		It("exits with an error if both image and image-tar flags are set", func() {
@@ -0,0 +1,107 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/diff"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("deplab diff", func() {
+	It("exits with an error if an image to compare is missing", func() {
+		_, stdErr := runDepLab([]string{"diff",
+			"--from-image-tar", "path/to/image.tar",
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: requires exactly one of --to-image, --to-image-tar or --to-metadata-file"))
+	})
+
+	It("exits with an error if an image to compare is given twice", func() {
+		_, stdErr := runDepLab([]string{"diff",
+			"--from-image-tar", "path/to/image.tar",
+			"--from-metadata-file", "path/to/metadata.json",
+			"--to-image-tar", "path/to/image.tar",
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: requires exactly one of --from-image, --from-image-tar or --from-metadata-file"))
+	})
+
+	It("compares two image tarballs", func() {
+		stdOut, _ := runDepLab([]string{"diff",
+			"--from-image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+			"--to-image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--output", "json",
+		}, 0)
+
+		d := diff.Diff{}
+		Expect(json.NewDecoder(stdOut).Decode(&d)).To(Succeed())
+
+		Expect(d.Packages).To(HaveLen(1))
+		Expect(d.Packages[0].Type).To(Equal(metadata.ApkPackageListSourceType))
+		Expect(d.Packages[0].Added).To(ConsistOf(
+			diff.Item{Name: "alpine-baselayout", Version: "3.2.0-r3"},
+			diff.Item{Name: "musl", Version: "1.1.24-r2"},
+			diff.Item{Name: "zlib", Version: "1.2.11-r3"},
+		))
+		Expect(d.Base).To(ContainElement(diff.Change{Name: "id", To: "alpine"}))
+	})
+
+	Context("with metadata files", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-diff-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("prints the changed packages", func() {
+			fromPath := filepath.Join(dir, "from.json")
+			Expect(metadata.WriteMetadataFile(metadata.Metadata{
+				Dependencies: []metadata.Dependency{{
+					Type: metadata.RPMPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.RpmPackageListSourceMetadata{Packages: []metadata.RpmPackage{
+							{Package: "bash", Version: "4.4.19"},
+						}},
+					},
+				}},
+			}, fromPath)).To(Succeed())
+
+			toPath := filepath.Join(dir, "to.json")
+			Expect(metadata.WriteMetadataFile(metadata.Metadata{
+				Dependencies: []metadata.Dependency{{
+					Type: metadata.RPMPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.RpmPackageListSourceMetadata{Packages: []metadata.RpmPackage{
+							{Package: "bash", Version: "5.0.17"},
+						}},
+					},
+				}},
+			}, toPath)).To(Succeed())
+
+			stdOut, _ := runDepLab([]string{"diff",
+				"--from-metadata-file", fromPath,
+				"--to-metadata-file", toPath,
+			}, 0)
+
+			Expect(string(getContentsOfReader(stdOut))).To(Equal("rpm_package_list\n  ~ bash 4.4.19 -> 5.0.17\n"))
+		})
+	})
+})
@@ -104,6 +104,114 @@ var _ = Describe("deplab dpkg", func() {
 		})
 	})
 
+	Context("with an image installing packages in several layers", func() {
+		It("records the layer which introduced each package with --attribute-layers", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-two-layers.tgz"), "--attribute-layers")
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs).To(HaveLen(2))
+
+			hello := pkgs[0].(map[string]interface{})["layer"].(map[string]interface{})
+			Expect(hello["digest"]).To(Equal("sha256:734b05f52f8ac809000d2b7112e699ecdd3ef52b03e17d1ac49e699acdfac7b9"))
+			Expect(hello["created_by"]).To(Equal("/bin/sh -c apt-get install -y hello"))
+
+			libc6 := pkgs[1].(map[string]interface{})["layer"].(map[string]interface{})
+			Expect(libc6["digest"]).To(Equal("sha256:6a218e16adfd169b0a11dc1dc21224790e15c5074662f3fdc059d7abe8990929"))
+			Expect(libc6["created_by"]).To(Equal("/bin/sh -c #(nop) ADD file:base.tar in / "))
+		})
+
+		It("reports the packages of the base image apart with --base-image-tar", func() {
+			basePath := getTestAssetPath("image-archives/dpkg-base.tgz")
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-two-layers.tgz"), "--base-image-tar", basePath)
+
+			Expect(metadataLabel.Base["image"]).To(Equal(basePath))
+			Expect(metadataLabel.Base["image_digest"]).To(MatchRegexp(`^sha256:[0-9a-f]{64}$`))
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs).To(HaveLen(1))
+			Expect(pkgs[0].(map[string]interface{})["package"]).To(Equal("hello"))
+
+			baseDependency, ok := test_utils.SelectDpkgDependency(metadataLabel.BaseDependencies)
+			Expect(ok).To(BeTrue())
+			basePkgs := baseDependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(basePkgs).To(HaveLen(1))
+			Expect(basePkgs[0].(map[string]interface{})["package"]).To(Equal("libc6"))
+		})
+	})
+
+	Context("with an image with a packaged file overwritten", func() {
+		It("reports the missing and modified files with --verify-packages", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-with-md5sums.tgz"), "--verify-packages")
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs).To(HaveLen(2))
+
+			hello := pkgs[0].(map[string]interface{})
+			Expect(hello["modified_files"]).To(Equal([]interface{}{"/usr/bin/hello"}))
+			Expect(hello["missing_files"]).To(Equal([]interface{}{"/usr/share/doc/hello/README"}))
+
+			zlib := pkgs[1].(map[string]interface{})
+			Expect(zlib).ToNot(HaveKey("modified_files"))
+			Expect(zlib).ToNot(HaveKey("missing_files"))
+		})
+
+		It("does not report the files excluded by the dpkg configuration as missing", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-with-path-excludes.tgz"), "--verify-packages")
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs).To(HaveLen(2))
+
+			hello := pkgs[0].(map[string]interface{})
+			Expect(hello["modified_files"]).To(Equal([]interface{}{"/usr/bin/hello"}))
+			Expect(hello).ToNot(HaveKey("missing_files"))
+		})
+
+		It("does not verify the packages by default", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-with-md5sums.tgz"))
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs[0]).ToNot(HaveKey("modified_files"))
+		})
+	})
+
+	Context("with an image with copyright files", func() {
+		It("reads the licenses of the packages with --dpkg-licenses", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-with-copyright.tgz"), "--dpkg-licenses")
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs).To(HaveLen(2))
+			Expect(pkgs[0].(map[string]interface{})["licenses"]).To(Equal([]interface{}{"GPL-2", "LGPL-2.1"}))
+			Expect(pkgs[1].(map[string]interface{})["licenses"]).To(Equal([]interface{}{"Zlib"}))
+		})
+
+		It("does not read the licenses by default", func() {
+			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/dpkg-with-copyright.tgz"))
+
+			dependency, ok := test_utils.SelectDpkgDependency(metadataLabel.Dependencies)
+			Expect(ok).To(BeTrue())
+
+			pkgs := dependency.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(pkgs[0]).ToNot(HaveKey("licenses"))
+		})
+	})
+
 	Context("with Pivotal Tiny", func() {
 		BeforeEach(func() {
 			metadataLabel = runDeplabAgainstTar(getTestAssetPath("image-archives/tiny.tgz"))
@@ -0,0 +1,63 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("[java] deplab java", func() {
+	Context("with an image with a spring boot jar", func() {
+		It("returns maven metadata, including the nested jars", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/java-on-scratch.tgz"))
+
+			mavenPackages := selectMavenDependencies(metadataLabel.Dependencies)
+			Expect(mavenPackages).To(HaveLen(1))
+			mavenPackage := mavenPackages[0]
+			Expect(mavenPackage.Type).To(Equal(metadata.MavenPackageListSourceType))
+			Expect(mavenPackage.Source.Type).To(Equal("inline"))
+
+			sourceMetadata := mavenPackage.Source.Metadata.(map[string]interface{})
+			Expect(sourceMetadata["packages"]).To(Equal([]interface{}{
+				map[string]interface{}{
+					"group_id":    "com.example",
+					"artifact_id": "demo",
+					"version":     "0.0.1-SNAPSHOT",
+					"path":        "/app/app.jar",
+				},
+				map[string]interface{}{
+					"group_id":    "org.springframework",
+					"artifact_id": "spring-core",
+					"version":     "5.2.9.RELEASE",
+					"path":        "/app/app.jar!/BOOT-INF/lib/spring-core-5.2.9.RELEASE.jar",
+				},
+			}))
+
+			By("generating a sha256 digest of the metadata content as version")
+			Expect(mavenPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+		})
+	})
+
+	Context("image without java archives", func() {
+		It("does not return maven metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch.tgz"))
+
+			Expect(selectMavenDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		})
+	})
+})
+
+func selectMavenDependencies(dependencies []metadata.Dependency) []metadata.Dependency {
+	var mavenDependencies []metadata.Dependency
+	for _, dependency := range dependencies {
+		if dependency.Type == metadata.MavenPackageListSourceType {
+			mavenDependencies = append(mavenDependencies, dependency)
+		}
+	}
+	return mavenDependencies
+}
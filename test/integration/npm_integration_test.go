@@ -0,0 +1,72 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("[npm] deplab npm", func() {
+	Context("with an image with node_modules directories", func() {
+		It("returns npm metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/npm-on-scratch.tgz"))
+
+			npmPackages := selectNpmDependencies(metadataLabel.Dependencies)
+			Expect(npmPackages).To(HaveLen(1))
+			npmPackage := npmPackages[0]
+			Expect(npmPackage.Type).To(Equal(metadata.NpmPackageListSourceType))
+			Expect(npmPackage.Source.Type).To(Equal("inline"))
+
+			sourceMetadata := npmPackage.Source.Metadata.(map[string]interface{})
+			packages := sourceMetadata["packages"].([]interface{})
+			Expect(packages).To(HaveLen(3))
+			Expect(ArePackagesSorted(packages)).To(BeTrue())
+
+			By("reading the application lockfile")
+			Expect(packages[1]).To(Equal(map[string]interface{}{
+				"package":   "ms",
+				"version":   "2.1.2",
+				"license":   "MIT",
+				"resolved":  "https://registry.npmjs.org/ms/-/ms-2.1.2.tgz",
+				"integrity": "sha512-sGkPx+VjMtmA6MX27oA4FBFELFCZZ4S4XqeGOXCv68tT+jb3vk/RyaKWP0PTKyWtmLSM0b+adUTEvbs1PEaH2w==",
+				"location":  "/app/node_modules/ms",
+			}))
+
+			By("reading the global installs")
+			Expect(packages[2]).To(Equal(map[string]interface{}{
+				"package":   "npm",
+				"version":   "6.14.8",
+				"license":   "Artistic-2.0",
+				"resolved":  "",
+				"integrity": "",
+				"location":  "/usr/local/lib/node_modules/npm",
+			}))
+
+			By("generating a sha256 digest of the metadata content as version")
+			Expect(npmPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+		})
+	})
+
+	Context("image without node_modules directories", func() {
+		It("does not return npm metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch.tgz"))
+
+			Expect(selectNpmDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		})
+	})
+})
+
+func selectNpmDependencies(dependencies []metadata.Dependency) []metadata.Dependency {
+	var npmDependencies []metadata.Dependency
+	for _, dependency := range dependencies {
+		if dependency.Type == metadata.NpmPackageListSourceType {
+			npmDependencies = append(npmDependencies, dependency)
+		}
+	}
+	return npmDependencies
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --output-image", func() {
+		var (
+			server       *httptest.Server
+			registryHost string
+			blobUploads  int32
+		)
+
+		BeforeEach(func() {
+			handler := registry.New(registry.Logger(log.New(ioutil.Discard, "", 0)))
+
+			atomic.StoreInt32(&blobUploads, 0)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPatch && strings.Contains(r.URL.Path, "/blobs/uploads/") {
+					atomic.AddInt32(&blobUploads, 1)
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			registryHost = strings.TrimPrefix(server.URL, "http://")
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("pushes the labelled image to the registry", func() {
+			metadataFile, err := ioutil.TempFile("", "")
+			Expect(err).ToNot(HaveOccurred())
+			defer test_utils.CleanupFile(metadataFile.Name())
+
+			outputImage := registryHost + "/deplab/labelled:latest"
+
+			_, _ = runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/all-file-types.tgz"),
+				"--git", pathToGitRepo,
+				"--metadata-file", metadataFile.Name(),
+				"--output-image", outputImage,
+			}, 0)
+
+			metadataFileContent := metadata.Metadata{}
+			err = json.NewDecoder(metadataFile).Decode(&metadataFileContent)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(getMetadataFromRemoteImage(outputImage)).To(Equal(metadataFileContent))
+		})
+
+		It("does not upload the layers already present in the registry", func() {
+			inputImage := registryHost + "/deplab/input:latest"
+			outputImage := registryHost + "/deplab/labelled:latest"
+
+			img, err := crane.Load(getTestAssetPath("image-archives/all-file-types.tgz"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(crane.Push(img, inputImage)).To(Succeed())
+
+			atomic.StoreInt32(&blobUploads, 0)
+
+			_, _ = runDepLab([]string{
+				"--image", inputImage,
+				"--git", pathToGitRepo,
+				"--output-image", outputImage,
+			}, 0)
+
+			By("uploading only the new config")
+			Expect(atomic.LoadInt32(&blobUploads)).To(Equal(int32(1)))
+
+			labelledImage, err := crane.Pull(outputImage)
+			Expect(err).ToNot(HaveOccurred())
+
+			inputLayers, err := img.Layers()
+			Expect(err).ToNot(HaveOccurred())
+			outputLayers, err := labelledImage.Layers()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputLayers).To(HaveLen(len(inputLayers)))
+
+			Expect(getMetadataFromRemoteImage(outputImage).Dependencies).ToNot(BeEmpty())
+		})
+	})
+})
+
+func getMetadataFromRemoteImage(ref string) metadata.Metadata {
+	img, err := crane.Pull(ref)
+	Expect(err).ToNot(HaveOccurred())
+
+	cf, err := img.ConfigFile()
+	Expect(err).ToNot(HaveOccurred())
+
+	md := metadata.Metadata{}
+	err = json.Unmarshal([]byte(cf.Config.Labels["io.deplab.metadata"]), &md)
+	Expect(err).ToNot(HaveOccurred())
+
+	return md
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --output-oci-layout", func() {
+		var outputDir string
+
+		BeforeEach(func() {
+			var err error
+			outputDir, err = ioutil.TempDir("", "output-oci-layout-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(outputDir)).To(Succeed())
+		})
+
+		It("writes the labelled image to an OCI image layout, annotated with the tag", func() {
+			metadataFile, err := ioutil.TempFile("", "")
+			Expect(err).ToNot(HaveOccurred())
+			defer test_utils.CleanupFile(metadataFile.Name())
+
+			ociLayout := filepath.Join(outputDir, "layout")
+
+			_, _ = runDepLab([]string{
+				"--image-tar", getTestAssetPath("image-archives/all-file-types.tgz"),
+				"--git", pathToGitRepo,
+				"--metadata-file", metadataFile.Name(),
+				"--output-oci-layout", ociLayout,
+				"--tag", "example.com/deplab/all-file-types:latest",
+			}, 0)
+
+			Expect(filepath.Join(ociLayout, "oci-layout")).To(BeAnExistingFile())
+			Expect(filepath.Join(ociLayout, "index.json")).To(BeAnExistingFile())
+
+			index, err := layout.ImageIndexFromPath(ociLayout)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest, err := index.IndexManifest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.Manifests).To(HaveLen(1))
+			Expect(manifest.Manifests[0].Annotations["org.opencontainers.image.ref.name"]).To(Equal("example.com/deplab/all-file-types:latest"))
+
+			img, err := index.Image(manifest.Manifests[0].Digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			cf, err := img.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+
+			md := metadata.Metadata{}
+			Expect(json.Unmarshal([]byte(cf.Config.Labels["io.deplab.metadata"]), &md)).To(Succeed())
+
+			metadataFileContent := metadata.Metadata{}
+			Expect(json.NewDecoder(metadataFile).Decode(&metadataFileContent)).To(Succeed())
+			Expect(md).To(Equal(metadataFileContent))
+		})
+
+		It("appends to an existing OCI image layout", func() {
+			for _, tag := range []string{"first", "second"} {
+				_, _ = runDepLab([]string{
+					"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+					"--git", pathToGitRepo,
+					"--output-oci-layout", outputDir,
+					"--tag", tag,
+				}, 0)
+			}
+
+			index, err := layout.ImageIndexFromPath(outputDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest, err := index.IndexManifest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manifest.Manifests).To(HaveLen(2))
+		})
+	})
+})
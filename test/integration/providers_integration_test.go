@@ -0,0 +1,66 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("deplab providers", func() {
+	It("lists the providers", func() {
+		stdOut, _ := runDepLab([]string{"providers"}, 0)
+
+		output := string(getContentsOfReader(stdOut))
+		Expect(output).To(MatchRegexp(`(?m)^rpm\s+deplab,inspect\s+files\s+packages of the rpm database$`))
+		Expect(output).To(MatchRegexp(`(?m)^kpack\s+inspect\s+config\s+`))
+	})
+
+	It("skips the providers given with --skip-providers", func() {
+		metadataLabel := runDeplabAgainstTar(getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--skip-providers", "apk,git")
+
+		Expect(selectApkDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		Expect(selectGitDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		Expect(metadataLabel.Base["id"]).To(Equal("alpine"))
+	})
+
+	It("runs only the providers given with --providers", func() {
+		metadataLabel := runDeplabAgainstTar(getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--providers", "apk")
+
+		Expect(selectApkDependencies(metadataLabel.Dependencies)).To(HaveLen(1))
+		Expect(selectGitDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		Expect(metadataLabel.Base).To(BeEmpty())
+		Expect(metadataLabel.Provenance).To(BeEmpty())
+	})
+
+	It("selects the providers of inspect", func() {
+		stdOut, _ := runDepLab([]string{"inspect",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--skip-providers", "apk",
+		}, 0)
+
+		md := metadata.Metadata{}
+		Expect(json.NewDecoder(stdOut).Decode(&md)).To(Succeed())
+		Expect(selectApkDependencies(md.Dependencies)).To(BeEmpty())
+		Expect(md.Base["id"]).To(Equal("alpine"))
+	})
+
+	It("exits with an error for an unknown provider", func() {
+		_, stdErr := runDepLab([]string{
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--metadata-file", "metadata.json",
+			"--skip-providers", "not-a-provider",
+		}, 1)
+
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: unknown provider not-a-provider, must be one of: dpkg, rpm, apk"))
+	})
+})
@@ -0,0 +1,68 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("[python] deplab python", func() {
+	Context("with an image with python distributions", func() {
+		It("returns python metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/python-on-scratch.tgz"))
+
+			pythonPackages := selectPythonDependencies(metadataLabel.Dependencies)
+			Expect(pythonPackages).To(HaveLen(1))
+			pythonPackage := pythonPackages[0]
+			Expect(pythonPackage.Type).To(Equal(metadata.PythonPackageListSourceType))
+			Expect(pythonPackage.Source.Type).To(Equal("inline"))
+
+			sourceMetadata := pythonPackage.Source.Metadata.(map[string]interface{})
+			packages := sourceMetadata["packages"].([]interface{})
+			Expect(packages).To(HaveLen(3))
+			Expect(ArePackagesSorted(packages)).To(BeTrue())
+			Expect(packages[1]).To(Equal(map[string]interface{}{
+				"package":     "requests",
+				"version":     "2.24.0",
+				"license":     "Apache 2.0",
+				"installer":   "pip",
+				"interpreter": "/opt/venv/bin/python3.8",
+				"location":    "/opt/venv/lib/python3.8/site-packages",
+			}))
+			Expect(packages[2]).To(Equal(map[string]interface{}{
+				"package":     "six",
+				"version":     "1.14.0",
+				"license":     "MIT",
+				"installer":   "",
+				"interpreter": "/usr/bin/python3",
+				"location":    "/usr/lib/python3/dist-packages",
+			}))
+
+			By("generating a sha256 digest of the metadata content as version")
+			Expect(pythonPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
+		})
+	})
+
+	Context("image without python distributions", func() {
+		It("does not return python metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/scratch.tgz"))
+
+			Expect(selectPythonDependencies(metadataLabel.Dependencies)).To(BeEmpty())
+		})
+	})
+})
+
+func selectPythonDependencies(dependencies []metadata.Dependency) []metadata.Dependency {
+	var pythonDependencies []metadata.Dependency
+	for _, dependency := range dependencies {
+		if dependency.Type == metadata.PythonPackageListSourceType {
+			pythonDependencies = append(pythonDependencies, dependency)
+		}
+	}
+	return pythonDependencies
+}
@@ -4,9 +4,9 @@
 package integration_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
-	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,49 +15,46 @@ import (
 
 var _ = Describe("[rpm] deplab rpm", func() {
 	Context("with an image with an rpm database", func() {
-		Context("rpm cli installed on the PATH", func() {
-			It("returns rpm metadata", func() {
-				metadataLabel := runDeplabAgainstTar(
-					getTestAssetPath("image-archives/photon.tgz"))
+		It("returns rpm metadata", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/photon.tgz"))
 
-				rpmPackages := selectRpmDependencies(metadataLabel.Dependencies)
-				Expect(rpmPackages).To(HaveLen(1))
-				rpmPackage := rpmPackages[0]
-				Expect(rpmPackage.Type).To(Equal(metadata.RPMPackageListSourceType))
-				Expect(rpmPackage.Source.Type).To(Equal("inline"))
-				packages := rpmPackage.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
-				Expect(packages).To(HaveLen(34))
-				Expect(ArePackagesSorted(packages)).To(BeTrue())
+			rpmPackages := selectRpmDependencies(metadataLabel.Dependencies)
+			Expect(rpmPackages).To(HaveLen(1))
+			rpmPackage := rpmPackages[0]
+			Expect(rpmPackage.Type).To(Equal(metadata.RPMPackageListSourceType))
+			Expect(rpmPackage.Source.Type).To(Equal("inline"))
+			packages := rpmPackage.Source.Metadata.(map[string]interface{})["packages"].([]interface{})
+			Expect(packages).To(HaveLen(34))
+			Expect(ArePackagesSorted(packages)).To(BeTrue())
 
-				By("generating a sha256 digest of the metadata content as version")
-				Expect(rpmPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
-			})
+			By("generating a sha256 digest of the metadata content as version")
+			Expect(rpmPackage.Source.Version["sha256"]).To(MatchRegexp(`^[0-9a-f]{64}$`))
 		})
-		Context("rpm cli is not installed on the PATH", func() {
-			It("provides a helpful error message", func() {
-				PATH := os.Getenv("PATH")
-				Expect(os.Setenv("PATH", "")).ToNot(HaveOccurred())
 
-				defer func() {
-					Expect(os.Setenv("PATH", PATH)).ToNot(HaveOccurred())
-				}()
+		It("does not need the rpm cli on the PATH", func() {
+			PATH := os.Getenv("PATH")
+			Expect(os.Setenv("PATH", "")).ToNot(HaveOccurred())
+
+			defer func() {
+				Expect(os.Setenv("PATH", PATH)).ToNot(HaveOccurred())
+			}()
 
-				f, err := ioutil.TempFile("", "")
-				Expect(err).ToNot(HaveOccurred())
-				defer func() {
-					Expect(os.Remove(f.Name())).ToNot(HaveOccurred())
-				}()
+			f, err := ioutil.TempFile("", "")
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				Expect(os.Remove(f.Name())).ToNot(HaveOccurred())
+			}()
 
-				By("executing it")
-				args := []string{"--image-tar", getTestAssetPath("image-archives/photon.tgz"), "--git", pathToGitRepo, "--metadata-file", f.Name()}
-				_, stdErr := runDepLab(args, 1)
+			By("executing it")
+			args := []string{"--image-tar", getTestAssetPath("image-archives/photon.tgz"), "--git", pathToGitRepo, "--metadata-file", f.Name()}
+			_, _ = runDepLab(args, 0)
 
-				errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+			metadataLabel := metadata.Metadata{}
+			err = json.NewDecoder(f).Decode(&metadataLabel)
+			Expect(err).ToNot(HaveOccurred())
 
-				Expect(errorOutput).To(SatisfyAll(
-					ContainSubstring("an rpm database exists at"),
-					ContainSubstring("but rpm is not installed and available on your path")))
-			})
+			Expect(selectRpmDependencies(metadataLabel.Dependencies)).To(HaveLen(1))
 		})
 	})
 	Context("image without rpm database", func() {
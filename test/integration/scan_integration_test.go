@@ -0,0 +1,153 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/scan"
+)
+
+var _ = Describe("deplab scan", func() {
+	It("exits with an error if the image is missing", func() {
+		_, stdErr := runDepLab([]string{"scan",
+			"--db", getTestAssetPath("advisories"),
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: requires exactly one of --image, --image-tar or --metadata-file"))
+	})
+
+	It("exits with an error if the advisory database is missing", func() {
+		_, stdErr := runDepLab([]string{"scan",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: requires --db"))
+	})
+
+	It("exits with an error if the severity threshold is not a severity", func() {
+		_, stdErr := runDepLab([]string{"scan",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--db", getTestAssetPath("advisories"),
+			"--fail-on", "severe",
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: --fail-on must be one of: unknown, negligible, low, medium, high, critical"))
+	})
+
+	It("prints the vulnerabilities of the packages of an image tarball", func() {
+		stdOut, _ := runDepLab([]string{"scan",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--db", getTestAssetPath("advisories"),
+			"--output", "json",
+		}, 0)
+
+		report := scan.Report{}
+		Expect(json.NewDecoder(stdOut).Decode(&report)).To(Succeed())
+
+		Expect(report.Findings).To(HaveLen(1))
+		Expect(report.Findings[0].ID).To(Equal("CVE-2018-25032"))
+		Expect(report.Findings[0].Package).To(Equal("zlib"))
+		Expect(report.Findings[0].FixedVersion).To(Equal("1.2.11-r4"))
+		Expect(report.Findings[0].Severity).To(Equal(scan.High))
+	})
+
+	It("exits with an error when finding vulnerabilities above the severity threshold", func() {
+		stdOut, stdErr := runDepLab([]string{"scan",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--db", getTestAssetPath("advisories"),
+			"--fail-on", "high",
+		}, 1)
+
+		Expect(string(getContentsOfReader(stdOut))).To(ContainSubstring("high      CVE-2018-25032  zlib     1.2.11-r3  1.2.11-r4  apk_package_list"))
+		Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("found 1 vulnerabilities of severity high or higher"))
+	})
+
+	It("succeeds when the vulnerabilities are below the severity threshold", func() {
+		runDepLab([]string{"scan",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--db", getTestAssetPath("advisories"),
+			"--fail-on", "critical",
+		}, 0)
+	})
+
+	Context("with a labelled image", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-scan-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("matches the packages of its label", func() {
+			imageTarPath := filepath.Join(dir, "image.tar")
+			runDeplabAgainstTar(getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--skip-providers", "apk")
+
+			stdOut, _ := runDepLab([]string{"scan",
+				"--image-tar", imageTarPath,
+				"--db", getTestAssetPath("advisories"),
+				"--output", "json",
+			}, 0)
+
+			report := scan.Report{}
+			Expect(json.NewDecoder(stdOut).Decode(&report)).To(Succeed())
+			Expect(report.Findings).To(BeEmpty())
+		})
+	})
+
+	Context("with a metadata file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-scan-")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("prints the vulnerabilities of its packages", func() {
+			path := filepath.Join(dir, "metadata.json")
+			Expect(metadata.WriteMetadataFile(metadata.Metadata{
+				Base: metadata.Base{"id": "debian", "version_id": "11", "version_codename": "bullseye"},
+				Dependencies: []metadata.Dependency{{
+					Type: metadata.DebianPackageListSourceType,
+					Source: metadata.Source{
+						Type: "inline",
+						Metadata: metadata.DebianPackageListSourceMetadata{Packages: []metadata.DpkgPackage{
+							{Package: "bash", Version: "5.1-2"},
+							{Package: "openssl", Version: "1.1.1n-0+deb11u1"},
+						}},
+					},
+				}},
+			}, path)).To(Succeed())
+
+			stdOut, _ := runDepLab([]string{"scan",
+				"--metadata-file", path,
+				"--db", getTestAssetPath("advisories"),
+			}, 0)
+
+			Expect(string(getContentsOfReader(stdOut))).To(Equal(
+				"SEVERITY    ID             PACKAGE  VERSION           FIXED IN  TYPE\n" +
+					"negligible  CVE-2007-6755  openssl  1.1.1n-0+deb11u1  -         debian_package_list\n"))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/signature"
+)
+
+var _ = Describe("deplab", func() {
+	Context("when called with --signing-key", func() {
+		var (
+			dir            string
+			privateKeyPath string
+			publicKeyPath  string
+			imageTarPath   string
+		)
+
+		writeKeys := func(name string) (string, string) {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			privateDER, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+			publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+			Expect(err).ToNot(HaveOccurred())
+
+			privatePath := filepath.Join(dir, name+".key")
+			publicPath := filepath.Join(dir, name+".pub")
+			Expect(ioutil.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateDER}), 0600)).To(Succeed())
+			Expect(ioutil.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)).To(Succeed())
+			return privatePath, publicPath
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "deplab-signing-")
+			Expect(err).ToNot(HaveOccurred())
+
+			privateKeyPath, publicKeyPath = writeKeys("deplab")
+			imageTarPath = filepath.Join(dir, "image.tar")
+
+			runDeplabAgainstTar(getTestAssetPath("image-archives/scratch.tgz"),
+				"--output-tar", imageTarPath,
+				"--signing-key", privateKeyPath)
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("stores the signature of the label in a companion label", func() {
+			img, err := crane.Load(imageTarPath)
+			Expect(err).ToNot(HaveOccurred())
+			config, err := img.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Config.Labels).To(HaveKey(signature.LabelName))
+			Expect(config.Config.Labels[signature.LabelName]).To(ContainSubstring(`"algorithm":"ecdsa"`))
+		})
+
+		It("verifies the signature with inspect --verify-key", func() {
+			runDepLab([]string{"inspect",
+				"--image-tar", imageTarPath,
+				"--verify-key", publicKeyPath,
+			}, 0)
+		})
+
+		It("fails to verify the signature with another key", func() {
+			_, otherPublicKeyPath := writeKeys("other")
+
+			_, stdErr := runDepLab([]string{"inspect",
+				"--image-tar", imageTarPath,
+				"--verify-key", otherPublicKeyPath,
+			}, 1)
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("invalid signature: signed with key sha256:"))
+		})
+
+		It("fails to verify the signature of a rewritten label", func() {
+			img, err := crane.Load(imageTarPath)
+			Expect(err).ToNot(HaveOccurred())
+			config, err := img.ConfigFile()
+			Expect(err).ToNot(HaveOccurred())
+
+			config.Config.Labels["io.deplab.metadata"] = `{"base":{"id":"debian"},"dependencies":[]}`
+			img, err = mutate.Config(img, config.Config)
+			Expect(err).ToNot(HaveOccurred())
+
+			rewrittenTarPath := filepath.Join(dir, "rewritten.tar")
+			Expect(crane.Save(img, "rewritten", rewrittenTarPath)).To(Succeed())
+
+			_, stdErr := runDepLab([]string{"inspect",
+				"--image-tar", rewrittenTarPath,
+				"--verify-key", publicKeyPath,
+			}, 1)
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("invalid signature: the label does not match its signature"))
+		})
+
+		It("drops the signature when labelling the image again without a signing key", func() {
+			relabelledTarPath := filepath.Join(dir, "relabelled.tar")
+			runDeplabAgainstTar(imageTarPath, "--output-tar", relabelledTarPath)
+
+			_, stdErr := runDepLab([]string{"inspect",
+				"--image-tar", relabelledTarPath,
+				"--verify-key", publicKeyPath,
+			}, 1)
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("the image has no io.deplab.signature label"))
+		})
+	})
+})
@@ -0,0 +1,96 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/gomega/gstruct"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/spdx"
+	"github.com/vmware-tanzu/dependency-labeler/test/test_utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deplab", func() {
+	Describe("when called with --spdx-file", func() {
+		Describe("and the spdx document can be written", func() {
+			It("succeeds", func() {
+				spdxDestinationPath := test_utils.ExistingFileName()
+				defer test_utils.CleanupFile(spdxDestinationPath)
+
+				_ = runDeplabAgainstTar(
+					getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+					"--spdx-file", spdxDestinationPath,
+					"--tag", "example.com/image:tag")
+
+				f, err := os.Open(spdxDestinationPath)
+				Expect(err).ToNot(HaveOccurred())
+				defer f.Close()
+
+				doc := spdx.Document{}
+				Expect(json.NewDecoder(f).Decode(&doc)).To(Succeed())
+
+				Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+				Expect(doc.Name).To(Equal("example.com/image:tag"))
+				Expect(doc.CreationInfo.Creators).To(ConsistOf("Tool: deplab-0.0.0-dev"))
+				Expect(doc.Packages).To(SatisfyAll(
+					ContainElement(MatchFields(IgnoreExtras, Fields{
+						"Name":        Equal("openjdk-jdk"),
+						"VersionInfo": Equal("11.0.6"),
+					})),
+					ContainElement(MatchFields(IgnoreExtras, Fields{
+						"VersionInfo":      Equal(commitHash),
+						"DownloadLocation": Equal("git+https://example.com/example.git@" + commitHash),
+					})),
+				))
+			})
+		})
+
+		Describe("and the spdx document can't be written", func() {
+			It("exits with an error about the file location", func() {
+				_, stdErr := runDepLab([]string{
+					"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+					"--git", pathToGitRepo,
+					"--spdx-file", "a-path-that-does-not-exist/foo.spdx.json",
+				}, 1)
+
+				Expect(string(getContentsOfReader(stdErr))).To(
+					ContainSubstring("a-path-that-does-not-exist/foo.spdx.json"))
+			})
+		})
+	})
+
+	Describe("inspect", func() {
+		It("prints an spdx document with --output spdx", func() {
+			stdOut, _ := runDepLab([]string{
+				"inspect",
+				"--image-tar", getTestAssetPath("image-archives/scratch-with-buildpack-metadata.tgz"),
+				"--output", "spdx",
+			}, 0)
+
+			doc := spdx.Document{}
+			Expect(json.NewDecoder(stdOut).Decode(&doc)).To(Succeed())
+
+			Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+			Expect(doc.Name).To(Equal("scratch-with-buildpack-metadata.tgz"))
+			Expect(doc.Packages).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Name": Equal("openjdk-jdk"),
+			})))
+		})
+
+		It("exits with an error if the output format is not supported", func() {
+			_, stdErr := runDepLab([]string{
+				"inspect",
+				"--image-tar", getTestAssetPath("image-archives/scratch.tgz"),
+				"--output", "yaml",
+			}, 1)
+
+			Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("ERROR: --output must be one of: json, spdx, cyclonedx"))
+		})
+	})
+})
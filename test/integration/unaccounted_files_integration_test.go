@@ -0,0 +1,52 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+var _ = Describe("[unaccounted-files] deplab unaccounted files", func() {
+	Context("with an image with files outside of the packages", func() {
+		It("lists the executables and archives owned by no package with --unaccounted-files", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/unaccounted-files.tgz"), "--unaccounted-files")
+
+			Expect(metadataLabel.UnaccountedFiles).To(Equal([]metadata.UnaccountedFile{
+				{
+					Path:   "/opt/app/vendor.jar",
+					Size:   17,
+					SHA256: "a3827465d05e9dccb3c8b86ab5d9bc4f798a5699961eb448b9d29c987705f7e2",
+				},
+				{
+					Path:   "/usr/local/bin/entrypoint.sh",
+					Size:   20,
+					SHA256: "7f812349eb1017d2122230d2daf3592fc5952efee881ffc42585d876687a97ee",
+				},
+			}))
+		})
+
+		It("lists them with inspect", func() {
+			stdOut, _ := runDepLab([]string{"inspect",
+				"--image-tar", getTestAssetPath("image-archives/unaccounted-files.tgz"),
+				"--unaccounted-files",
+			}, 0)
+
+			md := metadata.Metadata{}
+			Expect(json.NewDecoder(stdOut).Decode(&md)).To(Succeed())
+			Expect(md.UnaccountedFiles).To(HaveLen(2))
+		})
+
+		It("does not list them by default", func() {
+			metadataLabel := runDeplabAgainstTar(
+				getTestAssetPath("image-archives/unaccounted-files.tgz"))
+
+			Expect(metadataLabel.UnaccountedFiles).To(BeEmpty())
+		})
+	})
+})
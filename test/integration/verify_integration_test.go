@@ -0,0 +1,106 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/policy"
+)
+
+var _ = Describe("deplab verify", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "deplab-verify-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writePolicy := func(content string) string {
+		path := filepath.Join(dir, "policy.yml")
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	It("exits with an error if the policy is missing", func() {
+		_, stdErr := runDepLab([]string{"verify",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+		}, 1)
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("ERROR: requires --policy"))
+	})
+
+	It("succeeds when the image follows the policy", func() {
+		stdOut, _ := runDepLab([]string{"verify",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--policy", writePolicy(`
+required_base:
+  id: alpine
+  version_id: [">= 3.11"]
+licenses:
+  deny: [GPL-3.0*]
+`),
+		}, 0)
+
+		Expect(string(getContentsOfReader(stdOut))).To(Equal("no policy violations found\n"))
+	})
+
+	It("reports every violation and exits with an error", func() {
+		stdOut, stdErr := runDepLab([]string{"verify",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--policy", writePolicy(`
+denied_packages:
+- ecosystem: apk
+  name: zlib
+  versions: ["< 1.2.11-r4"]
+  reason: CVE-2018-25032
+required_base:
+  version_id: [">= 3.12"]
+required_git: {}
+`),
+			"--output", "json",
+		}, 1)
+
+		report := policy.Report{}
+		Expect(json.NewDecoder(stdOut).Decode(&report)).To(Succeed())
+		Expect(report.Violations).To(Equal([]policy.Violation{
+			{Rule: "denied_packages[0]", Message: "apk package zlib 1.2.11-r3 is denied: CVE-2018-25032"},
+			{Rule: "required_git", Message: "no git dependency"},
+			{Rule: "required_base.version_id", Message: `base version_id is "3.11.6", expected >= 3.12`},
+		}))
+
+		Expect(string(getContentsOfReader(stdErr))).To(ContainSubstring("found 3 policy violations"))
+	})
+
+	It("evaluates the label of a labelled image", func() {
+		imageTarPath := filepath.Join(dir, "image.tar")
+		runDeplabAgainstTar(getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--output-tar", imageTarPath,
+			"--skip-providers", "apk")
+
+		stdOut, _ := runDepLab([]string{"verify",
+			"--image-tar", imageTarPath,
+			"--policy", writePolicy(`
+denied_packages:
+- ecosystem: apk
+  name: zlib
+required_git: {}
+`),
+		}, 0)
+
+		Expect(string(getContentsOfReader(stdOut))).To(Equal("no policy violations found\n"))
+	})
+})
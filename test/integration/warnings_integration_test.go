@@ -0,0 +1,70 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vmware-tanzu/dependency-labeler/pkg/warnings"
+)
+
+var _ = Describe("deplab warnings", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "deplab-warnings-")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("summarises the warnings and writes them to the warnings file", func() {
+		warningsFile := filepath.Join(dir, "warnings.json")
+
+		_, stdErr := runDepLab([]string{
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--additional-source-url", "/foo/bar",
+			"--metadata-file", filepath.Join(dir, "metadata.json"),
+			"--ignore-validation-errors",
+			"--warnings-file", warningsFile,
+		}, 0)
+
+		errorOutput := strings.TrimSpace(string(getContentsOfReader(stdErr)))
+		Expect(errorOutput).To(ContainSubstring("1 warning:\nwarning [invalid_source_url] additional-source-url: failed to validate additional source url"))
+
+		content, err := ioutil.ReadFile(warningsFile)
+		Expect(err).ToNot(HaveOccurred())
+
+		var written []warnings.Warning
+		Expect(json.Unmarshal(content, &written)).To(Succeed())
+		Expect(written).To(ConsistOf(warnings.Warning{
+			Code:     warnings.InvalidSourceURL,
+			Provider: "additional-source-url",
+			Message:  "failed to validate additional source url: unsupported extension for url /foo/bar",
+			Subject:  "/foo/bar",
+		}))
+	})
+
+	It("writes an empty warnings file when there is no warning", func() {
+		warningsFile := filepath.Join(dir, "warnings.json")
+
+		runDepLab([]string{
+			"inspect",
+			"--image-tar", getTestAssetPath("image-archives/apk-on-scratch.tgz"),
+			"--warnings-file", warningsFile,
+		}, 0)
+
+		Expect(ioutil.ReadFile(warningsFile)).To(MatchJSON(`[]`))
+	})
+})
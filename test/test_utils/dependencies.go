@@ -13,6 +13,10 @@ func SelectRpmDependency(dependencies []metadata.Dependency) (metadata.Dependenc
 	return metadata.SelectDependency(dependencies, metadata.RPMPackageListSourceType)
 }
 
+func SelectApkDependency(dependencies []metadata.Dependency) (metadata.Dependency, bool) {
+	return metadata.SelectDependency(dependencies, metadata.ApkPackageListSourceType)
+}
+
 func SelectBuildpackDependency(dependencies []metadata.Dependency) (metadata.Dependency, bool) {
 	return metadata.SelectDependency(dependencies, metadata.BuildpackMetadataType)
 }
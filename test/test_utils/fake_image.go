@@ -0,0 +1,205 @@
+// Copyright (c) 2019-2020 VMware, Inc. All Rights Reserved.
+// SPDX-License-Identifier: BSD-2-Clause
+
+package test_utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
+	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
+)
+
+// FakeVersion is the content of a file as written by a layer
+type FakeVersion struct {
+	Layer   int
+	Content string
+}
+
+// FakeImage is an image held in memory, for the tests of the providers
+type FakeImage struct {
+	// Files are the regular files of the image, by absolute path
+	Files map[string]string
+	// Modes are the modes of the files, 0644 when missing
+	Modes map[string]os.FileMode
+	// Links are the directories linked to another one, which are only
+	// followed by GetFileContent
+	Links map[string]string
+	// History holds the versions of the files written by the layers, oldest
+	// first
+	History map[string][]FakeVersion
+
+	// ImageLayers, Config and Manifest are empty when not set
+	ImageLayers []image.Layer
+	Config      *v1.ConfigFile
+	Manifest    *v1.Manifest
+}
+
+func (m FakeImage) GetConfig() (*v1.ConfigFile, error) {
+	if m.Config == nil {
+		return &v1.ConfigFile{}, nil
+	}
+	return m.Config, nil
+}
+
+func (m FakeImage) GetManifest() (*v1.Manifest, error) {
+	if m.Manifest == nil {
+		return &v1.Manifest{}, nil
+	}
+	return m.Manifest, nil
+}
+
+func (m FakeImage) Cleanup() {}
+
+func (m FakeImage) GetFileContent(p string) (string, error) {
+	for link, target := range m.Links {
+		if strings.HasPrefix(p, link+"/") {
+			p = target + strings.TrimPrefix(p, link)
+		}
+	}
+
+	content, ok := m.Files[p]
+	if !ok {
+		return "", fmt.Errorf("could not find file %s", p)
+	}
+	return content, nil
+}
+
+// GetDirFileNames lists the names of the files and directories found in the
+// directory, from the paths of the files and of their history
+func (m FakeImage) GetDirFileNames(dir string, includeDir bool) ([]string, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	found := map[string]bool{}
+	var names []string
+	add := func(p string) {
+		if !strings.HasPrefix(p, prefix) {
+			return
+		}
+		name := strings.TrimPrefix(p, prefix)
+		isDir := strings.Contains(name, "/")
+		if isDir {
+			name = name[:strings.Index(name, "/")]
+		}
+		if (isDir && !includeDir) || found[name] {
+			return
+		}
+		found[name] = true
+		names = append(names, name)
+	}
+	for p := range m.Files {
+		add(p)
+	}
+	for p := range m.History {
+		add(p)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("could not find directory %s", dir)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m FakeImage) GetDirContents(dir string) ([]string, error) {
+	names, err := m.GetDirFileNames(dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []string
+	for _, name := range names {
+		contents = append(contents, m.Files[path.Join(dir, name)])
+	}
+	return contents, nil
+}
+
+func (m FakeImage) WalkFiles(fn image.WalkFilesFunc) error {
+	var paths []string
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		content := m.Files[p]
+		err := fn(p, NewFakeFileInfo(path.Base(p), int64(len(content)), m.mode(p)), strings.NewReader(content))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m FakeImage) Layers() ([]image.Layer, error) {
+	return m.ImageLayers, nil
+}
+
+func (m FakeImage) FileHistory(p string) ([]int, error) {
+	versions, ok := m.History[p]
+	if !ok {
+		return nil, fmt.Errorf("could not find file %s", p)
+	}
+
+	var layers []int
+	for _, v := range versions {
+		layers = append(layers, v.Layer)
+	}
+	return layers, nil
+}
+
+func (m FakeImage) GetLayerFileContent(p string, layer int) (string, error) {
+	for _, v := range m.History[p] {
+		if v.Layer == layer {
+			return v.Content, nil
+		}
+	}
+	return "", fmt.Errorf("could not find file %s in layer %d", p, layer)
+}
+
+func (m FakeImage) AbsolutePath(string) (string, error) {
+	panic("implement me")
+}
+
+func (m FakeImage) ExportWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
+}
+
+func (m FakeImage) PushWithMetadata(metadata.Metadata, string) error {
+	panic("implement me")
+}
+
+func (m FakeImage) WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
+}
+
+func (m FakeImage) mode(p string) os.FileMode {
+	if mode, ok := m.Modes[p]; ok {
+		return mode
+	}
+	return 0644
+}
+
+type fakeFileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+// NewFakeFileInfo describes a regular file
+func NewFakeFileInfo(name string, size int64, mode os.FileMode) os.FileInfo {
+	return fakeFileInfo{name: name, size: size, mode: mode}
+}
+
+func (i fakeFileInfo) Name() string       { return i.name }
+func (i fakeFileInfo) Size() int64        { return i.size }
+func (i fakeFileInfo) Mode() os.FileMode  { return i.mode }
+func (i fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (i fakeFileInfo) IsDir() bool        { return false }
+func (i fakeFileInfo) Sys() interface{}   { return nil }
@@ -4,6 +4,7 @@
 package test_utils
 
 import (
+	"github.com/vmware-tanzu/dependency-labeler/pkg/image"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	. "github.com/onsi/gomega"
 	"github.com/vmware-tanzu/dependency-labeler/pkg/metadata"
@@ -19,6 +20,10 @@ func (m MockImage) GetConfig() (*v1.ConfigFile, error) {
 	return m.config, nil
 }
 
+func (m MockImage) GetManifest() (*v1.Manifest, error) {
+	panic("implement me")
+}
+
 func NewMockImageWithEmptyConfig() MockImage {
 	return MockImage{
 		config: &v1.ConfigFile{},
@@ -41,6 +46,22 @@ func (m MockImage) GetDirContents(string) ([]string, error) {
 	return []string{}, nil
 }
 
+func (m MockImage) WalkFiles(image.WalkFilesFunc) error {
+	return nil
+}
+
+func (m MockImage) Layers() ([]image.Layer, error) {
+	panic("implement me")
+}
+
+func (m MockImage) FileHistory(string) ([]int, error) {
+	panic("implement me")
+}
+
+func (m MockImage) GetLayerFileContent(string, int) (string, error) {
+	panic("implement me")
+}
+
 func (m MockImage) AbsolutePath(string) (string, error) {
 	path, err := filepath.Abs(m.path)
 
@@ -56,4 +77,12 @@ func NewMockImageWithPath(path string) MockImage {
 
 func (m MockImage) ExportWithMetadata(metadata.Metadata, string, string) error {
 	panic("implement me")
+}
+
+func (m MockImage) PushWithMetadata(metadata.Metadata, string) error {
+	panic("implement me")
+}
+
+func (m MockImage) WriteOCILayoutWithMetadata(metadata.Metadata, string, string) error {
+	panic("implement me")
 }
\ No newline at end of file